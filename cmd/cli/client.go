@@ -0,0 +1,162 @@
+// Package main 命令行客户端：创建任务、等待执行结束并将生成的文档保存到本地目录。
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/browser-automation/internal/api/handler"
+	"github.com/browser-automation/internal/domain"
+)
+
+// client 任务 API 客户端
+type client struct {
+	base string
+	http *http.Client
+}
+
+func newClient(base string) *client {
+	return &client{
+		base: strings.TrimRight(base, "/"),
+		http: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// createTask 创建任务，返回任务 ID
+func (c *client) createTask(ctx context.Context, req *handler.CreateTaskRequest) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		TaskID string `json:"task_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/tasks", body, &resp); err != nil {
+		return "", fmt.Errorf("create task: %w", err)
+	}
+	return resp.TaskID, nil
+}
+
+// waitTask 轮询任务直到结束，状态或进度变化时在标准错误输出打印
+func (c *client) waitTask(ctx context.Context, taskID string, interval time.Duration) (*domain.Task, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		var task domain.Task
+		if err := c.do(ctx, http.MethodGet, "/api/v1/tasks/"+taskID, nil, &task); err != nil {
+			return nil, fmt.Errorf("get task: %w", err)
+		}
+		line := fmt.Sprintf("%s %d%%", task.Status, task.Progress)
+		if p := task.PendingApproval; task.Status == domain.TaskStatusWaitingForHuman && p != nil {
+			line = fmt.Sprintf("%s: step %d %q needs approval (POST /api/v1/tasks/%s/approve)", task.Status, p.StepOrder, p.Description, taskID)
+		}
+		if line != last {
+			fmt.Fprintf(os.Stderr, "Task %s %s\n", taskID, line)
+			last = line
+		}
+		if task.Status.Terminal() {
+			return &task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait task %s: %w", taskID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// saveDocuments 将任务的文档写入 <dir>/<任务 ID>/，返回文件路径。
+// download 为 false 时文档已由本地文档存储写入同一位置，只返回路径
+func (c *client) saveDocuments(ctx context.Context, task *domain.Task, dir string, download bool) ([]string, error) {
+	taskDir := filepath.Join(dir, task.ID)
+	if download && len(task.Result.Documents) > 0 {
+		if err := os.MkdirAll(taskDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	paths := make([]string, 0, len(task.Result.Documents))
+	for i := range task.Result.Documents {
+		doc := &task.Result.Documents[i]
+		path := filepath.Join(taskDir, doc.FileName())
+		if download {
+			if err := c.saveDocument(ctx, task.ID, doc, path); err != nil {
+				return paths, fmt.Errorf("save document %s: %w", doc.ID, err)
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// saveDocument 内容内联在任务中时直接写入，否则从服务下载
+func (c *client) saveDocument(ctx context.Context, taskID string, doc *domain.DocumentInfo, path string) error {
+	if doc.Content != "" {
+		return os.WriteFile(path, []byte(doc.Content), 0o644)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/api/v1/tasks/"+taskID+"/documents/"+doc.ID, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// do 发送 JSON 请求并解析响应，非 2xx 响应返回服务端的错误说明
+func (c *client) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// responseError 读取 {"error": "..."} 形式的错误响应
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var e struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &e) == nil && e.Error != "" {
+		return fmt.Errorf("%s: %s", resp.Status, e.Error)
+	}
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+}
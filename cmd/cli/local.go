@@ -0,0 +1,77 @@
+// Package main 命令行客户端：创建任务、等待执行结束并将生成的文档保存到本地目录。
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/browser-automation/internal/api"
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/config"
+	"github.com/browser-automation/internal/orchestrator"
+	"github.com/browser-automation/internal/planner"
+	"github.com/browser-automation/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// startLocalServer 在回环地址上启动与服务端相同的 API，文档存储目录即 -out。
+// 返回服务地址和关闭函数，关闭时停止编排器并关闭浏览器
+func startLocalServer(opts options) (string, func(), error) {
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 服务端日志只在 CLI_DEBUG=1 时输出，避免与进度信息混在一起
+	if os.Getenv("CLI_DEBUG") != "1" {
+		log.SetOutput(io.Discard)
+		gin.SetMode(gin.ReleaseMode)
+		gin.DefaultWriter = io.Discard
+	}
+
+	taskStore := storage.NewMemoryTaskStore()
+	docStore := storage.NewFileDocumentStore(opts.outDir)
+	llmFactory := planner.NewLLMClientFactory()
+
+	var ctrl browser.Controller
+	if opts.fake {
+		ctrl = browser.NewFakeController()
+	} else {
+		ctrl = browser.NewPlaywrightController(browser.PlaywrightOptions{
+			Headless:          os.Getenv("BROWSER_HEADLESS") != "0",
+			IgnoreHTTPSErrors: os.Getenv("BROWSER_IGNORE_HTTPS_ERRORS") == "1",
+		})
+	}
+
+	orch := orchestrator.NewOrchestrator(ctrl, taskStore, llmFactory)
+	orch.SetDocumentStore(docStore)
+	ctx, cancel := context.WithCancel(context.Background())
+	orch.Start(ctx, 1)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		cancel()
+		return "", nil, err
+	}
+	srv := &http.Server{Handler: api.SetupRouter(taskStore, docStore, llmFactory, orch, cfg)}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Local server stopped: %v", err)
+		}
+	}()
+
+	shutdown := func() {
+		shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		defer done()
+		srv.Shutdown(shutdownCtx)
+		cancel()
+		ctrl.Close(shutdownCtx)
+	}
+	return "http://" + ln.Addr().String(), shutdown, nil
+}
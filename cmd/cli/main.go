@@ -0,0 +1,152 @@
+// Package main 命令行客户端：创建任务、等待执行结束并将生成的文档保存到本地目录。
+// 默认调用已运行的服务，-local 时在本进程内启动同样的 API 执行任务
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/browser-automation/internal/api/handler"
+	"github.com/browser-automation/internal/domain"
+)
+
+// options 命令行参数
+type options struct {
+	server      string
+	local       bool
+	fake        bool
+	description string
+	targetURL   string
+	provider    string
+	model       string
+	endpoint    string
+	apiKey      string
+	formats     string
+	language    string
+	title       string
+	outDir      string
+	timeout     time.Duration
+	poll        time.Duration
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.server, "server", "http://localhost:8080", "服务地址")
+	flag.BoolVar(&opts.local, "local", false, "不连接服务，在本进程内启动浏览器执行任务")
+	flag.BoolVar(&opts.fake, "fake", false, "本地模式下使用不启动浏览器的模拟控制器（离线演示）")
+	flag.StringVar(&opts.description, "description", "", "任务描述（必填）")
+	flag.StringVar(&opts.targetURL, "url", "", "目标网站 URL（必填）")
+	flag.StringVar(&opts.provider, "provider", "openai", "LLM 提供商")
+	flag.StringVar(&opts.model, "model", "", "模型名称（必填）")
+	flag.StringVar(&opts.endpoint, "endpoint", "", "LLM 接口地址，为空时使用提供商默认地址")
+	flag.StringVar(&opts.apiKey, "api-key", os.Getenv("LLM_API_KEY"), "LLM API Key，默认读取环境变量 LLM_API_KEY")
+	flag.StringVar(&opts.formats, "formats", "", "输出格式，逗号分隔，如 markdown,html；为空时使用服务端默认格式")
+	flag.StringVar(&opts.language, "language", "", "文档语言，如 zh-CN、en")
+	flag.StringVar(&opts.title, "title", "", "文档标题")
+	flag.StringVar(&opts.outDir, "out", ".", "文档保存目录，文档写入 <out>/<任务 ID>/")
+	flag.DurationVar(&opts.timeout, "timeout", 15*time.Minute, "等待任务结束的最长时间")
+	flag.DurationVar(&opts.poll, "poll", 2*time.Second, "查询任务状态的间隔")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run 创建任务并等待结束，完成后向 stdout 逐行打印保存的文档路径
+func run(ctx context.Context, opts options, stdout io.Writer) error {
+	if opts.description == "" || opts.targetURL == "" || opts.model == "" {
+		return errors.New("-description, -url and -model are required")
+	}
+	if opts.fake && !opts.local {
+		return errors.New("-fake requires -local")
+	}
+
+	server := opts.server
+	if opts.local {
+		addr, shutdown, err := startLocalServer(opts)
+		if err != nil {
+			return err
+		}
+		defer shutdown()
+		server = addr
+	}
+	c := newClient(server)
+
+	taskID, err := c.createTask(ctx, opts.request())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Task %s created\n", taskID)
+
+	waitCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	task, err := c.waitTask(waitCtx, taskID, opts.poll)
+	if err != nil {
+		return err
+	}
+
+	// 失败的任务也可能已生成部分文档，照常保存
+	var paths []string
+	if task.Result != nil {
+		// 本地模式的文档存储目录即 -out，文档已写入
+		paths, err = c.saveDocuments(ctx, task, opts.outDir, !opts.local)
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range paths {
+		fmt.Fprintln(stdout, p)
+	}
+	if task.Status != domain.TaskStatusCompleted {
+		if task.ErrorMessage != "" {
+			return fmt.Errorf("task %s %s: %s", task.ID, task.Status, task.ErrorMessage)
+		}
+		return fmt.Errorf("task %s %s", task.ID, task.Status)
+	}
+	return nil
+}
+
+// request 由命令行参数构造创建任务的请求体
+func (o options) request() *handler.CreateTaskRequest {
+	req := &handler.CreateTaskRequest{
+		Description: o.description,
+		TargetURL:   o.targetURL,
+		LLM: &handler.LLMConfigRequest{
+			Provider: o.provider,
+			Model:    o.model,
+			Endpoint: o.endpoint,
+			APIKey:   o.apiKey,
+		},
+	}
+	if o.formats != "" || o.language != "" || o.title != "" {
+		req.Output = &handler.OutputConfigRequest{
+			Formats:  splitList(o.formats),
+			Language: o.language,
+			Title:    o.title,
+		}
+	}
+	return req
+}
+
+// splitList 按逗号拆分参数，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
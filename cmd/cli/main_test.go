@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubLLM 模拟 OpenAI 兼容接口，规划请求返回 reply，提示请求返回空提示
+func stubLLM(t *testing.T, reply string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		content := reply
+		for _, m := range req.Messages {
+			if strings.Contains(m.Content, `{"tips"`) {
+				content = `{"tips": []}`
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": content}, "finish_reason": "stop"}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// localOptions 本地模拟模式的参数，文档写入临时目录
+func localOptions(t *testing.T, endpoint string) options {
+	t.Setenv("CONFIG_FILE", "")
+	return options{
+		local:       true,
+		fake:        true,
+		description: "Create a project",
+		targetURL:   "https://app.example.com",
+		provider:    "openai",
+		model:       "gpt-test",
+		endpoint:    endpoint,
+		apiKey:      "sk-test",
+		formats:     "markdown,html",
+		outDir:      t.TempDir(),
+		timeout:     30 * time.Second,
+		poll:        20 * time.Millisecond,
+	}
+}
+
+func TestRunLocalFake(t *testing.T) {
+	llm := stubLLM(t, `{"description": "Create a project", "steps": [
+		{"order": 1, "action": "click", "target": "#new", "description": "Click New project", "screenshot": true},
+		{"order": 2, "action": "fill", "target": "#name", "value": "Demo", "description": "Enter the project name"}
+	]}`)
+	opts := localOptions(t, llm.URL)
+
+	var stdout bytes.Buffer
+	if err := run(context.Background(), opts, &stdout); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	paths := strings.Fields(stdout.String())
+	if len(paths) != 2 {
+		t.Fatalf("reported paths = %q, want one per format", paths)
+	}
+	exts := map[string]bool{}
+	for _, p := range paths {
+		if !strings.HasPrefix(p, opts.outDir+string(filepath.Separator)) {
+			t.Errorf("path %s outside -out %s", p, opts.outDir)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Errorf("reported document missing: %v", err)
+			continue
+		}
+		if !strings.Contains(string(data), "Enter the project name") {
+			t.Errorf("%s does not contain the steps", p)
+		}
+		exts[filepath.Ext(p)] = true
+	}
+	if !exts[".md"] || !exts[".html"] {
+		t.Errorf("document extensions = %v, want .md and .html", exts)
+	}
+}
+
+func TestRunLocalFakeFailure(t *testing.T) {
+	// 模型不返回计划，任务失败，run 返回错误（非零退出码）
+	llm := stubLLM(t, "sorry, I cannot help with that")
+	var stdout bytes.Buffer
+	err := run(context.Background(), localOptions(t, llm.URL), &stdout)
+	if err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("run = %v, want a failed task error", err)
+	}
+}
+
+func TestRunValidatesFlags(t *testing.T) {
+	opts := localOptions(t, "")
+	opts.local = false
+	if err := run(context.Background(), opts, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "-fake requires -local") {
+		t.Errorf("run = %v, want -fake requires -local", err)
+	}
+	opts.model = ""
+	if err := run(context.Background(), opts, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "required") {
+		t.Errorf("run = %v, want required flags error", err)
+	}
+}
@@ -2,32 +2,100 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/browser-automation/internal/api"
+	"github.com/browser-automation/internal/auth"
 	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/config"
 	"github.com/browser-automation/internal/orchestrator"
 	"github.com/browser-automation/internal/planner"
 	"github.com/browser-automation/internal/storage"
 )
 
 func main() {
+	// 加载服务端配置
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
 	// 初始化存储
 	taskStore := storage.NewMemoryTaskStore()
+	outputDir := os.Getenv("OUTPUT_DIR")
+	if outputDir == "" {
+		outputDir = "data/documents"
+	}
+	docStore := storage.NewFileDocumentStore(outputDir)
 
 	// 初始化 LLM 工厂
 	llmFactory := planner.NewLLMClientFactory()
+	logPolicy := planner.DefaultLogPolicy()
+	logPolicy.Debug = os.Getenv("LLM_DEBUG") == "1"
+	llmFactory.SetLogPolicy(logPolicy)
+	llmFactory.SetTransportOptions(planner.TransportOptions{
+		MaxIdleConnsPerHost: envInt("LLM_MAX_IDLE_CONNS_PER_HOST"),
+		MaxConnsPerHost:     envInt("LLM_MAX_CONNS_PER_HOST"),
+		IdleConnTimeout:     time.Duration(envInt("LLM_IDLE_CONN_TIMEOUT")) * time.Second,
+		DisableHTTP2:        os.Getenv("LLM_DISABLE_HTTP2") == "1",
+	})
+	// 同一接口连续失败 LLM_BREAKER_THRESHOLD 次后暂停请求 LLM_BREAKER_COOLDOWN 秒，阈值为负数时不熔断
+	llmFactory.SetCircuitBreaker(planner.CircuitBreakerOptions{
+		Threshold: envInt("LLM_BREAKER_THRESHOLD"),
+		Cooldown:  time.Duration(envInt("LLM_BREAKER_COOLDOWN")) * time.Second,
+	})
+	if v := strings.TrimSpace(os.Getenv("LLM_RETRY_JITTER")); v != "" {
+		jitter, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Fatalf("Invalid LLM_RETRY_JITTER %q: %v", v, err)
+		}
+		llmFactory.SetRetryJitter(jitter)
+	}
 
 	// 初始化浏览器控制器（非 headless 模式方便观察）
-	browserCtrl := browser.NewPlaywrightController(browser.PlaywrightOptions{
-		Headless: false, // 设为 false 可以看到浏览器操作
-	})
+	// 启动参数与证书校验仅由服务端环境变量配置
+	var browserCtrl browser.Controller
+	if os.Getenv("BROWSER_FAKE") == "1" {
+		// 离线演示：不启动浏览器，操作只记录不执行
+		log.Println("Using fake browser controller")
+		browserCtrl = browser.NewFakeController()
+	} else {
+		browserCtrl = browser.NewPlaywrightController(playwrightOptionsFromEnv())
+	}
 
 	// 初始化编排器
 	orch := orchestrator.NewOrchestrator(browserCtrl, taskStore, llmFactory)
+	orch.SetPlanCache(planner.NewMemoryPlanCache(time.Hour))
+	orch.SetDocumentStore(docStore)
+	orch.SetMaxDocumentSize(envInt("DOC_MAX_SIZE"))
+	// 代理池：逗号分隔的代理地址，任务按轮换方式分配
+	if list := os.Getenv("BROWSER_PROXIES"); list != "" {
+		pool, err := browser.ParseProxyPool(list, browser.ProxyRotation(os.Getenv("BROWSER_PROXY_ROTATION")))
+		if err != nil {
+			log.Fatalf("Failed to load proxies: %v", err)
+		}
+		log.Printf("Loaded %d proxy(ies)", pool.Len())
+		orch.SetProxyPool(pool)
+	}
+	// 服务端保存的站点凭据
+	if path := os.Getenv("CREDENTIALS_FILE"); path != "" {
+		provider, err := auth.LoadCredentialFile(path)
+		if err != nil {
+			log.Fatalf("Failed to load credentials: %v", err)
+		}
+		log.Printf("Loaded credentials for %d site(s)", provider.Len())
+		orch.SetCredentialProvider(provider)
+	}
+	// 浏览器控制器为共享实例，同一时间只执行一个任务
+	orch.Start(context.Background(), 1)
 
 	// 设置路由
-	r := api.SetupRouter(taskStore, llmFactory, orch)
+	r := api.SetupRouter(taskStore, docStore, llmFactory, orch, cfg)
 
 	// 启动服务
 	log.Println("Server starting on port 8080")
@@ -35,3 +103,33 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// playwrightOptionsFromEnv 读取浏览器启动配置
+func playwrightOptionsFromEnv() browser.PlaywrightOptions {
+	return browser.PlaywrightOptions{
+		Headless:          false, // 设为 false 可以看到浏览器操作
+		IgnoreHTTPSErrors: os.Getenv("BROWSER_IGNORE_HTTPS_ERRORS") == "1",
+		LaunchArgs:        splitEnvList(os.Getenv("BROWSER_LAUNCH_ARGS")),
+		ElementTextLength: envInt("BROWSER_ELEMENT_TEXT_LENGTH"),
+	}
+}
+
+// envInt 读取整数环境变量，未设置或无效时返回 0（使用默认值）
+func envInt(name string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name)))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// splitEnvList 按逗号拆分环境变量，忽略空项
+func splitEnvList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlaywrightOptionsFromEnv(t *testing.T) {
+	t.Setenv("BROWSER_IGNORE_HTTPS_ERRORS", "1")
+	t.Setenv("BROWSER_LAUNCH_ARGS", "--no-sandbox, --disable-gpu,,")
+	t.Setenv("BROWSER_ELEMENT_TEXT_LENGTH", "200")
+
+	opts := playwrightOptionsFromEnv()
+	if !opts.IgnoreHTTPSErrors {
+		t.Error("IgnoreHTTPSErrors not set")
+	}
+	if strings.Join(opts.LaunchArgs, " ") != "--no-sandbox --disable-gpu" {
+		t.Errorf("LaunchArgs = %q", opts.LaunchArgs)
+	}
+	if opts.ElementTextLength != 200 {
+		t.Errorf("ElementTextLength = %d", opts.ElementTextLength)
+	}
+
+	t.Setenv("BROWSER_IGNORE_HTTPS_ERRORS", "")
+	t.Setenv("BROWSER_LAUNCH_ARGS", "")
+	if opts := playwrightOptionsFromEnv(); opts.IgnoreHTTPSErrors || len(opts.LaunchArgs) != 0 {
+		t.Errorf("defaults = %+v, want certificate checks and no extra args", opts)
+	}
+}
@@ -3,7 +3,10 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/browser-automation/internal/domain"
@@ -29,14 +32,83 @@ func (h *ConfigHandler) GetLLMPresets(c *gin.Context) {
 	})
 }
 
+// modelListTimeout 查询模型列表的超时
+const modelListTimeout = 10 * time.Second
+
+// ListLLMModels 查询提供商当前可用的模型（OpenAI 兼容的 /models、Ollama 的 /api/tags），
+// 查询失败或提供商不支持时返回预设中的静态列表，source 标明来源。
+// API Key 通过 Authorization: Bearer 请求头传入，不接受查询参数，避免出现在访问日志中
+func (h *ConfigHandler) ListLLMModels(c *gin.Context) {
+	provider := domain.LLMProvider(c.Query("provider"))
+	if provider == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider is required"})
+		return
+	}
+	if c.Query("api_key") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "api_key must be sent in the Authorization header, not the query string"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), modelListTimeout)
+	defer cancel()
+
+	endpoint := c.Query("endpoint")
+	if err := checkModelEndpoint(ctx, endpoint); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid endpoint: " + err.Error()})
+		return
+	}
+	config := &domain.LLMConfig{
+		Provider: provider,
+		Endpoint: endpoint,
+		APIKey:   strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")),
+	}
+
+	models, err := h.llmFactory.ListModels(ctx, config)
+	if err == nil {
+		c.JSON(http.StatusOK, gin.H{"provider": provider, "models": models, "source": "live"})
+		return
+	}
+	models = []string{}
+	for _, preset := range domain.GetLLMPresets() {
+		if preset.Provider == provider {
+			models = preset.AvailableModels
+			break
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"provider": provider, "models": models, "source": "preset", "error": err.Error()})
+}
+
+// checkModelEndpoint 检查模型列表请求的地址，该请求由服务端发出：
+// 与某个预设默认地址同源（如本机 Ollama 的 localhost:11434）时放行，
+// 其余地址按目标网址的规则只允许 http/https，并拒绝解析到内网地址的主机
+func checkModelEndpoint(ctx context.Context, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if err := checkProbeURL(u); err != nil {
+		return err
+	}
+	for _, preset := range domain.GetLLMPresets() {
+		if p, err := url.Parse(preset.DefaultEndpoint); err == nil && p.Host != "" && p.Scheme == u.Scheme && p.Host == u.Host {
+			return nil
+		}
+	}
+	_, err = resolvePublicHost(ctx, u.Hostname())
+	return err
+}
+
 // ValidateLLMRequest LLM 验证请求
 type ValidateLLMRequest struct {
-	Provider    string  `json:"provider" binding:"required"`
-	Model       string  `json:"model" binding:"required"`
-	Endpoint    string  `json:"endpoint"`
-	APIKey      string  `json:"api_key"`
-	Temperature float64 `json:"temperature"`
-	MaxTokens   int     `json:"max_tokens"`
+	Provider    string   `json:"provider" binding:"required"`
+	Model       string   `json:"model" binding:"required"`
+	Endpoint    string   `json:"endpoint"`
+	APIKey      string   `json:"api_key"`
+	Temperature *float64 `json:"temperature"`
+	MaxTokens   int      `json:"max_tokens"`
 }
 
 // ValidateLLM 验证 LLM 配置
@@ -52,10 +124,10 @@ func (h *ConfigHandler) ValidateLLM(c *gin.Context) {
 		Model:    req.Model,
 		Endpoint: req.Endpoint,
 		APIKey:   req.APIKey,
-		Options: &domain.LLMOptions{
+		Options: domain.MergeLLMOptions(&domain.LLMOptions{
 			Temperature: req.Temperature,
 			MaxTokens:   req.MaxTokens,
-		},
+		}),
 	}
 
 	client, err := h.llmFactory.NewClient(config)
@@ -75,7 +147,7 @@ func (h *ConfigHandler) ValidateLLM(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"valid":   false,
 			"error":   err.Error(),
-			"message": "无法连接到 LLM 服务，请检查配置",
+			"message": llmErrorMessage(err),
 		})
 		return
 	}
@@ -86,6 +158,25 @@ func (h *ConfigHandler) ValidateLLM(c *gin.Context) {
 	})
 }
 
+// llmErrorMessage 按 LLM 错误分类给出提示
+func llmErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, planner.ErrLLMAuth):
+		return "LLM 认证失败，请检查 API Key 及其权限"
+	case errors.Is(err, planner.ErrLLMQuota):
+		return "LLM 账户额度不足，请充值或更换 API Key"
+	case errors.Is(err, planner.ErrLLMRateLimit):
+		return "LLM 服务限流，请稍后重试"
+	case errors.Is(err, planner.ErrLLMCircuitOpen):
+		return "LLM 服务连续失败，已暂停请求，请稍后重试"
+	case errors.Is(err, planner.ErrLLMContentFilter):
+		return "请求被 LLM 服务的内容审核拦截"
+	case errors.Is(err, planner.ErrLLMBadRequest):
+		return "LLM 服务拒绝了请求，请检查模型名称等配置"
+	}
+	return "无法连接到 LLM 服务，请检查配置"
+}
+
 // GetOutputFormats 获取支持的输出格式
 func (h *ConfigHandler) GetOutputFormats(c *gin.Context) {
 	formats := domain.GetSupportedFormats()
@@ -127,6 +218,11 @@ func (h *ConfigHandler) GetAuthTypes(c *gin.Context) {
 			"name":        "Token 注入",
 			"description": "使用 Bearer Token 或 API Key 认证",
 		},
+		{
+			"type":        "basic",
+			"name":        "HTTP Basic 认证",
+			"description": "使用用户名密码通过 HTTP Basic 认证",
+		},
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"auth_types": authTypes,
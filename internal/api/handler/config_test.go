@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/planner"
+	"github.com/gin-gonic/gin"
+)
+
+// listModels 请求 /llm/models，返回状态码与响应
+func listModels(t *testing.T, query url.Values, headers ...string) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/models", NewConfigHandler(planner.NewLLMClientFactory()).ListLLMModels)
+
+	req := httptest.NewRequest(http.MethodGet, "/models?"+query.Encode(), nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return w.Code, resp
+}
+
+func TestListLLMModelsRejectsInternalEndpoint(t *testing.T) {
+	srv, hits := countingServer(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, endpoint := range []string{srv.URL + "/v1", "http://169.254.169.254/latest", "file:///etc/passwd"} {
+		code, resp := listModels(t, url.Values{"provider": {"openai"}, "endpoint": {endpoint}})
+		if code != http.StatusBadRequest || !strings.Contains(resp["error"].(string), "invalid endpoint") {
+			t.Errorf("endpoint %s: %d %v, want 400 invalid endpoint", endpoint, code, resp)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Errorf("sent %d requests to an internal address", hits.Load())
+	}
+}
+
+func TestListLLMModelsAPIKeyHeader(t *testing.T) {
+	allowLoopback(t)
+	var auth string
+	srv, hits := countingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"data": [{"id": "gpt-b"}, {"id": "gpt-a"}]}`))
+	})
+	query := url.Values{"provider": {"openai"}, "endpoint": {srv.URL + "/v1"}}
+
+	code, resp := listModels(t, query, "Authorization", "Bearer sk-header")
+	if code != http.StatusOK || resp["source"] != "live" {
+		t.Fatalf("%d %v, want live models", code, resp)
+	}
+	if models, _ := json.Marshal(resp["models"]); string(models) != `["gpt-a","gpt-b"]` {
+		t.Errorf("models = %s", models)
+	}
+	if auth != "Bearer sk-header" {
+		t.Errorf("provider received Authorization %q", auth)
+	}
+
+	// 查询参数中的密钥会出现在访问日志里，直接拒绝
+	query.Set("api_key", "sk-query")
+	before := hits.Load()
+	if code, _ := listModels(t, query); code != http.StatusBadRequest {
+		t.Errorf("api_key query parameter: %d, want 400", code)
+	}
+	if hits.Load() != before {
+		t.Error("request with api_key query parameter reached the provider")
+	}
+}
+
+func TestCheckModelEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		ok       bool
+	}{
+		{"", true},
+		{"http://localhost:11434", true},
+		{"http://localhost:11434/v1", true},
+		{"http://localhost:8000/v1", true},
+		{"http://localhost:6379", false},
+		{"http://10.0.0.8:11434", false},
+		{"ftp://api.example.com", false},
+	}
+	for _, tt := range tests {
+		err := checkModelEndpoint(context.Background(), tt.endpoint)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkModelEndpoint(%q) = %v, want ok %v", tt.endpoint, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package handler 提供 HTTP 请求处理
+package handler
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportTask 以 ZIP 流式导出任务的文档、截图、下载文件、事件日志和清单，解压后文档中 screenshots/step_N 的相对路径可直接访问
+func (h *TaskHandler) ExportTask(c *gin.Context) {
+	taskID := c.Param("id")
+	ctx := c.Request.Context()
+
+	task, err := h.taskStore.Get(ctx, taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if task.Result == nil || len(task.Result.Documents) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "task has no documents yet"})
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, task.ID))
+	c.Status(http.StatusOK)
+
+	// 响应头已发送，之后的错误只能中断输出并记录日志
+	zw := zip.NewWriter(c.Writer)
+	if err := h.writeExport(ctx, zw, task); err != nil {
+		log.Printf("[Task %s] Export failed: %v", task.ID, err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("[Task %s] Export failed: %v", task.ID, err)
+	}
+}
+
+// writeExport 依次写入文档、截图、下载文件、事件日志和清单；未使用文件存储时只包含内联的文档
+func (h *TaskHandler) writeExport(ctx context.Context, zw *zip.Writer, task *domain.Task) error {
+	store, _ := h.docStore.(storage.ArtifactStore)
+
+	for i := range task.Result.Documents {
+		doc := &task.Result.Documents[i]
+		if doc.Content != "" || h.docStore == nil {
+			if err := addZipEntry(zw, doc.FileName(), doc.CreatedAt, strings.NewReader(doc.Content)); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := h.docStore.Open(ctx, task.ID, doc)
+		if err != nil {
+			return fmt.Errorf("open document %s: %w", doc.ID, err)
+		}
+		err = addZipEntry(zw, doc.FileName(), doc.CreatedAt, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if store == nil {
+		return nil
+	}
+
+	// 近似截图仍以各自步骤的文件名保存，按文件名去重即可
+	added := make(map[string]bool)
+	for i := range task.Result.Screenshots {
+		shot := &task.Result.Screenshots[i]
+		name := shot.FileName()
+		if added[name] {
+			continue
+		}
+		f, err := store.OpenScreenshot(ctx, task.ID, shot)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("open screenshot %s: %w", name, err)
+		}
+		err = addZipEntry(zw, name, shot.CreatedAt, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		added[name] = true
+	}
+
+	for i := range task.Result.Downloads {
+		d := &task.Result.Downloads[i]
+		if d.URL == "" {
+			continue
+		}
+		f, err := store.OpenDownload(ctx, task.ID, d)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("open download %s: %w", d.Name, err)
+		}
+		err = addZipEntry(zw, d.FileName(), d.CreatedAt, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if events, ok := h.docStore.(storage.EventStore); ok {
+		f, err := events.OpenEvents(ctx, task.ID)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+		case err != nil:
+			return fmt.Errorf("open event log: %w", err)
+		default:
+			err = addZipEntry(zw, domain.EventLogFileName, task.UpdatedAt, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	f, err := store.OpenManifest(ctx, task.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open manifest: %w", err)
+	}
+	defer f.Close()
+	return addZipEntry(zw, domain.ManifestFileName, task.UpdatedAt, f)
+}
+
+func addZipEntry(zw *zip.Writer, name string, modified time.Time, r io.Reader) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return fmt.Errorf("create zip entry %s: %w", name, err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("write zip entry %s: %w", name, err)
+	}
+	return nil
+}
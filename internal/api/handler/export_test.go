@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// imageRef 匹配 Markdown 与 HTML 文档中引用的截图相对路径
+var imageRef = regexp.MustCompile(`screenshots/step_\d+\.png`)
+
+func TestExportTaskZipMatchesDocumentReferences(t *testing.T) {
+	env := newHandlerEnv(t)
+	task := env.createTask(t, "t1", func(task *domain.Task) {
+		task.Status = domain.TaskStatusPending
+		task.Output.Formats = []domain.DocFormat{domain.DocFormatMarkdown, domain.DocFormatHTML}
+		task.LLM = planLLM(t, planner.TaskPlan{Description: "导出报表", Steps: []planner.ActionStep{
+			{Action: browser.ActionClick, Target: "#reports", Description: "打开报表", Screenshot: true},
+			{Action: browser.ActionClick, Target: "#export", Description: "点击导出", Screenshot: true},
+		}})
+	})
+
+	if w := env.do(http.MethodGet, "/api/v1/tasks/t1/export.zip", nil); w.Code != http.StatusConflict {
+		t.Errorf("export before execution status = %d, want 409", w.Code)
+	}
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	w := env.do(http.MethodGet, "/api/v1/tasks/t1/export.zip", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("status = %d %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+
+	entries := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		entries[f.Name] = string(data)
+	}
+
+	stored, _ := env.store.Get(context.Background(), "t1")
+	referenced := make(map[string]bool)
+	for _, doc := range stored.Result.Documents {
+		content, ok := entries[doc.FileName()]
+		if !ok {
+			t.Errorf("document %s missing from the zip", doc.FileName())
+			continue
+		}
+		refs := imageRef.FindAllString(content, -1)
+		if len(refs) != 2 {
+			t.Errorf("%s references %v, want both screenshots", doc.Format, refs)
+		}
+		for _, ref := range refs {
+			referenced[ref] = true
+			if !strings.HasPrefix(entries[ref], "\x89PNG") {
+				t.Errorf("%s references %s, which is not a PNG entry in the zip", doc.Format, ref)
+			}
+		}
+	}
+
+	var shots []string
+	for name := range entries {
+		if strings.HasPrefix(name, "screenshots/") {
+			shots = append(shots, name)
+			if !referenced[name] {
+				t.Errorf("%s is not referenced by any document", name)
+			}
+		}
+	}
+	sort.Strings(shots)
+	if strings.Join(shots, ",") != "screenshots/step_1.png,screenshots/step_2.png" {
+		t.Errorf("screenshot entries = %v", shots)
+	}
+	if _, ok := entries[domain.ManifestFileName]; !ok {
+		t.Error("manifest missing from the zip")
+	}
+
+	if w := env.do(http.MethodGet, "/api/v1/tasks/missing/export.zip", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown task status = %d, want 404", w.Code)
+	}
+}
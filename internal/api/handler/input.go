@@ -0,0 +1,52 @@
+// Package handler 提供 HTTP 请求处理
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// cleanText 去掉控制字符和首尾空白；multiline 为 false 时换行和制表符替换为空格
+func cleanText(s string, multiline bool) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			if multiline {
+				return r
+			}
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+// checkText 检查清理后的文本：required 时不能为空，长度不超过 max 个字符
+func checkText(field, s string, required bool, max int) error {
+	if required && s == "" {
+		return fmt.Errorf("%s is required", field)
+	}
+	if n := utf8.RuneCountInString(s); n > max {
+		return fmt.Errorf("%s is too long: %d characters, max %d", field, n, max)
+	}
+	return nil
+}
+
+// sanitizeTaskText 清理并检查任务描述与文档标题，写回请求
+func (h *TaskHandler) sanitizeTaskText(req *CreateTaskRequest) error {
+	req.Description = cleanText(req.Description, true)
+	if err := checkText("description", req.Description, true, h.config.MaxDescriptionLength()); err != nil {
+		return err
+	}
+	if req.Output != nil {
+		req.Output.Title = cleanText(req.Output.Title, false)
+		if err := checkText("title", req.Output.Title, false, h.config.MaxTitleLength()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
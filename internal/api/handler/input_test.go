@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/config"
+)
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		in        string
+		multiline bool
+		want      string
+	}{
+		{"  export the report \n", false, "export the report"},
+		{"line one\nline two\tend", false, "line one line two end"},
+		{"line one\nline two\tend", true, "line one\nline two\tend"},
+		{"bell\a and null\x00 and del\x7f", true, "bell and null and del"},
+		{"\r\nwindows\r\n", true, "windows"},
+		{"​零宽字符保留", false, "​零宽字符保留"},
+	}
+	for _, tt := range tests {
+		if got := cleanText(tt.in, tt.multiline); got != tt.want {
+			t.Errorf("cleanText(%q, %v) = %q, want %q", tt.in, tt.multiline, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeTaskText(t *testing.T) {
+	h := &TaskHandler{config: &config.Config{Task: config.TaskLimits{MaxDescriptionLength: 10, MaxTitleLength: 4}}}
+	tests := []struct {
+		name        string
+		description string
+		title       string
+		wantErr     string
+	}{
+		{"valid", " 导出本月报表 ", "报表", ""},
+		{"limit counts characters not bytes", "一二三四五六七八九十", "一二三四", ""},
+		{"blank description", " \n\t\x00 ", "", "description is required"},
+		{"description too long", "一二三四五六七八九十一", "", "description is too long: 11 characters, max 10"},
+		{"title too long", "导出", "12345", "title is too long: 5 characters, max 4"},
+		{"control characters do not count", "导出\x00\x00\x00\x00\x00\x00\x00\x00\x00", "a\x01b", ""},
+	}
+	for _, tt := range tests {
+		req := &CreateTaskRequest{Description: tt.description, Output: &OutputConfigRequest{Title: tt.title}}
+		err := h.sanitizeTaskText(req)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: err = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// 未配置时使用内置上限
+	req := &CreateTaskRequest{Description: strings.Repeat("a", config.DefaultMaxDescriptionLength+1)}
+	if err := (&TaskHandler{}).sanitizeTaskText(req); err == nil {
+		t.Error("description over the default limit accepted")
+	}
+}
+
+func TestCreateTaskCleansInput(t *testing.T) {
+	env := newHandlerEnv(t)
+	post := func(description, title string) (int, string) {
+		data, _ := json.Marshal(map[string]interface{}{
+			"description": description,
+			"target_url":  "https://app.example.com",
+			"llm":         map[string]interface{}{"provider": "openai", "model": "gpt-test", "api_key": "sk"},
+			"output":      map[string]interface{}{"title": title},
+		})
+		w := env.do(http.MethodPost, "/api/v1/tasks", strings.NewReader(string(data)), "Content-Type", "application/json")
+		return w.Code, w.Body.String()
+	}
+
+	if code, body := post("  \x00 ", ""); code != http.StatusBadRequest || !strings.Contains(body, "description is required") {
+		t.Errorf("blank description = %d %s, want 400", code, body)
+	}
+	if code, body := post("导出", strings.Repeat("长", config.DefaultMaxTitleLength+1)); code != http.StatusBadRequest || !strings.Contains(body, "title is too long") {
+		t.Errorf("long title = %d %s, want 400", code, body)
+	}
+
+	code, body := post("  导出报表\x07\n并下载  ", "月度\n报表")
+	if code != http.StatusAccepted {
+		t.Fatalf("valid task = %d %s", code, body)
+	}
+	var resp struct {
+		TaskID string `json:"task_id"`
+	}
+	json.Unmarshal([]byte(body), &resp)
+	task, err := env.store.Get(context.Background(), resp.TaskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Description != "导出报表\n并下载" || task.Output.Title != "月度 报表" {
+		t.Errorf("stored description %q title %q, want cleaned text", task.Description, task.Output.Title)
+	}
+}
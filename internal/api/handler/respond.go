@@ -0,0 +1,41 @@
+// Package handler 提供 HTTP 请求处理
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gopkg.in/yaml.v3"
+)
+
+// mimeYAML 标准 YAML 类型，binding.MIMEYAML 为旧的 application/x-yaml
+const mimeYAML = "application/yaml"
+
+// respond 按 Accept 头返回 JSON 或 YAML，默认 JSON。
+// YAML 先经 JSON 编码再转换，字段名与 JSON 保持一致
+func respond(c *gin.Context, code int, obj interface{}) {
+	format := c.NegotiateFormat(binding.MIMEJSON, mimeYAML, binding.MIMEYAML)
+	if format != mimeYAML && format != binding.MIMEYAML {
+		c.JSON(code, obj)
+		return
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode response"})
+		return
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode response"})
+		return
+	}
+	out, err := yaml.Marshal(generic)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode response"})
+		return
+	}
+	c.Data(code, mimeYAML+"; charset=utf-8", out)
+}
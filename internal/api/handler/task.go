@@ -3,12 +3,21 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/browser-automation/internal/config"
 	"github.com/browser-automation/internal/domain"
 	"github.com/browser-automation/internal/orchestrator"
+	"github.com/browser-automation/internal/planner"
 	"github.com/browser-automation/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,36 +26,92 @@ import (
 // TaskHandler 任务处理器
 type TaskHandler struct {
 	taskStore    storage.TaskStore
+	docStore     storage.DocumentStore
+	llmFactory   *planner.LLMClientFactory
 	orchestrator *orchestrator.Orchestrator
+	config       *config.Config
 }
 
-// NewTaskHandler 创建任务处理器
-func NewTaskHandler(taskStore storage.TaskStore, orch *orchestrator.Orchestrator) *TaskHandler {
+// NewTaskHandler 创建任务处理器，cfg 为 nil 时使用内置默认值
+func NewTaskHandler(taskStore storage.TaskStore, docStore storage.DocumentStore, llmFactory *planner.LLMClientFactory, orch *orchestrator.Orchestrator, cfg *config.Config) *TaskHandler {
 	return &TaskHandler{
 		taskStore:    taskStore,
+		docStore:     docStore,
+		llmFactory:   llmFactory,
 		orchestrator: orch,
+		config:       cfg,
 	}
 }
 
 // CreateTaskRequest 创建任务请求
 type CreateTaskRequest struct {
-	Description string               `json:"description" binding:"required"`
-	TargetURL   string               `json:"target_url" binding:"required,url"`
-	Auth        *AuthConfigRequest   `json:"auth,omitempty"`
-	LLM         *LLMConfigRequest    `json:"llm" binding:"required"`
-	Output      *OutputConfigRequest `json:"output,omitempty"`
+	Description       string               `json:"description" binding:"required"`
+	TargetURL         string               `json:"target_url" binding:"required,url"`
+	Auth              *AuthConfigRequest   `json:"auth,omitempty"`
+	LLM               *LLMConfigRequest    `json:"llm" binding:"required"`
+	Output            *OutputConfigRequest `json:"output,omitempty"`
+	KeepAlive         int                  `json:"keep_alive" binding:"omitempty,min=0,max=1800"` // 完成后保留浏览器会话的空闲秒数
+	Priority          int                  `json:"priority" binding:"omitempty,min=0,max=10"`     // 排队优先级 0-10，默认 0
+	Tags              []string             `json:"tags" binding:"omitempty,max=20,dive,required,max=64"`
+	NoCache           bool                 `json:"no_cache"`                                                // 跳过执行计划缓存，强制重新规划
+	NavigationRetries int                  `json:"navigation_retries" binding:"omitempty,min=0,max=10"`     // 初始导航失败重试次数
+	Locale            string               `json:"locale" binding:"omitempty,max=35"`                       // 浏览器语言区域，如 zh-CN，默认取输出语言
+	TimezoneID        string               `json:"timezone_id" binding:"omitempty,timezone"`                // 浏览器时区，如 Asia/Shanghai
+	SnapshotScope     string               `json:"snapshot_scope" binding:"omitempty,max=512"`              // 页面快照范围的 CSS 选择器，如 #app main
+	Pacing            string               `json:"pacing" binding:"omitempty,oneof=off normal human"`       // 操作节奏，human 模拟真人停顿与逐字输入
+	OnStepFailure     string               `json:"on_step_failure" binding:"omitempty,oneof=continue stop"` // 步骤最终失败时继续执行或终止任务
+	MaxTaskRetries    int                  `json:"max_task_retries" binding:"omitempty,min=0,max=5"`        // 任务整体失败后的重试次数
+	Deadline          int                  `json:"deadline" binding:"omitempty,min=0,max=86400"`            // 任务总时长上限（秒），默认 600
+	Safety            *SafetyRequest       `json:"safety,omitempty"`
+	DismissOverlays   bool                 `json:"dismiss_overlays"`                                     // 自动关闭 Cookie 同意横幅
+	SnapshotEvery     int                  `json:"snapshot_every" binding:"omitempty,min=0,max=100"`     // 每隔几步重新采集页面快照
+	MaxLLMSnapshots   int                  `json:"max_llm_snapshots" binding:"omitempty,min=0,max=1000"` // 发送给 LLM 的快照上限
+	Planning          *PlanningRequest     `json:"planning,omitempty"`
+	SuccessCriteria   *SuccessRequest      `json:"success_criteria,omitempty"`
+}
+
+// SuccessRequest 任务成功条件请求，设置的条件全部满足时任务才算完成
+type SuccessRequest struct {
+	URLPattern string `json:"url_pattern" binding:"omitempty,max=1024"` // 最终页面 URL 需匹配的正则表达式
+	Text       string `json:"text" binding:"omitempty,max=500"`         // 最终页面需出现的文本
+}
+
+// SafetyRequest 破坏性操作确认配置请求
+type SafetyRequest struct {
+	ConfirmDestructive bool     `json:"confirm_destructive"`                                      // 删除、支付等点击前暂停，等待 POST /tasks/:id/approve
+	Keywords           []string `json:"keywords" binding:"omitempty,max=50,dive,required,max=64"` // 自定义关键词，替换默认列表
+}
+
+// PlanningRequest 规划配置请求
+type PlanningRequest struct {
+	Examples         []PlanExampleRequest `json:"examples" binding:"omitempty,max=20,dive"`
+	MaxExampleTokens int                  `json:"max_example_tokens" binding:"omitempty,min=0,max=32000"` // 示例 token 预算，超出时丢弃最早的示例
+	StrictJSON       bool                 `json:"strict_json"`                                            // 响应只能是计划 JSON，不从说明文字中提取
+	Mode             string               `json:"mode" binding:"omitempty,oneof=one_shot iterative"`      // iterative 时逐页规划并执行，适合多页向导
+	MaxIterations    int                  `json:"max_iterations" binding:"omitempty,min=0,max=50"`        // iterative 模式的最大轮数，默认 10
+}
+
+// PlanExampleRequest few-shot 示例：用户任务与期望的计划 JSON
+type PlanExampleRequest struct {
+	User      string `json:"user" binding:"required"`
+	Assistant string `json:"assistant" binding:"required"`
 }
 
 // AuthConfigRequest 认证配置请求
 type AuthConfigRequest struct {
-	Type        string          `json:"type" binding:"required,oneof=none form sso manual cookie token"`
-	Username    string          `json:"username,omitempty"`
-	Password    string          `json:"password,omitempty"`
-	SSOProvider string          `json:"sso_provider,omitempty"`
-	SSOLoginURL string          `json:"sso_login_url,omitempty"`
-	Token       string          `json:"token,omitempty"`
-	SessionID   string          `json:"session_id,omitempty"`
-	Cookies     []CookieRequest `json:"cookies,omitempty"`
+	Type            string          `json:"type" binding:"required,oneof=none form sso manual cookie token basic"`
+	Username        string          `json:"username,omitempty"`
+	Password        string          `json:"password,omitempty"`
+	SSOProvider     string          `json:"sso_provider,omitempty"`
+	SSOLoginURL     string          `json:"sso_login_url,omitempty"`
+	SSODomain       string          `json:"sso_domain,omitempty"` // OIDC issuer 或 CAS 服务器地址
+	SSOClientID     string          `json:"sso_client_id,omitempty"`
+	SSOClientSecret string          `json:"sso_client_secret,omitempty"`
+	SSOCallbackURL  string          `json:"sso_callback_url,omitempty"`
+	Token           string          `json:"token,omitempty"`
+	SessionID       string          `json:"session_id,omitempty"`
+	Cookies         []CookieRequest `json:"cookies,omitempty"`
+	AllCookies      bool            `json:"all_cookies,omitempty"` // 注入全部 Cookie，不按目标域名过滤
 }
 
 // CookieRequest Cookie 请求
@@ -61,26 +126,46 @@ type CookieRequest struct {
 
 // LLMConfigRequest LLM 配置请求
 type LLMConfigRequest struct {
-	Provider    string  `json:"provider" binding:"required"`
-	Model       string  `json:"model" binding:"required"`
-	Endpoint    string  `json:"endpoint"`
-	APIKey      string  `json:"api_key,omitempty"`
-	Temperature float64 `json:"temperature"`
-	MaxTokens   int     `json:"max_tokens"`
+	Provider         string   `json:"provider" binding:"required"`
+	Model            string   `json:"model" binding:"required"`
+	Endpoint         string   `json:"endpoint"`
+	APIKey           string   `json:"api_key,omitempty"`
+	Temperature      *float64 `json:"temperature"` // 未设置时使用默认值，0 表示确定性输出
+	MaxTokens        int      `json:"max_tokens"`
+	TopP             *float64 `json:"top_p"`
+	FrequencyPenalty float64  `json:"frequency_penalty"`
+	PresencePenalty  float64  `json:"presence_penalty"`
+	Timeout          *int     `json:"timeout" binding:"omitempty,min=1"`     // 单次请求超时（秒）
+	RetryCount       *int     `json:"retry_count" binding:"omitempty,min=0"` // 失败重试次数，0 表示不重试
+	KeepAlive        string   `json:"keep_alive"`                            // Ollama 模型保活时长
+	OpenAICompat     bool     `json:"openai_compat"`                         // Ollama 使用 OpenAI 兼容接口
+	// Headers 自定义请求头，用于 LLM 网关或代理
+	Headers map[string]string `json:"headers" binding:"omitempty,max=20"`
+	// Fallbacks 备用模型，主模型过载或不可用时按顺序切换
+	Fallbacks []*LLMConfigRequest `json:"fallbacks" binding:"omitempty,max=3,dive"`
 }
 
 // OutputConfigRequest 输出配置请求
 type OutputConfigRequest struct {
-	Formats          []string `json:"formats" binding:"required,min=1"`
-	Language         string   `json:"language"`
-	Title            string   `json:"title"`
-	ScreenshotQuality int     `json:"screenshot_quality"`
-	Annotate         bool     `json:"annotate"`
-	IncludeTOC       bool     `json:"include_toc"`
-	IncludeCover     bool     `json:"include_cover"`
-	Template         string   `json:"template"`
-	LogoURL          string   `json:"logo_url"`
-	ThemeColor       string   `json:"theme_color"`
+	Formats           []string `json:"formats"` // 为空时使用服务端默认格式
+	Language          string   `json:"language"`
+	Title             string   `json:"title"`
+	ScreenshotFormat  string   `json:"screenshot_format" binding:"omitempty,oneof=png jpeg webp"`
+	ScreenshotQuality int      `json:"screenshot_quality" binding:"omitempty,min=1,max=100"`
+	FullPage          bool     `json:"full_page"`                                            // 默认截取整页，计划步骤可单独覆盖
+	ScreenshotDedup   bool     `json:"screenshot_dedup"`                                     // 去除相邻的近似截图
+	DedupThreshold    float64  `json:"dedup_threshold" binding:"omitempty,gt=0,max=1"`       // 相似度阈值，默认 0.95
+	ScreenshotDelay   int      `json:"screenshot_delay" binding:"omitempty,min=0,max=10000"` // 截图前等待的毫秒数，默认 300
+	Annotate          bool     `json:"annotate"`
+	IncludeTOC        bool     `json:"include_toc"`
+	IncludeCover      bool     `json:"include_cover"`
+	IncludeTips       bool     `json:"include_tips"`                                                  // 由 LLM 按输出语言生成步骤提示
+	FrontMatter       bool     `json:"front_matter"`                                                  // Markdown 开头输出 YAML front matter
+	Verbosity         string   `json:"verbosity" binding:"omitempty,oneof=minimal standard detailed"` // 说明文字详略，默认 standard
+	Debug             bool     `json:"debug"`                                                         // 额外生成调试产物，便于排查文档错误
+	Template          string   `json:"template"`
+	LogoURL           string   `json:"logo_url" binding:"omitempty,http_url,max=2048"`
+	ThemeColor        string   `json:"theme_color" binding:"omitempty,iscolor"` // hex、rgb() 或 hsl() 颜色
 }
 
 // CreateTask 创建任务
@@ -90,17 +175,44 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := h.sanitizeTaskText(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	success := convertSuccessCriteria(req.SuccessCriteria)
+	if err := success.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	task := &domain.Task{
-		ID:          uuid.New().String(),
-		Description: req.Description,
-		TargetURL:   req.TargetURL,
-		Status:      domain.TaskStatusPending,
-		Auth:        h.convertAuthConfig(req.Auth),
-		LLM:         h.convertLLMConfig(req.LLM),
-		Output:      h.convertOutputConfig(req.Output),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:                uuid.New().String(),
+		Description:       req.Description,
+		TargetURL:         req.TargetURL,
+		Status:            domain.TaskStatusPending,
+		Priority:          req.Priority,
+		Tags:              normalizeTags(req.Tags),
+		Auth:              h.convertAuthConfig(req.Auth),
+		LLM:               h.convertLLMConfig(req.LLM),
+		Output:            h.convertOutputConfig(req.Output),
+		KeepAlive:         req.KeepAlive,
+		NoCache:           req.NoCache,
+		NavigationRetries: req.NavigationRetries,
+		Locale:            req.Locale,
+		TimezoneID:        req.TimezoneID,
+		SnapshotScope:     strings.TrimSpace(req.SnapshotScope),
+		Pacing:            domain.Pacing(req.Pacing),
+		OnStepFailure:     domain.StepFailurePolicy(req.OnStepFailure),
+		MaxTaskRetries:    req.MaxTaskRetries,
+		Deadline:          req.Deadline,
+		Safety:            convertSafetyConfig(req.Safety),
+		DismissOverlays:   req.DismissOverlays,
+		SnapshotEvery:     req.SnapshotEvery,
+		MaxLLMSnapshots:   req.MaxLLMSnapshots,
+		Planning:          convertPlanningConfig(req.Planning),
+		SuccessCriteria:   success,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
 	}
 
 	if err := h.taskStore.Create(c.Request.Context(), task); err != nil {
@@ -108,60 +220,410 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
-	// 异步执行任务
-	go func() {
-		ctx := context.Background()
-		if err := h.orchestrator.ExecuteTask(ctx, task); err != nil {
-			log.Printf("Task execution failed: %v", err)
-		}
-	}()
-
-	c.JSON(http.StatusAccepted, gin.H{
+	// 提交后任务由执行协程修改，响应内容须在提交前确定，之后只从存储读取
+	accepted := gin.H{
 		"task_id": task.ID,
 		"status":  task.Status,
 		"message": "任务已创建，正在处理中",
-	})
+	}
+	taskID := task.ID
+
+	// 加入执行队列异步执行
+	h.orchestrator.Submit(task)
+
+	// ?wait=true 时同步等待任务结束，超时仍返回 202
+	if c.Query("wait") == "true" {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), syncWaitTimeout(c.Query("timeout")))
+		defer cancel()
+		if err := h.orchestrator.Wait(ctx, taskID); err == nil {
+			if done, err := h.taskStore.Get(c.Request.Context(), taskID); err == nil {
+				c.JSON(http.StatusOK, done)
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// maxSyncWait 同步执行最长等待时间
+const maxSyncWait = 10 * time.Minute
+
+// syncWaitTimeout 解析 timeout 参数（秒），缺省或超过上限时使用 maxSyncWait
+func syncWaitTimeout(raw string) time.Duration {
+	seconds, err := strconv.Atoi(raw)
+	if err != nil || seconds <= 0 {
+		return maxSyncWait
+	}
+	if d := time.Duration(seconds) * time.Second; d < maxSyncWait {
+		return d
+	}
+	return maxSyncWait
 }
 
 // GetTask 获取任务详情
 func (h *TaskHandler) GetTask(c *gin.Context) {
 	taskID := c.Param("id")
-	
+
+	task, err := h.taskStore.Get(c.Request.Context(), taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+
+	respond(c, http.StatusOK, task)
+}
+
+// GetTaskPlan 获取任务的执行计划
+func (h *TaskHandler) GetTaskPlan(c *gin.Context) {
+	taskID := c.Param("id")
+
 	task, err := h.taskStore.Get(c.Request.Context(), taskID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
 		return
 	}
+	if task.Plan == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "plan not generated yet", "status": task.Status})
+		return
+	}
 
-	c.JSON(http.StatusOK, task)
+	c.JSON(http.StatusOK, task.Plan)
 }
 
 // ListTasks 获取任务列表
 func (h *TaskHandler) ListTasks(c *gin.Context) {
-	tasks, err := h.taskStore.List(c.Request.Context(), 100, 0)
+	// 多个 tag 参数为 AND 语义，如 ?tag=a&tag=b
+	filter := storage.TaskFilter{Tags: normalizeTags(c.QueryArray("tag"))}
+	var err error
+	if filter.CreatedAfter, err = parseTimeQuery(c, "created_after"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.CreatedBefore, err = parseTimeQuery(c, "created_before"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tasks, err := h.taskStore.List(c.Request.Context(), filter, 100, 0)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tasks"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, http.StatusOK, gin.H{
 		"tasks": tasks,
 		"total": len(tasks),
 	})
 }
 
+// parseTimeQuery 解析 RFC3339 格式的时间查询参数，未提供时返回零值
+func parseTimeQuery(c *gin.Context, name string) (time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: expected RFC3339 time such as 2024-05-01T08:00:00Z", name)
+	}
+	return t, nil
+}
+
+// DownloadDocument 下载任务生成的文档
+func (h *TaskHandler) DownloadDocument(c *gin.Context) {
+	taskID := c.Param("id")
+	docID := c.Param("docId")
+
+	task, err := h.taskStore.Get(c.Request.Context(), taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+
+	var doc *domain.DocumentInfo
+	if task.Result != nil {
+		for i := range task.Result.Documents {
+			if task.Result.Documents[i].ID == docID {
+				doc = &task.Result.Documents[i]
+				break
+			}
+		}
+	}
+	if doc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	// 未使用文档存储时内容内联在任务中
+	var content io.ReadSeeker = strings.NewReader(doc.Content)
+	if doc.Content == "" && h.docStore != nil {
+		f, err := h.docStore.Open(c.Request.Context(), taskID, doc)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "document content not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load document"})
+			return
+		}
+		defer f.Close()
+		content = f
+	}
+
+	// 文档生成后不再修改，ID 与大小即可作为 ETag；ServeContent 负责 Range 与条件请求
+	c.Header("Content-Type", doc.Format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, doc.ID, doc.Format.Extension()))
+	c.Header("ETag", fmt.Sprintf(`"%s-%d"`, doc.ID, doc.Size))
+	http.ServeContent(c.Writer, c.Request, doc.ID+doc.Format.Extension(), doc.CreatedAt, content)
+}
+
+// GetTaskLog 获取任务事件日志（JSON Lines，每行一个事件，按发生顺序排列）
+func (h *TaskHandler) GetTaskLog(c *gin.Context) {
+	taskID := c.Param("id")
+
+	if _, err := h.taskStore.Get(c.Request.Context(), taskID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	store, ok := h.docStore.(storage.EventStore)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event log not available without file storage"})
+		return
+	}
+	f, err := store.OpenEvents(c.Request.Context(), taskID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no events recorded yet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load event log"})
+		return
+	}
+	defer f.Close()
+
+	// 执行中的任务日志仍在追加，不使用缓存校验
+	c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	io.Copy(c.Writer, f)
+}
+
+// GetManifest 获取任务产物清单（文档与截图的相对路径、尺寸及任务信息）
+func (h *TaskHandler) GetManifest(c *gin.Context) {
+	taskID := c.Param("id")
+
+	if _, err := h.taskStore.Get(c.Request.Context(), taskID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	store, ok := h.docStore.(storage.ArtifactStore)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "manifest not available without file storage"})
+		return
+	}
+	f, err := store.OpenManifest(c.Request.Context(), taskID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "manifest not generated yet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load manifest"})
+		return
+	}
+	defer f.Close()
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	http.ServeContent(c.Writer, c.Request, domain.ManifestFileName, time.Time{}, f)
+}
+
+// DownloadFile 下载 download 步骤保存的文件
+func (h *TaskHandler) DownloadFile(c *gin.Context) {
+	taskID := c.Param("id")
+	downloadID := c.Param("downloadId")
+
+	task, err := h.taskStore.Get(c.Request.Context(), taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	var d *domain.Download
+	if task.Result != nil {
+		for i := range task.Result.Downloads {
+			if task.Result.Downloads[i].ID == downloadID {
+				d = &task.Result.Downloads[i]
+				break
+			}
+		}
+	}
+	store, ok := h.docStore.(storage.ArtifactStore)
+	if d == nil || d.URL == "" || !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "download not found"})
+		return
+	}
+	f, err := store.OpenDownload(c.Request.Context(), taskID, d)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "download content not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load download"})
+		return
+	}
+	defer f.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(d.Name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": d.Name}))
+	http.ServeContent(c.Writer, c.Request, d.Name, d.CreatedAt, f)
+}
+
 // CancelTask 取消任务
 func (h *TaskHandler) CancelTask(c *gin.Context) {
 	taskID := c.Param("id")
-	
-	if err := h.taskStore.UpdateStatus(c.Request.Context(), taskID, domain.TaskStatusCancelled); err != nil {
+
+	cancelled, err := h.orchestrator.Cancel(c.Request.Context(), taskID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel task"})
 		return
 	}
+	if !cancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "task already finished"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "任务已取消"})
 }
 
+// ApproveTask 批准等待中的破坏性步骤，任务继续执行
+func (h *TaskHandler) ApproveTask(c *gin.Context) {
+	taskID := c.Param("id")
+
+	approved, err := h.orchestrator.Approve(c.Request.Context(), taskID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to approve task"})
+		return
+	}
+	if !approved {
+		c.JSON(http.StatusConflict, gin.H{"error": "task is not waiting for approval"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已批准，任务继续执行"})
+}
+
+// LiveScreenshot 返回执行中任务当前的浏览器画面（PNG）
+func (h *TaskHandler) LiveScreenshot(c *gin.Context) {
+	taskID := c.Param("id")
+
+	img, err := h.orchestrator.LiveScreenshot(c.Request.Context(), taskID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if errors.Is(err, orchestrator.ErrTaskNotRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": "task is not running"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to capture screenshot"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", img)
+}
+
+// CancelAllTasks 取消全部排队中和执行中的任务
+func (h *TaskHandler) CancelAllTasks(c *gin.Context) {
+	cancelled, skipped, err := h.orchestrator.CancelAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel tasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cancelled": cancelled,
+		"skipped":   skipped,
+	})
+}
+
+// ContinueTaskRequest 追加指令请求
+type ContinueTaskRequest struct {
+	Instruction string `json:"instruction" binding:"required"`
+}
+
+// ContinueTask 在保留的浏览器会话上继续执行追加指令
+func (h *TaskHandler) ContinueTask(c *gin.Context) {
+	taskID := c.Param("id")
+
+	var req ContinueTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.taskStore.Get(c.Request.Context(), taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if task.Status != domain.TaskStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "task is not completed"})
+		return
+	}
+	if !h.orchestrator.HasLiveSession(taskID) {
+		c.JSON(http.StatusConflict, gin.H{"error": orchestrator.ErrSessionNotAlive.Error()})
+		return
+	}
+
+	// 异步执行追加指令
+	go func() {
+		ctx := context.Background()
+		if err := h.orchestrator.ContinueTask(ctx, task, req.Instruction); err != nil {
+			log.Printf("Task continuation failed: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"task_id": task.ID,
+		"status":  domain.TaskStatusRunning,
+		"message": "追加指令已提交，正在处理中",
+	})
+}
+
+// normalizeTags 去除空白与重复标签
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// orDefault value 为空时返回 def
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
 func (h *TaskHandler) convertAuthConfig(req *AuthConfigRequest) *domain.AuthConfig {
 	if req == nil {
 		return nil
@@ -188,11 +650,16 @@ func (h *TaskHandler) convertAuthConfig(req *AuthConfigRequest) *domain.AuthConf
 			Token:    req.Token,
 		},
 		SSOConfig: &domain.SSOConfig{
-			Provider: domain.SSOProvider(req.SSOProvider),
-			LoginURL: req.SSOLoginURL,
+			Provider:     domain.SSOProvider(req.SSOProvider),
+			LoginURL:     req.SSOLoginURL,
+			Domain:       req.SSODomain,
+			ClientID:     req.SSOClientID,
+			ClientSecret: req.SSOClientSecret,
+			CallbackURL:  req.SSOCallbackURL,
 		},
-		SessionID: req.SessionID,
-		Cookies:   cookies,
+		SessionID:  req.SessionID,
+		Cookies:    cookies,
+		AllCookies: req.AllCookies,
 	}
 }
 
@@ -200,44 +667,110 @@ func (h *TaskHandler) convertLLMConfig(req *LLMConfigRequest) *domain.LLMConfig
 	if req == nil {
 		return nil
 	}
+	var fallbacks []*domain.LLMConfig
+	for _, fb := range req.Fallbacks {
+		if fb != nil {
+			fallbacks = append(fallbacks, h.convertLLMConfig(fb))
+		}
+	}
 	return &domain.LLMConfig{
 		Provider: domain.LLMProvider(req.Provider),
 		Model:    req.Model,
 		Endpoint: req.Endpoint,
 		APIKey:   req.APIKey,
-		Options: &domain.LLMOptions{
-			Temperature: req.Temperature,
-			MaxTokens:   req.MaxTokens,
-		},
+		Options: domain.MergeLLMOptions(&domain.LLMOptions{
+			Temperature:      req.Temperature,
+			MaxTokens:        req.MaxTokens,
+			TopP:             req.TopP,
+			FrequencyPenalty: req.FrequencyPenalty,
+			PresencePenalty:  req.PresencePenalty,
+			Timeout:          req.Timeout,
+			RetryCount:       req.RetryCount,
+			KeepAlive:        req.KeepAlive,
+		}),
+		OpenAICompat: req.OpenAICompat,
+		Headers:      req.Headers,
+		Fallbacks:    fallbacks,
+	}
+}
+
+func convertSafetyConfig(req *SafetyRequest) *domain.SafetyConfig {
+	if req == nil {
+		return nil
+	}
+	return &domain.SafetyConfig{
+		ConfirmDestructive: req.ConfirmDestructive,
+		Keywords:           req.Keywords,
+	}
+}
+
+func convertSuccessCriteria(req *SuccessRequest) *domain.SuccessCriteria {
+	if req == nil {
+		return nil
+	}
+	return &domain.SuccessCriteria{
+		URLPattern: strings.TrimSpace(req.URLPattern),
+		Text:       strings.TrimSpace(req.Text),
+	}
+}
+
+func convertPlanningConfig(req *PlanningRequest) *domain.PlanningConfig {
+	if req == nil {
+		return nil
+	}
+	examples := make([]domain.PlanExample, len(req.Examples))
+	for i, ex := range req.Examples {
+		examples[i] = domain.PlanExample{User: ex.User, Assistant: ex.Assistant}
+	}
+	return &domain.PlanningConfig{
+		Examples:         examples,
+		MaxExampleTokens: req.MaxExampleTokens,
+		StrictJSON:       req.StrictJSON,
+		Mode:             domain.PlanningMode(req.Mode),
+		MaxIterations:    req.MaxIterations,
 	}
 }
 
+// convertOutputConfig 转换输出配置，请求未指定的格式、语言、模板、主题色和 Logo 使用服务端默认值
 func (h *TaskHandler) convertOutputConfig(req *OutputConfigRequest) *domain.OutputConfig {
+	defaults := h.config.OutputConfig()
 	if req == nil {
-		return domain.DefaultOutputConfig()
+		return defaults
 	}
-	
-	formats := make([]domain.DocFormat, len(req.Formats))
-	for i, f := range req.Formats {
-		formats[i] = domain.DocFormat(f)
+
+	formats := defaults.Formats
+	if len(req.Formats) > 0 {
+		formats = make([]domain.DocFormat, len(req.Formats))
+		for i, f := range req.Formats {
+			formats[i] = domain.DocFormat(f)
+		}
 	}
-	
+
 	return &domain.OutputConfig{
 		Formats:  formats,
-		Language: req.Language,
+		Language: orDefault(req.Language, defaults.Language),
 		Title:    req.Title,
+		Debug:    req.Debug,
 		ScreenshotConfig: &domain.ScreenshotConf{
-			Quality:  req.ScreenshotQuality,
-			Annotate: req.Annotate,
+			Format:         domain.ScreenshotFormat(req.ScreenshotFormat),
+			Quality:        req.ScreenshotQuality,
+			FullPage:       req.FullPage,
+			Annotate:       req.Annotate,
+			Dedup:          req.ScreenshotDedup,
+			DedupThreshold: req.DedupThreshold,
+			Delay:          req.ScreenshotDelay,
 		},
 		StyleConfig: &domain.StyleConfig{
-			Template:   req.Template,
-			LogoURL:    req.LogoURL,
-			ThemeColor: req.ThemeColor,
+			Template:   orDefault(req.Template, defaults.StyleConfig.Template),
+			LogoURL:    orDefault(req.LogoURL, defaults.StyleConfig.LogoURL),
+			ThemeColor: orDefault(req.ThemeColor, defaults.StyleConfig.ThemeColor),
 		},
 		ContentConfig: &domain.ContentConfig{
 			IncludeTOC:   req.IncludeTOC,
 			IncludeCover: req.IncludeCover,
+			IncludeTips:  req.IncludeTips,
+			FrontMatter:  req.FrontMatter,
+			Verbosity:    domain.Verbosity(req.Verbosity),
 		},
 	}
 }
@@ -0,0 +1,674 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/config"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/orchestrator"
+	"github.com/browser-automation/internal/planner"
+	"github.com/browser-automation/internal/storage"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// handlerEnv 使用内存任务存储、临时目录文档存储与 FakeController 的任务处理器
+type handlerEnv struct {
+	h      *TaskHandler
+	store  *storage.MemoryTaskStore
+	docs   *storage.FileDocumentStore
+	dir    string // 文档存储根目录
+	orch   *orchestrator.Orchestrator
+	router *gin.Engine
+}
+
+func newHandlerEnv(t *testing.T) *handlerEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	env := &handlerEnv{
+		store: storage.NewMemoryTaskStore(),
+		dir:   t.TempDir(),
+	}
+	env.docs = storage.NewFileDocumentStore(env.dir)
+	factory := planner.NewLLMClientFactory()
+	env.orch = orchestrator.NewOrchestrator(browser.NewFakeController(), env.store, factory)
+	env.orch.SetDocumentStore(env.docs)
+	env.h = NewTaskHandler(env.store, env.docs, factory, env.orch, nil)
+
+	// 与 api.SetupRouter 相同的任务路由
+	env.router = gin.New()
+	tasks := env.router.Group("/api/v1/tasks")
+	tasks.POST("", env.h.CreateTask)
+	tasks.GET("", env.h.ListTasks)
+	tasks.POST("/cancel-all", env.h.CancelAllTasks)
+	tasks.GET("/:id", env.h.GetTask)
+	tasks.GET("/:id/plan", env.h.GetTaskPlan)
+	tasks.GET("/:id/log", env.h.GetTaskLog)
+	tasks.GET("/:id/live-screenshot", env.h.LiveScreenshot)
+	tasks.GET("/:id/documents/:docId", env.h.DownloadDocument)
+	tasks.GET("/:id/manifest", env.h.GetManifest)
+	tasks.GET("/:id/export.zip", env.h.ExportTask)
+	return env
+}
+
+// do 发送请求，headers 为键值对
+func (env *handlerEnv) do(method, path string, body io.Reader, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	return w
+}
+
+// createTask 保存任务，modify 可调整默认字段
+func (env *handlerEnv) createTask(t *testing.T, id string, modify func(*domain.Task)) *domain.Task {
+	t.Helper()
+	task := &domain.Task{
+		ID:          id,
+		Description: "导出报表",
+		TargetURL:   "https://app.example.com",
+		Status:      domain.TaskStatusCompleted,
+		Output:      domain.DefaultOutputConfig(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if modify != nil {
+		modify(task)
+	}
+	if err := env.store.Create(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+// saveDocument 将文档写入文档存储，任务中只保留元数据
+func (env *handlerEnv) saveDocument(t *testing.T, taskID string, content string) domain.DocumentInfo {
+	t.Helper()
+	doc := domain.DocumentInfo{
+		ID:        "doc-1",
+		Format:    domain.DocFormatMarkdown,
+		URL:       "/api/v1/tasks/" + taskID + "/documents/doc-1",
+		Size:      int64(len(content)),
+		CreatedAt: time.Now(),
+	}
+	if err := env.docs.Save(context.Background(), taskID, &doc, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestDownloadDocumentReadsFromStore(t *testing.T) {
+	env := newHandlerEnv(t)
+	doc := env.saveDocument(t, "t1", "# 来自文档存储")
+	env.createTask(t, "t1", func(task *domain.Task) {
+		task.Result = &domain.TaskResult{Documents: []domain.DocumentInfo{doc}}
+	})
+
+	w := env.do(http.MethodGet, doc.URL, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "# 来自文档存储" {
+		t.Fatalf("GET %s = %d %q, want the stored content", doc.URL, w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != domain.DocFormatMarkdown.ContentType() {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// 任务中不内联内容
+	w = env.do(http.MethodGet, "/api/v1/tasks/t1", nil)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "来自文档存储") {
+		t.Errorf("task body contains the document content: %s", w.Body)
+	}
+
+	if w := env.do(http.MethodGet, "/api/v1/tasks/t1/documents/missing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown document status = %d, want 404", w.Code)
+	}
+}
+
+// planLLM 模拟 LLM 服务，规划请求返回 plan，其余请求（如提示生成）返回空提示
+func planLLM(t *testing.T, plan planner.TaskPlan) *domain.LLMConfig {
+	t.Helper()
+	data, _ := json.Marshal(plan)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		content := string(data)
+		if strings.Contains(string(body), `{\"tips\"`) {
+			content = `{"tips": []}`
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message":       map[string]string{"role": "assistant", "content": content},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return &domain.LLMConfig{
+		Provider: domain.LLMProviderOpenAI,
+		Model:    "gpt-test",
+		Endpoint: srv.URL,
+		APIKey:   "sk-test",
+		Options:  &domain.LLMOptions{RetryCount: domain.Int(0)},
+	}
+}
+
+func TestGetTaskPlanAfterCompletion(t *testing.T) {
+	env := newHandlerEnv(t)
+	task := env.createTask(t, "t1", func(task *domain.Task) {
+		task.Status = domain.TaskStatusPending
+		task.LLM = planLLM(t, planner.TaskPlan{Description: "导出报表", Steps: []planner.ActionStep{
+			{Action: browser.ActionClick, Target: "#export", Description: "点击导出"},
+		}})
+	})
+
+	if w := env.do(http.MethodGet, "/api/v1/tasks/t1/plan", nil); w.Code != http.StatusNotFound {
+		t.Errorf("plan before execution status = %d, want 404", w.Code)
+	}
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	w := env.do(http.MethodGet, "/api/v1/tasks/t1/plan", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var plan domain.TaskPlan
+	if err := json.Unmarshal(w.Body.Bytes(), &plan); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].Target != "#export" || plan.Steps[0].Action != "click" {
+		t.Errorf("plan = %+v", plan)
+	}
+	if w := env.do(http.MethodGet, "/api/v1/tasks/missing/plan", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown task status = %d, want 404", w.Code)
+	}
+}
+
+// eventKinds 将事件日志逐行解析为 "类型:详情" 序列，便于按顺序比较
+func eventKinds(t *testing.T, data []byte) []string {
+	t.Helper()
+	var kinds []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var ev domain.TaskEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid event line %q: %v", line, err)
+		}
+		if ev.Time.IsZero() {
+			t.Errorf("event without time: %s", line)
+		}
+		switch ev.Type {
+		case domain.TaskEventStatus:
+			kinds = append(kinds, "status:"+string(ev.Status))
+		case domain.TaskEventPhase:
+			kinds = append(kinds, "phase:"+ev.Phase)
+		case domain.TaskEventStep:
+			kinds = append(kinds, fmt.Sprintf("step:%d:%s:%v", ev.Step.Order, ev.Step.Selector, ev.Step.Success))
+		case domain.TaskEventLLM:
+			kinds = append(kinds, "llm:"+ev.LLM.Model)
+		default:
+			t.Errorf("unknown event type %q", ev.Type)
+		}
+	}
+	return kinds
+}
+
+func TestTaskEventLog(t *testing.T) {
+	env := newHandlerEnv(t)
+	task := env.createTask(t, "t1", func(task *domain.Task) {
+		task.Status = domain.TaskStatusPending
+		task.LLM = planLLM(t, planner.TaskPlan{Description: "导出报表", Steps: []planner.ActionStep{
+			{Action: browser.ActionClick, Target: "#export", Description: "点击导出"},
+		}})
+	})
+
+	if w := env.do(http.MethodGet, "/api/v1/tasks/t1/log", nil); w.Code != http.StatusNotFound {
+		t.Errorf("log before execution status = %d, want 404", w.Code)
+	}
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(env.dir, "t1", domain.EventLogFileName))
+	if err != nil {
+		t.Fatalf("read event log: %v", err)
+	}
+	// 规划与文档提示生成各调用一次 LLM
+	want := []string{
+		"status:running",
+		"phase:connecting",
+		"phase:authenticating",
+		"phase:planning",
+		"llm:openai/gpt-test",
+		"phase:executing",
+		"step:1:#export:true",
+		"phase:generating",
+		"llm:openai/gpt-test",
+		"status:completed",
+	}
+	if got := eventKinds(t, data); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	w := env.do(http.MethodGet, "/api/v1/tasks/t1/log", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("log status = %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/x-ndjson") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if w.Body.String() != string(data) {
+		t.Errorf("log body differs from events.jsonl:\n%s\nvs\n%s", w.Body, data)
+	}
+	if w := env.do(http.MethodGet, "/api/v1/tasks/missing/log", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown task status = %d, want 404", w.Code)
+	}
+}
+
+// listIDs 请求任务列表，返回排序后的任务 ID
+func (env *handlerEnv) listIDs(t *testing.T, query string) []string {
+	t.Helper()
+	w := env.do(http.MethodGet, "/api/v1/tasks"+query, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/tasks%s = %d %s", query, w.Code, w.Body)
+	}
+	var resp struct {
+		Tasks []domain.Task `json:"tasks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, task := range resp.Tasks {
+		ids = append(ids, task.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestListTasksByTag(t *testing.T) {
+	env := newHandlerEnv(t)
+	env.createTask(t, "billing-guide", func(task *domain.Task) { task.Tags = []string{"billing", "guide"} })
+	env.createTask(t, "billing", func(task *domain.Task) { task.Tags = []string{"billing"} })
+	env.createTask(t, "untagged", nil)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "billing,billing-guide,untagged"},
+		{"?tag=billing", "billing,billing-guide"},
+		{"?tag=billing&tag=guide", "billing-guide"},
+		{"?tag=%20guide%20&tag=", "billing-guide"},
+		{"?tag=missing", ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(env.listIDs(t, tt.query), ","); got != tt.want {
+			t.Errorf("GET %q = [%s], want [%s]", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestListTasksByCreatedTime(t *testing.T) {
+	env := newHandlerEnv(t)
+	day := func(d int) time.Time { return time.Date(2026, 5, d, 8, 0, 0, 0, time.UTC) }
+	for _, d := range []int{1, 2, 3} {
+		env.createTask(t, fmt.Sprintf("may-%d", d), func(task *domain.Task) { task.CreatedAt = day(d) })
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"?created_after=2026-05-02T08:00:00Z", "may-2,may-3"},
+		{"?created_before=2026-05-02T08:00:00Z", "may-1"},
+		{"?created_after=2026-05-01T12:00:00Z&created_before=2026-05-03T08:00:00Z", "may-2"},
+		{"?created_after=2026-05-02T10:00:00%2B02:00", "may-2,may-3"},
+		{"?created_after=2026-06-01T00:00:00Z", ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(env.listIDs(t, tt.query), ","); got != tt.want {
+			t.Errorf("GET %q = [%s], want [%s]", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"?created_after=2026-05-02", "?created_before=yesterday"} {
+		if w := env.do(http.MethodGet, "/api/v1/tasks"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %q = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestCreateTaskWait(t *testing.T) {
+	llm := planLLM(t, planner.TaskPlan{Description: "导出报表", Steps: []planner.ActionStep{
+		{Action: browser.ActionClick, Target: "#export", Description: "点击导出"},
+	}})
+	body := func() io.Reader {
+		data, _ := json.Marshal(map[string]interface{}{
+			"description": "导出报表",
+			"target_url":  "https://app.example.com",
+			"llm":         map[string]interface{}{"provider": "openai", "model": llm.Model, "endpoint": llm.Endpoint, "api_key": "sk", "retry_count": 0},
+		})
+		return strings.NewReader(string(data))
+	}
+
+	t.Run("completes in time", func(t *testing.T) {
+		env := newHandlerEnv(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		env.orch.Start(ctx, 1)
+
+		w := env.do(http.MethodPost, "/api/v1/tasks?wait=true&timeout=30", body(), "Content-Type", "application/json")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		var task domain.Task
+		if err := json.Unmarshal(w.Body.Bytes(), &task); err != nil {
+			t.Fatal(err)
+		}
+		if task.Status != domain.TaskStatusCompleted || task.Result == nil || len(task.Result.Steps) != 1 {
+			t.Errorf("task = %s, result %+v", task.Status, task.Result)
+		}
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		// 未启动 worker，任务一直排队
+		env := newHandlerEnv(t)
+		start := time.Now()
+		w := env.do(http.MethodPost, "/api/v1/tasks?wait=true&timeout=1", body(), "Content-Type", "application/json")
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second || elapsed > 5*time.Second {
+			t.Errorf("returned after %s, want about 1s", elapsed)
+		}
+		var resp struct {
+			TaskID string `json:"task_id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if task, err := env.store.Get(context.Background(), resp.TaskID); err != nil || task.Status != domain.TaskStatusPending {
+			t.Errorf("stored task = %v, %v; want pending", task, err)
+		}
+	})
+}
+
+func TestSyncWaitTimeout(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want time.Duration
+	}{
+		{"", maxSyncWait},
+		{"abc", maxSyncWait},
+		{"-5", maxSyncWait},
+		{"30", 30 * time.Second},
+		{"3600", maxSyncWait},
+	}
+	for _, tt := range tests {
+		if got := syncWaitTimeout(tt.raw); got != tt.want {
+			t.Errorf("syncWaitTimeout(%q) = %s, want %s", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadDocumentRange(t *testing.T) {
+	env := newHandlerEnv(t)
+	content := "0123456789abcdefghij"
+	doc := env.saveDocument(t, "t1", content)
+	env.createTask(t, "t1", func(task *domain.Task) {
+		task.Result = &domain.TaskResult{Documents: []domain.DocumentInfo{doc}}
+	})
+
+	w := env.do(http.MethodGet, doc.URL, nil, "Range", "bytes=10-14")
+	if w.Code != http.StatusPartialContent || w.Body.String() != "abcde" {
+		t.Fatalf("range response = %d %q, want 206 \"abcde\"", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 10-14/20" {
+		t.Errorf("Content-Range = %q", got)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Last-Modified") == "" || w.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("headers = %v, want ETag, Last-Modified and Accept-Ranges", w.Header())
+	}
+
+	// 断点续传：If-Range 与 ETag 一致时只返回剩余部分
+	w = env.do(http.MethodGet, doc.URL, nil, "Range", "bytes=15-", "If-Range", etag)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "fghij" {
+		t.Errorf("resumed response = %d %q", w.Code, w.Body)
+	}
+	if w := env.do(http.MethodGet, doc.URL, nil, "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("conditional GET status = %d, want 304", w.Code)
+	}
+	if w := env.do(http.MethodGet, doc.URL, nil, "Range", "bytes=50-60"); w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("out of range status = %d, want 416", w.Code)
+	}
+}
+
+// blockingLLM 模拟规划请求一直挂起直到客户端取消的 LLM 服务，任务停留在执行中
+func blockingLLM(t *testing.T) *domain.LLMConfig {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	return &domain.LLMConfig{Provider: domain.LLMProviderOpenAI, Model: "gpt-test", Endpoint: srv.URL, APIKey: "sk",
+		Options: &domain.LLMOptions{RetryCount: domain.Int(0)}}
+}
+
+// waitRunning 等待任务进入执行中
+func (env *handlerEnv) waitRunning(t *testing.T, id string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if task, _ := env.store.Get(context.Background(), id); task.Status == domain.TaskStatusRunning {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not start", id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCancelAllTasks(t *testing.T) {
+	env := newHandlerEnv(t)
+	llm := blockingLLM(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	env.orch.Start(ctx, 1)
+
+	var ids []string
+	for _, id := range []string{"t1", "t2", "t3"} {
+		task := env.createTask(t, id, func(task *domain.Task) {
+			task.Status = domain.TaskStatusPending
+			task.LLM = llm
+		})
+		env.orch.Submit(task)
+		ids = append(ids, id)
+	}
+	env.createTask(t, "done", nil)
+	env.waitRunning(t, "t1")
+
+	w := env.do(http.MethodPost, "/api/v1/tasks/cancel-all", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp struct{ Cancelled, Skipped int }
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Cancelled != 3 || resp.Skipped != 1 {
+		t.Errorf("cancelled %d skipped %d, want 3 and 1", resp.Cancelled, resp.Skipped)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer waitCancel()
+	for _, id := range ids {
+		if err := env.orch.Wait(waitCtx, id); err != nil {
+			t.Fatalf("wait %s: %v", id, err)
+		}
+		if task, _ := env.store.Get(ctx, id); task.Status != domain.TaskStatusCancelled {
+			t.Errorf("%s status = %s, want cancelled", id, task.Status)
+		}
+	}
+	if task, _ := env.store.Get(ctx, "done"); task.Status != domain.TaskStatusCompleted {
+		t.Errorf("finished task status = %s", task.Status)
+	}
+}
+
+func TestTaskResponseNegotiation(t *testing.T) {
+	env := newHandlerEnv(t)
+	env.createTask(t, "t1", nil)
+
+	tests := []struct {
+		accept   string
+		wantType string
+	}{
+		{"", "application/json"},
+		{"application/json", "application/json"},
+		{"application/yaml", "application/yaml"},
+		{"application/x-yaml", "application/yaml"},
+		{"text/html, application/yaml;q=0.9", "application/yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			decode := json.Unmarshal
+			if tt.wantType == "application/yaml" {
+				decode = yaml.Unmarshal
+			}
+
+			w := env.do(http.MethodGet, "/api/v1/tasks/t1", nil, "Accept", tt.accept)
+			if ct := w.Header().Get("Content-Type"); w.Code != http.StatusOK || !strings.HasPrefix(ct, tt.wantType) {
+				t.Fatalf("GET task = %d %q, want %s", w.Code, ct, tt.wantType)
+			}
+			var task map[string]interface{}
+			if err := decode(w.Body.Bytes(), &task); err != nil || task["id"] != "t1" || task["target_url"] != "https://app.example.com" {
+				t.Errorf("task = %v (%v)", task, err)
+			}
+
+			w = env.do(http.MethodGet, "/api/v1/tasks", nil, "Accept", tt.accept)
+			if ct := w.Header().Get("Content-Type"); w.Code != http.StatusOK || !strings.HasPrefix(ct, tt.wantType) {
+				t.Fatalf("GET tasks = %d %q, want %s", w.Code, ct, tt.wantType)
+			}
+			var list struct {
+				Tasks []map[string]interface{} `json:"tasks" yaml:"tasks"`
+				Total int                      `json:"total" yaml:"total"`
+			}
+			if err := decode(w.Body.Bytes(), &list); err != nil || list.Total != 1 || len(list.Tasks) != 1 || list.Tasks[0]["id"] != "t1" {
+				t.Errorf("list = %+v (%v)", list, err)
+			}
+		})
+	}
+}
+
+func TestLiveScreenshot(t *testing.T) {
+	env := newHandlerEnv(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	env.orch.Start(ctx, 1)
+
+	task := env.createTask(t, "running", func(task *domain.Task) {
+		task.Status = domain.TaskStatusPending
+		task.LLM = blockingLLM(t)
+	})
+	env.orch.Submit(task)
+	env.createTask(t, "done", nil)
+	env.waitRunning(t, "running")
+
+	w := env.do(http.MethodGet, "/api/v1/tasks/running/live-screenshot", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("running task = %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Header().Get("Cache-Control"))
+	}
+	if !strings.HasPrefix(w.Body.String(), "\x89PNG") {
+		t.Errorf("body is not a PNG: % x", w.Body.Bytes()[:min(8, w.Body.Len())])
+	}
+
+	if w := env.do(http.MethodGet, "/api/v1/tasks/done/live-screenshot", nil); w.Code != http.StatusConflict {
+		t.Errorf("finished task = %d, want 409", w.Code)
+	}
+	if w := env.do(http.MethodGet, "/api/v1/tasks/missing/live-screenshot", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown task = %d, want 404", w.Code)
+	}
+
+	env.orch.Cancel(ctx, "running")
+	waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer waitCancel()
+	env.orch.Wait(waitCtx, "running")
+	if w := env.do(http.MethodGet, "/api/v1/tasks/running/live-screenshot", nil); w.Code != http.StatusConflict {
+		t.Errorf("cancelled task = %d, want 409", w.Code)
+	}
+}
+
+func TestServerOutputDefaults(t *testing.T) {
+	h := NewTaskHandler(nil, nil, nil, nil, &config.Config{Output: config.OutputDefaults{
+		Formats:    []string{"html"},
+		Language:   "en",
+		Template:   "corporate",
+		ThemeColor: "#112233",
+	}})
+
+	tests := []struct {
+		name         string
+		req          *OutputConfigRequest
+		wantFormats  string
+		wantLanguage string
+		wantTemplate string
+		wantColor    string
+	}{
+		{"no output config", nil, "html", "en", "corporate", "#112233"},
+		{"request omits fields", &OutputConfigRequest{Title: "Guide"}, "html", "en", "corporate", "#112233"},
+		{"request overrides", &OutputConfigRequest{Formats: []string{"markdown", "pdf"}, Language: "zh", Template: "simple", ThemeColor: "#FF0000"},
+			"markdown,pdf", "zh", "simple", "#FF0000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := h.convertOutputConfig(tt.req)
+			var formats []string
+			for _, f := range out.Formats {
+				formats = append(formats, string(f))
+			}
+			if strings.Join(formats, ",") != tt.wantFormats || out.Language != tt.wantLanguage ||
+				out.StyleConfig.Template != tt.wantTemplate || out.StyleConfig.ThemeColor != tt.wantColor {
+				t.Errorf("output = formats %v language %q style %+v", formats, out.Language, out.StyleConfig)
+			}
+		})
+	}
+
+	// 未配置服务端默认值时使用内置默认值
+	builtin := domain.DefaultOutputConfig()
+	out := NewTaskHandler(nil, nil, nil, nil, nil).convertOutputConfig(&OutputConfigRequest{})
+	if out.Language != builtin.Language || out.StyleConfig.ThemeColor != builtin.StyleConfig.ThemeColor || out.Formats[0] != builtin.Formats[0] {
+		t.Errorf("builtin defaults not applied: %+v", out)
+	}
+}
+
+func TestLLMConfigKeepsExplicitZeroSampling(t *testing.T) {
+	tests := []struct {
+		body     string
+		wantTemp *float64
+		wantTopP *float64
+	}{
+		{`{"provider": "openai", "model": "gpt", "temperature": 0, "top_p": 0}`, domain.Float64(0), domain.Float64(0)},
+		{`{"provider": "openai", "model": "gpt", "temperature": 0.2}`, domain.Float64(0.2), domain.Float64(1)},
+		{`{"provider": "openai", "model": "gpt"}`, domain.Float64(0.7), domain.Float64(1)},
+	}
+	h := NewTaskHandler(nil, nil, nil, nil, nil)
+	for _, tt := range tests {
+		var req LLMConfigRequest
+		if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+			t.Fatal(err)
+		}
+		opts := h.convertLLMConfig(&req).Options
+		if *opts.Temperature != *tt.wantTemp || *opts.TopP != *tt.wantTopP {
+			t.Errorf("%s: temperature %v top_p %v", tt.body, *opts.Temperature, *opts.TopP)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Package handler 提供 HTTP 请求处理
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// ValidationSection 单项校验结果
+type ValidationSection struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (s *ValidationSection) fail(format string, args ...interface{}) {
+	s.Valid = false
+	s.Errors = append(s.Errors, fmt.Sprintf(format, args...))
+}
+
+func (s *ValidationSection) warn(format string, args ...interface{}) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+}
+
+// ValidationReport 任务配置校验报告
+type ValidationReport struct {
+	Valid    bool                          `json:"valid"`
+	Sections map[string]*ValidationSection `json:"sections"`
+}
+
+// ValidateTask 预检任务配置（任务文本、目标地址、认证、LLM、输出、成功条件），不创建任务
+func (h *TaskHandler) ValidateTask(c *gin.Context) {
+	// 不使用 binding 校验，以便逐项返回问题
+	var req CreateTaskRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	report := &ValidationReport{
+		Valid: true,
+		Sections: map[string]*ValidationSection{
+			"task":       h.validateTaskText(&req),
+			"target_url": validateTargetURL(ctx, req.TargetURL),
+			"auth":       validateAuth(req.Auth, h.orchestrator.HasCredentials(req.TargetURL)),
+			"llm":        h.validateLLM(ctx, req.LLM),
+			"output":     validateOutput(req.Output),
+			"success":    validateSuccess(req.SuccessCriteria),
+		},
+	}
+	for _, section := range report.Sections {
+		if !section.Valid {
+			report.Valid = false
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// validateTaskText 按创建任务时的规则检查描述与标题
+func (h *TaskHandler) validateTaskText(req *CreateTaskRequest) *ValidationSection {
+	section := &ValidationSection{Valid: true}
+	if err := h.sanitizeTaskText(req); err != nil {
+		section.fail("%v", err)
+	}
+	return section
+}
+
+// validateSuccess 检查成功条件是否完整、URL 正则表达式是否有效
+func validateSuccess(req *SuccessRequest) *ValidationSection {
+	section := &ValidationSection{Valid: true}
+	if err := convertSuccessCriteria(req).Validate(); err != nil {
+		section.fail("%v", err)
+	}
+	return section
+}
+
+// validateTargetURL 检查协议、主机解析及可达性。探测请求由服务端发出，
+// 解析到内网地址时直接判为无效且不发起请求，避免被用来探测内部网络
+func validateTargetURL(ctx context.Context, raw string) *ValidationSection {
+	section := &ValidationSection{Valid: true}
+	if raw == "" {
+		section.fail("target_url is required")
+		return section
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		section.fail("invalid url: %v", err)
+		return section
+	}
+	if err := checkProbeURL(u); err != nil {
+		section.fail("%v", err)
+		return section
+	}
+	if _, err := resolvePublicHost(ctx, u.Hostname()); err != nil {
+		section.fail("%v", err)
+		return section
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		section.fail("create request: %v", err)
+		return section
+	}
+	resp, err := newProbeClient().Do(req)
+	if err != nil {
+		section.fail("url not reachable: %v", err)
+		return section
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		section.warn("url responded with %s", resp.Status)
+	}
+	return section
+}
+
+// internalAddress 判断是否为回环、私有、链路本地或未指定地址，测试中可替换
+var internalAddress = func(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+}
+
+// checkProbeURL 检查探测地址的协议与主机
+func checkProbeURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q, only http and https are allowed", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("url has no host")
+	}
+	return nil
+}
+
+// resolvePublicHost 解析主机，任一地址为内网地址时返回错误
+func resolvePublicHost(ctx context.Context, host string) ([]net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("resolve host: %w", err)
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if internalAddress(addr.IP) {
+			return nil, fmt.Errorf("host resolves to internal address %s", addr.IP)
+		}
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// newProbeClient 创建可达性探测客户端：每次重定向按相同规则重新校验；
+// 建立连接时重新解析并只连接校验过的 IP，防止 DNS 重绑定绕过检查。不使用环境代理
+func newProbeClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			ips, err := resolvePublicHost(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			var lastErr error
+			for _, ip := range ips {
+				conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+				if err == nil {
+					return conn, nil
+				}
+				lastErr = err
+			}
+			return nil, lastErr
+		},
+		TLSHandshakeTimeout: 5 * time.Second,
+	}
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if err := checkProbeURL(req.URL); err != nil {
+				return err
+			}
+			_, err := resolvePublicHost(req.Context(), req.URL.Hostname())
+			return err
+		},
+	}
+}
+
+// validateAuth 检查认证配置各类型所需字段是否齐全，serverCreds 表示服务端保存了目标站点的凭据
+func validateAuth(req *AuthConfigRequest, serverCreds bool) *ValidationSection {
+	section := &ValidationSection{Valid: true}
+	if req == nil {
+		return section
+	}
+	switch domain.AuthType(req.Type) {
+	case domain.AuthTypeNone, domain.AuthTypeManual:
+	case domain.AuthTypeForm:
+		if (req.Username == "" || req.Password == "") && !serverCreds {
+			section.fail("form auth requires username and password")
+		}
+	case domain.AuthTypeCookie:
+		if len(req.Cookies) == 0 {
+			section.fail("cookie auth requires at least one cookie")
+		}
+		for i, cookie := range req.Cookies {
+			if cookie.Name == "" || cookie.Domain == "" {
+				section.fail("cookie %d requires name and domain", i)
+			}
+		}
+	case domain.AuthTypeToken:
+		if req.Token == "" && !serverCreds {
+			section.fail("token auth requires token")
+		}
+	case domain.AuthTypeBasic:
+		if req.Username == "" && !serverCreds {
+			section.fail("basic auth requires username")
+		}
+	case domain.AuthTypeSSO:
+		switch domain.SSOProvider(req.SSOProvider) {
+		case domain.SSOProviderOIDC:
+			if req.SSODomain == "" || req.SSOClientID == "" || req.SSOCallbackURL == "" {
+				section.fail("oidc requires sso_domain, sso_client_id and sso_callback_url")
+			}
+		case domain.SSOProviderCAS:
+			if req.SSODomain == "" || req.SSOCallbackURL == "" {
+				section.fail("cas requires sso_domain and sso_callback_url")
+			}
+		case "":
+			section.warn("sso_provider not set, generic sso flow will be used")
+		}
+		if (req.Username == "" || req.Password == "") && !serverCreds {
+			section.warn("no credentials provided, sso login must already be established")
+		}
+	default:
+		section.fail("unsupported auth type %q", req.Type)
+	}
+	return section
+}
+
+// validateLLM 检查提供商与模型，并实际调用一次验证连通性
+func (h *TaskHandler) validateLLM(ctx context.Context, req *LLMConfigRequest) *ValidationSection {
+	section := &ValidationSection{Valid: true}
+	if req == nil {
+		section.fail("llm config is required")
+		return section
+	}
+	if req.Provider == "" || req.Model == "" {
+		section.fail("llm provider and model are required")
+		return section
+	}
+	known := false
+	for _, preset := range domain.GetLLMPresets() {
+		if string(preset.Provider) == req.Provider {
+			known = true
+			break
+		}
+	}
+	if !known {
+		section.warn("unknown provider %q, the OpenAI-compatible client will be used", req.Provider)
+	}
+
+	client, err := h.llmFactory.NewClient(h.convertLLMConfig(req))
+	if err != nil {
+		section.fail("create llm client: %v", err)
+		return section
+	}
+	if err := client.Validate(ctx); err != nil {
+		section.fail("llm not reachable: %v", err)
+	}
+	return section
+}
+
+// validateOutput 检查输出格式与截图配置
+func validateOutput(req *OutputConfigRequest) *ValidationSection {
+	section := &ValidationSection{Valid: true}
+	if req == nil {
+		return section
+	}
+	if len(req.Formats) == 0 {
+		section.warn("no output format specified, server default formats will be used")
+	}
+	for _, f := range req.Formats {
+		switch domain.DocFormat(f) {
+		case domain.DocFormatMarkdown, domain.DocFormatHTML, domain.DocFormatConfluence:
+		case domain.DocFormatPDF, domain.DocFormatDOCX:
+			section.warn("format %q is not implemented yet and will be skipped", f)
+		default:
+			section.fail("unsupported format %q", f)
+		}
+	}
+	if req.ScreenshotFormat != "" && !domain.ScreenshotFormat(req.ScreenshotFormat).Valid() {
+		section.fail("unsupported screenshot format %q", req.ScreenshotFormat)
+	}
+	if req.ScreenshotQuality < 0 || req.ScreenshotQuality > 100 {
+		section.fail("screenshot_quality must be between 1 and 100")
+	}
+	if req.DedupThreshold < 0 || req.DedupThreshold > 1 {
+		section.fail("dedup_threshold must be between 0 and 1")
+	}
+	style := domain.StyleConfig{ThemeColor: req.ThemeColor, LogoURL: req.LogoURL}
+	if err := style.Validate(); err != nil {
+		section.fail("%v", err)
+	}
+	return section
+}
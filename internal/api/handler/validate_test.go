@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/orchestrator"
+	"github.com/browser-automation/internal/planner"
+	"github.com/browser-automation/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// allowLoopback 测试期间放行回环地址（httptest 服务监听在 127.0.0.1），其余规则不变
+func allowLoopback(t *testing.T) {
+	t.Helper()
+	orig := internalAddress
+	internalAddress = func(ip net.IP) bool { return !ip.IsLoopback() && orig(ip) }
+	t.Cleanup(func() { internalAddress = orig })
+}
+
+// countingServer 记录请求次数的目标站点
+func countingServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestValidateTargetURLRejectsInternalAddress(t *testing.T) {
+	srv, hits := countingServer(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	section := validateTargetURL(context.Background(), srv.URL)
+	if section.Valid || len(section.Errors) == 0 || !strings.Contains(section.Errors[0], "internal address") {
+		t.Fatalf("section = %+v, want internal address error", section)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("probe sent %d requests to an internal address", hits.Load())
+	}
+}
+
+func TestValidateTargetURLRechecksRedirects(t *testing.T) {
+	allowLoopback(t)
+	srv, _ := countingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/private":
+			http.Redirect(w, r, "http://10.0.0.1/admin", http.StatusFound)
+		case "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		}
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/private", "internal address 10.0.0.1"},
+		{"/file", "unsupported scheme"},
+	}
+	for _, tt := range tests {
+		section := validateTargetURL(context.Background(), srv.URL+tt.path)
+		if section.Valid || len(section.Errors) == 0 || !strings.Contains(section.Errors[0], tt.want) {
+			t.Errorf("%s: section = %+v, want error containing %q", tt.path, section, tt.want)
+		}
+	}
+}
+
+func TestValidateTask(t *testing.T) {
+	allowLoopback(t)
+	gin.SetMode(gin.TestMode)
+	target, _ := countingServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message":       map[string]string{"role": "assistant", "content": "hi"},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	t.Cleanup(llm.Close)
+
+	factory := planner.NewLLMClientFactory()
+	store := storage.NewMemoryTaskStore()
+	h := NewTaskHandler(store, nil, factory, orchestrator.NewOrchestrator(browser.NewFakeController(), store, factory), nil)
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"description": "导出本月报表",
+			"target_url":  target.URL,
+			"llm":         map[string]interface{}{"provider": "openai", "model": "gpt-test", "endpoint": llm.URL, "api_key": "sk", "retry_count": 0},
+			"output":      map[string]interface{}{"formats": []string{"markdown"}},
+		}
+	}
+	tests := []struct {
+		name    string
+		modify  func(req map[string]interface{})
+		invalid []string // 期望无效的部分，为空表示全部有效
+	}{
+		{"fully valid", func(map[string]interface{}) {}, nil},
+		{"empty description", func(req map[string]interface{}) { req["description"] = "  " }, []string{"task"}},
+		{"ftp target", func(req map[string]interface{}) { req["target_url"] = "ftp://example.com" }, []string{"target_url"}},
+		{"form auth without password", func(req map[string]interface{}) {
+			req["auth"] = map[string]interface{}{"type": "form", "username": "u"}
+		}, []string{"auth"}},
+		{"missing llm", func(req map[string]interface{}) { delete(req, "llm") }, []string{"llm"}},
+		{"unsupported format and bad success regexp", func(req map[string]interface{}) {
+			req["output"] = map[string]interface{}{"formats": []string{"rtf"}}
+			req["success_criteria"] = map[string]interface{}{"url_pattern": "("}
+		}, []string{"output", "success"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(req)
+			body, _ := json.Marshal(req)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/tasks/validate", bytes.NewReader(body))
+
+			h.ValidateTask(c)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			var report ValidationReport
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			invalid := map[string]bool{}
+			for _, name := range tt.invalid {
+				invalid[name] = true
+			}
+			for name, section := range report.Sections {
+				if section.Valid == invalid[name] {
+					t.Errorf("section %s valid = %v, errors %v", name, section.Valid, section.Errors)
+				}
+			}
+			if report.Valid != (len(tt.invalid) == 0) {
+				t.Errorf("report valid = %v", report.Valid)
+			}
+		})
+	}
+	if tasks, _ := store.List(context.Background(), storage.TaskFilter{}, 100, 0); len(tasks) != 0 {
+		t.Errorf("validation created %d tasks", len(tasks))
+	}
+}
@@ -3,14 +3,15 @@ package api
 
 import (
 	"github.com/browser-automation/internal/api/handler"
+	"github.com/browser-automation/internal/config"
 	"github.com/browser-automation/internal/orchestrator"
 	"github.com/browser-automation/internal/planner"
 	"github.com/browser-automation/internal/storage"
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRouter 设置路由
-func SetupRouter(taskStore storage.TaskStore, llmFactory *planner.LLMClientFactory, orch *orchestrator.Orchestrator) *gin.Engine {
+// SetupRouter 设置路由，cfg 为服务端配置
+func SetupRouter(taskStore storage.TaskStore, docStore storage.DocumentStore, llmFactory *planner.LLMClientFactory, orch *orchestrator.Orchestrator, cfg *config.Config) *gin.Engine {
 	r := gin.Default()
 
 	// CORS 中间件
@@ -25,13 +26,24 @@ func SetupRouter(taskStore storage.TaskStore, llmFactory *planner.LLMClientFacto
 	v1 := r.Group("/api/v1")
 	{
 		// 任务相关
-		taskHandler := handler.NewTaskHandler(taskStore, orch)
+		taskHandler := handler.NewTaskHandler(taskStore, docStore, llmFactory, orch, cfg)
 		tasks := v1.Group("/tasks")
 		{
 			tasks.POST("", taskHandler.CreateTask)
 			tasks.GET("", taskHandler.ListTasks)
+			tasks.POST("/validate", taskHandler.ValidateTask)
+			tasks.POST("/cancel-all", taskHandler.CancelAllTasks)
 			tasks.GET("/:id", taskHandler.GetTask)
+			tasks.GET("/:id/plan", taskHandler.GetTaskPlan)
 			tasks.POST("/:id/cancel", taskHandler.CancelTask)
+			tasks.POST("/:id/approve", taskHandler.ApproveTask)
+			tasks.GET("/:id/live-screenshot", taskHandler.LiveScreenshot)
+			tasks.POST("/:id/continue", taskHandler.ContinueTask)
+			tasks.GET("/:id/documents/:docId", taskHandler.DownloadDocument)
+			tasks.GET("/:id/downloads/:downloadId", taskHandler.DownloadFile)
+			tasks.GET("/:id/manifest", taskHandler.GetManifest)
+			tasks.GET("/:id/log", taskHandler.GetTaskLog)
+			tasks.GET("/:id/export.zip", taskHandler.ExportTask)
 		}
 
 		// 配置相关
@@ -39,6 +51,7 @@ func SetupRouter(taskStore storage.TaskStore, llmFactory *planner.LLMClientFacto
 		config := v1.Group("/config")
 		{
 			config.GET("/llm/presets", configHandler.GetLLMPresets)
+			config.GET("/llm/models", configHandler.ListLLMModels)
 			config.POST("/llm/validate", configHandler.ValidateLLM)
 			config.GET("/output/formats", configHandler.GetOutputFormats)
 			config.GET("/auth/types", configHandler.GetAuthTypes)
@@ -3,7 +3,9 @@ package auth
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -20,12 +22,16 @@ type Authenticator interface {
 
 // Service 认证服务
 type Service struct {
-	browser browser.Controller
+	browser    browser.Controller
+	httpClient *http.Client // 用于 OIDC 发现与令牌交换等直连请求
 }
 
 // NewService 创建认证服务
 func NewService(browser browser.Controller) *Service {
-	return &Service{browser: browser}
+	return &Service{
+		browser:    browser,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
 }
 
 // Authenticate 执行认证
@@ -49,11 +55,30 @@ func (s *Service) Authenticate(ctx context.Context, config *domain.AuthConfig) (
 	case domain.AuthTypeToken:
 		return s.authenticateWithToken(ctx, config)
 
+	case domain.AuthTypeBasic:
+		return s.authenticateWithBasic(ctx, config)
+
 	default:
 		return nil, fmt.Errorf("unsupported auth type: %s", config.Type)
 	}
 }
 
+// NeedsTargetPage 认证流程是否需要先打开目标页面（表单、手动登录及通用 SSO 跳转）。
+// Cookie、Token、Basic 注入与 OIDC/CAS 不依赖当前页面，可在首次导航前完成
+func NeedsTargetPage(config *domain.AuthConfig) bool {
+	switch config.Type {
+	case domain.AuthTypeForm, domain.AuthTypeManual:
+		return true
+	case domain.AuthTypeSSO:
+		if config.SSOConfig == nil {
+			return true
+		}
+		provider := config.SSOConfig.Provider
+		return provider != domain.SSOProviderOIDC && provider != domain.SSOProviderCAS
+	}
+	return false
+}
+
 // ValidateSession 验证会话是否有效
 func (s *Service) ValidateSession(ctx context.Context, session *domain.Session) (bool, error) {
 	if session == nil {
@@ -84,6 +109,13 @@ func (s *Service) authenticateWithForm(ctx context.Context, config *domain.AuthC
 		return nil, fmt.Errorf("login form not found: %w", err)
 	}
 
+	// 记录提交前的页面和 Cookie，用于校验登录结果
+	loginURL, _ := s.browser.GetCurrentURL(ctx)
+	before, err := s.browser.GetCookies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get cookies: %w", err)
+	}
+
 	// 填写用户名（尝试常见选择器）
 	usernameSelectors := []string{
 		"input[name='username']",
@@ -116,13 +148,10 @@ func (s *Service) authenticateWithForm(ctx context.Context, config *domain.AuthC
 		}
 	}
 
-	// 等待登录完成
-	time.Sleep(3 * time.Second)
-
-	// 提取 cookies
-	cookies, err := s.browser.GetCookies(ctx)
+	// 等待登录完成并校验结果
+	cookies, err := s.verifyLogin(ctx, before, loginURL, 3*time.Second)
 	if err != nil {
-		return nil, fmt.Errorf("get cookies: %w", err)
+		return nil, err
 	}
 
 	return &domain.Session{
@@ -138,6 +167,12 @@ func (s *Service) authenticateWithSSO(ctx context.Context, config *domain.AuthCo
 	if config.SSOConfig == nil {
 		return nil, fmt.Errorf("sso config required")
 	}
+	switch config.SSOConfig.Provider {
+	case domain.SSOProviderOIDC:
+		return s.authenticateWithOIDC(ctx, config)
+	case domain.SSOProviderCAS:
+		return s.authenticateWithCAS(ctx, config)
+	}
 
 	// 等待 SSO 页面加载
 	if err := s.browser.WaitForNavigation(ctx, 10*time.Second); err != nil {
@@ -147,22 +182,32 @@ func (s *Service) authenticateWithSSO(ctx context.Context, config *domain.AuthCo
 	// 获取当前 URL 判断是否在 SSO 页面
 	currentURL, _ := s.browser.GetCurrentURL(ctx)
 
-	// 如果在 SSO 登录页，执行登录
-	if s.isOnSSOPage(currentURL, config.SSOConfig) {
-		if config.Credentials != nil {
-			if err := s.performSSOLogin(ctx, config.Credentials, config.SSOConfig); err != nil {
-				return nil, fmt.Errorf("sso login: %w", err)
-			}
+	// 如果在 SSO 登录页，执行登录并校验结果
+	var cookies []domain.Cookie
+	if s.isOnSSOPage(currentURL, config.SSOConfig) && config.Credentials != nil {
+		before, err := s.browser.GetCookies(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get cookies: %w", err)
+		}
+		if err := s.performSSOLogin(ctx, config.Credentials, config.SSOConfig); err != nil {
+			return nil, fmt.Errorf("sso login: %w", err)
+		}
+		// 等待回调完成
+		if cookies, err = s.verifyLogin(ctx, before, currentURL, 5*time.Second); err != nil {
+			return nil, fmt.Errorf("sso login: %w", err)
+		}
+	} else {
+		// 等待回调完成
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
 		}
-	}
-
-	// 等待回调完成
-	time.Sleep(5 * time.Second)
 
-	// 提取 cookies
-	cookies, err := s.browser.GetCookies(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("get cookies: %w", err)
+		var err error
+		if cookies, err = s.browser.GetCookies(ctx); err != nil {
+			return nil, fmt.Errorf("get cookies: %w", err)
+		}
 	}
 
 	return &domain.Session{
@@ -231,7 +276,7 @@ func (s *Service) authenticateWithToken(ctx context.Context, config *domain.Auth
 	}
 
 	return &domain.Session{
-		ID:        uuid.New().String(),
+		ID: uuid.New().String(),
 		Headers: map[string]string{
 			"Authorization": "Bearer " + config.Credentials.Token,
 		},
@@ -240,6 +285,23 @@ func (s *Service) authenticateWithToken(ctx context.Context, config *domain.Auth
 	}, nil
 }
 
+// authenticateWithBasic HTTP Basic 认证，请求头由调用方按目标站点注入
+func (s *Service) authenticateWithBasic(ctx context.Context, config *domain.AuthConfig) (*domain.Session, error) {
+	if config.Credentials == nil || config.Credentials.Username == "" {
+		return nil, fmt.Errorf("username required for basic auth")
+	}
+
+	userPass := config.Credentials.Username + ":" + config.Credentials.Password
+	return &domain.Session{
+		ID: uuid.New().String(),
+		Headers: map[string]string{
+			"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(userPass)),
+		},
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
 func (s *Service) isOnSSOPage(url string, ssoConfig *domain.SSOConfig) bool {
 	if ssoConfig.LoginURL != "" {
 		return strings.Contains(url, ssoConfig.LoginURL)
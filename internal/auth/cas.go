@@ -0,0 +1,114 @@
+// Package auth 提供认证功能
+package auth
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+	"github.com/google/uuid"
+)
+
+// casTicketTimeout 等待 CAS 携带 ticket 跳转回服务地址的最长时间
+const casTicketTimeout = 2 * time.Minute
+
+// casServiceResponse CAS 2.0 /serviceValidate 响应
+type casServiceResponse struct {
+	XMLName xml.Name `xml:"serviceResponse"`
+	Success *struct {
+		User string `xml:"user"`
+	} `xml:"authenticationSuccess"`
+	Failure *struct {
+		Code    string `xml:"code,attr"`
+		Message string `xml:",chardata"`
+	} `xml:"authenticationFailure"`
+}
+
+// authenticateWithCAS CAS 协议登录：打开 /login?service=，填写登录表单，
+// 跟随携带 ticket 的服务跳转，再以 /serviceValidate 和会话 Cookie 确认登录结果
+func (s *Service) authenticateWithCAS(ctx context.Context, config *domain.AuthConfig) (*domain.Session, error) {
+	sso := config.SSOConfig
+	if sso.Domain == "" || sso.CallbackURL == "" {
+		return nil, fmt.Errorf("cas requires domain (cas server url) and callback_url (service url)")
+	}
+	server := strings.TrimSuffix(sso.Domain, "/")
+
+	loginURL := server + "/login?" + url.Values{"service": {sso.CallbackURL}}.Encode()
+	if err := s.browser.Navigate(ctx, loginURL); err != nil {
+		return nil, fmt.Errorf("navigate to cas login: %w", err)
+	}
+
+	// 已有 CAS 会话（TGT）时会直接跳转回服务地址
+	if currentURL, _ := s.browser.GetCurrentURL(ctx); !strings.HasPrefix(currentURL, sso.CallbackURL) && config.Credentials != nil {
+		if err := s.performSSOLogin(ctx, config.Credentials, sso); err != nil {
+			return nil, fmt.Errorf("cas login: %w", err)
+		}
+	}
+
+	serviceURL, err := s.waitForCallback(ctx, sso.CallbackURL, casTicketTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("wait for cas service redirect: %w", err)
+	}
+	ticket := serviceURL.Query().Get("ticket")
+
+	// 服务端通常已在跳转时消费了 ticket（票据一次性有效），此时以会话 Cookie 为准
+	var user string
+	if ticket != "" {
+		user, err = s.validateCASTicket(ctx, server, sso.CallbackURL, ticket)
+		if err != nil && !strings.Contains(err.Error(), "INVALID_TICKET") {
+			return nil, err
+		}
+	}
+
+	cookies, err := s.browser.GetCookies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get cookies: %w", err)
+	}
+	cookies = domain.FilterCookiesForURL(cookies, sso.CallbackURL)
+	if user == "" && len(cookies) == 0 {
+		return nil, fmt.Errorf("cas login not confirmed: no ticket validated and no session cookies for service")
+	}
+
+	return &domain.Session{
+		ID:        uuid.New().String(),
+		UserID:    user,
+		Cookies:   cookies,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// validateCASTicket 调用 /serviceValidate 校验 ticket，返回登录用户名
+func (s *Service) validateCASTicket(ctx context.Context, server, service, ticket string) (string, error) {
+	endpoint := server + "/serviceValidate?" + url.Values{"service": {service}, "ticket": {ticket}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("create cas validate request: %w", err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("validate cas ticket: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("validate cas ticket: %s", resp.Status)
+	}
+
+	var result casServiceResponse
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode cas validate response: %w", err)
+	}
+	if result.Failure != nil {
+		return "", fmt.Errorf("cas ticket rejected: %s %s", result.Failure.Code, strings.TrimSpace(result.Failure.Message))
+	}
+	if result.Success == nil || result.Success.User == "" {
+		return "", fmt.Errorf("cas validate response missing user")
+	}
+	return result.Success.User, nil
+}
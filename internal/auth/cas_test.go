@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+)
+
+// casLoginPage 模拟 CAS 登录页：提交表单后写入服务端会话 Cookie，并携带 ticket 跳转回服务地址
+type casLoginPage struct {
+	*browser.FakeController
+	ticket  string
+	cookies []domain.Cookie
+}
+
+func (p *casLoginPage) Click(ctx context.Context, selector string) error {
+	if err := p.FakeController.Click(ctx, selector); err != nil {
+		return err
+	}
+	current, _ := p.GetCurrentURL(ctx)
+	u, _ := url.Parse(current)
+	service := u.Query().Get("service")
+	if service == "" {
+		return nil
+	}
+	if err := p.SetCookies(ctx, p.cookies); err != nil {
+		return err
+	}
+	return p.Navigate(ctx, service+"?ticket="+url.QueryEscape(p.ticket))
+}
+
+// newCASServer 模拟 /serviceValidate：ST-valid 校验成功，ST-other 返回 INVALID_SERVICE，其余为 INVALID_TICKET
+func newCASServer(t *testing.T, service string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/serviceValidate" || r.URL.Query().Get("service") != service {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		switch r.URL.Query().Get("ticket") {
+		case "ST-valid":
+			fmt.Fprint(w, `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess><cas:user>alice</cas:user></cas:authenticationSuccess>
+</cas:serviceResponse>`)
+		case "ST-other":
+			fmt.Fprint(w, `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationFailure code="INVALID_SERVICE">service mismatch</cas:authenticationFailure>
+</cas:serviceResponse>`)
+		default:
+			fmt.Fprint(w, `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationFailure code="INVALID_TICKET">ticket not recognized</cas:authenticationFailure>
+</cas:serviceResponse>`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAuthenticateWithCAS(t *testing.T) {
+	const service = "https://app.example.com/cas/callback"
+	session := domain.Cookie{Name: "JSESSIONID", Value: "s1", Domain: "app.example.com", Path: "/"}
+	tests := []struct {
+		name     string
+		ticket   string
+		cookies  []domain.Cookie
+		wantUser string
+		wantErr  string
+	}{
+		{"validated ticket", "ST-valid", []domain.Cookie{session}, "alice", ""},
+		{"ticket consumed by the service", "ST-used", []domain.Cookie{session}, "", ""},
+		{"no ticket and no session", "ST-used", nil, "", "not confirmed"},
+		{"rejected ticket", "ST-other", []domain.Cookie{session}, "", "INVALID_SERVICE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cas := newCASServer(t, service)
+			page := &casLoginPage{FakeController: browser.NewFakeController(), ticket: tt.ticket, cookies: tt.cookies}
+			page.Connect(context.Background(), browser.ContextOptions{})
+
+			got, err := NewService(page).Authenticate(context.Background(), &domain.AuthConfig{
+				Type:        domain.AuthTypeSSO,
+				Credentials: &domain.Credentials{Username: "alice", Password: "secret"},
+				SSOConfig:   &domain.SSOConfig{Provider: domain.SSOProviderCAS, Domain: cas.URL + "/", CallbackURL: service},
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.UserID != tt.wantUser || len(got.Cookies) != 1 || got.Cookies[0].Name != "JSESSIONID" {
+				t.Errorf("session user %q cookies %v", got.UserID, got.Cookies)
+			}
+
+			navigated := page.Actions()[1]
+			if navigated.Method != "Navigate" || navigated.Selector != cas.URL+"/login?service="+url.QueryEscape(service) {
+				t.Errorf("first navigation = %+v", navigated)
+			}
+		})
+	}
+}
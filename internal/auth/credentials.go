@@ -0,0 +1,88 @@
+// Package auth 提供认证功能
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/browser-automation/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// CredentialProvider 按目标站点提供服务端保存的登录凭据
+type CredentialProvider interface {
+	// Lookup 返回目标 URL 对应的凭据，未配置时返回 false
+	Lookup(targetURL string) (*domain.Credentials, bool)
+}
+
+// credentialEntry 凭据文件中的一项
+type credentialEntry struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"`
+	APIKey   string `yaml:"api_key"`
+}
+
+// FileCredentialProvider 从 YAML/JSON 文件加载的凭据，按域名索引。
+// 域名同时匹配其子域名，多个域名都匹配时取最具体的一个
+type FileCredentialProvider struct {
+	sites map[string]*domain.Credentials
+}
+
+// LoadCredentialFile 读取凭据文件，格式为域名到凭据的映射：
+//
+//	example.com:
+//	  username: docs-bot
+//	  password: secret
+func LoadCredentialFile(path string) (*FileCredentialProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read credentials file: %w", err)
+	}
+	var entries map[string]credentialEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse credentials file: %w", err)
+	}
+
+	sites := make(map[string]*domain.Credentials, len(entries))
+	for site, e := range entries {
+		host := strings.ToLower(strings.Trim(strings.TrimSpace(site), "."))
+		if host == "" {
+			return nil, fmt.Errorf("parse credentials file: empty site key")
+		}
+		sites[host] = &domain.Credentials{
+			Username: e.Username,
+			Password: e.Password,
+			Token:    e.Token,
+			APIKey:   e.APIKey,
+		}
+	}
+	return &FileCredentialProvider{sites: sites}, nil
+}
+
+// Lookup 依次尝试目标主机及其各级父域名
+func (p *FileCredentialProvider) Lookup(targetURL string) (*domain.Credentials, bool) {
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Hostname() == "" {
+		return nil, false
+	}
+	host := strings.ToLower(u.Hostname())
+	for {
+		if creds, ok := p.sites[host]; ok {
+			c := *creds
+			return &c, true
+		}
+		i := strings.Index(host, ".")
+		if i < 0 {
+			return nil, false
+		}
+		host = host[i+1:]
+	}
+}
+
+// Len 返回已配置的站点数
+func (p *FileCredentialProvider) Len() int {
+	return len(p.sites)
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeCredentialFile 将内容写入临时凭据文件并加载
+func writeCredentialFile(t *testing.T, content string) (*FileCredentialProvider, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return LoadCredentialFile(path)
+}
+
+func TestFileCredentialProviderLookup(t *testing.T) {
+	p, err := writeCredentialFile(t, `
+example.com:
+  username: docs-bot
+  password: parent-secret
+"App.Example.com.":
+  username: app-bot
+  password: app-secret
+api.internal:
+  token: tok-1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", p.Len())
+	}
+
+	tests := []struct {
+		target   string
+		wantUser string
+		wantOK   bool
+	}{
+		{"https://example.com/login", "docs-bot", true},
+		{"https://wiki.example.com/", "docs-bot", true},
+		{"https://app.example.com/form", "app-bot", true},
+		{"https://eu.app.example.com:8443/", "app-bot", true},
+		{"https://notexample.com/", "", false},
+		{"not a url", "", false},
+	}
+	for _, tt := range tests {
+		creds, ok := p.Lookup(tt.target)
+		if ok != tt.wantOK || (ok && creds.Username != tt.wantUser) {
+			t.Errorf("Lookup(%q) = %+v, %v, want user %q %v", tt.target, creds, ok, tt.wantUser, tt.wantOK)
+		}
+	}
+
+	// 返回副本，调用方修改不影响后续查找
+	creds, _ := p.Lookup("https://example.com")
+	creds.Password = "changed"
+	if again, _ := p.Lookup("https://example.com"); again.Password != "parent-secret" {
+		t.Errorf("stored password modified: %q", again.Password)
+	}
+	if creds, _ := p.Lookup("https://api.internal/v1"); creds.Token != "tok-1" {
+		t.Errorf("token = %q", creds.Token)
+	}
+}
+
+func TestLoadCredentialFileErrors(t *testing.T) {
+	if _, err := LoadCredentialFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing file accepted")
+	}
+	for _, content := range []string{"example.com: [not, a, map]", `"": {username: a}`} {
+		if _, err := writeCredentialFile(t, content); err == nil {
+			t.Errorf("%q accepted", content)
+		}
+	}
+}
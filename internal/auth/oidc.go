@@ -0,0 +1,225 @@
+// Package auth 提供认证功能
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+	"github.com/google/uuid"
+)
+
+// oidcCallbackTimeout 等待授权回调的最长时间
+const oidcCallbackTimeout = 2 * time.Minute
+
+// oidcDiscovery OIDC 发现文档
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// oidcTokenResponse 令牌端点响应
+type oidcTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// authenticateWithOIDC 基于发现文档的 OIDC 授权码流程（PKCE）：
+// 浏览器完成授权端点登录，回调后用授权码换取令牌
+func (s *Service) authenticateWithOIDC(ctx context.Context, config *domain.AuthConfig) (*domain.Session, error) {
+	sso := config.SSOConfig
+	if sso.Domain == "" || sso.ClientID == "" || sso.CallbackURL == "" {
+		return nil, fmt.Errorf("oidc requires domain (issuer), client_id and callback_url")
+	}
+
+	discovery, err := s.discoverOIDC(ctx, sso.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	state := uuid.New().String()
+	verifier, challenge, err := newPKCEPair()
+	if err != nil {
+		return nil, fmt.Errorf("generate pkce: %w", err)
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse authorization endpoint: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", sso.ClientID)
+	q.Set("redirect_uri", sso.CallbackURL)
+	q.Set("scope", "openid profile email")
+	q.Set("state", state)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+
+	if err := s.browser.Navigate(ctx, authURL.String()); err != nil {
+		return nil, fmt.Errorf("navigate to authorization endpoint: %w", err)
+	}
+
+	// 已有 IdP 会话时可能直接回调，否则在登录页填写凭据
+	if currentURL, _ := s.browser.GetCurrentURL(ctx); !strings.HasPrefix(currentURL, sso.CallbackURL) && config.Credentials != nil {
+		if err := s.performSSOLogin(ctx, config.Credentials, sso); err != nil {
+			return nil, fmt.Errorf("oidc login: %w", err)
+		}
+	}
+
+	callbackURL, err := s.waitForCallback(ctx, sso.CallbackURL, oidcCallbackTimeout)
+	if err != nil {
+		return nil, err
+	}
+	params := callbackURL.Query()
+	if e := params.Get("error"); e != "" {
+		return nil, fmt.Errorf("oidc authorization error: %s %s", e, params.Get("error_description"))
+	}
+	if params.Get("state") != state {
+		return nil, fmt.Errorf("oidc state mismatch")
+	}
+	code := params.Get("code")
+	if code == "" {
+		return nil, fmt.Errorf("oidc callback missing code")
+	}
+
+	tokens, err := s.exchangeOIDCCode(ctx, discovery.TokenEndpoint, sso, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	cookies, err := s.browser.GetCookies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get cookies: %w", err)
+	}
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	if tokens.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	}
+	return &domain.Session{
+		ID:      uuid.New().String(),
+		Cookies: cookies,
+		Headers: map[string]string{
+			"Authorization": "Bearer " + tokens.AccessToken,
+		},
+		Tokens: &domain.OAuthTokens{
+			AccessToken:  tokens.AccessToken,
+			IDToken:      tokens.IDToken,
+			RefreshToken: tokens.RefreshToken,
+			TokenType:    tokens.TokenType,
+		},
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// discoverOIDC 获取 issuer 的 .well-known/openid-configuration
+func (s *Service) discoverOIDC(ctx context.Context, issuer string) (*oidcDiscovery, error) {
+	endpoint := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create discovery request: %w", err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch oidc discovery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch oidc discovery: %s", resp.Status)
+	}
+
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&discovery); err != nil {
+		return nil, fmt.Errorf("decode oidc discovery: %w", err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" {
+		return nil, fmt.Errorf("oidc discovery missing authorization or token endpoint")
+	}
+	return &discovery, nil
+}
+
+// waitForCallback 轮询浏览器 URL 直到跳转到回调地址
+func (s *Service) waitForCallback(ctx context.Context, callbackURL string, timeout time.Duration) (*url.URL, error) {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if current, err := s.browser.GetCurrentURL(ctx); err == nil && strings.HasPrefix(current, callbackURL) {
+			return url.Parse(current)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return nil, fmt.Errorf("oidc callback timeout")
+		case <-ticker.C:
+		}
+	}
+}
+
+// exchangeOIDCCode 用授权码换取令牌
+func (s *Service) exchangeOIDCCode(ctx context.Context, tokenEndpoint string, sso *domain.SSOConfig, code, verifier string) (*oidcTokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {sso.CallbackURL},
+		"client_id":     {sso.ClientID},
+		"code_verifier": {verifier},
+	}
+	if sso.ClientSecret != "" {
+		form.Set("client_secret", sso.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("exchange oidc code: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange oidc code: %s", resp.Status)
+	}
+
+	var tokens oidcTokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("decode token response: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return nil, fmt.Errorf("token response missing access_token")
+	}
+	return &tokens, nil
+}
+
+// newPKCEPair 生成 PKCE code_verifier 及其 S256 code_challenge
+func newPKCEPair() (verifier, challenge string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+)
+
+// redirectingBrowser 模拟已有 IdP 会话的浏览器：打开以 prefix 开头的地址时由 redirect 决定跳转目标
+type redirectingBrowser struct {
+	*browser.FakeController
+	prefix   string
+	redirect func(u *url.URL) string
+}
+
+func (b *redirectingBrowser) Navigate(ctx context.Context, target string) error {
+	if strings.HasPrefix(target, b.prefix) {
+		u, err := url.Parse(target)
+		if err != nil {
+			return err
+		}
+		target = b.redirect(u)
+	}
+	return b.FakeController.Navigate(ctx, target)
+}
+
+// oidcProvider 模拟发现文档、授权端点与令牌端点
+type oidcProvider struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	challenge string     // 授权请求中的 code_challenge
+	tokenForm url.Values // 令牌请求的表单
+}
+
+func newOIDCProvider(t *testing.T) *oidcProvider {
+	t.Helper()
+	p := &oidcProvider{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		p.tokenForm = r.PostForm
+		challenge := p.challenge
+		p.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "auth-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-1", "id_token": "id-1", "refresh_token": "refresh-1",
+			"token_type": "Bearer", "expires_in": 3600,
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// authorize 记录授权请求并返回回调地址，modify 可篡改回调参数
+func (p *oidcProvider) authorize(modify func(q url.Values)) func(u *url.URL) string {
+	return func(u *url.URL) string {
+		q := u.Query()
+		p.mu.Lock()
+		p.challenge = q.Get("code_challenge")
+		p.mu.Unlock()
+		callback := url.Values{"code": {"auth-code"}, "state": {q.Get("state")}}
+		if modify != nil {
+			modify(callback)
+		}
+		return q.Get("redirect_uri") + "?" + callback.Encode()
+	}
+}
+
+func TestAuthenticateWithOIDC(t *testing.T) {
+	const callback = "https://app.example.com/callback"
+	tests := []struct {
+		name    string
+		modify  func(q url.Values)
+		wantErr string
+	}{
+		{"code flow", nil, ""},
+		{"state mismatch", func(q url.Values) { q.Set("state", "forged") }, "state mismatch"},
+		{"authorization error", func(q url.Values) {
+			q.Del("code")
+			q.Set("error", "access_denied")
+		}, "access_denied"},
+		{"invalid code", func(q url.Values) { q.Set("code", "stolen") }, "exchange oidc code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newOIDCProvider(t)
+			b := &redirectingBrowser{
+				FakeController: browser.NewFakeController(),
+				prefix:         provider.URL + "/authorize",
+				redirect:       provider.authorize(tt.modify),
+			}
+			b.Connect(context.Background(), browser.ContextOptions{})
+
+			session, err := NewService(b).Authenticate(context.Background(), &domain.AuthConfig{
+				Type: domain.AuthTypeSSO,
+				SSOConfig: &domain.SSOConfig{
+					Provider:     domain.SSOProviderOIDC,
+					Domain:       provider.URL,
+					ClientID:     "client-1",
+					ClientSecret: "secret-1",
+					CallbackURL:  callback,
+				},
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if session.Tokens == nil || session.Tokens.AccessToken != "access-1" || session.Tokens.IDToken != "id-1" {
+				t.Errorf("tokens = %+v", session.Tokens)
+			}
+			if session.Headers["Authorization"] != "Bearer access-1" {
+				t.Errorf("headers = %v", session.Headers)
+			}
+			form := provider.tokenForm
+			if form.Get("client_id") != "client-1" || form.Get("client_secret") != "secret-1" || form.Get("redirect_uri") != callback {
+				t.Errorf("token request form = %v", form)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package auth 提供认证功能
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// ErrAuthFailed 提交凭据后既未离开登录页、Cookie 也无变化，通常是用户名或密码错误
+var ErrAuthFailed = errors.New("authentication failed")
+
+// loginPollInterval 校验登录结果的轮询间隔
+const loginPollInterval = 500 * time.Millisecond
+
+// CookieDiff 登录前后 Cookie 的变化，按 domain/path/name 区分
+type CookieDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Empty 是否没有任何变化
+func (d CookieDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+func (d CookieDiff) String() string {
+	return fmt.Sprintf("added=[%s] changed=[%s] removed=[%s]",
+		strings.Join(d.Added, ","), strings.Join(d.Changed, ","), strings.Join(d.Removed, ","))
+}
+
+// DiffCookies 比较登录前后的 Cookie
+func DiffCookies(before, after []domain.Cookie) CookieDiff {
+	prev := make(map[string]string, len(before))
+	for _, c := range before {
+		prev[cookieKey(c)] = c.Value
+	}
+
+	var diff CookieDiff
+	seen := make(map[string]bool, len(after))
+	for _, c := range after {
+		key := cookieKey(c)
+		seen[key] = true
+		value, ok := prev[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, key)
+		case value != c.Value:
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for _, c := range before {
+		if key := cookieKey(c); !seen[key] {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	return diff
+}
+
+func cookieKey(c domain.Cookie) string {
+	return c.Domain + c.Path + ":" + c.Name
+}
+
+// verifyLogin 提交凭据后轮询登录结果：页面离开登录页或 Cookie 发生变化即视为成功，
+// 超时仍无变化时返回 ErrAuthFailed。成功时返回最新的 Cookie。
+func (s *Service) verifyLogin(ctx context.Context, before []domain.Cookie, loginURL string, timeout time.Duration) ([]domain.Cookie, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(loginPollInterval):
+		}
+
+		cookies, err := s.browser.GetCookies(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get cookies: %w", err)
+		}
+		currentURL, _ := s.browser.GetCurrentURL(ctx)
+
+		if currentURL != "" && currentURL != loginURL && !s.isOnLoginPage(currentURL) {
+			return cookies, nil
+		}
+		if diff := DiffCookies(before, cookies); !diff.Empty() {
+			log.Printf("[Auth] Cookies changed after login: %s", diff)
+			return cookies, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: still on login page %s with no cookie changes", ErrAuthFailed, currentURL)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+)
+
+func TestDiffCookies(t *testing.T) {
+	session := domain.Cookie{Name: "sid", Value: "1", Domain: "app.example.com", Path: "/"}
+	rotated := session
+	rotated.Value = "2"
+	tracking := domain.Cookie{Name: "_ga", Value: "x", Domain: "app.example.com", Path: "/"}
+
+	tests := []struct {
+		name          string
+		before, after []domain.Cookie
+		want          CookieDiff
+	}{
+		{"unchanged", []domain.Cookie{session}, []domain.Cookie{session}, CookieDiff{}},
+		{"added", nil, []domain.Cookie{session}, CookieDiff{Added: []string{"app.example.com/:sid"}}},
+		{"changed", []domain.Cookie{session}, []domain.Cookie{rotated}, CookieDiff{Changed: []string{"app.example.com/:sid"}}},
+		{"removed", []domain.Cookie{session, tracking}, []domain.Cookie{session}, CookieDiff{Removed: []string{"app.example.com/:_ga"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffCookies(tt.before, tt.after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff = %+v, want %+v", got, tt.want)
+			}
+			if got.Empty() != (tt.name == "unchanged") {
+				t.Errorf("Empty() = %v", got.Empty())
+			}
+		})
+	}
+}
+
+// formLoginPage 模拟登录表单：accept 为 true 时提交后跳转到首页并写入会话 Cookie，否则停留在登录页
+type formLoginPage struct {
+	*browser.FakeController
+	accept bool
+}
+
+func (p *formLoginPage) Click(ctx context.Context, selector string) error {
+	if err := p.FakeController.Click(ctx, selector); err != nil || !p.accept {
+		return err
+	}
+	p.SetCookies(ctx, []domain.Cookie{{Name: "sid", Value: "s1", Domain: "app.example.com", Path: "/"}})
+	return p.Navigate(ctx, "https://app.example.com/home")
+}
+
+func TestFormAuthVerifiesLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		accept  bool
+		wantErr error
+	}{
+		{"valid credentials", true, nil},
+		{"wrong credentials", false, ErrAuthFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := &formLoginPage{FakeController: browser.NewFakeController(), accept: tt.accept}
+			page.Connect(context.Background(), browser.ContextOptions{})
+			page.Navigate(context.Background(), "https://app.example.com/login")
+
+			start := time.Now()
+			session, err := NewService(page).Authenticate(context.Background(), &domain.AuthConfig{
+				Type:        domain.AuthTypeForm,
+				Credentials: &domain.Credentials{Username: "alice", Password: "secret"},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			// 登录失败在校验超时后立即返回，不会静默地产生空会话
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("authentication took %v", elapsed)
+			}
+			if tt.wantErr == nil && (len(session.Cookies) != 1 || session.Cookies[0].Name != "sid") {
+				t.Errorf("session cookies = %v", session.Cookies)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Package browser 提供浏览器控制功能
+package browser
+
+import (
+	"context"
+)
+
+// findCaptchaJS 返回页面上第一个尚未填入令牌的 reCAPTCHA 的站点密钥，没有时返回空字符串。
+// 站点密钥取自 data-sitekey 属性，或 reCAPTCHA iframe 地址中的 k 参数
+const findCaptchaJS = `() => {
+	const solved = (root) => {
+		const area = (root || document).querySelector('textarea[name="g-recaptcha-response"]');
+		return !!(area && area.value);
+	};
+	for (const el of document.querySelectorAll('.g-recaptcha[data-sitekey], [data-sitekey]')) {
+		if (!solved(el.closest('form') || el)) return el.getAttribute('data-sitekey') || '';
+	}
+	for (const frame of document.querySelectorAll('iframe[src*="/recaptcha/"]')) {
+		try {
+			const key = new URL(frame.src, location.href).searchParams.get('k');
+			if (key && !solved(frame.closest('form'))) return key;
+		} catch (e) {}
+	}
+	return '';
+}`
+
+// injectCaptchaJS 将令牌写入所有 g-recaptcha-response 文本框（不存在时在 reCAPTCHA 容器内创建），
+// 并调用容器 data-callback 指定的回调，与用户手动通过验证时页面收到的结果一致
+const injectCaptchaJS = `(token) => {
+	const widgets = document.querySelectorAll('.g-recaptcha, [data-sitekey]');
+	for (const el of widgets) {
+		if (!el.querySelector('textarea[name="g-recaptcha-response"]')) {
+			const area = document.createElement('textarea');
+			area.name = 'g-recaptcha-response';
+			area.style.display = 'none';
+			el.appendChild(area);
+		}
+	}
+	const areas = document.querySelectorAll('textarea[name="g-recaptcha-response"]');
+	for (const area of areas) {
+		area.value = token;
+		area.innerHTML = token;
+	}
+	for (const el of widgets) {
+		const name = el.getAttribute('data-callback');
+		if (name && typeof window[name] === 'function') {
+			try { window[name](token); } catch (e) {}
+		}
+	}
+	return areas.length;
+}`
+
+// FindCaptchaSiteKey 返回当前文档（主文档或 SwitchToFrame 进入的 iframe）中待完成的 reCAPTCHA 的站点密钥，
+// 没有时返回空字符串
+func (c *PlaywrightController) FindCaptchaSiteKey(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	frame, err := c.currentFrame()
+	if err != nil {
+		return "", err
+	}
+	raw, err := frame.Evaluate(findCaptchaJS)
+	if err != nil {
+		return "", err
+	}
+	key, _ := raw.(string)
+	return key, nil
+}
+
+// InjectCaptchaToken 在当前文档中通过 g-recaptcha-response 文本框和 data-callback 回调提交验证码令牌
+func (c *PlaywrightController) InjectCaptchaToken(ctx context.Context, token string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	frame, err := c.currentFrame()
+	if err != nil {
+		return err
+	}
+	_, err = frame.Evaluate(injectCaptchaJS, token)
+	return err
+}
@@ -0,0 +1,88 @@
+package browser
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// captchaWidget reCAPTCHA 容器：站点密钥在 data-sitekey 上，通过后页面回调记录令牌
+const captchaWidget = `<html><body><form>
+<div class="g-recaptcha" data-sitekey="site-key-1" data-callback="onSolved"></div>
+<button type="submit">Sign up</button>
+</form>
+<script>function onSolved(token) { window.solvedToken = token; }</script>
+</body></html>`
+
+func TestCaptchaSiteKeyAndToken(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	base := serveFixture(t, map[string]string{
+		"/":      captchaWidget,
+		"/plain": `<html><body><form><button>Sign up</button></form></body></html>`,
+	})
+
+	if err := c.Navigate(ctx, base+"/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := c.FindCaptchaSiteKey(ctx); err != nil || key != "" {
+		t.Errorf("FindCaptchaSiteKey on a page without captcha = %q, %v", key, err)
+	}
+
+	if err := c.Navigate(ctx, base+"/"); err != nil {
+		t.Fatal(err)
+	}
+	key, err := c.FindCaptchaSiteKey(ctx)
+	if err != nil || key != "site-key-1" {
+		t.Fatalf("FindCaptchaSiteKey = %q, %v; want site-key-1", key, err)
+	}
+	if err := c.InjectCaptchaToken(ctx, "token-abc"); err != nil {
+		t.Fatalf("InjectCaptchaToken: %v", err)
+	}
+	if got, err := c.page.Locator(`textarea[name="g-recaptcha-response"]`).InputValue(); err != nil || got != "token-abc" {
+		t.Errorf("g-recaptcha-response = %q, %v; want the injected token", got, err)
+	}
+	if got, _ := c.page.Evaluate(`window.solvedToken`); got != "token-abc" {
+		t.Errorf("data-callback received %v, want the injected token", got)
+	}
+	// 已写入令牌的验证码不再视为待完成
+	if key, err := c.FindCaptchaSiteKey(ctx); err != nil || key != "" {
+		t.Errorf("FindCaptchaSiteKey after injection = %q, %v; want empty", key, err)
+	}
+}
+
+func TestCaptchaInActiveFrame(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	base := serveFixture(t, map[string]string{
+		"/":       `<html><body><iframe id="signup" src="/widget"></iframe></body></html>`,
+		"/widget": captchaWidget,
+	})
+	if err := c.Navigate(ctx, base+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WaitForSelector(ctx, "frame:#signup >> .g-recaptcha", 5*time.Second); err != nil {
+		t.Fatalf("iframe content not loaded: %v", err)
+	}
+
+	// 主文档中没有验证码，进入 iframe 后在其中查找和写入
+	if key, err := c.FindCaptchaSiteKey(ctx); err != nil || key != "" {
+		t.Errorf("FindCaptchaSiteKey in main document = %q, %v; want empty", key, err)
+	}
+	if err := c.SwitchToFrame(ctx, "signup"); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := c.FindCaptchaSiteKey(ctx); err != nil || key != "site-key-1" {
+		t.Fatalf("FindCaptchaSiteKey in frame = %q, %v; want site-key-1", key, err)
+	}
+	if err := c.InjectCaptchaToken(ctx, "token-frame"); err != nil {
+		t.Fatalf("InjectCaptchaToken: %v", err)
+	}
+	area := c.page.FrameLocator("#signup").Locator(`textarea[name="g-recaptcha-response"]`)
+	if got, err := area.InputValue(); err != nil || got != "token-frame" {
+		t.Errorf("g-recaptcha-response in frame = %q, %v; want the injected token", got, err)
+	}
+	if n, _ := c.page.Locator(`textarea[name="g-recaptcha-response"]`).Count(); n != 0 {
+		t.Errorf("main document got %d response fields, want the token only inside the frame", n)
+	}
+}
@@ -3,6 +3,8 @@ package browser
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/browser-automation/internal/domain"
@@ -10,9 +12,12 @@ import (
 
 // Controller 浏览器控制器接口
 type Controller interface {
-	// 生命周期
-	Connect(ctx context.Context) error
+	// 生命周期：每次 Connect 都创建新的浏览器上下文，不同任务之间不共享 Cookie 与存储
+	Connect(ctx context.Context, opts ContextOptions) error
+	CloseContext(ctx context.Context) error // 关闭当前上下文，浏览器保持运行
 	Close(ctx context.Context) error
+	NewPage(ctx context.Context) error                                 // 关闭当前页面并在同一上下文中新建页面（保留 Cookie）
+	EnsureConnected(ctx context.Context) (reconnected bool, err error) // 连接断开时有限次重连，重连后页面状态丢失
 
 	// 导航
 	Navigate(ctx context.Context, url string) error
@@ -22,23 +27,48 @@ type Controller interface {
 
 	// 元素操作
 	Click(ctx context.Context, selector string) error
+	ClickByText(ctx context.Context, text string, opts ClickOptions) error // 按可见文本点击，多个匹配时可指定序号
 	Fill(ctx context.Context, selector string, value string) error
+	TypeText(ctx context.Context, selector string, value string, delay time.Duration) error // 清空后逐字键入，每个字符间隔 delay
 	Hover(ctx context.Context, selector string) error
 	Select(ctx context.Context, selector string, value string) error
+	DismissOverlays(ctx context.Context) (int, error) // 关闭 Cookie 同意横幅等遮挡层，返回关闭的数量
+	ResolvedSelector(ctx context.Context) string      // 最近一次元素操作实际使用的选择器（回退匹配、frame 前缀均已展开）
+
+	// 验证码：检测页面上的 reCAPTCHA 并写入外部服务求解的令牌
+	FindCaptchaSiteKey(ctx context.Context) (string, error)     // 待完成的 reCAPTCHA 站点密钥，没有时为空
+	InjectCaptchaToken(ctx context.Context, token string) error // 写入 g-recaptcha-response 并触发页面回调
+
+	// iframe 切换：进入后元素操作只在该 iframe 内查找，导航或重连后自动回到主文档
+	SwitchToFrame(ctx context.Context, nameOrSelector string) error // 按 name/id 或选择器进入当前文档中的 iframe
+	SwitchToMainFrame(ctx context.Context) error
+
+	// 下载：返回保存到临时目录的文件路径（文件名为浏览器建议的名称），调用方负责删除
+	WaitForDownload(ctx context.Context, timeout time.Duration) (string, error) // 等待下一个下载完成，包括调用前已触发的下载
 
 	// 等待
 	WaitForSelector(ctx context.Context, selector string, timeout time.Duration) error
+	// WaitForAny 等待任一候选选择器出现，返回出现的那个，便于根据结果（如成功提示或错误横幅）判断后续操作
+	WaitForAny(ctx context.Context, selectors []string, timeout time.Duration) (string, error)
+	WaitForEnabled(ctx context.Context, selector string, timeout time.Duration) error // 等待元素可见、稳定且未禁用，可以点击
 	WaitForText(ctx context.Context, text string, timeout time.Duration) error
+	WaitForCondition(ctx context.Context, jsExpr string, timeout time.Duration) error // 轮询 JS 布尔表达式直到为真
 
 	// 页面分析
 	TakeSnapshot(ctx context.Context) (*PageSnapshot, error)
+	// PageFingerprint 页面内容的廉价摘要（URL、文本、表单值等），与上次相同说明页面未变化、无需重新采集快照
+	PageFingerprint(ctx context.Context) (string, error)
 	TakeScreenshot(ctx context.Context, opts ScreenshotOptions) ([]byte, error)
 	GetPageTitle(ctx context.Context) (string, error)
+	ExtractText(ctx context.Context, selector string) (string, error) // 元素文本，输入框返回其值
 
 	// Cookie 管理
 	GetCookies(ctx context.Context) ([]domain.Cookie, error)
 	SetCookies(ctx context.Context, cookies []domain.Cookie) error
 	ClearCookies(ctx context.Context) error
+
+	// 请求头注入
+	SetOriginHeaders(ctx context.Context, targetURL string, headers map[string]string) error // 只为与 targetURL 同源的请求附加请求头
 }
 
 // PageSnapshot 页面快照
@@ -46,11 +76,24 @@ type PageSnapshot struct {
 	URL       string    `json:"url"`
 	Title     string    `json:"title"`
 	HTML      string    `json:"html"`
-	A11yTree  string    `json:"a11y_tree"`  // 无障碍树（供 AI 分析）
-	Elements  []Element `json:"elements"`   // 可交互元素
+	A11yTree  string    `json:"a11y_tree"` // 无障碍树（供 AI 分析）
+	Elements  []Element `json:"elements"`  // 可交互元素
+	DOMSize   int       `json:"dom_size"`  // 页面元素总数
+	Truncated bool      `json:"truncated"` // 页面过大，元素被截断且未采集无障碍树
+	Stats     PageStats `json:"stats"`     // 页面结构统计（供 AI 规划）
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// PageStats 页面结构统计，按完整 DOM 计算，不受元素采集上限影响
+type PageStats struct {
+	TagCounts    map[string]int `json:"tag_counts"` // 常见结构/交互标签的数量
+	Forms        int            `json:"forms"`
+	Links        int            `json:"links"`
+	Buttons      int            `json:"buttons"`
+	Inputs       int            `json:"inputs"`
+	HasLoginForm bool           `json:"has_login_form"` // 存在可见的密码输入框
+}
+
 // Element 页面元素
 type Element struct {
 	TagName    string            `json:"tag_name"`
@@ -60,6 +103,24 @@ type Element struct {
 	Rect       *Rect             `json:"rect"`
 	Visible    bool              `json:"visible"`
 	Clickable  bool              `json:"clickable"`
+	Frame      string            `json:"frame,omitempty"` // 所在 iframe 的 frame: 选择器前缀，主文档为空
+}
+
+// DefaultElementTextLength 快照中元素文本的默认长度上限（字符）
+const DefaultElementTextLength = 80
+
+// CleanElementText 将元素文本中的换行与连续空白合并为单个空格并去掉首尾空白，
+// max > 0 时截断到 max 个字符并以 "…" 结尾，保证提示词中每个元素只占一行
+func CleanElementText(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if max <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max])) + "…"
 }
 
 // Rect 元素位置
@@ -70,11 +131,87 @@ type Rect struct {
 	Height float64 `json:"height"`
 }
 
+// ClickOptions 按文本点击选项
+type ClickOptions struct {
+	Exact bool `json:"exact"` // 文本完全匹配（默认为包含、忽略大小写）
+	Nth   int  `json:"nth"`   // 第几个匹配（从 1 开始），0 表示自动选择可见的按钮/链接
+}
+
+// ParseClickOptions 解析点击步骤 value 中的选项，如 "exact"、"nth=2"、"exact,nth=2"；
+// value 不是选项语法时 ok 为 false
+func ParseClickOptions(value string) (opts ClickOptions, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return opts, false
+	}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "exact":
+			opts.Exact = true
+		case strings.HasPrefix(part, "nth="):
+			n, err := strconv.Atoi(strings.TrimPrefix(part, "nth="))
+			if err != nil || n < 1 {
+				return ClickOptions{}, false
+			}
+			opts.Nth = n
+		default:
+			return ClickOptions{}, false
+		}
+	}
+	return opts, true
+}
+
+// SplitAlternatives 拆分以 | 分隔的候选选择器，如 ".toast-success | .error-banner"；
+// 方括号、圆括号和引号内的 | 不作分隔（如 [lang|=en]），空白的候选被忽略
+func SplitAlternatives(selector string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range selector {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '(':
+			depth++
+		case r == ']' || r == ')':
+			depth--
+		case r == '|' && depth == 0:
+			parts = append(parts, selector[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, selector[start:])
+
+	out := parts[:0]
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ContextOptions 浏览器上下文选项
+type ContextOptions struct {
+	Locale     string `json:"locale,omitempty"`      // 如 zh-CN，同时决定 Accept-Language 与 navigator.language
+	TimezoneID string `json:"timezone_id,omitempty"` // 如 Asia/Shanghai
+	// SnapshotScope 快照范围的 CSS 选择器（如 "#app main"），设置后只采集主文档中该元素内的可交互元素、
+	// iframe 与无障碍树；选择器无效或未匹配时采集整个页面
+	SnapshotScope string `json:"snapshot_scope,omitempty"`
+	// Proxy 上下文内所有请求使用的代理，为空时直连
+	Proxy *Proxy `json:"-"`
+}
+
 // ScreenshotOptions 截图选项
 type ScreenshotOptions struct {
 	FullPage bool   `json:"full_page"`
 	Quality  int    `json:"quality"` // 1-100
-	Type     string `json:"type"`    // png, jpeg
+	Type     string `json:"type"`    // png, jpeg, webp（为空时为 png）
 	Clip     *Rect  `json:"clip,omitempty"`
 }
 
@@ -90,6 +227,11 @@ const (
 	ActionScreenshot ActionType = "screenshot"
 	ActionWait       ActionType = "wait"
 	ActionScroll     ActionType = "scroll"
+	ActionExtract    ActionType = "extract" // 读取 Target 元素文本，Value 为数据名称
+
+	ActionSwitchFrame     ActionType = "switch_frame"      // 进入 Target 指定的 iframe（name/id 或选择器）
+	ActionSwitchMainFrame ActionType = "switch_main_frame" // 回到主文档
+	ActionDownload        ActionType = "download"          // 点击 Target 触发下载并保存文件
 )
 
 // Action 浏览器操作
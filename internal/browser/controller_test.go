@@ -0,0 +1,73 @@
+package browser
+
+import "testing"
+
+func TestParseClickOptions(t *testing.T) {
+	tests := []struct {
+		value string
+		want  ClickOptions
+		ok    bool
+	}{
+		{"exact", ClickOptions{Exact: true}, true},
+		{"nth=2", ClickOptions{Nth: 2}, true},
+		{" exact , nth=3 ", ClickOptions{Exact: true, Nth: 3}, true},
+		{"", ClickOptions{}, false},
+		{"nth=0", ClickOptions{}, false},
+		{"nth=x", ClickOptions{}, false},
+		{"alice@example.com", ClickOptions{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseClickOptions(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseClickOptions(%q) = %+v, %v; want %+v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCleanElementText(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{"  Save\n\n  changes  ", 80, "Save changes"},
+		{"Name\t(required)\r\n*", 80, "Name (required) *"},
+		{"Continue to checkout", 8, "Continue…"},
+		{"提交订单并支付", 4, "提交订单…"},
+		{"exactly", 7, "exactly"},
+		{"  no\nlimit  ", 0, "no limit"},
+		{"", 10, ""},
+	}
+	for _, tt := range tests {
+		if got := CleanElementText(tt.text, tt.max); got != tt.want {
+			t.Errorf("CleanElementText(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestSplitAlternatives(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"#done", []string{"#done"}},
+		{".toast-success | .error-banner", []string{".toast-success", ".error-banner"}},
+		{" a |  | b ", []string{"a", "b"}},
+		{"[lang|=en] | #fallback", []string{"[lang|=en]", "#fallback"}},
+		{`text="a|b" | :is(.x|.y)`, []string{`text="a|b"`, ":is(.x|.y)"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := SplitAlternatives(tt.selector)
+		if len(got) != len(tt.want) {
+			t.Errorf("SplitAlternatives(%q) = %q, want %q", tt.selector, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("SplitAlternatives(%q) = %q, want %q", tt.selector, got, tt.want)
+				break
+			}
+		}
+	}
+}
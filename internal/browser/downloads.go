@@ -0,0 +1,76 @@
+// Package browser 提供浏览器控制功能
+package browser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// maxPendingDownloads 未被 WaitForDownload 取走的下载上限，超出时丢弃新的下载
+const maxPendingDownloads = 8
+
+// watchDownloads 监听页面的下载事件，回调在 Playwright 的事件协程中执行，不获取锁
+func (c *PlaywrightController) watchDownloads(page playwright.Page) {
+	page.OnDownload(func(d playwright.Download) {
+		select {
+		case c.downloads <- d:
+			log.Printf("[Browser] Download started: %s", d.SuggestedFilename())
+		default:
+			log.Printf("[Browser] Too many pending downloads, dropping %s", d.SuggestedFilename())
+		}
+	})
+}
+
+// drainDownloads 丢弃上一个浏览器会话遗留的下载
+func (c *PlaywrightController) drainDownloads() {
+	for {
+		select {
+		case <-c.downloads:
+		default:
+			return
+		}
+	}
+}
+
+// WaitForDownload 等待下一个下载完成并复制到临时目录。
+// 等待期间不持有锁，触发下载的点击可在调用前或调用期间进行
+func (c *PlaywrightController) WaitForDownload(ctx context.Context, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var d playwright.Download
+	select {
+	case d = <-c.downloads:
+	case <-timer.C:
+		return "", fmt.Errorf("timeout waiting for download")
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	dir, err := os.MkdirTemp("", "browser-download-")
+	if err != nil {
+		return "", fmt.Errorf("create download dir: %w", err)
+	}
+	path := filepath.Join(dir, DownloadFileName(d.SuggestedFilename()))
+	if err := d.SaveAs(path); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("save download: %w", err)
+	}
+	return path, nil
+}
+
+// DownloadFileName 清理浏览器建议的文件名，去掉路径部分，为空时使用 download
+func DownloadFileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "download"
+	}
+	return name
+}
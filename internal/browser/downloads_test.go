@@ -0,0 +1,64 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadFileName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"report.csv", "report.csv"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\report.xlsx`, "report.xlsx"},
+		{"  ", "download"},
+		{"..", "download"},
+		{"/", "download"},
+	}
+	for _, tt := range tests {
+		if got := DownloadFileName(tt.in); got != tt.want {
+			t.Errorf("DownloadFileName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWaitForDownload(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/export" {
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", `attachment; filename="report.csv"`)
+			fmt.Fprint(w, "id,total\n1,99\n")
+			return
+		}
+		fmt.Fprint(w, `<html><body><a id="export" href="/export">Export report</a></body></html>`)
+	}))
+	defer srv.Close()
+	if err := c.Navigate(ctx, srv.URL+"/"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有下载时超时
+	if _, err := c.WaitForDownload(ctx, 200*time.Millisecond); err == nil {
+		t.Fatal("WaitForDownload succeeded without a download")
+	}
+
+	if err := c.Click(ctx, "#export"); err != nil {
+		t.Fatal(err)
+	}
+	path, err := c.WaitForDownload(ctx, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(filepath.Dir(path))
+	data, err := os.ReadFile(path)
+	if err != nil || filepath.Base(path) != "report.csv" || string(data) != "id,total\n1,99\n" {
+		t.Errorf("download %s = %q (%v)", path, data, err)
+	}
+}
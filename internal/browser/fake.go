@@ -0,0 +1,549 @@
+// Package browser 提供浏览器控制功能
+package browser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// ErrFakeNotConnected FakeController 未连接时调用页面操作
+var ErrFakeNotConnected = errors.New("fake browser not connected")
+
+// FakeAction FakeController 记录的一次调用
+type FakeAction struct {
+	Method   string `json:"method"`
+	Selector string `json:"selector,omitempty"` // 选择器、URL 或文本
+	Value    string `json:"value,omitempty"`
+}
+
+// FakeController 脚本化的 Controller 实现，不启动浏览器：TakeSnapshot 按顺序返回预设快照，
+// 所有调用按顺序记录，可通过 Errors 按方法名注入错误。用于测试和离线演示
+type FakeController struct {
+	mu sync.Mutex
+
+	snapshots []*PageSnapshot
+	next      int
+	actions   []FakeAction
+	connected bool
+	url       string
+	cookies   []domain.Cookie
+	frames    []string
+	headers   originHeaders
+	captcha   string // 已写入的验证码令牌，导航后清空
+	resolved  string // 最近一次元素操作实际使用的选择器
+	shots     []ScreenshotOptions
+
+	// Errors 按方法名注入的错误，如 {"Click": err}
+	Errors map[string]error
+	// Texts ExtractText 按选择器返回的文本
+	Texts map[string]string
+	// Screenshot TakeScreenshot 返回的图片，为空时返回 1x1 的白色 PNG
+	Screenshot []byte
+	// Downloads WaitForDownload 依次返回的下载文件，用尽后等待超时
+	Downloads []FakeDownload
+	// CaptchaSiteKey 页面上 reCAPTCHA 的站点密钥，为空表示页面没有验证码
+	CaptchaSiteKey string
+	// Resolved 模拟回退匹配：元素操作的选择器到实际使用的选择器，未设置时即为原选择器
+	Resolved map[string]string
+	// Visible WaitForAny 视为已出现的选择器，为空时第一个候选即出现
+	Visible map[string]bool
+	// Fingerprint PageFingerprint 返回的页面指纹，为空时表示无法判断，调用方每次都会重新采集快照
+	Fingerprint string
+}
+
+// FakeDownload FakeController 模拟的下载文件
+type FakeDownload struct {
+	Name string
+	Data []byte
+}
+
+// NewFakeController 创建 FakeController，快照用尽后重复返回最后一个，未提供时返回当前 URL 的空白页
+func NewFakeController(snapshots ...*PageSnapshot) *FakeController {
+	return &FakeController{
+		snapshots: snapshots,
+		Errors:    make(map[string]error),
+		Texts:     make(map[string]string),
+	}
+}
+
+// Actions 返回已记录的调用
+func (f *FakeController) Actions() []FakeAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeAction(nil), f.actions...)
+}
+
+// record 记录调用并返回注入的错误，要求已连接的方法未连接时返回 ErrFakeNotConnected
+func (f *FakeController) record(method, selector, value string, needConn bool) error {
+	f.actions = append(f.actions, FakeAction{Method: method, Selector: selector, Value: value})
+	if err := f.Errors[method]; err != nil {
+		return err
+	}
+	if needConn && !f.connected {
+		return ErrFakeNotConnected
+	}
+	return nil
+}
+
+// Connect 标记为已连接，模拟新的浏览器上下文：清空 Cookie 与页面状态。
+// 记录的 Selector 为使用的代理地址，Value 为语言区域
+func (f *FakeController) Connect(ctx context.Context, opts ContextOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Connect", opts.Proxy.String(), opts.Locale, false); err != nil {
+		return err
+	}
+	f.resetContext()
+	f.connected = true
+	return nil
+}
+
+// CloseContext 清空 Cookie 与页面状态，保持已连接
+func (f *FakeController) CloseContext(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resetContext()
+	return f.record("CloseContext", "", "", false)
+}
+
+// Close 标记为未连接，清空页面状态
+func (f *FakeController) Close(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connected = false
+	f.resetContext()
+	return f.record("Close", "", "", false)
+}
+
+// resetContext 丢弃上下文中的状态，调用方需持有锁
+func (f *FakeController) resetContext() {
+	f.url = ""
+	f.cookies = nil
+	f.frames = nil
+	f.captcha = ""
+	f.headers = originHeaders{}
+}
+
+// NewPage 保留 Cookie，重置当前 URL
+func (f *FakeController) NewPage(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("NewPage", "", "", true); err != nil {
+		return err
+	}
+	f.url = ""
+	f.frames = nil
+	f.captcha = ""
+	return nil
+}
+
+// EnsureConnected 未连接时返回错误，不模拟重连
+func (f *FakeController) EnsureConnected(ctx context.Context) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return false, f.record("EnsureConnected", "", "", true)
+}
+
+// Navigate 记录导航并更新当前 URL
+func (f *FakeController) Navigate(ctx context.Context, url string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Navigate", url, "", true); err != nil {
+		return err
+	}
+	f.url = url
+	f.frames = nil
+	f.captcha = ""
+	return nil
+}
+
+// GetCurrentURL 返回最近一次导航的 URL
+func (f *FakeController) GetCurrentURL(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.url, f.record("GetCurrentURL", "", "", true)
+}
+
+// WaitForNavigation 立即返回
+func (f *FakeController) WaitForNavigation(ctx context.Context, timeout time.Duration) error {
+	return f.do("WaitForNavigation", "", "")
+}
+
+// WaitForURL 当前 URL 不包含 urlPattern 时立即超时
+func (f *FakeController) WaitForURL(ctx context.Context, urlPattern string, timeout time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("WaitForURL", urlPattern, "", true); err != nil {
+		return err
+	}
+	if !strings.Contains(f.url, urlPattern) {
+		return fmt.Errorf("timeout waiting for url %s", urlPattern)
+	}
+	return nil
+}
+
+// Click 记录点击
+func (f *FakeController) Click(ctx context.Context, selector string) error {
+	return f.doElement("Click", selector, "")
+}
+
+// ClickByText 记录按文本点击
+func (f *FakeController) ClickByText(ctx context.Context, text string, opts ClickOptions) error {
+	value := ""
+	if opts.Exact || opts.Nth > 0 {
+		value = fmt.Sprintf("exact=%t,nth=%d", opts.Exact, opts.Nth)
+	}
+	return f.doElement("ClickByText", text, value)
+}
+
+// Fill 记录填写
+func (f *FakeController) Fill(ctx context.Context, selector string, value string) error {
+	return f.doElement("Fill", selector, value)
+}
+
+// TypeText 记录逐字键入，不等待 delay
+func (f *FakeController) TypeText(ctx context.Context, selector string, value string, delay time.Duration) error {
+	return f.doElement("TypeText", selector, value)
+}
+
+// Hover 记录悬停
+func (f *FakeController) Hover(ctx context.Context, selector string) error {
+	return f.doElement("Hover", selector, "")
+}
+
+// Select 记录下拉选择
+func (f *FakeController) Select(ctx context.Context, selector string, value string) error {
+	return f.doElement("Select", selector, value)
+}
+
+// ResolvedSelector 返回最近一次元素操作实际使用的选择器
+func (f *FakeController) ResolvedSelector(ctx context.Context) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.resolved
+}
+
+// DismissOverlays 记录调用，不关闭任何遮挡层
+func (f *FakeController) DismissOverlays(ctx context.Context) (int, error) {
+	return 0, f.do("DismissOverlays", "", "")
+}
+
+// FindCaptchaSiteKey 返回 CaptchaSiteKey，已写入令牌后返回空
+func (f *FakeController) FindCaptchaSiteKey(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("FindCaptchaSiteKey", "", "", true); err != nil {
+		return "", err
+	}
+	if f.captcha != "" {
+		return "", nil
+	}
+	return f.CaptchaSiteKey, nil
+}
+
+// InjectCaptchaToken 记录写入的令牌，可通过 CaptchaToken 查询
+func (f *FakeController) InjectCaptchaToken(ctx context.Context, token string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("InjectCaptchaToken", "", token, true); err != nil {
+		return err
+	}
+	f.captcha = token
+	return nil
+}
+
+// CaptchaToken 返回当前页面已写入的验证码令牌
+func (f *FakeController) CaptchaToken() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.captcha
+}
+
+// SwitchToFrame 记录进入的 iframe，不校验是否存在
+func (f *FakeController) SwitchToFrame(ctx context.Context, nameOrSelector string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("SwitchToFrame", nameOrSelector, "", true); err != nil {
+		return err
+	}
+	f.frames = append(f.frames, nameOrSelector)
+	return nil
+}
+
+// SwitchToMainFrame 回到主文档
+func (f *FakeController) SwitchToMainFrame(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("SwitchToMainFrame", "", "", true); err != nil {
+		return err
+	}
+	f.frames = nil
+	return nil
+}
+
+// ActiveFrame 返回当前进入的 iframe 链，主文档为空
+func (f *FakeController) ActiveFrame() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.frames...)
+}
+
+// WaitForDownload 将下一个预设下载写入临时目录，没有预设下载时立即超时
+func (f *FakeController) WaitForDownload(ctx context.Context, timeout time.Duration) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("WaitForDownload", "", "", true); err != nil {
+		return "", err
+	}
+	if len(f.Downloads) == 0 {
+		return "", fmt.Errorf("timeout waiting for download")
+	}
+	d := f.Downloads[0]
+	f.Downloads = f.Downloads[1:]
+
+	dir, err := os.MkdirTemp("", "fake-download-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, DownloadFileName(d.Name))
+	if err := os.WriteFile(path, d.Data, 0o644); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return path, nil
+}
+
+// WaitForSelector 立即返回
+func (f *FakeController) WaitForSelector(ctx context.Context, selector string, timeout time.Duration) error {
+	return f.do("WaitForSelector", selector, "")
+}
+
+// WaitForAny 返回第一个在 Visible 中的候选，Visible 为空时返回第一个候选，都不在时立即超时
+func (f *FakeController) WaitForAny(ctx context.Context, selectors []string, timeout time.Duration) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("WaitForAny", strings.Join(selectors, " | "), "", true); err != nil {
+		return "", err
+	}
+	for _, selector := range selectors {
+		if len(f.Visible) == 0 || f.Visible[selector] {
+			return selector, nil
+		}
+	}
+	return "", fmt.Errorf("none of %q appeared within %s", selectors, timeout)
+}
+
+// WaitForEnabled 立即返回
+func (f *FakeController) WaitForEnabled(ctx context.Context, selector string, timeout time.Duration) error {
+	return f.doElement("WaitForEnabled", selector, "")
+}
+
+// WaitForText 立即返回
+func (f *FakeController) WaitForText(ctx context.Context, text string, timeout time.Duration) error {
+	return f.do("WaitForText", text, "")
+}
+
+// WaitForCondition 立即返回
+func (f *FakeController) WaitForCondition(ctx context.Context, jsExpr string, timeout time.Duration) error {
+	return f.do("WaitForCondition", jsExpr, "")
+}
+
+// TakeSnapshot 按顺序返回预设快照，未设置 URL 的快照使用当前 URL
+func (f *FakeController) TakeSnapshot(ctx context.Context) (*PageSnapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("TakeSnapshot", "", "", true); err != nil {
+		return nil, err
+	}
+	snapshot := f.currentSnapshot()
+	if len(f.snapshots) > 0 && f.next < len(f.snapshots)-1 {
+		f.next++
+	}
+	return snapshot, nil
+}
+
+// PageFingerprint 返回 Fingerprint 字段
+func (f *FakeController) PageFingerprint(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("PageFingerprint", "", "", true); err != nil {
+		return "", err
+	}
+	return f.Fingerprint, nil
+}
+
+// currentSnapshot 返回当前快照的副本，调用方需持有锁
+func (f *FakeController) currentSnapshot() *PageSnapshot {
+	snapshot := &PageSnapshot{}
+	if len(f.snapshots) > 0 {
+		*snapshot = *f.snapshots[f.next]
+	}
+	if snapshot.URL == "" {
+		snapshot.URL = f.url
+	}
+	snapshot.Timestamp = time.Now()
+	return snapshot
+}
+
+// TakeScreenshot 返回预设图片
+func (f *FakeController) TakeScreenshot(ctx context.Context, opts ScreenshotOptions) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("TakeScreenshot", "", opts.Type, true); err != nil {
+		return nil, err
+	}
+	f.shots = append(f.shots, opts)
+	if len(f.Screenshot) > 0 {
+		return f.Screenshot, nil
+	}
+	return blankPNG()
+}
+
+// ScreenshotRequests 返回成功截图时使用的选项
+func (f *FakeController) ScreenshotRequests() []ScreenshotOptions {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ScreenshotOptions(nil), f.shots...)
+}
+
+// GetPageTitle 返回当前快照的标题
+func (f *FakeController) GetPageTitle(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("GetPageTitle", "", "", true); err != nil {
+		return "", err
+	}
+	return f.currentSnapshot().Title, nil
+}
+
+// ExtractText 返回 Texts 中预设的文本，未设置时返回错误
+func (f *FakeController) ExtractText(ctx context.Context, selector string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("ExtractText", selector, "", true); err != nil {
+		return "", err
+	}
+	f.resolve(selector)
+	text, ok := f.Texts[selector]
+	if !ok {
+		return "", fmt.Errorf("element not found: %s", selector)
+	}
+	return text, nil
+}
+
+// GetCookies 返回已设置的 Cookie
+func (f *FakeController) GetCookies(ctx context.Context) ([]domain.Cookie, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("GetCookies", "", "", true); err != nil {
+		return nil, err
+	}
+	return append([]domain.Cookie(nil), f.cookies...), nil
+}
+
+// SetCookies 按 domain/path/name 覆盖或追加 Cookie
+func (f *FakeController) SetCookies(ctx context.Context, cookies []domain.Cookie) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("SetCookies", "", fmt.Sprint(len(cookies)), true); err != nil {
+		return err
+	}
+	for _, c := range cookies {
+		replaced := false
+		for i, existing := range f.cookies {
+			if existing.Domain == c.Domain && existing.Path == c.Path && existing.Name == c.Name {
+				f.cookies[i] = c
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			f.cookies = append(f.cookies, c)
+		}
+	}
+	return nil
+}
+
+// ClearCookies 清空 Cookie
+func (f *FakeController) ClearCookies(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("ClearCookies", "", "", true); err != nil {
+		return err
+	}
+	f.cookies = nil
+	return nil
+}
+
+// SetOriginHeaders 记录请求头及其作用的源，可通过 HeadersFor 查询某个 URL 实际附加的请求头
+func (f *FakeController) SetOriginHeaders(ctx context.Context, targetURL string, headers map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("SetOriginHeaders", targetURL, fmt.Sprint(len(headers)), true); err != nil {
+		return err
+	}
+	scoped, err := newOriginHeaders(targetURL, headers)
+	if err != nil {
+		return err
+	}
+	f.headers = scoped
+	return nil
+}
+
+// HeadersFor 返回请求 requestURL 时附加的请求头（小写名），不同源时返回 nil
+func (f *FakeController) HeadersFor(requestURL string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	merged, _ := f.headers.apply(requestURL, nil)
+	return merged
+}
+
+// do 记录只需连接检查的调用
+func (f *FakeController) do(method, selector, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record(method, selector, value, true)
+}
+
+// doElement 记录元素操作，成功时按 Resolved 更新实际使用的选择器
+func (f *FakeController) doElement(method, selector, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record(method, selector, value, true); err != nil {
+		return err
+	}
+	f.resolve(selector)
+	return nil
+}
+
+func (f *FakeController) resolve(selector string) {
+	if actual, ok := f.Resolved[selector]; ok {
+		selector = actual
+	}
+	f.resolved = selector
+}
+
+// blankPNG 生成 1x1 白色 PNG
+func blankPNG() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.White)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var _ Controller = (*FakeController)(nil)
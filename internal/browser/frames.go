@@ -0,0 +1,248 @@
+// Package browser 提供浏览器控制功能
+package browser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// FrameSelectorPrefix iframe 作用域前缀：frame:<iframe 选择器> >> <元素选择器>，可多级嵌套。
+// 未指定前缀时先查主文档再依次查子 frame；Playwright 的 CSS/文本选择器本身会穿透开放的 shadow root
+const FrameSelectorPrefix = "frame:"
+
+// frameSelectorSep frame 前缀与元素选择器之间的分隔符
+const frameSelectorSep = ">>"
+
+// splitFrameSelector 拆分开头的 frame: 片段，如 "frame:#a >> frame:#b >> button" → ([#a #b], "button")
+func splitFrameSelector(selector string) (frames []string, inner string) {
+	rest := strings.TrimSpace(selector)
+	for strings.HasPrefix(rest, FrameSelectorPrefix) {
+		idx := strings.Index(rest, frameSelectorSep)
+		if idx < 0 {
+			break
+		}
+		frames = append(frames, strings.TrimSpace(strings.TrimPrefix(rest[:idx], FrameSelectorPrefix)))
+		rest = strings.TrimSpace(rest[idx+len(frameSelectorSep):])
+	}
+	return frames, rest
+}
+
+// locatorScope 选择器的查询范围：主文档、子 frame 或 frame: 前缀指定的 iframe
+type locatorScope interface {
+	Locator(selector string) playwright.Locator
+	GetByText(text string, exact bool) playwright.Locator
+	GetByRole(role playwright.AriaRole, name string, exact bool) playwright.Locator
+}
+
+type pageScope struct{ page playwright.Page }
+
+func (s pageScope) Locator(selector string) playwright.Locator { return s.page.Locator(selector) }
+
+func (s pageScope) GetByText(text string, exact bool) playwright.Locator {
+	return s.page.GetByText(text, playwright.PageGetByTextOptions{Exact: playwright.Bool(exact)})
+}
+
+func (s pageScope) GetByRole(role playwright.AriaRole, name string, exact bool) playwright.Locator {
+	return s.page.GetByRole(role, playwright.PageGetByRoleOptions{Name: name, Exact: playwright.Bool(exact)})
+}
+
+type frameScope struct{ frame playwright.Frame }
+
+func (s frameScope) Locator(selector string) playwright.Locator { return s.frame.Locator(selector) }
+
+func (s frameScope) GetByText(text string, exact bool) playwright.Locator {
+	return s.frame.GetByText(text, playwright.FrameGetByTextOptions{Exact: playwright.Bool(exact)})
+}
+
+func (s frameScope) GetByRole(role playwright.AriaRole, name string, exact bool) playwright.Locator {
+	return s.frame.GetByRole(role, playwright.FrameGetByRoleOptions{Name: name, Exact: playwright.Bool(exact)})
+}
+
+type frameLocatorScope struct{ frame playwright.FrameLocator }
+
+func (s frameLocatorScope) Locator(selector string) playwright.Locator {
+	return s.frame.Locator(selector)
+}
+
+func (s frameLocatorScope) GetByText(text string, exact bool) playwright.Locator {
+	return s.frame.GetByText(text, playwright.FrameLocatorGetByTextOptions{Exact: playwright.Bool(exact)})
+}
+
+func (s frameLocatorScope) GetByRole(role playwright.AriaRole, name string, exact bool) playwright.Locator {
+	return s.frame.GetByRole(role, playwright.FrameLocatorGetByRoleOptions{Name: name, Exact: playwright.Bool(exact)})
+}
+
+// scopes 返回选择器的查询范围及去掉 frame: 前缀后的选择器。
+// 有前缀时只在指定 iframe 内查询（前缀从主文档算起）；已切换到 iframe 时只在该 iframe 内查询；
+// 否则依次为主文档和各子 frame
+func (c *PlaywrightController) scopes(selector string) ([]locatorScope, string) {
+	frames, inner := splitFrameSelector(selector)
+	if len(frames) > 0 {
+		return []locatorScope{c.frameChainScope(frames)}, inner
+	}
+	if len(c.activeFrame) > 0 {
+		return []locatorScope{c.frameChainScope(c.activeFrame)}, inner
+	}
+
+	scopes := []locatorScope{pageScope{c.page}}
+	main := c.page.MainFrame()
+	for _, f := range c.page.Frames() {
+		if f != main {
+			scopes = append(scopes, frameScope{f})
+		}
+	}
+	return scopes, inner
+}
+
+// scopePrefix 返回查询范围对应的 frame: 前缀，使记录的选择器脱离当前 iframe 状态也能复现
+func (c *PlaywrightController) scopePrefix(selector string, scope locatorScope) string {
+	chain, _ := splitFrameSelector(selector)
+	if len(chain) == 0 {
+		chain = c.activeFrame
+	}
+	if fs, ok := scope.(frameScope); ok {
+		chain = nil
+		if sel := frameSelector(fs.frame); sel != "" {
+			chain = []string{sel}
+		}
+	}
+	var b strings.Builder
+	for _, f := range chain {
+		b.WriteString(FrameSelectorPrefix + f + " " + frameSelectorSep + " ")
+	}
+	return b.String()
+}
+
+// frameChainScope 按 iframe 选择器链逐级进入，返回最内层 iframe 的查询范围
+func (c *PlaywrightController) frameChainScope(chain []string) locatorScope {
+	fl := c.page.FrameLocator(chain[0])
+	for _, f := range chain[1:] {
+		fl = fl.FrameLocator(f)
+	}
+	return frameLocatorScope{fl}
+}
+
+// frameNamePattern 只含字母、数字、下划线和连字符的目标视为 iframe 的 name 或 id
+var frameNamePattern = regexp.MustCompile(`^[\w-]+$`)
+
+// frameTargetSelector 将 SwitchToFrame 的参数转换为 iframe 选择器：
+// 名称按 name/id 匹配，其余（含 frame: 前缀写法）按 CSS 选择器处理
+func frameTargetSelector(nameOrSelector string) string {
+	target := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(nameOrSelector), FrameSelectorPrefix))
+	if frameNamePattern.MatchString(target) && target != "iframe" && target != "frame" {
+		return fmt.Sprintf(`iframe[name="%[1]s"], iframe[id="%[1]s"], frame[name="%[1]s"]`, target)
+	}
+	return target
+}
+
+// SwitchToFrame 进入当前文档（主文档或已进入的 iframe）中的 iframe，
+// 之后不带 frame: 前缀的元素操作只在该 iframe 内查找，直到 SwitchToMainFrame 或页面跳转
+func (c *PlaywrightController) SwitchToFrame(ctx context.Context, nameOrSelector string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sel := frameTargetSelector(nameOrSelector)
+	if sel == "" {
+		return fmt.Errorf("frame name or selector required")
+	}
+	var scope locatorScope = pageScope{c.page}
+	if len(c.activeFrame) > 0 {
+		scope = c.frameChainScope(c.activeFrame)
+	}
+	n, err := scope.Locator(sel).Count()
+	if err != nil {
+		return fmt.Errorf("switch to frame %s: %w", nameOrSelector, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("frame not found: %s", nameOrSelector)
+	}
+	c.activeFrame = append(c.activeFrame, sel)
+	log.Printf("[Browser] Switched to frame %s", strings.Join(c.activeFrame, " >> "))
+	return nil
+}
+
+// SwitchToMainFrame 回到主文档
+func (c *PlaywrightController) SwitchToMainFrame(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.activeFrame = nil
+	return nil
+}
+
+// activeFrameTimeout 定位已进入 iframe 的等待时间（毫秒），SwitchToFrame 时已确认其存在
+const activeFrameTimeout = 2000
+
+// currentFrame 返回 SwitchToFrame 进入的 iframe 对应的 frame，未进入 iframe 时返回主 frame，
+// 供需要在当前文档中执行脚本的操作使用
+func (c *PlaywrightController) currentFrame() (playwright.Frame, error) {
+	n := len(c.activeFrame)
+	if n == 0 {
+		return c.page.MainFrame(), nil
+	}
+	var scope locatorScope = pageScope{c.page}
+	if n > 1 {
+		scope = c.frameChainScope(c.activeFrame[:n-1])
+	}
+	el, err := scope.Locator(c.activeFrame[n-1]).First().ElementHandle(playwright.LocatorElementHandleOptions{
+		Timeout: playwright.Float(activeFrameTimeout),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("locate frame %s: %w", framePrefix(c.activeFrame), err)
+	}
+	defer el.Dispose()
+	frame, err := el.ContentFrame()
+	if err != nil || frame == nil {
+		return nil, fmt.Errorf("frame %s has no document", framePrefix(c.activeFrame))
+	}
+	return frame, nil
+}
+
+// frameElementSelectorJS 为 iframe 元素生成 CSS 选择器
+const frameElementSelectorJS = `e => {
+	if (e.id) return '#' + CSS.escape(e.id);
+	if (e.name) return 'iframe[name="' + e.name + '"]';
+	const src = e.getAttribute('src');
+	if (src) return 'iframe[src="' + src + '"]';
+	return '';
+}`
+
+// frameSelector 返回子 frame 对应 iframe 元素的选择器，无法定位时返回空串
+func frameSelector(frame playwright.Frame) string {
+	el, err := frame.FrameElement()
+	if err != nil {
+		return ""
+	}
+	defer el.Dispose()
+	sel, err := el.Evaluate(frameElementSelectorJS)
+	if err != nil {
+		return ""
+	}
+	s, _ := sel.(string)
+	return s
+}
+
+// sameOrigin 判断两个 URL 是否同源
+func sameOrigin(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return ua.Scheme == ub.Scheme && ua.Host == ub.Host
+}
+
+// framePrefix 生成元素所在 frame 的选择器前缀
+func framePrefix(chain []string) string {
+	var b strings.Builder
+	for _, sel := range chain {
+		fmt.Fprintf(&b, "%s%s %s ", FrameSelectorPrefix, sel, frameSelectorSep)
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,171 @@
+package browser
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestSplitFrameSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		frames   []string
+		inner    string
+	}{
+		{"#submit", nil, "#submit"},
+		{"frame:#pay >> #card", []string{"#pay"}, "#card"},
+		{" frame:#outer >> frame:iframe[name='inner'] >> button ", []string{"#outer", "iframe[name='inner']"}, "button"},
+		{"frame:#broken", nil, "frame:#broken"},
+	}
+	for _, tt := range tests {
+		frames, inner := splitFrameSelector(tt.selector)
+		if strings.Join(frames, "|") != strings.Join(tt.frames, "|") || inner != tt.inner {
+			t.Errorf("splitFrameSelector(%q) = %q, %q; want %q, %q", tt.selector, frames, inner, tt.frames, tt.inner)
+		}
+	}
+	if got := framePrefix([]string{"#outer", "#inner"}); got != "frame:#outer >> frame:#inner >>" {
+		t.Errorf("framePrefix = %q", got)
+	}
+}
+
+func TestFrameTargetSelector(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"payment", `iframe[name="payment"], iframe[id="payment"], frame[name="payment"]`},
+		{"frame:#payment", "#payment"},
+		{"iframe", "iframe"},
+		{"iframe.editor", "iframe.editor"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := frameTargetSelector(tt.target); got != tt.want {
+			t.Errorf("frameTargetSelector(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestFrameAndShadowSelectors(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	base := serveFixture(t, map[string]string{
+		"/": `<html><body>
+<iframe id="child" src="/child"></iframe>
+<x-card></x-card>
+<script>
+customElements.define('x-card', class extends HTMLElement {
+	constructor() {
+		super();
+		this.attachShadow({mode: 'open'}).innerHTML = '<input id="shadow-name"><button id="shadow-save">Save</button>';
+	}
+});
+</script></body></html>`,
+		"/child": `<html><body><input id="inner-email"><button id="inner-pay">Pay now</button></body></html>`,
+	})
+	if err := c.Navigate(ctx, base+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WaitForSelector(ctx, "frame:#child >> #inner-pay", 5*time.Second); err != nil {
+		t.Fatalf("iframe content not loaded: %v", err)
+	}
+
+	// iframe：显式前缀与自动查找子 frame
+	if err := c.Fill(ctx, "frame:#child >> #inner-email", "a@example.com"); err != nil {
+		t.Errorf("Fill with frame prefix: %v", err)
+	}
+	if err := c.Click(ctx, "#inner-pay"); err != nil {
+		t.Errorf("Click inside iframe without prefix: %v", err)
+	}
+	if got := c.ResolvedSelector(ctx); !strings.HasPrefix(got, "frame:#child") || !strings.HasSuffix(got, "#inner-pay") {
+		t.Errorf("resolved selector = %q, want frame-prefixed", got)
+	}
+
+	// shadow root：CSS 选择器直接穿透
+	if err := c.Fill(ctx, "#shadow-name", "Alice"); err != nil {
+		t.Errorf("Fill inside shadow root: %v", err)
+	}
+	if err := c.Click(ctx, "#shadow-save"); err != nil {
+		t.Errorf("Click inside shadow root: %v", err)
+	}
+
+	snapshot, err := c.TakeSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inFrame bool
+	for _, el := range snapshot.Elements {
+		inFrame = inFrame || (strings.HasPrefix(el.Selector, FrameSelectorPrefix) && strings.Contains(el.Selector, "inner-pay"))
+	}
+	if !inFrame {
+		t.Error("snapshot does not include elements of the same-origin iframe")
+	}
+}
+
+func TestSwitchToFrame(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	base := serveFixture(t, map[string]string{
+		"/":        `<html><body><input id="name"><iframe name="payment" src="/payment"></iframe></body></html>`,
+		"/payment": `<html><body><form><input id="name"><input id="card"><iframe id="otp" src="/otp"></iframe></form></body></html>`,
+		"/otp":     `<html><body><input id="code"></body></html>`,
+	})
+	if err := c.Navigate(ctx, base+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WaitForSelector(ctx, "frame:iframe[name=payment] >> frame:#otp >> #code", 5*time.Second); err != nil {
+		t.Fatalf("iframe content not loaded: %v", err)
+	}
+
+	if err := c.SwitchToFrame(ctx, "missing"); err == nil {
+		t.Error("switching to a missing frame succeeded")
+	}
+	// 进入支付 iframe 后，同名元素在 iframe 内查找
+	if err := c.SwitchToFrame(ctx, "payment"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Fill(ctx, "#name", "Alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Fill(ctx, "#card", "4242"); err != nil {
+		t.Fatal(err)
+	}
+	// 记录的选择器带上当前 iframe 的前缀，离开 iframe 后仍可复现
+	if got := c.ResolvedSelector(ctx); !strings.HasPrefix(got, `frame:iframe[name="payment"]`) || !strings.HasSuffix(got, ">> #card") {
+		t.Errorf("resolved selector inside frame = %q, want frame-prefixed #card", got)
+	}
+	// 嵌套 iframe 从当前 iframe 算起
+	if err := c.SwitchToFrame(ctx, "#otp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Fill(ctx, "#code", "123456"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SwitchToMainFrame(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Fill(ctx, "#name", "Main"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ResolvedSelector(ctx); got != "#name" {
+		t.Errorf("resolved selector after leaving the frame = %q, want #name", got)
+	}
+
+	payment := c.page.FrameLocator("iframe[name=payment]")
+	for _, tt := range []struct {
+		loc  playwright.Locator
+		want string
+	}{
+		{c.page.Locator("#name"), "Main"},
+		{payment.Locator("#name"), "Alice"},
+		{payment.Locator("#card"), "4242"},
+		{payment.FrameLocator("#otp").Locator("#code"), "123456"},
+	} {
+		if got, err := tt.loc.InputValue(); err != nil || got != tt.want {
+			t.Errorf("value = %q (%v), want %q", got, err, tt.want)
+		}
+	}
+}
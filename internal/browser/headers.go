@@ -0,0 +1,87 @@
+// Package browser 提供浏览器控制功能
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// originRoutePattern 请求头注入拦截的 URL 模式
+const originRoutePattern = "**/*"
+
+// originHeaders 只附加到指定源请求的请求头
+type originHeaders struct {
+	origin  string
+	headers map[string]string
+}
+
+// newOriginHeaders 以 targetURL 的 scheme://host 为源，请求头名统一转为小写
+func newOriginHeaders(targetURL string, headers map[string]string) (originHeaders, error) {
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return originHeaders{}, fmt.Errorf("invalid target url %q", targetURL)
+	}
+	h := make(map[string]string, len(headers))
+	for k, v := range headers {
+		h[strings.ToLower(k)] = v
+	}
+	return originHeaders{origin: u.Scheme + "://" + u.Host, headers: h}, nil
+}
+
+// apply 请求与目标同源时返回合并后的请求头，否则返回 false
+func (o originHeaders) apply(requestURL string, current map[string]string) (map[string]string, bool) {
+	if len(o.headers) == 0 || !sameOrigin(requestURL, o.origin) {
+		return nil, false
+	}
+	merged := make(map[string]string, len(current)+len(o.headers))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range o.headers {
+		merged[k] = v
+	}
+	return merged, true
+}
+
+// SetOriginHeaders 拦截浏览器上下文的请求，只为与 targetURL 同源的请求附加请求头，第三方资源的请求保持原样。
+// Playwright 会把附加的请求头沿用到该请求后续的重定向，同源请求跳转到其他站点时仍会携带。
+// 再次调用时替换之前的设置，只移除本方法注册的拦截
+func (c *PlaywrightController) SetOriginHeaders(ctx context.Context, targetURL string, headers map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.page == nil {
+		return fmt.Errorf("browser not connected")
+	}
+	scoped, err := newOriginHeaders(targetURL, headers)
+	if err != nil {
+		return err
+	}
+	browserCtx := c.page.Context()
+	if c.headerRoute != nil {
+		if err := browserCtx.Unroute(originRoutePattern, c.headerRoute); err != nil {
+			return fmt.Errorf("unroute: %w", err)
+		}
+		c.headerRoute = nil
+	}
+	if len(scoped.headers) == 0 {
+		return nil
+	}
+	handler := func(route playwright.Route) {
+		req := route.Request()
+		if merged, ok := scoped.apply(req.URL(), req.Headers()); ok {
+			route.Continue(playwright.RouteContinueOptions{Headers: merged})
+			return
+		}
+		route.Continue()
+	}
+	if err := browserCtx.Route(originRoutePattern, handler); err != nil {
+		return err
+	}
+	c.headerRoute = handler
+	return nil
+}
@@ -0,0 +1,70 @@
+package browser
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOriginHeadersApply(t *testing.T) {
+	scoped, err := newOriginHeaders("https://app.example.com/dashboard?x=1", map[string]string{"Authorization": "Bearer t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://app.example.com/api/items", true},
+		{"https://app.example.com", true},
+		{"http://app.example.com/api", false},
+		{"https://app.example.com:8443/api", false},
+		{"https://cdn.example.com/app.js", false},
+		{"https://evil.test/?next=https://app.example.com", false},
+		{"not a url", false},
+	}
+	for _, tt := range tests {
+		merged, ok := scoped.apply(tt.url, map[string]string{"accept": "*/*"})
+		if ok != tt.want {
+			t.Errorf("apply(%s) = %v, want %v", tt.url, ok, tt.want)
+			continue
+		}
+		if ok && (merged["authorization"] != "Bearer t" || merged["accept"] != "*/*") {
+			t.Errorf("apply(%s) headers = %v, want the lower-cased header merged into the request headers", tt.url, merged)
+		}
+	}
+
+	if _, err := newOriginHeaders("ftp://files.example.com", nil); err == nil {
+		t.Error("non-http target accepted")
+	}
+	if _, ok := (originHeaders{}).apply("https://app.example.com", nil); ok {
+		t.Error("empty headers applied")
+	}
+}
+
+func TestFakeSetOriginHeaders(t *testing.T) {
+	ctx := context.Background()
+	f := NewFakeController()
+	if err := f.Connect(ctx, ContextOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetOriginHeaders(ctx, "https://app.example.com/", map[string]string{"Authorization": "Bearer t"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.HeadersFor("https://app.example.com/api"); got["authorization"] != "Bearer t" {
+		t.Errorf("target origin headers = %v, want the token", got)
+	}
+	if got := f.HeadersFor("https://tracker.test/pixel.gif"); got != nil {
+		t.Errorf("other origin headers = %v, want none", got)
+	}
+
+	// 再次设置时替换之前的请求头
+	if err := f.SetOriginHeaders(ctx, "https://other.example.com/", map[string]string{"X-Api-Key": "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.HeadersFor("https://app.example.com/api"); got != nil {
+		t.Errorf("previous origin headers = %v, want them replaced", got)
+	}
+	if got := f.HeadersFor("https://other.example.com/"); got["x-api-key"] != "k" {
+		t.Errorf("new origin headers = %v", got)
+	}
+}
@@ -0,0 +1,65 @@
+// Package browser 提供浏览器控制功能
+package browser
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// consentButtonSelectors 常见同意管理平台（OneTrust、Cookiebot、Didomi 等）的"全部接受"按钮
+var consentButtonSelectors = []string{
+	"#onetrust-accept-btn-handler",
+	"#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll",
+	"#CybotCookiebotDialogBodyButtonAccept",
+	"#didomi-notice-agree-button",
+	"#truste-consent-button",
+	"button[data-testid='uc-accept-all-button']",
+	".fc-cta-consent",
+	".cc-allow",
+	".cc-dismiss",
+}
+
+// consentContainerSelector 可能是同意横幅的容器，文本匹配只在这些容器内进行，避免误点页面上的普通按钮
+const consentContainerSelector = `[id*="cookie" i], [class*="cookie" i], [id*="consent" i], [class*="consent" i], ` +
+	`[id*="gdpr" i], [class*="gdpr" i], [aria-label*="cookie" i], [aria-label*="consent" i]`
+
+// consentButtonText 横幅内接受按钮的文本
+var consentButtonText = regexp.MustCompile(`(?i)^\s*(accept( all)?( cookies)?|allow( all)?( cookies)?|i agree|agree|got it|同意|我同意|接受|全部接受|接受全部|接受所有|我知道了|知道了)\s*$`)
+
+// overlayClickTimeout 点击横幅按钮的超时（毫秒），横幅不可点击时尽快放弃
+const overlayClickTimeout = 2000
+
+// DismissOverlays 检测并关闭常见的 Cookie 同意横幅，返回关闭的数量
+func (c *PlaywrightController) DismissOverlays(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dismissed := 0
+	for _, sel := range consentButtonSelectors {
+		if clickIfVisible(c.page.Locator(sel).First()) {
+			dismissed++
+		}
+	}
+	if dismissed > 0 {
+		return dismissed, nil
+	}
+
+	buttons := c.page.Locator(consentContainerSelector).
+		Locator(`button, a, [role="button"], input[type="button"], input[type="submit"]`).
+		Filter(playwright.LocatorFilterOptions{HasText: consentButtonText})
+	if clickIfVisible(buttons.First()) {
+		dismissed++
+	}
+	return dismissed, nil
+}
+
+// clickIfVisible 元素可见时点击，返回是否点击成功
+func clickIfVisible(loc playwright.Locator) bool {
+	visible, err := loc.IsVisible()
+	if err != nil || !visible {
+		return false
+	}
+	return loc.Click(playwright.LocatorClickOptions{Timeout: playwright.Float(overlayClickTimeout)}) == nil
+}
@@ -0,0 +1,56 @@
+package browser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestDismissOverlays(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+
+	const buy = `<button id="buy" onclick="document.getElementById('status').textContent = 'bought'">Buy</button><span id="status"></span>`
+	tests := []struct {
+		name          string
+		html          string
+		wantDismissed int
+	}{
+		{"text match inside a consent banner", `<html><body>` + buy + `
+<div id="cookie-banner" style="position:fixed;inset:0;background:#fff">
+  <p>We use cookies.</p><button onclick="this.parentElement.remove()">Accept all</button>
+</div></body></html>`, 1},
+		{"known consent platform button", `<html><body>` + buy + `
+<div style="position:fixed;inset:0;background:#fff">
+  <button id="onetrust-accept-btn-handler" onclick="this.parentElement.remove()">OK</button>
+</div></body></html>`, 1},
+		{"accept button outside a banner is left alone", `<html><body>` + buy + `
+<button onclick="document.getElementById('status').textContent = 'accepted'">Accept</button></body></html>`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openFixture(t, c, tt.html)
+			blocked := tt.wantDismissed > 0
+			// 横幅遮挡时按钮无法点击
+			trial := c.page.Locator("#buy").Click(playwright.LocatorClickOptions{Trial: playwright.Bool(true), Timeout: playwright.Float(500)})
+			if (trial != nil) != blocked {
+				t.Fatalf("trial click before dismissing: %v, want blocked %v", trial, blocked)
+			}
+
+			n, err := c.DismissOverlays(ctx)
+			if err != nil || n != tt.wantDismissed {
+				t.Fatalf("DismissOverlays = %d, %v, want %d", n, err, tt.wantDismissed)
+			}
+			if got, _ := c.ExtractText(ctx, "#status"); got != "" {
+				t.Fatalf("status after dismissing = %q, page button clicked", got)
+			}
+			if err := c.Click(ctx, "#buy"); err != nil {
+				t.Fatalf("Click after dismissing: %v", err)
+			}
+			if got, _ := c.ExtractText(ctx, "#status"); got != "bought" {
+				t.Errorf("status = %q, want bought", got)
+			}
+		})
+	}
+}
@@ -3,84 +3,300 @@ package browser
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/browser-automation/internal/domain"
 	"github.com/playwright-community/playwright-go"
 )
 
-// PlaywrightController Playwright 浏览器控制器
+// 快照元素数量上限
+const (
+	defaultSnapshotMaxElements  = 30
+	largeDOMSnapshotMaxElements = 10
+	defaultLargeDOMThreshold    = 5000
+)
+
+// maxReconnectAttempts 浏览器断开后的最大重连次数
+const maxReconnectAttempts = 3
+
+// PlaywrightController Playwright 浏览器控制器。
+// 所有导出方法由内部互斥锁串行化，可被多个 goroutine 并发调用；
+// 长时间等待（如 WaitForSelector）会阻塞其他调用直到返回
 type PlaywrightController struct {
-	pw       *playwright.Playwright
-	browser  playwright.Browser
-	page     playwright.Page
-	headless bool
-	wsURL    string
+	mu sync.Mutex
+
+	pw         *playwright.Playwright
+	browser    playwright.Browser
+	browserCtx playwright.BrowserContext // 当前任务的浏览器上下文，Cookie 与存储只在其中可见
+	page       playwright.Page
+	headless   bool
+	wsURL      string
+
+	largeDOMThreshold int
+	elementTextLength int
+	ignoreHTTPSErrors bool
+	launchArgs        []string
+
+	activeFrame []string               // SwitchToFrame 进入的 iframe 选择器链，为空时为主文档
+	resolved    string                 // 最近一次元素操作实际使用的选择器
+	headerRoute func(playwright.Route) // SetOriginHeaders 注册的请求拦截，替换设置时只移除它
+
+	downloads chan playwright.Download // 页面触发的下载，由 WaitForDownload 依次取出
+
+	contextOpts  ContextOptions // 重连时沿用
+	disconnected atomic.Bool    // 由 OnDisconnected 回调置位
+	browserGen   atomic.Int64   // 浏览器实例代数
 }
 
 // PlaywrightOptions Playwright 选项
 type PlaywrightOptions struct {
-	Headless  bool
+	Headless   bool
 	WSEndpoint string
+	// LargeDOMThreshold 页面元素总数超过该值时视为大页面：缩小元素采集上限并跳过无障碍树，默认 5000
+	LargeDOMThreshold int
+	// ElementTextLength 快照中元素文本的长度上限（字符），默认 DefaultElementTextLength
+	ElementTextLength int
+	// IgnoreHTTPSErrors 忽略证书错误，用于自签名证书的内部站点
+	IgnoreHTTPSErrors bool
+	// LaunchArgs 本地启动浏览器的额外参数（如 --no-sandbox）。
+	// 只能由服务端配置，不接受任务参数，避免调用方传入任意浏览器参数
+	LaunchArgs []string
 }
 
 // NewPlaywrightController 创建 Playwright 控制器
 func NewPlaywrightController(opts PlaywrightOptions) *PlaywrightController {
+	threshold := opts.LargeDOMThreshold
+	if threshold <= 0 {
+		threshold = defaultLargeDOMThreshold
+	}
+	textLength := opts.ElementTextLength
+	if textLength <= 0 {
+		textLength = DefaultElementTextLength
+	}
 	return &PlaywrightController{
-		headless: opts.Headless,
-		wsURL:    opts.WSEndpoint,
+		headless:          opts.Headless,
+		wsURL:             opts.WSEndpoint,
+		largeDOMThreshold: threshold,
+		elementTextLength: textLength,
+		ignoreHTTPSErrors: opts.IgnoreHTTPSErrors,
+		launchArgs:        opts.LaunchArgs,
+		downloads:         make(chan playwright.Download, maxPendingDownloads),
 	}
 }
 
-// Connect 连接浏览器
-func (c *PlaywrightController) Connect(ctx context.Context) error {
-	pw, err := playwright.Run()
-	if err != nil {
-		return fmt.Errorf("start playwright: %w", err)
+// Connect 连接浏览器，并按选项创建新的浏览器上下文。浏览器已在运行时直接复用，
+// 之前的上下文先关闭，Cookie、localStorage 等不会带入新的任务
+func (c *PlaywrightController) Connect(ctx context.Context, opts ContextOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.contextOpts = opts
+	if c.browser != nil && c.browser.IsConnected() && !c.disconnected.Load() {
+		return c.newContext()
+	}
+	if c.pw == nil {
+		pw, err := playwright.Run()
+		if err != nil {
+			return fmt.Errorf("start playwright: %w", err)
+		}
+		c.pw = pw
 	}
-	c.pw = pw
+	if c.browser != nil {
+		c.closeContext()
+		c.browser.Close()
+		c.browser = nil
+	}
+	return c.openBrowser()
+}
 
+// CloseContext 关闭当前浏览器上下文及其页面，浏览器保持运行，下次 Connect 时创建新的上下文
+func (c *PlaywrightController) CloseContext(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeContext()
+	return nil
+}
+
+// openBrowser 启动或连接浏览器并创建上下文和页面，调用方需持有锁
+func (c *PlaywrightController) openBrowser() error {
 	var browser playwright.Browser
+	var err error
 	if c.wsURL != "" {
 		// 连接远程浏览器
-		browser, err = pw.Chromium.Connect(c.wsURL)
+		browser, err = c.pw.Chromium.Connect(c.wsURL)
 	} else {
 		// 启动本地浏览器
-		browser, err = pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-			Headless: playwright.Bool(c.headless),
-		})
+		browser, err = c.pw.Chromium.Launch(c.launchOptions())
 	}
 	if err != nil {
 		return fmt.Errorf("launch browser: %w", err)
 	}
 	c.browser = browser
+	// 只响应当前浏览器实例的断开事件，忽略重连时关闭旧实例触发的回调
+	gen := c.browserGen.Add(1)
+	c.disconnected.Store(false)
+	browser.OnDisconnected(func(playwright.Browser) {
+		if c.browserGen.Load() == gen {
+			log.Printf("[Browser] Browser disconnected")
+			c.disconnected.Store(true)
+		}
+	})
+
+	return c.newContext()
+}
 
-	page, err := browser.NewPage()
+// launchOptions 本地启动浏览器的选项，由服务端配置决定
+func (c *PlaywrightController) launchOptions() playwright.BrowserTypeLaunchOptions {
+	return playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(c.headless),
+		Args:     c.launchArgs,
+	}
+}
+
+// newContextOptions 按服务端配置与 contextOpts 生成浏览器上下文选项
+func (c *PlaywrightController) newContextOptions() playwright.BrowserNewContextOptions {
+	contextOpts := playwright.BrowserNewContextOptions{
+		IgnoreHttpsErrors: playwright.Bool(c.ignoreHTTPSErrors),
+	}
+	if c.contextOpts.Locale != "" {
+		contextOpts.Locale = playwright.String(c.contextOpts.Locale)
+	}
+	if c.contextOpts.TimezoneID != "" {
+		contextOpts.TimezoneId = playwright.String(c.contextOpts.TimezoneID)
+	}
+	if p := c.contextOpts.Proxy; p != nil {
+		contextOpts.Proxy = &playwright.Proxy{Server: p.Server}
+		if p.Username != "" {
+			contextOpts.Proxy.Username = playwright.String(p.Username)
+			contextOpts.Proxy.Password = playwright.String(p.Password)
+		}
+	}
+	return contextOpts
+}
+
+// newContext 关闭当前上下文，按 contextOpts 创建新的上下文和页面，调用方需持有锁
+func (c *PlaywrightController) newContext() error {
+	c.closeContext()
+
+	browserCtx, err := c.browser.NewContext(c.newContextOptions())
 	if err != nil {
+		return fmt.Errorf("new context: %w", err)
+	}
+
+	page, err := browserCtx.NewPage()
+	if err != nil {
+		browserCtx.Close()
 		return fmt.Errorf("new page: %w", err)
 	}
+	c.browserCtx = browserCtx
 	c.page = page
+	c.drainDownloads()
+	c.watchDownloads(page)
 
 	return nil
 }
 
+// closeContext 关闭当前上下文，其中的页面、Cookie 与存储随之丢弃，调用方需持有锁
+func (c *PlaywrightController) closeContext() {
+	if c.browserCtx != nil {
+		c.browserCtx.Close()
+	}
+	c.browserCtx = nil
+	c.page = nil
+	c.activeFrame = nil
+	c.headerRoute = nil
+}
+
+// EnsureConnected 检查浏览器连接，断开时按退避重连（最多 maxReconnectAttempts 次）并新建页面。
+// 重连后原页面状态（URL、Cookie）丢失，返回 reconnected=true 由调用方恢复
+func (c *PlaywrightController) EnsureConnected(ctx context.Context) (reconnected bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pw == nil {
+		return false, fmt.Errorf("browser not connected")
+	}
+	if c.browser != nil && c.browser.IsConnected() && !c.disconnected.Load() {
+		return false, nil
+	}
+
+	backoff := time.Second
+	for attempt := 1; attempt <= maxReconnectAttempts; attempt++ {
+		log.Printf("[Browser] Reconnecting (attempt %d/%d)", attempt, maxReconnectAttempts)
+		if c.browser != nil {
+			c.browser.Close()
+			c.browser = nil
+			c.browserCtx = nil
+			c.page = nil
+		}
+		if err = c.openBrowser(); err == nil {
+			return true, nil
+		}
+		log.Printf("[Browser] Reconnect failed: %v", err)
+		if attempt == maxReconnectAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return false, fmt.Errorf("reconnect browser after %d attempts: %w", maxReconnectAttempts, err)
+}
+
 // Close 关闭浏览器
 func (c *PlaywrightController) Close(ctx context.Context) error {
-	if c.page != nil {
-		c.page.Close()
-	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 可重复调用：关闭后清空引用
+	c.closeContext()
 	if c.browser != nil {
 		c.browser.Close()
+		c.browser = nil
 	}
 	if c.pw != nil {
 		c.pw.Stop()
+		c.pw = nil
 	}
 	return nil
 }
 
-// Navigate 导航到 URL
+// NewPage 关闭当前页面并在同一浏览器上下文中新建页面
+func (c *PlaywrightController) NewPage(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.page == nil {
+		return fmt.Errorf("browser not connected")
+	}
+	c.page.Close()
+	page, err := c.browserCtx.NewPage()
+	if err != nil {
+		return fmt.Errorf("new page: %w", err)
+	}
+	c.page = page
+	c.activeFrame = nil
+	c.watchDownloads(page)
+	return nil
+}
+
+// Navigate 导航到 URL，并回到主文档
 func (c *PlaywrightController) Navigate(ctx context.Context, url string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.activeFrame = nil
 	_, err := c.page.Goto(url, playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateNetworkidle,
 	})
@@ -89,11 +305,17 @@ func (c *PlaywrightController) Navigate(ctx context.Context, url string) error {
 
 // GetCurrentURL 获取当前 URL
 func (c *PlaywrightController) GetCurrentURL(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return c.page.URL(), nil
 }
 
 // WaitForNavigation 等待导航完成
 func (c *PlaywrightController) WaitForNavigation(ctx context.Context, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return c.page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
 		Timeout: playwright.Float(float64(timeout.Milliseconds())),
 	})
@@ -101,6 +323,9 @@ func (c *PlaywrightController) WaitForNavigation(ctx context.Context, timeout ti
 
 // WaitForURL 等待 URL 匹配
 func (c *PlaywrightController) WaitForURL(ctx context.Context, urlPattern string, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return c.page.WaitForURL(urlPattern, playwright.PageWaitForURLOptions{
 		Timeout: playwright.Float(float64(timeout.Milliseconds())),
 	})
@@ -108,58 +333,465 @@ func (c *PlaywrightController) WaitForURL(ctx context.Context, urlPattern string
 
 // Click 点击元素
 func (c *PlaywrightController) Click(ctx context.Context, selector string) error {
-	return c.page.Click(selector)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.resolveLocator(selector).Click()
+}
+
+// ClickByText 按可见文本点击；text 也可以是 text=... 等带文本的选择器。
+// 未指定 Nth 时在多个匹配中优先选择可见的按钮/链接，避免严格模式报错
+func (c *PlaywrightController) ClickByText(ctx context.Context, text string, opts ClickOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	selector := text
+	scopes, text := c.scopes(text)
+	if t := selectorText(text); t != "" {
+		text = t
+	}
+
+	loc := scopes[0].GetByText(text, opts.Exact)
+	c.resolved = c.scopePrefix(selector, scopes[0]) + textSelector(text)
+	for _, scope := range scopes {
+		if found, desc, ok := findByText(scope, text, opts.Exact); ok {
+			loc = found
+			c.resolved = c.scopePrefix(selector, scope) + desc
+			break
+		}
+	}
+
+	if opts.Nth > 0 {
+		c.resolved += fmt.Sprintf(" >> nth=%d", opts.Nth-1)
+		return loc.Nth(opts.Nth - 1).Click()
+	}
+	return pickLocator(loc).Click()
 }
 
 // Fill 填写输入框
 func (c *PlaywrightController) Fill(ctx context.Context, selector string, value string) error {
-	return c.page.Fill(selector, value)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.resolveLocator(selector).Fill(value)
+}
+
+// TypeText 清空输入框后逐字键入，用于模拟真人输入
+func (c *PlaywrightController) TypeText(ctx context.Context, selector string, value string, delay time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	loc := c.resolveLocator(selector)
+	if err := loc.Fill(""); err != nil {
+		return err
+	}
+	return loc.PressSequentially(value, playwright.LocatorPressSequentiallyOptions{
+		Delay: playwright.Float(float64(delay.Milliseconds())),
+	})
+}
+
+// ExtractText 读取元素文本；输入框等无文本元素返回其值
+func (c *PlaywrightController) ExtractText(ctx context.Context, selector string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	loc := c.resolveLocator(selector)
+	text, err := loc.InnerText()
+	if err != nil {
+		return "", err
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		return text, nil
+	}
+	if value, err := loc.InputValue(); err == nil {
+		return strings.TrimSpace(value), nil
+	}
+	return "", nil
 }
 
 // Hover 悬停元素
 func (c *PlaywrightController) Hover(ctx context.Context, selector string) error {
-	return c.page.Hover(selector)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.resolveLocator(selector).Hover()
+}
+
+// fallbackRoles 按角色+名称回退匹配时依次尝试的角色
+var fallbackRoles = []*playwright.AriaRole{
+	playwright.AriaRoleButton,
+	playwright.AriaRoleLink,
+	playwright.AriaRoleTextbox,
+	playwright.AriaRoleMenuitem,
+	playwright.AriaRoleTab,
+	playwright.AriaRoleCheckbox,
+	playwright.AriaRoleCombobox,
+}
+
+// resolveLocator 按 CSS → 文本 → 角色+名称 的顺序解析选择器，在本地处理常见的选择器失配，
+// 避免调用 LLM 重新规划。主文档未匹配时依次在子 frame 中查找；
+// 均未匹配时返回原 CSS 定位器，由 Playwright 自动等待元素出现。实际采用的选择器记录在 resolved 中
+func (c *PlaywrightController) resolveLocator(selector string) playwright.Locator {
+	scopes, inner := c.scopes(selector)
+	text := selectorText(inner)
+
+	for i, scope := range scopes {
+		css := scope.Locator(inner)
+		if n, err := css.Count(); err == nil && n > 0 {
+			if i > 0 {
+				log.Printf("[Browser] Selector %q resolved in child frame", selector)
+			}
+			c.resolved = c.scopePrefix(selector, scope) + inner
+			return pickLocator(css)
+		}
+		if text == "" {
+			continue
+		}
+		if loc, desc, ok := findByText(scope, text, false); ok {
+			log.Printf("[Browser] Selector %q resolved by text/role %q", selector, text)
+			c.resolved = c.scopePrefix(selector, scope) + desc
+			return pickLocator(loc)
+		}
+	}
+
+	c.resolved = selector
+	return scopes[0].Locator(inner)
+}
+
+// ResolvedSelector 返回最近一次元素操作实际使用的选择器
+func (c *PlaywrightController) ResolvedSelector(ctx context.Context) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resolved
+}
+
+// findByText 在范围内按文本、再按角色+名称查找元素，返回全部匹配及对应的 Playwright 选择器
+func findByText(scope locatorScope, text string, exact bool) (playwright.Locator, string, bool) {
+	byText := scope.GetByText(text, exact)
+	if n, err := byText.Count(); err == nil && n > 0 {
+		return byText, textSelector(text), true
+	}
+	for _, role := range fallbackRoles {
+		byRole := scope.GetByRole(*role, text, exact)
+		if n, err := byRole.Count(); err == nil && n > 0 {
+			return byRole, fmt.Sprintf("role=%s[name=%q]", *role, text), true
+		}
+	}
+	return nil, "", false
+}
+
+// textSelector 按文本匹配的 Playwright 选择器
+func textSelector(text string) string {
+	return fmt.Sprintf("text=%q", text)
+}
+
+// maxPickCandidates 多个匹配时参与比较的元素上限
+const maxPickCandidates = 20
+
+// isControlJS 判断元素是否为按钮/链接类控件
+const isControlJS = `e => {
+	const tag = e.tagName.toLowerCase();
+	const role = (e.getAttribute('role') || '').toLowerCase();
+	const type = (e.getAttribute('type') || '').toLowerCase();
+	return tag === 'button' || tag === 'a' || role === 'button' || role === 'link' ||
+		(tag === 'input' && ['submit', 'button', 'reset'].includes(type));
+}`
+
+// pickLocator 多个匹配时消歧：优先可见的按钮/链接，其次第一个可见元素，都不可见时取第一个
+func pickLocator(loc playwright.Locator) playwright.Locator {
+	n, err := loc.Count()
+	if err != nil || n <= 1 {
+		return loc.First()
+	}
+	if n > maxPickCandidates {
+		n = maxPickCandidates
+	}
+
+	var firstVisible playwright.Locator
+	for i := 0; i < n; i++ {
+		el := loc.Nth(i)
+		if visible, err := el.IsVisible(); err != nil || !visible {
+			continue
+		}
+		if isControl, err := el.Evaluate(isControlJS, nil); err == nil && isControl == true {
+			return el
+		}
+		if firstVisible == nil {
+			firstVisible = el
+		}
+	}
+	if firstVisible != nil {
+		return firstVisible
+	}
+	return loc.First()
+}
+
+// selectorTextPatterns 从选择器中提取可见文本/名称的模式
+var selectorTextPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^text=["']?(.+?)["']?$`),
+	regexp.MustCompile(`:has-text\(["'](.+?)["']\)`),
+	regexp.MustCompile(`:contains\(["'](.+?)["']\)`),
+	regexp.MustCompile(`\[(?:aria-label|title|placeholder|value|name)\s*[*^$~|]?=\s*["']?([^"'\]]+)["']?\]`),
+}
+
+// cssLikePattern 判断字符串是否像 CSS 选择器
+var cssLikePattern = regexp.MustCompile(`^[#.\[]|^[a-zA-Z][\w-]*([#.\[:>\s]|$)`)
+
+// selectorText 从选择器中提取用于文本/角色匹配的名称；纯文本（非 CSS）直接作为名称
+func selectorText(selector string) string {
+	selector = strings.TrimSpace(selector)
+	for _, re := range selectorTextPatterns {
+		if m := re.FindStringSubmatch(selector); m != nil {
+			return strings.TrimSpace(m[1])
+		}
+	}
+	if !cssLikePattern.MatchString(selector) {
+		return strings.Trim(selector, `"'`)
+	}
+	return ""
 }
 
 // Select 选择下拉选项
 func (c *PlaywrightController) Select(ctx context.Context, selector string, value string) error {
-	_, err := c.page.SelectOption(selector, playwright.SelectOptionValues{
-		Values: playwright.StringSlice(value),
-	})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values := playwright.SelectOptionValues{Values: playwright.StringSlice(value)}
+	if len(c.activeFrame) > 0 {
+		_, err := c.resolveLocator(selector).SelectOption(values)
+		return err
+	}
+	_, err := c.page.SelectOption(selector, values)
 	return err
 }
 
 // WaitForSelector 等待选择器出现
 func (c *PlaywrightController) WaitForSelector(ctx context.Context, selector string, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// frame: 前缀的选择器或已切换到 iframe 时在对应 iframe 内等待
+	if frames, inner := splitFrameSelector(selector); len(frames) > 0 || len(c.activeFrame) > 0 {
+		scopes, _ := c.scopes(selector)
+		return scopes[0].Locator(inner).First().WaitFor(playwright.LocatorWaitForOptions{
+			Timeout: playwright.Float(float64(timeout.Milliseconds())),
+		})
+	}
+
 	_, err := c.page.WaitForSelector(selector, playwright.PageWaitForSelectorOptions{
 		Timeout: playwright.Float(float64(timeout.Milliseconds())),
 	})
 	return err
 }
 
+// waitAnyPollInterval WaitForAny 检查各候选选择器的间隔
+const waitAnyPollInterval = 100 * time.Millisecond
+
+// WaitForAny 轮询各候选选择器直到任一可见，同时可见时按列表顺序取第一个。
+// 候选可以位于不同的 iframe，frame: 前缀与当前 iframe 的处理同 WaitForSelector
+func (c *PlaywrightController) WaitForAny(ctx context.Context, selectors []string, timeout time.Duration) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(selectors) == 0 {
+		return "", fmt.Errorf("no selectors to wait for")
+	}
+	locators := make([]playwright.Locator, len(selectors))
+	for i, selector := range selectors {
+		scopes, inner := c.scopes(selector)
+		locators[i] = scopes[0].Locator(inner).First()
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		for i, loc := range locators {
+			if visible, err := loc.IsVisible(); err == nil && visible {
+				return selectors[i], nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("none of %q appeared within %s", selectors, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(waitAnyPollInterval):
+		}
+	}
+}
+
+// WaitForEnabled 等待元素通过 Playwright 点击前的可操作性检查（可见、稳定、未禁用），不执行点击
+func (c *PlaywrightController) WaitForEnabled(ctx context.Context, selector string, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.resolveLocator(selector).Click(playwright.LocatorClickOptions{
+		Trial:   playwright.Bool(true),
+		Timeout: playwright.Float(float64(timeout.Milliseconds())),
+	})
+}
+
 // WaitForText 等待文本出现
 func (c *PlaywrightController) WaitForText(ctx context.Context, text string, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.activeFrame) > 0 {
+		return c.frameChainScope(c.activeFrame).Locator(fmt.Sprintf("text=%s", text)).First().WaitFor(playwright.LocatorWaitForOptions{
+			Timeout: playwright.Float(float64(timeout.Milliseconds())),
+		})
+	}
 	_, err := c.page.WaitForSelector(fmt.Sprintf("text=%s", text), playwright.PageWaitForSelectorOptions{
 		Timeout: playwright.Float(float64(timeout.Milliseconds())),
 	})
 	return err
 }
 
+// WaitForCondition 轮询 JS 表达式直到结果为真，适用于计数变化、文本消失等动态条件
+func (c *PlaywrightController) WaitForCondition(ctx context.Context, jsExpr string, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.page.WaitForFunction(jsExpr, nil, playwright.PageWaitForFunctionOptions{
+		Polling: 100,
+		Timeout: playwright.Float(float64(timeout.Milliseconds())),
+	})
+	return err
+}
+
+// pageFingerprintJS 对 URL、标题、元素数量、可见文本与表单控件状态做哈希，包括同源 iframe 的内容
+const pageFingerprintJS = `() => {
+	let h = 5381;
+	const add = (s) => {
+		for (let i = 0; i < s.length; i++) h = ((h << 5) + h + s.charCodeAt(i)) | 0;
+		h = ((h << 5) + h) | 0;
+	};
+	const visit = (doc) => {
+		add(String(doc.getElementsByTagName('*').length));
+		add(doc.body ? doc.body.innerText : '');
+		for (const el of doc.querySelectorAll('input, textarea, select')) {
+			add(String(el.value) + (el.checked ? '1' : '0') + (el.disabled ? 'd' : ''));
+		}
+		for (const frame of doc.querySelectorAll('iframe')) {
+			try { if (frame.contentDocument) visit(frame.contentDocument); } catch (e) {}
+		}
+	};
+	add(location.href);
+	add(document.title);
+	visit(document);
+	return location.href + '#' + (h >>> 0).toString(36);
+}`
+
+// PageFingerprint 计算当前页面的指纹，只读取页面状态，比完整快照廉价得多
+func (c *PlaywrightController) PageFingerprint(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.page == nil {
+		return "", fmt.Errorf("browser not connected")
+	}
+	result, err := c.page.Evaluate(pageFingerprintJS)
+	if err != nil {
+		return "", err
+	}
+	fp, _ := result.(string)
+	return fp, nil
+}
+
 // TakeSnapshot 获取页面快照
 func (c *PlaywrightController) TakeSnapshot(ctx context.Context) (*PageSnapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	url := c.page.URL()
 	title, _ := c.page.Title()
-	
-	// 使用 JavaScript 直接获取页面信息，避免多次 IPC 调用
-	result, err := c.page.Evaluate(`() => {
-		const elements = [];
-		const selectors = 'a, button, input, select, textarea, [role="button"], [onclick]';
-		const els = document.querySelectorAll(selectors);
-		const max = Math.min(els.length, 30);
-		for (let i = 0; i < max; i++) {
-			const el = els[i];
+
+	// 先用廉价的元素计数判断页面规模（限定范围时只计范围内的元素），大页面采用更严格的上限
+	scope := c.contextOpts.SnapshotScope
+	domSize := 0
+	if sizeRaw, err := c.page.Evaluate(scopedJS(`(root) => root.querySelectorAll('*').length`), scope); err == nil {
+		domSize = toInt(sizeRaw)
+	}
+	largeDOM := domSize > c.largeDOMThreshold
+	maxElements := defaultSnapshotMaxElements
+	if largeDOM {
+		maxElements = largeDOMSnapshotMaxElements
+		log.Printf("[Browser] Large DOM detected (%d elements > %d), truncating snapshot", domSize, c.largeDOMThreshold)
+	}
+
+	// 主文档及同源 iframe 内的可交互元素（含开放的 shadow root）
+	elements := collectElements(c.page.MainFrame(), "", scope, maxElements, c.elementTextLength)
+	elements = collectFrameElements(c.page.MainFrame(), url, scope, nil, elements, maxElements, c.elementTextLength)
+
+	stats := pageStats(c.page.MainFrame())
+
+	// 大页面跳过完整的无障碍树遍历
+	var a11yTree string
+	if !largeDOM {
+		a11yTree, _ = c.getAccessibilityTree(ctx, scope)
+	}
+
+	return &PageSnapshot{
+		URL:       url,
+		Title:     title,
+		A11yTree:  a11yTree,
+		Elements:  elements,
+		DOMSize:   domSize,
+		Truncated: largeDOM,
+		Stats:     stats,
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// pageStatsJS 统计常见标签数量及是否存在登录表单，只做计数不序列化元素
+const pageStatsJS = `() => {
+	const tags = ['a', 'button', 'input', 'select', 'textarea', 'form', 'iframe', 'img', 'table'];
+	const counts = {};
+	for (const t of tags) {
+		const n = document.getElementsByTagName(t).length;
+		if (n > 0) counts[t] = n;
+	}
+	const buttons = document.querySelectorAll('button, [role="button"], input[type="submit"], input[type="button"]').length;
+	const login = Array.from(document.querySelectorAll('input[type="password"]')).some(el => el.offsetParent !== null);
+	return {
+		counts: counts,
+		links: document.querySelectorAll('a[href]').length,
+		buttons: buttons,
+		login: login
+	};
+}`
+
+// pageStats 计算主文档的结构统计，失败时返回零值
+func pageStats(frame playwright.Frame) PageStats {
+	stats := PageStats{TagCounts: map[string]int{}}
+	raw, err := frame.Evaluate(pageStatsJS)
+	if err != nil {
+		return stats
+	}
+	m, _ := raw.(map[string]interface{})
+	if counts, ok := m["counts"].(map[string]interface{}); ok {
+		for tag, n := range counts {
+			stats.TagCounts[tag] = toInt(n)
+		}
+	}
+	stats.Forms = stats.TagCounts["form"]
+	stats.Inputs = stats.TagCounts["input"] + stats.TagCounts["select"] + stats.TagCounts["textarea"]
+	stats.Links = toInt(m["links"])
+	stats.Buttons = toInt(m["buttons"])
+	stats.HasLoginForm, _ = m["login"].(bool)
+	return stats
+}
+
+// collectElementsJS 采集可交互元素，递归进入开放的 shadow root。
+// 文本在页面内先合并空白并粗截断以减少传输，最终长度由 CleanElementText 按字符截断
+const collectElementsJS = `({limit, textLength, scope}) => {
+	const elements = [];
+	const selectors = 'a, button, input, select, textarea, [role="button"], [onclick]';
+	const visit = (root) => {
+		for (const el of root.querySelectorAll(selectors)) {
+			if (elements.length >= limit) return;
 			if (!el.offsetParent) continue; // 跳过不可见元素
-			const text = (el.innerText || el.value || el.placeholder || '').slice(0, 50);
+			const text = (el.innerText || el.value || el.placeholder || '').replace(/\s+/g, ' ').trim().slice(0, textLength * 2 + 2);
 			elements.push({
 				tagName: el.tagName.toLowerCase(),
 				text: text,
@@ -168,63 +800,213 @@ func (c *PlaywrightController) TakeSnapshot(ctx context.Context) (*PageSnapshot,
 				type: el.type || ''
 			});
 		}
-		return {
-			elements: elements,
-			elementCount: els.length
-		};
-	}`)
+		for (const host of root.querySelectorAll('*')) {
+			if (elements.length >= limit) return;
+			if (host.shadowRoot) visit(host.shadowRoot);
+		}
+	};
+	visit(` + scopeRootJS + ` || document);
+	return elements;
+}`
+
+// collectElements 在单个 frame 中采集可交互元素，prefix 为该 frame 的 frame: 选择器前缀，
+// scope 非空时只采集该选择器匹配的元素内部，textLength 为元素文本的长度上限
+func collectElements(frame playwright.Frame, prefix, scope string, limit, textLength int) []Element {
+	if limit <= 0 {
+		return nil
+	}
+	// 使用 JavaScript 直接获取页面信息，避免多次 IPC 调用
+	result, err := frame.Evaluate(collectElementsJS, map[string]interface{}{
+		"limit":      limit,
+		"textLength": textLength,
+		"scope":      scope,
+	})
 	if err != nil {
-		result = map[string]interface{}{"elements": []interface{}{}, "elementCount": 0}
+		return nil
 	}
-	
-	// 解析结果
+
 	var elements []Element
-	if resultMap, ok := result.(map[string]interface{}); ok {
-		if elsRaw, ok := resultMap["elements"].([]interface{}); ok {
-			for _, elRaw := range elsRaw {
-				if el, ok := elRaw.(map[string]interface{}); ok {
-					elements = append(elements, Element{
-						TagName:   fmt.Sprintf("%v", el["tagName"]),
-						Text:      fmt.Sprintf("%v", el["text"]),
-						Visible:   true,
-						Clickable: true,
-					})
-				}
-			}
+	elsRaw, _ := result.([]interface{})
+	for _, elRaw := range elsRaw {
+		if el, ok := elRaw.(map[string]interface{}); ok {
+			elements = append(elements, Element{
+				TagName:   fmt.Sprintf("%v", el["tagName"]),
+				Text:      CleanElementText(fmt.Sprintf("%v", el["text"]), textLength),
+				Frame:     prefix,
+				Visible:   true,
+				Clickable: true,
+			})
 		}
 	}
+	return elements
+}
 
-	return &PageSnapshot{
-		URL:       url,
-		Title:     title,
-		Elements:  elements,
-		Timestamp: time.Now(),
-	}, nil
+// collectFrameElements 递归采集同源子 frame 的元素，直到达到数量上限。
+// scope 非空时只采集位于该范围内的 iframe，范围只作用于主文档
+func collectFrameElements(parent playwright.Frame, pageURL, scope string, chain []string, elements []Element, limit, textLength int) []Element {
+	for _, child := range parent.ChildFrames() {
+		if len(elements) >= limit {
+			break
+		}
+		if !sameOrigin(child.URL(), pageURL) || !frameInScope(child, scope) {
+			continue
+		}
+		sel := frameSelector(child)
+		if sel == "" {
+			continue
+		}
+		childChain := append(append([]string(nil), chain...), sel)
+		elements = append(elements, collectElements(child, framePrefix(childChain), "", limit-len(elements), textLength)...)
+		elements = collectFrameElements(child, pageURL, "", childChain, elements, limit, textLength)
+	}
+	return elements
+}
+
+// scopeRootJS 求值为变量 scope 中的快照范围选择器匹配的元素，未设置、选择器无效或未匹配时为 null
+const scopeRootJS = `(() => {
+	if (!scope) return null;
+	try { return document.querySelector(scope); } catch (e) { return null; }
+})()`
+
+// scopedJS 将以范围根元素为参数的函数包装为以选择器为参数的函数，范围无效时根元素为 document
+func scopedJS(fn string) string {
+	return `(scope) => (` + fn + `)(` + scopeRootJS + ` || document)`
+}
+
+// frameInScope 判断 iframe 是否位于快照范围内，未设置范围或范围未匹配时总是 true
+func frameInScope(frame playwright.Frame, scope string) bool {
+	if scope == "" {
+		return true
+	}
+	el, err := frame.FrameElement()
+	if err != nil {
+		return false
+	}
+	defer el.Dispose()
+	inside, err := el.Evaluate(`(el, scope) => {
+		const root = `+scopeRootJS+`;
+		return !root || root.contains(el);
+	}`, scope)
+	return err == nil && inside == true
+}
+
+// toInt 将 Evaluate 返回的数值转换为 int
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
 }
 
 // TakeScreenshot 截图
 func (c *PlaywrightController) TakeScreenshot(ctx context.Context, opts ScreenshotOptions) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	format := domain.ScreenshotFormat(opts.Type)
+	if format == "" {
+		format = domain.ScreenshotFormatPNG
+	}
+	if !format.Valid() {
+		return nil, fmt.Errorf("unsupported screenshot type: %s", opts.Type)
+	}
+
+	// Playwright 不支持 WebP，通过 CDP 直接截取
+	if format == domain.ScreenshotFormatWebP {
+		return c.takeCDPScreenshot(opts, format)
+	}
+
 	screenshotOpts := playwright.PageScreenshotOptions{
 		FullPage: playwright.Bool(opts.FullPage),
+		Type:     playwright.ScreenshotTypePng,
 	}
-	
-	if opts.Type == "jpeg" {
+	if format == domain.ScreenshotFormatJPEG {
 		screenshotOpts.Type = playwright.ScreenshotTypeJpeg
 		if opts.Quality > 0 {
 			screenshotOpts.Quality = playwright.Int(opts.Quality)
 		}
 	}
+	if opts.Clip != nil {
+		screenshotOpts.Clip = &playwright.Rect{
+			X:      opts.Clip.X,
+			Y:      opts.Clip.Y,
+			Width:  opts.Clip.Width,
+			Height: opts.Clip.Height,
+		}
+	}
 
 	return c.page.Screenshot(screenshotOpts)
 }
 
+// takeCDPScreenshot 使用 Chrome DevTools Protocol 截图（仅 Chromium）
+func (c *PlaywrightController) takeCDPScreenshot(opts ScreenshotOptions, format domain.ScreenshotFormat) ([]byte, error) {
+	session, err := c.page.Context().NewCDPSession(c.page)
+	if err != nil {
+		return nil, fmt.Errorf("new cdp session: %w", err)
+	}
+	defer session.Detach()
+
+	params := map[string]interface{}{
+		"format": string(format),
+	}
+	if format.IsLossy() && opts.Quality > 0 {
+		params["quality"] = opts.Quality
+	}
+
+	switch {
+	case opts.Clip != nil:
+		params["clip"] = map[string]interface{}{
+			"x": opts.Clip.X, "y": opts.Clip.Y,
+			"width": opts.Clip.Width, "height": opts.Clip.Height,
+			"scale": 1,
+		}
+	case opts.FullPage:
+		metrics, err := session.Send("Page.getLayoutMetrics", nil)
+		if err != nil {
+			return nil, fmt.Errorf("get layout metrics: %w", err)
+		}
+		if m, ok := metrics.(map[string]interface{}); ok {
+			if size, ok := m["cssContentSize"].(map[string]interface{}); ok {
+				params["clip"] = map[string]interface{}{
+					"x": 0, "y": 0,
+					"width": size["width"], "height": size["height"],
+					"scale": 1,
+				}
+				params["captureBeyondViewport"] = true
+			}
+		}
+	}
+
+	result, err := session.Send("Page.captureScreenshot", params)
+	if err != nil {
+		return nil, fmt.Errorf("capture screenshot: %w", err)
+	}
+	m, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected screenshot result: %T", result)
+	}
+	data, _ := m["data"].(string)
+	return base64.StdEncoding.DecodeString(data)
+}
+
 // GetPageTitle 获取页面标题
 func (c *PlaywrightController) GetPageTitle(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return c.page.Title()
 }
 
 // GetCookies 获取 Cookies
 func (c *PlaywrightController) GetCookies(ctx context.Context) ([]domain.Cookie, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	cookies, err := c.page.Context().Cookies()
 	if err != nil {
 		return nil, err
@@ -246,6 +1028,9 @@ func (c *PlaywrightController) GetCookies(ctx context.Context) ([]domain.Cookie,
 
 // SetCookies 设置 Cookies
 func (c *PlaywrightController) SetCookies(ctx context.Context, cookies []domain.Cookie) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	pwCookies := make([]playwright.OptionalCookie, len(cookies))
 	for i, cookie := range cookies {
 		pwCookies[i] = playwright.OptionalCookie{
@@ -262,18 +1047,21 @@ func (c *PlaywrightController) SetCookies(ctx context.Context, cookies []domain.
 
 // ClearCookies 清除 Cookies
 func (c *PlaywrightController) ClearCookies(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return c.page.Context().ClearCookies()
 }
 
-// getAccessibilityTree 获取无障碍树
-func (c *PlaywrightController) getAccessibilityTree(ctx context.Context) (string, error) {
+// getAccessibilityTree 获取无障碍树，scope 非空时以该选择器匹配的元素为根
+func (c *PlaywrightController) getAccessibilityTree(ctx context.Context, scope string) (string, error) {
 	// 使用 JavaScript 获取页面结构信息
-	result, err := c.page.Evaluate(`() => {
+	result, err := c.page.Evaluate(scopedJS(`(root) => {
 		function getTree(node, indent) {
 			if (!node) return '';
 			let result = '';
 			const role = node.getAttribute && node.getAttribute('role') || node.tagName?.toLowerCase() || '';
-			const text = node.innerText?.slice(0, 50) || '';
+			const text = (node.innerText || '').replace(/\s+/g, ' ').trim().slice(0, 50);
 			if (role) {
 				result += '  '.repeat(indent) + '[' + role + '] ' + text + '\\n';
 			}
@@ -282,8 +1070,8 @@ func (c *PlaywrightController) getAccessibilityTree(ctx context.Context) (string
 			}
 			return result;
 		}
-		return getTree(document.body, 0);
-	}`)
+		return getTree(root === document ? document.body : root, 0);
+	}`), scope)
 	if err != nil {
 		return "", err
 	}
@@ -310,16 +1098,16 @@ func (c *PlaywrightController) getInteractiveElements(ctx context.Context) ([]El
 	// 限制元素数量，避免处理太长时间
 	maxElements := 50
 	elements := make([]Element, 0, maxElements)
-	
+
 	for i, loc := range locators {
 		if i >= maxElements {
 			break
 		}
-		
+
 		tagName, _ := loc.Evaluate("el => el.tagName.toLowerCase()", nil)
 		text, _ := loc.InnerText()
 		visible, _ := loc.IsVisible()
-		
+
 		if !visible {
 			continue
 		}
@@ -0,0 +1,574 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestBrowser 启动本地无头浏览器，未安装 Playwright 驱动或浏览器时跳过测试
+func newTestBrowser(t *testing.T, opts PlaywrightOptions, ctxOpts ContextOptions) *PlaywrightController {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("browser test skipped in short mode")
+	}
+	opts.Headless = true
+	c := NewPlaywrightController(opts)
+	if err := c.Connect(context.Background(), ctxOpts); err != nil {
+		t.Skipf("playwright not available: %v", err)
+	}
+	t.Cleanup(func() {
+		c.Close(context.Background())
+		if c.pw != nil {
+			c.pw.Stop()
+		}
+	})
+	return c
+}
+
+// serveFixture 提供测试页面，键为路径，返回服务地址
+func serveFixture(t *testing.T, pages map[string]string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// openFixture 在浏览器中打开单个测试页面
+func openFixture(t *testing.T, c *PlaywrightController, html string) {
+	t.Helper()
+	url := serveFixture(t, map[string]string{"/": html})
+	if err := c.Navigate(context.Background(), url+"/"); err != nil {
+		t.Fatalf("Navigate: %v", err)
+	}
+}
+
+func TestTakeScreenshotRejectsUnknownType(t *testing.T) {
+	c := NewPlaywrightController(PlaywrightOptions{})
+	// 格式校验在访问页面之前，未连接的控制器即可验证
+	if _, err := c.TakeScreenshot(context.Background(), ScreenshotOptions{Type: "gif"}); err == nil {
+		t.Error("TakeScreenshot accepted an unsupported type")
+	}
+}
+
+func TestTakeSnapshotTruncatesLargeDOM(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{LargeDOMThreshold: 500}, ContextOptions{})
+
+	small := `<html><body><button id="b1">One</button><button id="b2">Two</button></body></html>`
+	openFixture(t, c, small)
+	snapshot, err := c.TakeSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Truncated || snapshot.A11yTree == "" {
+		t.Errorf("small page: truncated %v, a11y tree %d bytes", snapshot.Truncated, len(snapshot.A11yTree))
+	}
+
+	// 合成的大页面：2000 个按钮
+	var b strings.Builder
+	b.WriteString("<html><body>")
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, `<button id="b%d">Button %d</button>`, i, i)
+	}
+	b.WriteString("</body></html>")
+	openFixture(t, c, b.String())
+	snapshot, err = c.TakeSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.Truncated || snapshot.DOMSize <= 2000 {
+		t.Errorf("large page: truncated %v, dom size %d", snapshot.Truncated, snapshot.DOMSize)
+	}
+	if len(snapshot.Elements) > largeDOMSnapshotMaxElements || snapshot.A11yTree != "" {
+		t.Errorf("large page: %d elements, a11y tree %d bytes, want at most %d elements and no tree",
+			len(snapshot.Elements), len(snapshot.A11yTree), largeDOMSnapshotMaxElements)
+	}
+}
+
+func TestWaitForConditionCounter(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	openFixture(t, c, `<html><body><span id="count">0</span><span id="spinner">Loading…</span>
+<script>
+let n = 0;
+const timer = setInterval(() => {
+	document.getElementById('count').textContent = String(++n);
+	if (n === 3) { clearInterval(timer); document.getElementById('spinner').remove(); }
+}, 200);
+</script></body></html>`)
+
+	if err := c.WaitForCondition(ctx, `document.getElementById('count').textContent === '3'`, 5*time.Second); err != nil {
+		t.Fatalf("wait for count: %v", err)
+	}
+	if err := c.WaitForCondition(ctx, `!document.body.innerText.includes('Loading')`, time.Second); err != nil {
+		t.Errorf("wait for spinner text gone: %v", err)
+	}
+	if err := c.WaitForCondition(ctx, `document.getElementById('count').textContent === '4'`, 500*time.Millisecond); err == nil {
+		t.Error("condition that never holds did not time out")
+	}
+}
+
+func TestWaitForAnyReportsMatchedSelector(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	base := serveFixture(t, map[string]string{
+		"/success": `<html><body><div class="toast-success" hidden>Saved</div><div class="error-banner" hidden>Failed</div>
+<script>setTimeout(() => document.querySelector('.toast-success').hidden = false, 300);</script></body></html>`,
+		"/error": `<html><body><div class="toast-success" hidden>Saved</div><div class="error-banner" hidden>Failed</div>
+<script>setTimeout(() => document.querySelector('.error-banner').hidden = false, 300);</script></body></html>`,
+		"/none": `<html><body><div class="toast-success" hidden>Saved</div></body></html>`,
+	})
+	selectors := []string{".toast-success", ".error-banner"}
+	for path, want := range map[string]string{"/success": ".toast-success", "/error": ".error-banner"} {
+		if err := c.Navigate(ctx, base+path); err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.WaitForAny(ctx, selectors, 5*time.Second)
+		if err != nil || got != want {
+			t.Errorf("%s: WaitForAny = %q, %v; want %q", path, got, err, want)
+		}
+	}
+
+	if err := c.Navigate(ctx, base+"/none"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.WaitForAny(ctx, selectors, 500*time.Millisecond); err == nil {
+		t.Errorf("WaitForAny = %q, want a timeout when no candidate appears", got)
+	}
+}
+
+func TestSelectorText(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"#submit", ""},
+		{"button.primary", ""},
+		{`text="Save draft"`, "Save draft"},
+		{`button:has-text('Sign in')`, "Sign in"},
+		{`[aria-label='Close dialog']`, "Close dialog"},
+		{`input[placeholder="Email"]`, "Email"},
+		{"登录", "登录"},
+	}
+	for _, tt := range tests {
+		if got := selectorText(tt.selector); got != tt.want {
+			t.Errorf("selectorText(%q) = %q, want %q", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestResolveLocatorFallbacks(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	openFixture(t, c, `<html><body>
+<button id="save">Save</button>
+<span>Save draft</span>
+<button aria-label="Close dialog">×</button>
+<input id="email">
+</body></html>`)
+
+	tests := []struct {
+		name     string
+		selector string
+		resolved string
+	}{
+		{"css", "#save", "#save"},
+		{"text", `[aria-label='Save draft']`, `text="Save draft"`},
+		{"role and name", `[title='Close dialog']`, `role=button[name="Close dialog"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.Click(ctx, tt.selector); err != nil {
+				t.Fatalf("Click(%q): %v", tt.selector, err)
+			}
+			if got := c.ResolvedSelector(ctx); got != tt.resolved {
+				t.Errorf("resolved = %q, want %q", got, tt.resolved)
+			}
+		})
+	}
+
+	if err := c.Fill(ctx, "#email", "a@example.com"); err != nil {
+		t.Errorf("Fill: %v", err)
+	}
+	if err := c.Hover(ctx, `[title='Close dialog']`); err != nil {
+		t.Errorf("Hover: %v", err)
+	}
+	// 均未匹配时保留原选择器
+	c.mu.Lock()
+	c.resolveLocator("#missing")
+	resolved := c.resolved
+	c.mu.Unlock()
+	if resolved != "#missing" {
+		t.Errorf("unmatched selector resolved to %q", resolved)
+	}
+}
+
+// 用 -race 运行：多个协程共用同一控制器时页面操作被串行化
+func TestConcurrentClicksAndFills(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	openFixture(t, c, `<html><body>
+<button id="inc" onclick="document.getElementById('count').textContent = String(+document.getElementById('count').textContent + 1)">+1</button>
+<span id="count">0</span>
+<input id="a"><input id="b">
+</body></html>`)
+
+	const workers, clicks = 8, 5
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*clicks*3)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			field := []string{"#a", "#b"}[i%2]
+			for j := 0; j < clicks; j++ {
+				if err := c.Click(ctx, "#inc"); err != nil {
+					errs <- fmt.Errorf("click: %w", err)
+				}
+				if err := c.Fill(ctx, field, fmt.Sprintf("worker %d", i)); err != nil {
+					errs <- fmt.Errorf("fill: %w", err)
+				}
+				if _, err := c.GetCurrentURL(ctx); err != nil {
+					errs <- fmt.Errorf("url: %w", err)
+				}
+				c.ResolvedSelector(ctx)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got, err := c.ExtractText(ctx, "#count"); err != nil || got != fmt.Sprint(workers*clicks) {
+		t.Errorf("count = %q (%v), want %d", got, err, workers*clicks)
+	}
+}
+
+func TestContextLocaleAndTimezone(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{Locale: "zh-CN", TimezoneID: "Asia/Shanghai"})
+
+	acceptLanguage := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case acceptLanguage <- r.Header.Get("Accept-Language"):
+		default:
+		}
+		fmt.Fprint(w, `<html><body>locale</body></html>`)
+	}))
+	t.Cleanup(srv.Close)
+	if err := c.Navigate(ctx, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := <-acceptLanguage; !strings.HasPrefix(got, "zh-CN") {
+		t.Errorf("Accept-Language = %q, want zh-CN", got)
+	}
+	if err := c.WaitForCondition(ctx, `navigator.language === 'zh-CN'`, time.Second); err != nil {
+		t.Errorf("navigator.language is not zh-CN: %v", err)
+	}
+	if err := c.WaitForCondition(ctx, `Intl.DateTimeFormat().resolvedOptions().timeZone === 'Asia/Shanghai'`, time.Second); err != nil {
+		t.Errorf("timezone is not Asia/Shanghai: %v", err)
+	}
+}
+
+func TestClickByTextPrefersVisibleControl(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	openFixture(t, c, `<html><body>
+<p>Click Submit when you are done.</p>
+<button style="display:none" onclick="document.title='hidden'">Submit</button>
+<button onclick="document.title='visible'">Submit</button>
+<a href="#" onclick="document.title='link'">Submit later</a>
+</body></html>`)
+	title := func(want string) {
+		t.Helper()
+		if err := c.WaitForCondition(ctx, fmt.Sprintf("document.title === %q", want), time.Second); err != nil {
+			t.Errorf("title is not %q: %v", want, err)
+		}
+	}
+
+	// 多个匹配时点击可见的按钮，而不是段落文本或隐藏按钮
+	if err := c.Click(ctx, "text=Submit"); err != nil {
+		t.Fatalf("Click text=Submit: %v", err)
+	}
+	title("visible")
+	if err := c.ClickByText(ctx, "Submit", ClickOptions{Exact: true}); err != nil {
+		t.Fatalf("ClickByText exact: %v", err)
+	}
+	title("visible")
+	if err := c.ClickByText(ctx, "Submit later", ClickOptions{Nth: 1}); err != nil {
+		t.Fatalf("ClickByText nth: %v", err)
+	}
+	title("link")
+}
+
+func TestLaunchAndContextOptions(t *testing.T) {
+	c := NewPlaywrightController(PlaywrightOptions{
+		Headless:          true,
+		IgnoreHTTPSErrors: true,
+		LaunchArgs:        []string{"--no-sandbox"},
+	})
+	launch := c.launchOptions()
+	if !*launch.Headless || len(launch.Args) != 1 || launch.Args[0] != "--no-sandbox" {
+		t.Errorf("launch options = headless %v args %v", *launch.Headless, launch.Args)
+	}
+
+	c.contextOpts = ContextOptions{
+		Locale: "zh-CN",
+		Proxy:  &Proxy{Server: "http://proxy:8080", Username: "u", Password: "p"},
+	}
+	ctxOpts := c.newContextOptions()
+	if ctxOpts.IgnoreHttpsErrors == nil || !*ctxOpts.IgnoreHttpsErrors {
+		t.Error("IgnoreHttpsErrors not forwarded to the context")
+	}
+	if ctxOpts.Locale == nil || *ctxOpts.Locale != "zh-CN" || ctxOpts.TimezoneId != nil {
+		t.Errorf("locale %v timezone %v", ctxOpts.Locale, ctxOpts.TimezoneId)
+	}
+	if ctxOpts.Proxy == nil || ctxOpts.Proxy.Server != "http://proxy:8080" || *ctxOpts.Proxy.Username != "u" {
+		t.Errorf("proxy = %+v", ctxOpts.Proxy)
+	}
+
+	// 默认校验证书
+	if opts := NewPlaywrightController(PlaywrightOptions{}).newContextOptions(); *opts.IgnoreHttpsErrors {
+		t.Error("certificate errors ignored by default")
+	}
+}
+
+func TestEnsureConnectedReconnectsAfterDisconnect(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	openFixture(t, c, `<html><body>before</body></html>`)
+
+	if reconnected, err := c.EnsureConnected(ctx); err != nil || reconnected {
+		t.Fatalf("healthy browser: reconnected %v, err %v", reconnected, err)
+	}
+	// 模拟连接断开
+	c.browser.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.disconnected.Load() && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	reconnected, err := c.EnsureConnected(ctx)
+	if err != nil || !reconnected {
+		t.Fatalf("after disconnect: reconnected %v, err %v", reconnected, err)
+	}
+	openFixture(t, c, `<html><body><button id="b">after</button></body></html>`)
+	if err := c.Click(ctx, "#b"); err != nil {
+		t.Errorf("page unusable after reconnect: %v", err)
+	}
+}
+
+func TestTakeSnapshotStats(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	var links strings.Builder
+	for i := 0; i < 120; i++ {
+		fmt.Fprintf(&links, `<a href="/p/%d">Page %d</a>`, i, i)
+	}
+	openFixture(t, c, `<html><body>`+links.String()+`<a name="anchor">no href</a>
+<form action="/login"><input name="user"><input type="password" name="pass"><button type="submit">Sign in</button></form>
+<form><select><option>A</option></select><textarea></textarea><input type="submit" value="Send"></form>
+<div role="button">Menu</div><table><tr><td>1</td></tr></table>
+<input type="password" style="display:none">
+</body></html>`)
+
+	snapshot, err := c.TakeSnapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := snapshot.Stats
+	if s.Forms != 2 || s.Links != 120 || s.Buttons != 3 || s.Inputs != 6 || !s.HasLoginForm {
+		t.Errorf("stats = %+v", s)
+	}
+	if s.TagCounts["a"] != 121 || s.TagCounts["table"] != 1 || s.TagCounts["iframe"] != 0 {
+		t.Errorf("tag counts = %v", s.TagCounts)
+	}
+
+	openFixture(t, c, `<html><body><input type="password" style="display:none"><button>Go</button></body></html>`)
+	if snapshot, _ := c.TakeSnapshot(ctx); snapshot.Stats.HasLoginForm {
+		t.Error("hidden password input reported as a login form")
+	}
+}
+
+func TestTakeSnapshotElementTextLength(t *testing.T) {
+	c := newTestBrowser(t, PlaywrightOptions{ElementTextLength: 12}, ContextOptions{})
+	openFixture(t, c, `<html><body><button id="go">Continue
+		to the   next step of checkout</button></body></html>`)
+
+	snapshot, err := c.TakeSnapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, el := range snapshot.Elements {
+		if el.TagName == "button" {
+			if el.Text != "Continue to…" {
+				t.Errorf("button text = %q, want whitespace collapsed and cut at 12 characters", el.Text)
+			}
+			return
+		}
+	}
+	t.Errorf("button not in snapshot: %+v", snapshot.Elements)
+}
+
+func TestTakeSnapshotScope(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	base := serveFixture(t, map[string]string{
+		"/": `<html><body>
+<nav><a href="/home">Home</a><button>Open sidebar</button><iframe id="ads" src="/ads"></iframe></nav>
+<div id="app"><main><button>Save draft</button><input id="title" placeholder="Title">
+<iframe id="editor" src="/editor"></iframe></main></div>
+</body></html>`,
+		"/ads":    `<html><body><button>Ad link</button></body></html>`,
+		"/editor": `<html><body><button>Bold</button></body></html>`,
+	})
+	if err := c.Navigate(ctx, base+"/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WaitForSelector(ctx, "frame:#editor >> button", 5*time.Second); err != nil {
+		t.Fatalf("iframe content not loaded: %v", err)
+	}
+
+	// texts 返回快照中元素的文本
+	texts := func(scope string) (map[string]bool, *PageSnapshot) {
+		t.Helper()
+		c.contextOpts.SnapshotScope = scope
+		snapshot, err := c.TakeSnapshot(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := map[string]bool{}
+		for _, el := range snapshot.Elements {
+			seen[el.Text] = true
+		}
+		return seen, snapshot
+	}
+
+	full, fullSnapshot := texts("")
+	for _, text := range []string{"Home", "Open sidebar", "Ad link", "Save draft", "Bold"} {
+		if !full[text] {
+			t.Errorf("unscoped snapshot misses %q", text)
+		}
+	}
+
+	scoped, scopedSnapshot := texts("#app main")
+	for text, want := range map[string]bool{"Save draft": true, "Bold": true, "Home": false, "Open sidebar": false, "Ad link": false} {
+		if scoped[text] != want {
+			t.Errorf("scoped snapshot has %q = %v, want %v", text, scoped[text], want)
+		}
+	}
+	if scopedSnapshot.DOMSize >= fullSnapshot.DOMSize {
+		t.Errorf("scoped DOM size %d not smaller than the page's %d", scopedSnapshot.DOMSize, fullSnapshot.DOMSize)
+	}
+	if strings.Contains(scopedSnapshot.A11yTree, "Open sidebar") {
+		t.Error("accessibility tree includes elements outside the scope")
+	}
+
+	// 无效或未匹配的范围退回整个页面
+	for _, scope := range []string{"#missing", "main[[["} {
+		if seen, _ := texts(scope); !seen["Home"] || !seen["Save draft"] {
+			t.Errorf("scope %q: snapshot = %v, want the whole page", scope, seen)
+		}
+	}
+}
+
+func TestConnectCreatesIsolatedContext(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	base := serveFixture(t, map[string]string{
+		"/":      `<html><body><script>document.cookie = "session=alice; path=/"; localStorage.setItem("draft", "1");</script></body></html>`,
+		"/blank": `<html><body></body></html>`,
+	})
+	if err := c.Navigate(ctx, base+"/"); err != nil {
+		t.Fatal(err)
+	}
+	cookies, err := c.GetCookies(ctx)
+	if err != nil || len(cookies) != 1 {
+		t.Fatalf("cookies in the first context = %+v, %v", cookies, err)
+	}
+	first := c.browserCtx
+
+	// 再次 Connect 复用浏览器，但换成新的上下文
+	if err := c.Connect(ctx, ContextOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if c.browserCtx == first {
+		t.Fatal("Connect reused the previous browser context")
+	}
+	if len(first.Pages()) != 0 {
+		t.Error("previous context still has open pages")
+	}
+	if cookies, _ := c.GetCookies(ctx); len(cookies) != 0 {
+		t.Errorf("cookies leaked into the new context: %+v", cookies)
+	}
+	if err := c.Navigate(ctx, base+"/blank"); err != nil {
+		t.Fatal(err)
+	}
+	if draft, _ := c.page.Evaluate(`localStorage.getItem("draft")`); draft != nil {
+		t.Errorf("localStorage leaked into the new context: %v", draft)
+	}
+
+	if err := c.CloseContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.browserCtx != nil || c.page != nil {
+		t.Error("CloseContext kept the context or page")
+	}
+}
+
+func TestPageFingerprint(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBrowser(t, PlaywrightOptions{}, ContextOptions{})
+	openFixture(t, c, `<html><body><input id="name"><ul id="list"><li>One</li></ul></body></html>`)
+
+	fingerprint := func() string {
+		t.Helper()
+		fp, err := c.PageFingerprint(ctx)
+		if err != nil || fp == "" {
+			t.Fatalf("PageFingerprint = %q, %v", fp, err)
+		}
+		return fp
+	}
+	base := fingerprint()
+	if again := fingerprint(); again != base {
+		t.Errorf("fingerprint of an unchanged page changed: %s -> %s", base, again)
+	}
+
+	changes := []struct {
+		name string
+		js   string
+	}{
+		{"DOM change", `document.getElementById('list').insertAdjacentHTML('beforeend', '<li>Two</li>')`},
+		{"input value", `document.getElementById('name').value = 'Alice'`},
+		{"URL change", `history.pushState({}, '', '/step-2')`},
+	}
+	prev := base
+	for _, ch := range changes {
+		if _, err := c.page.Evaluate(ch.js); err != nil {
+			t.Fatal(err)
+		}
+		if fp := fingerprint(); fp == prev {
+			t.Errorf("%s did not change the fingerprint", ch.name)
+		} else {
+			prev = fp
+		}
+	}
+}
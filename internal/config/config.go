@@ -0,0 +1,118 @@
+// Package config 提供服务端配置
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/browser-automation/internal/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// Config 服务端配置，启动时加载，作用于所有任务
+type Config struct {
+	// Output 请求未指定时使用的输出默认值
+	Output OutputDefaults `yaml:"output"`
+	// Task 任务输入限制
+	Task TaskLimits `yaml:"task"`
+}
+
+// OutputDefaults 服务端输出默认值，空字段使用内置默认值
+type OutputDefaults struct {
+	Formats    []string `yaml:"formats"`
+	Language   string   `yaml:"language"`
+	Template   string   `yaml:"template"`
+	ThemeColor string   `yaml:"theme_color"`
+	LogoURL    string   `yaml:"logo_url"`
+}
+
+// TaskLimits 任务输入限制（按字符计），0 使用内置默认值
+type TaskLimits struct {
+	MaxDescriptionLength int `yaml:"max_description_length"`
+	MaxTitleLength       int `yaml:"max_title_length"`
+}
+
+// 任务输入的内置长度上限
+const (
+	DefaultMaxDescriptionLength = 4000
+	DefaultMaxTitleLength       = 200
+)
+
+// Default 返回空配置，全部使用内置默认值
+func Default() *Config {
+	return &Config{}
+}
+
+// Load 读取 YAML 配置文件，path 为空时返回默认配置
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	style := domain.StyleConfig{ThemeColor: cfg.Output.ThemeColor, LogoURL: cfg.Output.LogoURL}
+	if err := style.Validate(); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if cfg.Task.MaxDescriptionLength < 0 || cfg.Task.MaxTitleLength < 0 {
+		return nil, fmt.Errorf("parse config: task length limits must not be negative")
+	}
+	for _, f := range cfg.Output.Formats {
+		switch domain.DocFormat(f) {
+		case domain.DocFormatMarkdown, domain.DocFormatHTML, domain.DocFormatConfluence, domain.DocFormatPDF, domain.DocFormatDOCX:
+		default:
+			return nil, fmt.Errorf("parse config: unsupported output format %q", f)
+		}
+	}
+	return cfg, nil
+}
+
+// MaxDescriptionLength 返回任务描述的长度上限
+func (c *Config) MaxDescriptionLength() int {
+	if c == nil || c.Task.MaxDescriptionLength <= 0 {
+		return DefaultMaxDescriptionLength
+	}
+	return c.Task.MaxDescriptionLength
+}
+
+// MaxTitleLength 返回文档标题的长度上限
+func (c *Config) MaxTitleLength() int {
+	if c == nil || c.Task.MaxTitleLength <= 0 {
+		return DefaultMaxTitleLength
+	}
+	return c.Task.MaxTitleLength
+}
+
+// OutputConfig 返回叠加服务端默认值后的输出配置，每次调用返回新的实例
+func (c *Config) OutputConfig() *domain.OutputConfig {
+	out := domain.DefaultOutputConfig()
+	if c == nil {
+		return out
+	}
+	d := c.Output
+	if len(d.Formats) > 0 {
+		out.Formats = make([]domain.DocFormat, len(d.Formats))
+		for i, f := range d.Formats {
+			out.Formats[i] = domain.DocFormat(f)
+		}
+	}
+	if d.Language != "" {
+		out.Language = d.Language
+	}
+	if d.Template != "" {
+		out.StyleConfig.Template = d.Template
+	}
+	if d.ThemeColor != "" {
+		out.StyleConfig.ThemeColor = d.ThemeColor
+	}
+	if d.LogoURL != "" {
+		out.StyleConfig.LogoURL = d.LogoURL
+	}
+	return out
+}
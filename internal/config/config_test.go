@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `
+output:
+  formats: [markdown, html]
+  language: en
+  template: corporate
+  theme_color: "#112233"
+task:
+  max_title_length: 80
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxTitleLength() != 80 || cfg.MaxDescriptionLength() != DefaultMaxDescriptionLength {
+		t.Errorf("limits = %d, %d", cfg.MaxTitleLength(), cfg.MaxDescriptionLength())
+	}
+
+	out := cfg.OutputConfig()
+	if len(out.Formats) != 2 || out.Formats[1] != domain.DocFormatHTML || out.Language != "en" ||
+		out.StyleConfig.Template != "corporate" || out.StyleConfig.ThemeColor != "#112233" {
+		t.Errorf("output = formats %v language %q style %+v", out.Formats, out.Language, out.StyleConfig)
+	}
+	// 未配置的字段使用内置默认值，每次返回新的实例
+	builtin := domain.DefaultOutputConfig()
+	if out.ScreenshotConfig.Quality != builtin.ScreenshotConfig.Quality || out.ContentConfig.StepNumbering != builtin.ContentConfig.StepNumbering {
+		t.Errorf("builtin defaults lost: %+v %+v", out.ScreenshotConfig, out.ContentConfig)
+	}
+	out.Formats[0] = domain.DocFormatPDF
+	if cfg.OutputConfig().Formats[0] != domain.DocFormatMarkdown {
+		t.Error("OutputConfig shares state between calls")
+	}
+
+	if cfg, err := Load(""); err != nil || cfg.OutputConfig().Language != builtin.Language {
+		t.Errorf("Load(\"\") = %+v, %v", cfg, err)
+	}
+	var nilCfg *Config
+	if nilCfg.OutputConfig().Language != builtin.Language || nilCfg.MaxTitleLength() != DefaultMaxTitleLength {
+		t.Error("nil config does not use builtin defaults")
+	}
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		content string
+		wantErr string
+	}{
+		{"output:\n  formats: [rtf]\n", `unsupported output format "rtf"`},
+		{"output:\n  theme_color: \"red; }\"\n", "invalid theme_color"},
+		{"output:\n  logo_url: javascript:alert(1)\n", "invalid logo_url"},
+		{"task:\n  max_description_length: -1\n", "must not be negative"},
+		{"output: [", "parse config"},
+	}
+	for _, tt := range tests {
+		_, err := Load(writeConfig(t, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Load(%q) err = %v, want %q", tt.content, err, tt.wantErr)
+		}
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing config file accepted")
+	}
+}
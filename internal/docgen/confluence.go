@@ -0,0 +1,228 @@
+// Package docgen 提供文档生成功能
+package docgen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// ConfluenceGenerator Confluence 存储格式（XHTML）文档生成器。
+// 每个步骤是一个带标题的 panel 宏，提示使用 tip 宏，截图引用页面附件 step_N.<扩展名>，
+// 上传文档时将 screenshots/ 下的截图作为附件一并上传即可显示
+type ConfluenceGenerator struct {
+	maxSize int
+}
+
+// NewConfluenceGenerator 创建 Confluence 生成器
+func NewConfluenceGenerator() *ConfluenceGenerator {
+	return &ConfluenceGenerator{}
+}
+
+// SetMaxSize 设置文档大小上限（字节），0 使用 DefaultMaxSize；超出时省略其后的步骤并附加截断说明
+func (g *ConfluenceGenerator) SetMaxSize(n int) {
+	g.maxSize = n
+}
+
+// Generate 生成 Confluence 存储格式文档，页面标题由 Document.Title 给出，正文不重复
+func (g *ConfluenceGenerator) Generate(ctx context.Context, task *domain.Task, plan *planner.TaskPlan, results []planner.StepResult) (*Document, error) {
+	var buf bytes.Buffer
+
+	title := task.Output.Title
+	if title == "" {
+		title = plan.Description
+	}
+	verbosity := task.Output.ContentConfig.VerbosityLevel()
+	minimal := verbosity == domain.VerbosityMinimal
+	content := task.Output.ContentConfig
+
+	if !minimal && content != nil && content.IncludeTOC {
+		buf.WriteString(`<ac:structured-macro ac:name="toc" />` + "\n")
+	}
+
+	// 概述（精简模式省略）
+	if !minimal {
+		buf.WriteString("<h2>概述</h2>\n")
+		buf.WriteString(fmt.Sprintf("<p>本指南将演示如何在 <a href=\"%s\">%s</a> 上完成以下操作：</p>\n",
+			xmlEscape(task.TargetURL), xmlEscape(task.TargetURL)))
+		buf.WriteString(confluenceMacro("info", "", "<p>"+xmlEscape(task.Description)+"</p>"))
+	}
+
+	buf.WriteString("<h2>操作步骤</h2>\n")
+	if minimal {
+		buf.WriteString("<ol>\n")
+	}
+
+	guard := newSizeGuard(g.maxSize, &buf)
+	omittedSteps, omittedFields := 0, 0
+	for i, step := range plan.Steps {
+		// 上一步写入后超出大小上限时撤回该步骤，其余步骤不再写入
+		if i > 0 && guard.exceeded() {
+			omittedSteps = len(plan.Steps) - i + 1
+			break
+		}
+		guard.begin()
+		result := getStepResult(results, i)
+		stepNum := formatStepNumber(i+1, content)
+		image := ""
+		if step.Screenshot && result != nil && result.Success {
+			image = fmt.Sprintf("<p><ac:image><ri:attachment ri:filename=\"step_%d.%s\" /></ac:image></p>",
+				i+1, task.ScreenshotFormat().Extension())
+		}
+
+		// 精简模式：编号清单，每步只保留描述与截图
+		if minimal {
+			buf.WriteString(fmt.Sprintf("<li><p>%s</p>%s</li>\n", xmlEscape(step.Description), image))
+			continue
+		}
+
+		var body bytes.Buffer
+		body.WriteString("<p>" + confluenceStepContent(step) + "</p>")
+		if verbosity == domain.VerbosityDetailed {
+			body.WriteString(confluenceStepDetails(step, result))
+		}
+		body.WriteString(image)
+		if content != nil && content.IncludeTips {
+			for _, tip := range stepTips(step, task.Output.Language) {
+				body.WriteString(confluenceMacro("tip", "", "<p>"+xmlEscape(tip)+"</p>"))
+			}
+		}
+		panelTitle := "步骤：" + step.Description
+		if stepNum != "" {
+			panelTitle = fmt.Sprintf("步骤 %s：%s", stepNum, step.Description)
+		}
+		buf.WriteString(confluenceMacro("panel", panelTitle, body.String()))
+	}
+
+	if omittedSteps == 0 && len(plan.Steps) > 0 && guard.exceeded() {
+		omittedSteps = 1
+	}
+	if minimal {
+		buf.WriteString("</ol>\n")
+	}
+
+	// 提取的数据，步骤已被截断时整体省略
+	fields := extractedFields(plan, results)
+	if omittedSteps > 0 {
+		omittedFields = len(fields)
+	} else if len(fields) > 0 {
+		buf.WriteString("<h2>提取的数据</h2>\n<table>\n<tbody>\n<tr><th>名称</th><th>值</th></tr>\n")
+		for j, f := range fields {
+			guard.begin()
+			buf.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td></tr>\n", xmlEscape(f.Key), xmlEscape(f.Value)))
+			if guard.exceeded() {
+				omittedFields = len(fields) - j
+				break
+			}
+		}
+		buf.WriteString("</tbody>\n</table>\n")
+	}
+
+	truncated := omittedSteps > 0 || omittedFields > 0
+	if truncated {
+		buf.WriteString(confluenceMacro("warning", "文档已截断",
+			"<p>"+xmlEscape(truncationNotice(g.maxSize, omittedSteps, omittedFields))+"</p>"))
+	}
+
+	// 总结与生成时间（精简模式省略）
+	if !minimal {
+		buf.WriteString("<h2>总结</h2>\n")
+		buf.WriteString(fmt.Sprintf("<p>通过以上 %d 个步骤，您已成功完成了「%s」操作。</p>\n", len(plan.Steps), xmlEscape(task.Description)))
+		buf.WriteString(fmt.Sprintf("<p><em>文档生成时间：%s</em></p>\n", time.Now().Format("2006-01-02 15:04:05")))
+	}
+
+	return &Document{
+		Title:     title,
+		Content:   buf.String(),
+		Format:    domain.DocFormatConfluence,
+		Truncated: truncated,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// confluenceMacro 生成带富文本正文的宏，title 为空时不设置标题参数；body 须为已转义的 XHTML
+func confluenceMacro(name, title, body string) string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("<ac:structured-macro ac:name=\"%s\">", name))
+	if title != "" {
+		buf.WriteString(fmt.Sprintf("<ac:parameter ac:name=\"title\">%s</ac:parameter>", xmlEscape(title)))
+	}
+	buf.WriteString("<ac:rich-text-body>" + body + "</ac:rich-text-body></ac:structured-macro>\n")
+	return buf.String()
+}
+
+// confluenceStepContent 步骤说明，措辞同 Markdown 文档
+func confluenceStepContent(step planner.ActionStep) string {
+	switch step.Action {
+	case "navigate":
+		return fmt.Sprintf("打开网址：<code>%s</code>", xmlEscape(step.Target))
+	case "click":
+		return fmt.Sprintf("点击「%s」按钮/链接。", xmlEscape(step.Description))
+	case "fill":
+		return fmt.Sprintf("在输入框中填写：<code>%s</code>", xmlEscape(step.Value))
+	case "hover":
+		return fmt.Sprintf("将鼠标悬停在「%s」上。", xmlEscape(step.Description))
+	case "select":
+		return fmt.Sprintf("从下拉列表中选择「%s」。", xmlEscape(step.Value))
+	case "wait":
+		return "等待页面加载完成。"
+	case "extract":
+		return fmt.Sprintf("读取「%s」的内容。", xmlEscape(step.Description))
+	case "download":
+		return fmt.Sprintf("点击「%s」下载文件。", xmlEscape(step.Description))
+	default:
+		return xmlEscape(step.Description)
+	}
+}
+
+// confluenceStepDetails 详细模式下列出步骤的操作类型、目标、输入与执行结果
+func confluenceStepDetails(step planner.ActionStep, result *planner.StepResult) string {
+	var buf bytes.Buffer
+	item := func(label, value string) {
+		buf.WriteString(fmt.Sprintf("<li>%s：<code>%s</code></li>", label, xmlEscape(value)))
+	}
+	buf.WriteString("<ul>")
+	item("操作类型", string(step.Action))
+	// navigate 的网址已在步骤说明中给出
+	if step.Target != "" && step.Action != "navigate" {
+		item("目标元素", step.Target)
+	}
+	if result != nil && result.Selector != "" && result.Selector != step.Target {
+		item("实际选择器", result.Selector)
+	}
+	if step.Value != "" && step.Action != "navigate" {
+		item("输入值", step.Value)
+	}
+	if step.WaitFor != "" {
+		item("等待元素", step.WaitFor)
+	}
+	switch {
+	case result == nil:
+	case result.Skipped:
+		buf.WriteString("<li>执行结果：未执行</li>")
+	case result.Success:
+		buf.WriteString("<li>执行结果：成功</li>")
+	default:
+		buf.WriteString(fmt.Sprintf("<li>执行结果：失败（%s）</li>", xmlEscape(result.Error)))
+	}
+	buf.WriteString("</ul>")
+	return buf.String()
+}
+
+// xmlEscape 转义 XHTML 文本与属性值，只产生 XML 预定义实体和数字字符引用；
+// 去掉 XML 不允许的控制字符，避免页面提取的文本导致文档无法导入
+func xmlEscape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || r == 0xFFFE || r == 0xFFFF {
+			return -1
+		}
+		return r
+	}, s)
+	return html.EscapeString(s)
+}
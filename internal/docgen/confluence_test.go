@@ -0,0 +1,129 @@
+package docgen
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// generatedAt 匹配文档生成时间，比较前替换为固定值
+var generatedAt = regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`)
+
+// confluencePlan 返回步骤文本含 XML 特殊字符的计划及执行结果
+func confluencePlan() (*planner.TaskPlan, []planner.StepResult) {
+	plan := &planner.TaskPlan{Description: "Create a project", Steps: []planner.ActionStep{
+		{Order: 1, Action: browser.ActionNavigate, Target: "https://app.example.com/new?a=1&b=2", Description: "Open the form"},
+		{Order: 2, Action: browser.ActionFill, Target: `input[name="title"]`, Value: `Q3 <Report> & "Plan"`,
+			Description: "Enter the title", Screenshot: true, Tips: []string{"Keep it < 80 chars & unique"}},
+		{Order: 3, Action: browser.ActionClick, Target: "#save", Description: `Click <Save> & "continue"`},
+		{Order: 4, Action: browser.ActionExtract, Target: "#id", Value: "project_id", Description: "Read the ID"},
+	}}
+	results := []planner.StepResult{
+		{Order: 1, Success: true},
+		{Order: 2, Success: true},
+		{Order: 3, Success: true},
+		{Order: 4, Success: true, Data: map[string]string{"project_id": "P-1 <x>\x01"}},
+	}
+	return plan, results
+}
+
+func TestConfluenceGolden(t *testing.T) {
+	plan, results := confluencePlan()
+	task := newDocTask(func(task *domain.Task) { task.Description = "Create a project & share it" })
+	doc, err := NewConfluenceGenerator().Generate(context.Background(), task, plan, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<ac:structured-macro ac:name="toc" />
+<h2>概述</h2>
+<p>本指南将演示如何在 <a href="https://app.example.com">https://app.example.com</a> 上完成以下操作：</p>
+<ac:structured-macro ac:name="info"><ac:rich-text-body><p>Create a project &amp; share it</p></ac:rich-text-body></ac:structured-macro>
+<h2>操作步骤</h2>
+<ac:structured-macro ac:name="panel"><ac:parameter ac:name="title">步骤 1：Open the form</ac:parameter><ac:rich-text-body><p>打开网址：<code>https://app.example.com/new?a=1&amp;b=2</code></p></ac:rich-text-body></ac:structured-macro>
+<ac:structured-macro ac:name="panel"><ac:parameter ac:name="title">步骤 2：Enter the title</ac:parameter><ac:rich-text-body><p>在输入框中填写：<code>Q3 &lt;Report&gt; &amp; &#34;Plan&#34;</code></p><p><ac:image><ri:attachment ri:filename="step_2.png" /></ac:image></p><ac:structured-macro ac:name="tip"><ac:rich-text-body><p>Keep it &lt; 80 chars &amp; unique</p></ac:rich-text-body></ac:structured-macro>
+</ac:rich-text-body></ac:structured-macro>
+<ac:structured-macro ac:name="panel"><ac:parameter ac:name="title">步骤 3：Click &lt;Save&gt; &amp; &#34;continue&#34;</ac:parameter><ac:rich-text-body><p>点击「Click &lt;Save&gt; &amp; &#34;continue&#34;」按钮/链接。</p></ac:rich-text-body></ac:structured-macro>
+<ac:structured-macro ac:name="panel"><ac:parameter ac:name="title">步骤 4：Read the ID</ac:parameter><ac:rich-text-body><p>读取「Read the ID」的内容。</p></ac:rich-text-body></ac:structured-macro>
+<h2>提取的数据</h2>
+<table>
+<tbody>
+<tr><th>名称</th><th>值</th></tr>
+<tr><td>project_id</td><td>P-1 &lt;x&gt;</td></tr>
+</tbody>
+</table>
+<h2>总结</h2>
+<p>通过以上 4 个步骤，您已成功完成了「Create a project &amp; share it」操作。</p>
+<p><em>文档生成时间：2026-01-02 03:04:05</em></p>
+`
+	if got := generatedAt.ReplaceAllString(doc.Content, "2026-01-02 03:04:05"); got != want {
+		t.Errorf("document differs from golden\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	if doc.Title != "Create a project" || doc.Format != domain.DocFormatConfluence {
+		t.Errorf("title/format = %q/%s", doc.Title, doc.Format)
+	}
+}
+
+func TestConfluenceMinimalGolden(t *testing.T) {
+	plan, results := confluencePlan()
+	task := newDocTask(func(task *domain.Task) { task.Output.ContentConfig.Verbosity = domain.VerbosityMinimal })
+	doc, err := NewConfluenceGenerator().Generate(context.Background(), task, plan, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<h2>操作步骤</h2>
+<ol>
+<li><p>Open the form</p></li>
+<li><p>Enter the title</p><p><ac:image><ri:attachment ri:filename="step_2.png" /></ac:image></p></li>
+<li><p>Click &lt;Save&gt; &amp; &#34;continue&#34;</p></li>
+<li><p>Read the ID</p></li>
+</ol>
+<h2>提取的数据</h2>
+<table>
+<tbody>
+<tr><th>名称</th><th>值</th></tr>
+<tr><td>project_id</td><td>P-1 &lt;x&gt;</td></tr>
+</tbody>
+</table>
+`
+	if doc.Content != want {
+		t.Errorf("document differs from golden\ngot:\n%s\nwant:\n%s", doc.Content, want)
+	}
+}
+
+func TestConfluenceOutputIsWellFormedXML(t *testing.T) {
+	plan, results := confluencePlan()
+	for _, verbosity := range []domain.Verbosity{domain.VerbosityMinimal, domain.VerbosityStandard, domain.VerbosityDetailed} {
+		task := newDocTask(func(task *domain.Task) {
+			task.Description = "Guide <b>bold</b> & \x0bmore"
+			task.TargetURL = `https://app.example.com/?q="x"&y='z'`
+			task.Output.ContentConfig.Verbosity = verbosity
+		})
+		doc, err := NewConfluenceGenerator().Generate(context.Background(), task, plan, results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 存储格式是带 ac:/ri: 命名空间的 XHTML 片段，包上根元素后须能被严格的 XML 解析器读取
+		root := `<root xmlns:ac="http://atlassian.com/content" xmlns:ri="http://atlassian.com/resource/identifier">` + doc.Content + `</root>`
+		dec := xml.NewDecoder(strings.NewReader(root))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s: not well-formed XML: %v", verbosity, err)
+				break
+			}
+		}
+		if strings.Contains(doc.Content, "<b>") {
+			t.Errorf("%s: task description not escaped", verbosity)
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"context"
 	"fmt"
 	"html/template"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,20 +25,29 @@ type Document struct {
 	Title     string
 	Content   string
 	Format    domain.DocFormat
+	Truncated bool // 超出大小上限，省略了部分步骤或提取的数据
 	CreatedAt time.Time
 }
 
 // MarkdownGenerator Markdown 文档生成器
-type MarkdownGenerator struct{}
+type MarkdownGenerator struct {
+	maxSize int
+}
 
 // NewMarkdownGenerator 创建 Markdown 生成器
 func NewMarkdownGenerator() *MarkdownGenerator {
 	return &MarkdownGenerator{}
 }
 
+// SetMaxSize 设置文档大小上限（字节），0 使用 DefaultMaxSize；超出时省略其后的步骤并附加截断说明
+func (g *MarkdownGenerator) SetMaxSize(n int) {
+	g.maxSize = n
+}
+
 // Generate 生成 Markdown 文档
 func (g *MarkdownGenerator) Generate(ctx context.Context, task *domain.Task, plan *planner.TaskPlan, results []planner.StepResult) (*Document, error) {
-	var buf bytes.Buffer
+	// head 为标题与目录，buf 为正文
+	var head, buf bytes.Buffer
 	
 	// 标题
 	title := task.Output.Title
@@ -44,91 +55,230 @@ func (g *MarkdownGenerator) Generate(ctx context.Context, task *domain.Task, pla
 		title = plan.Description
 	}
 	
-	buf.WriteString(fmt.Sprintf("# %s\n\n", title))
+	// 静态站点 front matter（如果启用）
+	if task.Output.ContentConfig != nil && task.Output.ContentConfig.FrontMatter {
+		head.WriteString(frontMatter(task, title))
+	}
+
+	head.WriteString(fmt.Sprintf("# %s\n\n", escapeMarkdown(title)))
+	verbosity := task.Output.ContentConfig.VerbosityLevel()
+	minimal := verbosity == domain.VerbosityMinimal
 	
-	// 目录（如果启用）
-	if task.Output.ContentConfig != nil && task.Output.ContentConfig.IncludeTOC {
-		buf.WriteString("## 目录\n\n")
-		for i, step := range plan.Steps {
-			buf.WriteString(fmt.Sprintf("%d. [%s](#步骤-%d)\n", i+1, step.Description, i+1))
-		}
-		buf.WriteString("\n---\n\n")
+	// 目录（如果启用），条目随步骤逐个写入，截断时只列出保留的步骤
+	toc := !minimal && task.Output.ContentConfig != nil && task.Output.ContentConfig.IncludeTOC
+	if toc {
+		head.WriteString("## 目录\n\n")
 	}
 	
-	// 概述
-	buf.WriteString("## 概述\n\n")
-	buf.WriteString(fmt.Sprintf("本指南将演示如何在 [%s](%s) 上完成以下操作：\n\n", task.TargetURL, task.TargetURL))
-	buf.WriteString(fmt.Sprintf("> %s\n\n", task.Description))
+	// 概述（精简模式省略）
+	if !minimal {
+		buf.WriteString("## 概述\n\n")
+		buf.WriteString(fmt.Sprintf("本指南将演示如何在 [%s](%s) 上完成以下操作：\n\n", escapeMarkdown(task.TargetURL), escapeLinkDestination(task.TargetURL)))
+		buf.WriteString(fmt.Sprintf("> %s\n\n", escapeMarkdown(task.Description)))
+	}
 	
 	// 步骤
 	buf.WriteString("## 操作步骤\n\n")
 	
+	guard := newSizeGuard(g.maxSize, &head, &buf)
+	omittedSteps, omittedFields := 0, 0
 	for i, step := range plan.Steps {
+		// 上一步写入后超出大小上限时撤回该步骤，其余步骤不再写入
+		if i > 0 && guard.exceeded() {
+			omittedSteps = len(plan.Steps) - i + 1
+			break
+		}
+		guard.begin()
+		if toc {
+			head.WriteString(fmt.Sprintf("%d. [%s](#步骤-%d)\n", i+1, escapeMarkdown(step.Description), i+1))
+		}
 		result := getStepResult(results, i)
+		stepNum := formatStepNumber(i+1, task.Output.ContentConfig)
+
+		// 精简模式：编号清单，每步只保留描述与截图
+		if minimal {
+			buf.WriteString(checklistItem(i+1, stepNum, escapeMarkdown(step.Description)))
+			if step.Screenshot && result != nil && result.Success {
+				buf.WriteString(fmt.Sprintf("\n    ![步骤 %s 截图](screenshots/step_%d.%s)\n\n", stepNum, i+1, task.ScreenshotFormat().Extension()))
+			}
+			continue
+		}
 		
 		// 步骤标题
-		stepNum := formatStepNumber(i+1, task.Output.ContentConfig)
-		buf.WriteString(fmt.Sprintf("### 步骤 %s：%s\n\n", stepNum, step.Description))
+		buf.WriteString(fmt.Sprintf("### 步骤 %s：%s\n\n", stepNum, escapeMarkdown(step.Description)))
 		
 		// 步骤详情
 		buf.WriteString(g.formatStepContent(step, result))
+		if verbosity == domain.VerbosityDetailed {
+			buf.WriteString(formatStepDetails(step, result))
+		}
 		
 		// 截图占位符
 		if step.Screenshot && result != nil && result.Success {
-			buf.WriteString(fmt.Sprintf("\n![步骤 %s 截图](screenshots/step_%d.png)\n\n", stepNum, i+1))
+			buf.WriteString(fmt.Sprintf("\n![步骤 %s 截图](screenshots/step_%d.%s)\n\n", stepNum, i+1, task.ScreenshotFormat().Extension()))
 		}
 		
 		// 提示（如果启用）
 		if task.Output.ContentConfig != nil && task.Output.ContentConfig.IncludeTips {
-			tips := g.generateTips(step)
+			tips := stepTips(step, task.Output.Language)
 			if len(tips) > 0 {
 				buf.WriteString("\n> **提示**：")
-				buf.WriteString(strings.Join(tips, " "))
+				escaped := make([]string, len(tips))
+				for i, tip := range tips {
+					escaped[i] = escapeMarkdown(tip)
+				}
+				buf.WriteString(strings.Join(escaped, " "))
 				buf.WriteString("\n\n")
 			}
 		}
 	}
 	
-	// 总结
-	buf.WriteString("## 总结\n\n")
-	buf.WriteString(fmt.Sprintf("通过以上 %d 个步骤，您已成功完成了「%s」操作。\n\n", len(plan.Steps), task.Description))
+	if omittedSteps == 0 && len(plan.Steps) > 0 && guard.exceeded() {
+		omittedSteps = 1
+	}
 	
-	// 生成时间
-	buf.WriteString("---\n\n")
-	buf.WriteString(fmt.Sprintf("*文档生成时间：%s*\n", time.Now().Format("2006-01-02 15:04:05")))
+	if minimal && len(plan.Steps) > 0 {
+		buf.WriteString("\n")
+	}
+	if toc {
+		head.WriteString("\n---\n\n")
+	}
+
+	// 提取的数据，步骤已被截断时整体省略
+	fields := extractedFields(plan, results)
+	if omittedSteps > 0 {
+		omittedFields = len(fields)
+	} else if len(fields) > 0 {
+		buf.WriteString("## 提取的数据\n\n")
+		buf.WriteString("| 名称 | 值 |\n|------|------|\n")
+		for j, f := range fields {
+			guard.begin()
+			buf.WriteString(fmt.Sprintf("| %s | %s |\n", escapeTableCell(f.Key), escapeTableCell(f.Value)))
+			if guard.exceeded() {
+				omittedFields = len(fields) - j
+				break
+			}
+		}
+		buf.WriteString("\n")
+	}
+	
+	truncated := omittedSteps > 0 || omittedFields > 0
+	if truncated {
+		buf.WriteString(fmt.Sprintf("> **文档已截断**：%s\n\n", truncationNotice(g.maxSize, omittedSteps, omittedFields)))
+	}
+
+	// 总结与生成时间（精简模式省略）
+	if !minimal {
+		buf.WriteString("## 总结\n\n")
+		buf.WriteString(fmt.Sprintf("通过以上 %d 个步骤，您已成功完成了「%s」操作。\n\n", len(plan.Steps), escapeMarkdown(task.Description)))
+		buf.WriteString("---\n\n")
+		buf.WriteString(fmt.Sprintf("*文档生成时间：%s*\n", time.Now().Format("2006-01-02 15:04:05")))
+	}
 	
 	return &Document{
 		Title:     title,
-		Content:   buf.String(),
+		Content:   head.String() + buf.String(),
 		Format:    domain.DocFormatMarkdown,
+		Truncated: truncated,
 		CreatedAt: time.Now(),
 	}, nil
 }
 
+// frontMatter 生成 YAML front matter，字符串值统一使用双引号转义
+func frontMatter(task *domain.Task, title string) string {
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.WriteString(fmt.Sprintf("title: %s\n", strconv.Quote(title)))
+	buf.WriteString(fmt.Sprintf("date: %s\n", task.CreatedAt.Format(time.RFC3339)))
+	if len(task.Tags) > 0 {
+		buf.WriteString("tags:\n")
+		for _, tag := range task.Tags {
+			buf.WriteString(fmt.Sprintf("  - %s\n", strconv.Quote(tag)))
+		}
+	}
+	buf.WriteString(fmt.Sprintf("target_url: %s\n", strconv.Quote(task.TargetURL)))
+	buf.WriteString("---\n\n")
+	return buf.String()
+}
+
 func (g *MarkdownGenerator) formatStepContent(step planner.ActionStep, result *planner.StepResult) string {
 	var buf bytes.Buffer
 	
 	switch step.Action {
 	case "navigate":
-		buf.WriteString(fmt.Sprintf("打开网址：`%s`\n", step.Target))
+		buf.WriteString(fmt.Sprintf("打开网址：%s\n", codeSpan(step.Target)))
 	case "click":
-		buf.WriteString(fmt.Sprintf("点击「%s」按钮/链接。\n", step.Description))
+		buf.WriteString(fmt.Sprintf("点击「%s」按钮/链接。\n", escapeMarkdown(step.Description)))
 	case "fill":
-		buf.WriteString(fmt.Sprintf("在输入框中填写：`%s`\n", step.Value))
+		buf.WriteString(fmt.Sprintf("在输入框中填写：%s\n", codeSpan(step.Value)))
 	case "hover":
-		buf.WriteString(fmt.Sprintf("将鼠标悬停在「%s」上。\n", step.Description))
+		buf.WriteString(fmt.Sprintf("将鼠标悬停在「%s」上。\n", escapeMarkdown(step.Description)))
 	case "select":
-		buf.WriteString(fmt.Sprintf("从下拉列表中选择「%s」。\n", step.Value))
+		buf.WriteString(fmt.Sprintf("从下拉列表中选择「%s」。\n", escapeMarkdown(step.Value)))
 	case "wait":
 		buf.WriteString("等待页面加载完成。\n")
+	case "extract":
+		buf.WriteString(fmt.Sprintf("读取「%s」的内容。\n", escapeMarkdown(step.Description)))
+	case "download":
+		buf.WriteString(fmt.Sprintf("点击「%s」下载文件。\n", escapeMarkdown(step.Description)))
 	default:
-		buf.WriteString(step.Description + "\n")
+		buf.WriteString(escapeMarkdown(step.Description) + "\n")
 	}
 	
 	return buf.String()
 }
 
-func (g *MarkdownGenerator) generateTips(step planner.ActionStep) []string {
+// formatStepDetails 详细模式下列出步骤的操作类型、目标、输入与执行结果
+func formatStepDetails(step planner.ActionStep, result *planner.StepResult) string {
+	var buf bytes.Buffer
+	buf.WriteString("\n")
+	buf.WriteString(fmt.Sprintf("- 操作类型：%s\n", codeSpan(string(step.Action))))
+	// navigate 的网址已在步骤说明中给出
+	if step.Target != "" && step.Action != "navigate" {
+		buf.WriteString(fmt.Sprintf("- 目标元素：%s\n", codeSpan(step.Target)))
+	}
+	if result != nil && result.Selector != "" && result.Selector != step.Target {
+		buf.WriteString(fmt.Sprintf("- 实际选择器：%s\n", codeSpan(result.Selector)))
+	}
+	if step.Value != "" && step.Action != "navigate" {
+		buf.WriteString(fmt.Sprintf("- 输入值：%s\n", codeSpan(step.Value)))
+	}
+	if step.WaitFor != "" {
+		buf.WriteString(fmt.Sprintf("- 等待元素：%s\n", codeSpan(step.WaitFor)))
+	}
+	switch {
+	case result == nil:
+	case result.Skipped:
+		buf.WriteString("- 执行结果：未执行\n")
+	case result.Success:
+		buf.WriteString("- 执行结果：成功\n")
+	default:
+		buf.WriteString(fmt.Sprintf("- 执行结果：失败（%s）\n", escapeMarkdown(result.Error)))
+	}
+	return buf.String()
+}
+
+// checklistItem 精简模式的清单项：数字编号使用有序列表，字母编号和无编号使用无序列表
+func checklistItem(num int, stepNum, text string) string {
+	switch {
+	case stepNum == "":
+		return fmt.Sprintf("- %s\n", text)
+	case stepNum == strconv.Itoa(num):
+		return fmt.Sprintf("%d. %s\n", num, text)
+	default:
+		return fmt.Sprintf("- **%s.** %s\n", stepNum, text)
+	}
+}
+
+// stepTips 优先使用 LLM 生成的提示，否则对中文文档降级为内置提示
+func stepTips(step planner.ActionStep, language string) []string {
+	if len(step.Tips) > 0 {
+		return step.Tips
+	}
+	if language != "" && language != "zh" {
+		return nil
+	}
+
 	var tips []string
 	
 	switch step.Action {
@@ -147,17 +297,24 @@ func (g *MarkdownGenerator) generateTips(step planner.ActionStep) []string {
 // HTMLGenerator HTML 文档生成器
 type HTMLGenerator struct {
 	template *template.Template
+	maxSize  int
 }
 
 // NewHTMLGenerator 创建 HTML 生成器
 func NewHTMLGenerator() *HTMLGenerator {
 	funcMap := template.FuncMap{
-		"add": func(a, b int) int { return a + b },
+		"add":        func(a, b int) int { return a + b },
+		"stepResult": getStepResult,
 	}
 	tmpl := template.Must(template.New("doc").Funcs(funcMap).Parse(htmlTemplate))
 	return &HTMLGenerator{template: tmpl}
 }
 
+// SetMaxSize 设置文档大小上限（字节），0 使用 DefaultMaxSize；超出时省略其后的步骤并附加截断说明
+func (g *HTMLGenerator) SetMaxSize(n int) {
+	g.maxSize = n
+}
+
 // Generate 生成 HTML 文档
 func (g *HTMLGenerator) Generate(ctx context.Context, task *domain.Task, plan *planner.TaskPlan, results []planner.StepResult) (*Document, error) {
 	title := task.Output.Title
@@ -165,10 +322,18 @@ func (g *HTMLGenerator) Generate(ctx context.Context, task *domain.Task, plan *p
 		title = plan.Description
 	}
 	
-	// 默认主题色
+	verbosity := task.Output.ContentConfig.VerbosityLevel()
+
+	// 默认主题色；主题色写入 CSS 上下文，仅在通过严格校验后作为可信 CSS 使用
 	themeColor := "#3B82F6"
-	if task.Output.StyleConfig != nil && task.Output.StyleConfig.ThemeColor != "" {
-		themeColor = task.Output.StyleConfig.ThemeColor
+	logoURL := ""
+	if style := task.Output.StyleConfig; style != nil {
+		if domain.ValidThemeColor(style.ThemeColor) {
+			themeColor = style.ThemeColor
+		}
+		if domain.ValidLogoURL(style.LogoURL) {
+			logoURL = style.LogoURL
+		}
 	}
 	
 	data := map[string]interface{}{
@@ -177,7 +342,13 @@ func (g *HTMLGenerator) Generate(ctx context.Context, task *domain.Task, plan *p
 		"TargetURL":   task.TargetURL,
 		"Steps":       plan.Steps,
 		"Results":     results,
-		"ThemeColor":  themeColor,
+		"ThemeColor":  template.CSS(themeColor),
+		"LogoURL":     logoURL,
+		"ScreenshotExt": task.ScreenshotFormat().Extension(),
+		"IncludeTips":   task.Output.ContentConfig != nil && task.Output.ContentConfig.IncludeTips && verbosity != domain.VerbosityMinimal,
+		"Minimal":       verbosity == domain.VerbosityMinimal,
+		"Detailed":      verbosity == domain.VerbosityDetailed,
+		"Data":          extractedFields(plan, results),
 		"GeneratedAt": time.Now().Format("2006-01-02 15:04:05"),
 	}
 	
@@ -186,14 +357,81 @@ func (g *HTMLGenerator) Generate(ctx context.Context, task *domain.Task, plan *p
 		return nil, fmt.Errorf("execute template: %w", err)
 	}
 	
+	truncated := buf.Len() > effectiveMaxSize(g.maxSize)
+	if truncated {
+		buf = g.renderTruncated(data, plan.Steps)
+	}
+	
 	return &Document{
 		Title:     title,
 		Content:   buf.String(),
 		Format:    domain.DocFormatHTML,
+		Truncated: truncated,
 		CreatedAt: time.Now(),
 	}, nil
 }
 
+// renderTruncated 文档超出大小上限时重新渲染：保留能容纳的前若干步骤，
+// 步骤全部保留时再截断提取的数据，并在文末附加截断说明
+func (g *HTMLGenerator) renderTruncated(data map[string]interface{}, steps []planner.ActionStep) bytes.Buffer {
+	fields := data["Data"].([]extractedField)
+	render := func(nSteps, nFields int) (bytes.Buffer, bool) {
+		data["Steps"] = steps[:nSteps]
+		data["Data"] = fields[:nFields]
+		data["Truncated"] = truncationNotice(g.maxSize, len(steps)-nSteps, len(fields)-nFields)
+		var buf bytes.Buffer
+		err := g.template.Execute(&buf, data)
+		return buf, err == nil && buf.Len() <= effectiveMaxSize(g.maxSize)
+	}
+	// 能容纳的步骤数随数量单调变化，二分查找最大值
+	nSteps := sort.Search(len(steps)+1, func(k int) bool {
+		_, fits := render(k, 0)
+		return !fits
+	}) - 1
+	if nSteps < 0 {
+		nSteps = 0
+	}
+	nFields := 0
+	if nSteps == len(steps) {
+		nFields = sort.Search(len(fields), func(k int) bool {
+			_, fits := render(nSteps, k)
+			return !fits
+		}) - 1
+		if nFields < 0 {
+			nFields = 0
+		}
+	}
+	buf, _ := render(nSteps, nFields)
+	return buf
+}
+
+// extractedField 提取的数据项
+type extractedField struct {
+	Key   string
+	Value string
+}
+
+// extractedFields 按步骤顺序列出成功的 extract 步骤提取的数据
+func extractedFields(plan *planner.TaskPlan, results []planner.StepResult) []extractedField {
+	var fields []extractedField
+	for i, step := range plan.Steps {
+		result := getStepResult(results, i)
+		if step.Action != "extract" || result == nil || !result.Success {
+			continue
+		}
+		if value, ok := result.Data[step.Value]; ok {
+			fields = append(fields, extractedField{Key: step.Value, Value: value})
+		}
+	}
+	return fields
+}
+
+// escapeTableCell 转义 Markdown 表格单元格中的竖线和换行
+func escapeTableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
 func getStepResult(results []planner.StepResult, index int) *planner.StepResult {
 	if index < len(results) {
 		return &results[index]
@@ -239,6 +477,10 @@ const htmlTemplate = `<!DOCTYPE html>
             box-shadow: 0 4px 6px -1px rgba(0,0,0,0.1);
             padding: 2rem;
         }
+        .logo {
+            max-height: 48px;
+            margin-bottom: 1rem;
+        }
         h1 {
             color: {{.ThemeColor}};
             margin-bottom: 1rem;
@@ -281,6 +523,38 @@ const htmlTemplate = `<!DOCTYPE html>
             margin-top: 1rem;
             box-shadow: 0 2px 4px rgba(0,0,0,0.1);
         }
+        .step .detail code {
+            background: #f1f5f9;
+            padding: 0 0.25rem;
+            border-radius: 4px;
+        }
+        .step .tip {
+            margin-top: 0.5rem;
+            padding: 0.5rem 0.75rem;
+            background: #fefce8;
+            border-radius: 6px;
+            color: #854d0e;
+            font-size: 0.9rem;
+        }
+        table.data {
+            width: 100%;
+            border-collapse: collapse;
+            margin-bottom: 1.5rem;
+        }
+        table.data th, table.data td {
+            border: 1px solid #e2e8f0;
+            padding: 0.5rem 0.75rem;
+            text-align: left;
+        }
+        table.data th {
+            background: #f1f5f9;
+        }
+        .truncated {
+            padding: 0.75rem 1rem;
+            background: #fef2f2;
+            border-radius: 6px;
+            color: #991b1b;
+        }
         .footer {
             margin-top: 2rem;
             padding-top: 1rem;
@@ -293,26 +567,50 @@ const htmlTemplate = `<!DOCTYPE html>
 </head>
 <body>
     <div class="container">
+        {{if .LogoURL}}<img class="logo" src="{{.LogoURL}}" alt="logo">{{end}}
         <h1>{{.Title}}</h1>
+        {{if not .Minimal}}
         <div class="description">
             <p>{{.Description}}</p>
             <p><small>目标网站：<a href="{{.TargetURL}}">{{.TargetURL}}</a></small></p>
         </div>
+        {{end}}
         
         <h2>操作步骤</h2>
         {{range $i, $step := .Steps}}
         <div class="step">
             <span class="step-number">{{add $i 1}}</span>
             <h3>{{$step.Description}}</h3>
+            {{if $.Detailed}}
+            <p class="detail">操作类型：<code>{{$step.Action}}</code>{{if $step.Target}}；目标元素：<code>{{$step.Target}}</code>{{end}}{{with stepResult $.Results $i}}{{if and .Selector (ne .Selector $step.Target)}}；实际选择器：<code>{{.Selector}}</code>{{end}}{{end}}{{if $step.Value}}；输入值：<code>{{$step.Value}}</code>{{end}}{{with stepResult $.Results $i}}；执行结果：{{if .Success}}成功{{else if .Skipped}}未执行{{else}}失败（{{.Error}}）{{end}}{{end}}</p>
+            {{end}}
+            {{if $.IncludeTips}}{{range $step.Tips}}
+            <p class="tip">{{.}}</p>
+            {{end}}{{end}}
             {{if $step.Screenshot}}
-            <img src="screenshots/step_{{add $i 1}}.png" alt="步骤 {{add $i 1}} 截图">
+            <img src="screenshots/step_{{add $i 1}}.{{$.ScreenshotExt}}" alt="步骤 {{add $i 1}} 截图">
             {{end}}
         </div>
         {{end}}
+        {{if .Data}}
+        <h2>提取的数据</h2>
+        <table class="data">
+            <tr><th>名称</th><th>值</th></tr>
+            {{range .Data}}
+            <tr><td>{{.Key}}</td><td>{{.Value}}</td></tr>
+            {{end}}
+        </table>
+        {{end}}
         
+        {{with .Truncated}}
+        <p class="truncated"><strong>文档已截断</strong>：{{.}}</p>
+        {{end}}
+        
+        {{if not .Minimal}}
         <div class="footer">
             文档生成时间：{{.GeneratedAt}}
         </div>
+        {{end}}
     </div>
 </body>
 </html>`
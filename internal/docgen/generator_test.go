@@ -0,0 +1,169 @@
+package docgen
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+	"gopkg.in/yaml.v3"
+)
+
+// newDocTask 返回使用默认输出配置的任务，modify 可调整字段
+func newDocTask(modify func(*domain.Task)) *domain.Task {
+	task := &domain.Task{
+		ID:          "task-1",
+		Description: "Create a project",
+		TargetURL:   "https://app.example.com",
+		Output:      domain.DefaultOutputConfig(),
+		CreatedAt:   time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+	}
+	if modify != nil {
+		modify(task)
+	}
+	return task
+}
+
+// testPlan 返回一个点击与填写两步的计划及成功的执行结果
+func testPlan() (*planner.TaskPlan, []planner.StepResult) {
+	plan := &planner.TaskPlan{
+		TaskID:      "task-1",
+		Description: "Create a project",
+		Steps: []planner.ActionStep{
+			{Order: 1, Action: browser.ActionClick, Target: "#new", Description: "Click New project", Screenshot: true},
+			{Order: 2, Action: browser.ActionFill, Target: "#name", Value: "Demo", Description: "Enter the project name"},
+		},
+	}
+	results := []planner.StepResult{{Order: 1, Success: true}, {Order: 2, Success: true}}
+	return plan, results
+}
+
+func TestMarkdownFrontMatter(t *testing.T) {
+	plan, results := testPlan()
+	for _, enabled := range []bool{false, true} {
+		task := newDocTask(func(task *domain.Task) {
+			task.Output.Title = `Guide: "quotes" & colons`
+			task.Tags = []string{"onboarding", "team: core"}
+			task.Output.ContentConfig.FrontMatter = enabled
+		})
+		doc, err := NewMarkdownGenerator().Generate(context.Background(), task, plan, results)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !enabled {
+			if strings.HasPrefix(doc.Content, "---") {
+				t.Errorf("front matter emitted although disabled:\n%s", doc.Content)
+			}
+			continue
+		}
+		rest, ok := strings.CutPrefix(doc.Content, "---\n")
+		if !ok {
+			t.Fatalf("document does not start with front matter:\n%s", doc.Content)
+		}
+		block, body, ok := strings.Cut(rest, "\n---\n")
+		if !ok {
+			t.Fatalf("front matter not closed:\n%s", doc.Content)
+		}
+		if !strings.HasPrefix(strings.TrimLeft(body, "\n"), "# ") {
+			t.Errorf("title heading does not follow the front matter:\n%s", body)
+		}
+
+		var meta struct {
+			Title     string    `yaml:"title"`
+			Date      time.Time `yaml:"date"`
+			Tags      []string  `yaml:"tags"`
+			TargetURL string    `yaml:"target_url"`
+		}
+		if err := yaml.Unmarshal([]byte(block), &meta); err != nil {
+			t.Fatalf("front matter is not valid YAML: %v\n%s", err, block)
+		}
+		if meta.Title != task.Output.Title || !meta.Date.Equal(task.CreatedAt) ||
+			strings.Join(meta.Tags, ",") != "onboarding,team: core" || meta.TargetURL != task.TargetURL {
+			t.Errorf("front matter = %+v", meta)
+		}
+	}
+}
+
+func TestHTMLStyleFallsBackOnInvalidValues(t *testing.T) {
+	plan, results := testPlan()
+	tests := []struct {
+		name        string
+		style       *domain.StyleConfig
+		color, logo string
+	}{
+		{"valid", &domain.StyleConfig{ThemeColor: "#10b981", LogoURL: "https://cdn.example.com/logo.png"},
+			"color: #10b981", `src="https://cdn.example.com/logo.png"`},
+		{"invalid", &domain.StyleConfig{ThemeColor: "red;}</style><script>x()</script>", LogoURL: "javascript:alert(1)"},
+			"color: #3B82F6", ""},
+	}
+	for _, tt := range tests {
+		task := newDocTask(func(task *domain.Task) { task.Output.StyleConfig = tt.style })
+		doc, err := NewHTMLGenerator().Generate(context.Background(), task, plan, results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(doc.Content, tt.color) {
+			t.Errorf("%s: theme color %q not used", tt.name, tt.color)
+		}
+		if tt.logo == "" && strings.Contains(doc.Content, `class="logo"`) {
+			t.Errorf("%s: invalid logo rendered", tt.name)
+		}
+		if tt.logo != "" && !strings.Contains(doc.Content, tt.logo) {
+			t.Errorf("%s: logo %q not rendered", tt.name, tt.logo)
+		}
+		if strings.Contains(doc.Content, "<script>") || strings.Contains(doc.Content, "javascript:") {
+			t.Errorf("%s: unsafe style value reached the document", tt.name)
+		}
+	}
+}
+
+func TestVerbosityLevels(t *testing.T) {
+	plan, results := testPlan()
+	plan.Steps[1].Tips = []string{"Names must be unique."}
+	generators := []struct {
+		name       string
+		gen        Generator
+		overview   string
+		screenshot string
+	}{
+		{"markdown", NewMarkdownGenerator(), "## 概述", "screenshots/step_1.png"},
+		{"html", NewHTMLGenerator(), `<div class="description">`, "screenshots/step_1.png"},
+		{"confluence", NewConfluenceGenerator(), "<h2>概述</h2>", `ri:filename="step_1.png"`},
+	}
+	levels := []struct {
+		verbosity               domain.Verbosity
+		overview, tips, details bool
+	}{
+		{domain.VerbosityMinimal, false, false, false},
+		{"", true, true, false},
+		{domain.VerbosityStandard, true, true, false},
+		{domain.VerbosityDetailed, true, true, true},
+	}
+	for _, g := range generators {
+		for _, lv := range levels {
+			task := newDocTask(func(task *domain.Task) { task.Output.ContentConfig.Verbosity = lv.verbosity })
+			doc, err := g.gen.Generate(context.Background(), task, plan, results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := g.name + "/" + string(lv.verbosity)
+			if got := strings.Contains(doc.Content, g.overview); got != lv.overview {
+				t.Errorf("%s: overview present = %v, want %v", name, got, lv.overview)
+			}
+			if got := strings.Contains(doc.Content, "Names must be unique."); got != lv.tips {
+				t.Errorf("%s: tips present = %v, want %v", name, got, lv.tips)
+			}
+			if got := strings.Contains(doc.Content, "操作类型"); got != lv.details {
+				t.Errorf("%s: step details present = %v, want %v", name, got, lv.details)
+			}
+			// 各级别均保留步骤描述与截图
+			if !strings.Contains(doc.Content, "Enter the project name") || !strings.Contains(doc.Content, g.screenshot) {
+				t.Errorf("%s: step description or screenshot missing:\n%s", name, doc.Content)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Package docgen 提供文档生成功能
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxSize 生成文档的默认大小上限（字节）
+const DefaultMaxSize = 5 << 20
+
+// truncationReserve 为截断说明与文档结尾预留的字节数
+const truncationReserve = 1024
+
+// effectiveMaxSize 返回生效的大小上限，未设置时为 DefaultMaxSize
+func effectiveMaxSize(maxSize int) int {
+	if maxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return maxSize
+}
+
+// sizeGuard 按大小上限逐段写入文档：每段写入前调用 begin，写入后超限时撤回该段。
+// 文档分多个缓冲区拼接时（如 Markdown 目录与正文），上限按全部缓冲区的总长度计算，撤回时各缓冲区一并撤回
+type sizeGuard struct {
+	bufs  []*bytes.Buffer
+	limit int
+	marks []int
+}
+
+func newSizeGuard(maxSize int, bufs ...*bytes.Buffer) *sizeGuard {
+	g := &sizeGuard{bufs: bufs, limit: effectiveMaxSize(maxSize) - truncationReserve, marks: make([]int, len(bufs))}
+	g.begin()
+	return g
+}
+
+func (g *sizeGuard) begin() {
+	for i, buf := range g.bufs {
+		g.marks[i] = buf.Len()
+	}
+}
+
+// exceeded 判断是否超出上限，超出时撤回 begin 之后写入的内容
+func (g *sizeGuard) exceeded() bool {
+	size := 0
+	for _, buf := range g.bufs {
+		size += buf.Len()
+	}
+	if size <= g.limit {
+		return false
+	}
+	for i, buf := range g.bufs {
+		buf.Truncate(g.marks[i])
+	}
+	return true
+}
+
+// truncationNotice 文档截断时附加的说明，列出省略的步骤与提取数据的数量
+func truncationNotice(maxSize, steps, fields int) string {
+	var omitted []string
+	if steps > 0 {
+		omitted = append(omitted, fmt.Sprintf("%d 个步骤", steps))
+	}
+	if fields > 0 {
+		omitted = append(omitted, fmt.Sprintf("%d 项提取的数据", fields))
+	}
+	return fmt.Sprintf("文档超过 %d 字节的大小上限，已省略其后的 %s，完整结果请通过任务接口查看。",
+		effectiveMaxSize(maxSize), strings.Join(omitted, "和 "))
+}
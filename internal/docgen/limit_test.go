@@ -0,0 +1,92 @@
+package docgen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/planner"
+)
+
+// sizedGenerator 可设置大小上限的生成器
+type sizedGenerator interface {
+	Generator
+	SetMaxSize(n int)
+}
+
+// longPlan 返回 n 个点击步骤和 fields 个提取步骤的计划及成功的执行结果
+func longPlan(n, fields int) (*planner.TaskPlan, []planner.StepResult) {
+	plan := &planner.TaskPlan{Description: "Long guide"}
+	var results []planner.StepResult
+	for i := 0; i < n; i++ {
+		plan.Steps = append(plan.Steps, planner.ActionStep{
+			Order:       i + 1,
+			Action:      browser.ActionClick,
+			Target:      fmt.Sprintf("#item-%d", i),
+			Description: fmt.Sprintf("Open item %d %s", i, strings.Repeat("x", 150)),
+		})
+		results = append(results, planner.StepResult{Order: i + 1, Success: true})
+	}
+	for i := 0; i < fields; i++ {
+		key := fmt.Sprintf("field_%d", i)
+		plan.Steps = append(plan.Steps, planner.ActionStep{
+			Order: n + i + 1, Action: browser.ActionExtract, Target: "#" + key, Value: key, Description: "Read " + key,
+		})
+		results = append(results, planner.StepResult{
+			Order: n + i + 1, Success: true, Data: map[string]string{key: strings.Repeat("v", 150)},
+		})
+	}
+	return plan, results
+}
+
+func TestDocumentSizeLimit(t *testing.T) {
+	const maxSize = 16 << 10
+	generators := map[string]func() sizedGenerator{
+		"markdown":   func() sizedGenerator { return NewMarkdownGenerator() },
+		"html":       func() sizedGenerator { return NewHTMLGenerator() },
+		"confluence": func() sizedGenerator { return NewConfluenceGenerator() },
+	}
+	tests := []struct {
+		name          string
+		steps, fields int
+		truncated     bool
+	}{
+		{"under limit", 5, 2, false},
+		{"too many steps", 300, 0, true},
+		{"too much extracted data", 5, 300, true},
+	}
+	for name, newGen := range generators {
+		for _, tt := range tests {
+			gen := newGen()
+			gen.SetMaxSize(maxSize)
+			plan, results := longPlan(tt.steps, tt.fields)
+			doc, err := gen.Generate(context.Background(), newDocTask(nil), plan, results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			label := name + "/" + tt.name
+			if len(doc.Content) > maxSize {
+				t.Errorf("%s: %d bytes, over the %d byte limit", label, len(doc.Content), maxSize)
+			}
+			if doc.Truncated != tt.truncated {
+				t.Errorf("%s: Truncated = %v, want %v", label, doc.Truncated, tt.truncated)
+			}
+			if got := strings.Contains(doc.Content, "文档已截断"); got != tt.truncated {
+				t.Errorf("%s: truncation notice present = %v, want %v", label, got, tt.truncated)
+			}
+			// 保留的步骤仍从第一步开始
+			if !strings.Contains(doc.Content, "Open item 0 ") {
+				t.Errorf("%s: first step missing", label)
+			}
+			if tt.truncated && tt.fields == 0 && strings.Contains(doc.Content, fmt.Sprintf("Open item %d ", tt.steps-1)) {
+				t.Errorf("%s: last step kept although the document was truncated", label)
+			}
+			// Markdown 目录只列出保留的步骤
+			if tt.truncated && tt.fields == 0 && strings.Contains(doc.Content, fmt.Sprintf("(#步骤-%d)", tt.steps)) {
+				t.Errorf("%s: table of contents lists an omitted step", label)
+			}
+		}
+	}
+}
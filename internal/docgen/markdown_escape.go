@@ -0,0 +1,71 @@
+// Package docgen 提供文档生成功能
+package docgen
+
+import (
+	"strings"
+)
+
+// markdownEscaper 转义行内有语法含义的 Markdown 字符
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"#", `\#`,
+	"|", `\|`,
+	"!", `\!`,
+	"~", `\~`,
+)
+
+// escapeMarkdown 将用户或 LLM 提供的文本转义为字面显示的行内 Markdown，换行合并为空格，
+// 避免在标题、引用等单行位置引入新的结构
+func escapeMarkdown(s string) string {
+	s = markdownEscaper.Replace(strings.Join(strings.Fields(s), " "))
+	// 行首的 - + 会被解析为列表，数字加点会被解析为有序列表
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		return `\` + s
+	}
+	if i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }); i > 0 && (s[i] == '.' || s[i] == ')') {
+		return s[:i] + `\` + s[i:]
+	}
+	return s
+}
+
+// codeSpan 生成行内代码，反引号数量多于内容中最长的连续反引号，内容按原样显示
+func codeSpan(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+			continue
+		}
+		run = 0
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// linkDestinationEscaper 转义链接地址中会截断 Markdown 链接的字符
+var linkDestinationEscaper = strings.NewReplacer(
+	" ", "%20",
+	"(", "%28",
+	")", "%29",
+	"<", "%3C",
+	">", "%3E",
+)
+
+// escapeLinkDestination 转义 [text](url) 中的 url 部分
+func escapeLinkDestination(s string) string {
+	return linkDestinationEscaper.Replace(s)
+}
@@ -0,0 +1,87 @@
+package docgen
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain text", "plain text"},
+		{"**bold** and _em_", `\*\*bold\*\* and \_em\_`},
+		{"[link](http://x)", `\[link\](http://x)`},
+		{"<script>alert(1)</script>", `\<script\>alert(1)\</script\>`},
+		{"# heading", `\# heading`},
+		{"a | b", `a \| b`},
+		{`back\slash`, `back\\slash`},
+		{"- item", `\- item`},
+		{"+ item", `\+ item`},
+		{"1. first", `1\. first`},
+		{"12) twelfth", `12\) twelfth`},
+		{"2026 roadmap", "2026 roadmap"},
+		{"line one\n# line two", `line one \# line two`},
+	}
+	for _, tt := range tests {
+		if got := escapeMarkdown(tt.in); got != tt.want {
+			t.Errorf("escapeMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCodeSpan(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"#name", "`#name`"},
+		{"a`b", "``a`b``"},
+		{"x``y", "```x``y```"},
+		{"`quoted`", "`` `quoted` ``"},
+		{"multi\nline", "`multi line`"},
+	}
+	for _, tt := range tests {
+		if got := codeSpan(tt.in); got != tt.want {
+			t.Errorf("codeSpan(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeLinkDestination(t *testing.T) {
+	if got := escapeLinkDestination("https://x.test/a b(c)<d>"); got != "https://x.test/a%20b%28c%29%3Cd%3E" {
+		t.Errorf("escapeLinkDestination = %q", got)
+	}
+}
+
+func TestMarkdownEscapesDynamicText(t *testing.T) {
+	plan, results := testPlan()
+	plan.Steps[0].Description = "Click **New** [now]\n# injected"
+	plan.Steps[1].Value = "a`b"
+	task := newDocTask(func(task *domain.Task) {
+		task.Output.Title = "# Title <b>"
+		task.Description = "Create\n> quoted"
+	})
+	doc, err := NewMarkdownGenerator().Generate(context.Background(), task, plan, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`# \# Title \<b\>`,
+		`Click \*\*New\*\* \[now\] \# injected`,
+		"> Create \\> quoted",
+		"``a`b``",
+	} {
+		if !strings.Contains(doc.Content, want) {
+			t.Errorf("document missing %q:\n%s", want, doc.Content)
+		}
+	}
+	for _, line := range strings.Split(doc.Content, "\n") {
+		if strings.HasPrefix(line, "# injected") || strings.HasPrefix(line, "> quoted") {
+			t.Errorf("dynamic text started a new Markdown block: %q", line)
+		}
+	}
+}
@@ -1,18 +1,23 @@
 // Package domain 定义核心业务模型
 package domain
 
-import "time"
+import (
+	"net/url"
+	"strings"
+	"time"
+)
 
 // AuthType 认证类型
 type AuthType string
 
 const (
-	AuthTypeNone      AuthType = "none"       // 无需认证
-	AuthTypeForm      AuthType = "form"       // 表单登录
-	AuthTypeSSO       AuthType = "sso"        // SSO 单点登录
-	AuthTypeManual    AuthType = "manual"     // 手动登录
-	AuthTypeCookie    AuthType = "cookie"     // Cookie 注入
-	AuthTypeToken     AuthType = "token"      // Token 注入
+	AuthTypeNone   AuthType = "none"   // 无需认证
+	AuthTypeForm   AuthType = "form"   // 表单登录
+	AuthTypeSSO    AuthType = "sso"    // SSO 单点登录
+	AuthTypeManual AuthType = "manual" // 手动登录
+	AuthTypeCookie AuthType = "cookie" // Cookie 注入
+	AuthTypeToken  AuthType = "token"  // Token 注入
+	AuthTypeBasic  AuthType = "basic"  // HTTP Basic 认证
 )
 
 // AuthConfig 认证配置
@@ -23,6 +28,7 @@ type AuthConfig struct {
 	SessionID   string            `json:"session_id,omitempty"`
 	Cookies     []Cookie          `json:"cookies,omitempty"`
 	Headers     map[string]string `json:"headers,omitempty"`
+	AllCookies  bool              `json:"all_cookies,omitempty"` // 不按目标域名过滤 Cookie
 }
 
 // Credentials 登录凭据
@@ -33,6 +39,11 @@ type Credentials struct {
 	APIKey   string `json:"api_key,omitempty"`
 }
 
+// IsEmpty 未提供任何凭据字段，nil 视为空
+func (c *Credentials) IsEmpty() bool {
+	return c == nil || (c.Username == "" && c.Password == "" && c.Token == "" && c.APIKey == "")
+}
+
 // SSOConfig SSO 配置
 type SSOConfig struct {
 	Provider     SSOProvider `json:"provider"`
@@ -66,12 +77,47 @@ type Cookie struct {
 	HTTPOnly bool      `json:"http_only"`
 }
 
+// OAuthTokens OAuth2/OIDC 令牌
+type OAuthTokens struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+}
+
 // Session 认证会话
 type Session struct {
 	ID        string            `json:"id"`
 	UserID    string            `json:"user_id,omitempty"`
 	Cookies   []Cookie          `json:"cookies"`
 	Headers   map[string]string `json:"headers,omitempty"`
+	Tokens    *OAuthTokens      `json:"tokens,omitempty"` // OAuth2/OIDC 令牌
 	ExpiresAt time.Time         `json:"expires_at"`
 	CreatedAt time.Time         `json:"created_at"`
 }
+
+// FilterCookiesForURL 仅保留与目标 URL 主机相关的 Cookie：同域名或父域名，子域名和其他站点的 Cookie 被丢弃。
+// 未设置域名的 Cookie 默认属于目标站点，予以保留；URL 无法解析时原样返回
+func FilterCookiesForURL(cookies []Cookie, targetURL string) []Cookie {
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Hostname() == "" {
+		return cookies
+	}
+	host := strings.ToLower(u.Hostname())
+
+	filtered := make([]Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		if cookieMatchesHost(c.Domain, host) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+func cookieMatchesHost(cookieDomain, host string) bool {
+	d := strings.ToLower(strings.TrimPrefix(cookieDomain, "."))
+	if d == "" || d == host {
+		return true
+	}
+	return strings.HasSuffix(host, "."+d)
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterCookiesForURL(t *testing.T) {
+	cookies := []Cookie{
+		{Name: "host", Domain: "app.example.com"},
+		{Name: "parent", Domain: ".example.com"},
+		{Name: "hostless"},
+		{Name: "child", Domain: "api.app.example.com"},
+		{Name: "sibling", Domain: "shop.example.com"},
+		{Name: "tracker", Domain: ".tracker.net"},
+		{Name: "lookalike", Domain: "badexample.com"},
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://app.example.com/login", "host,parent,hostless"},
+		{"https://APP.Example.com:8443/", "host,parent,hostless"},
+		{"https://example.com/", "parent,hostless"},
+		{"not a url", "host,parent,hostless,child,sibling,tracker,lookalike"},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, c := range FilterCookiesForURL(cookies, tt.url) {
+			names = append(names, c.Name)
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("FilterCookiesForURL(%q) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+}
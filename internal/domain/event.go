@@ -0,0 +1,57 @@
+// Package domain 定义核心业务模型
+package domain
+
+import "time"
+
+// EventLogFileName 任务事件日志文件名（JSON Lines，每行一个 TaskEvent），位于任务输出目录下
+const EventLogFileName = "events.jsonl"
+
+// TaskEventType 任务事件类型
+type TaskEventType string
+
+const (
+	TaskEventStatus TaskEventType = "status" // 任务状态变化
+	TaskEventPhase  TaskEventType = "phase"  // 进入执行阶段，如 connecting、planning
+	TaskEventStep   TaskEventType = "step"   // 步骤执行结束
+	TaskEventLLM    TaskEventType = "llm"    // LLM 调用结束
+)
+
+// 执行阶段名称
+const (
+	PhaseConnecting     = "connecting"
+	PhaseAuthenticating = "authenticating"
+	PhasePlanning       = "planning"
+	PhaseExecuting      = "executing"
+	PhaseGenerating     = "generating"
+	PhaseRetrying       = "retrying" // 本次尝试失败，使用新的浏览器会话整体重试
+)
+
+// TaskEvent 任务事件日志中的一条记录，按发生顺序只追加不修改
+type TaskEvent struct {
+	Time       time.Time     `json:"time"`
+	Type       TaskEventType `json:"type"`
+	Status     TaskStatus    `json:"status,omitempty"`
+	Phase      string        `json:"phase,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	DurationMS int64         `json:"duration_ms,omitempty"`
+	Step       *StepEvent    `json:"step,omitempty"`
+	LLM        *LLMEvent     `json:"llm,omitempty"`
+}
+
+// StepEvent 步骤事件的详情
+type StepEvent struct {
+	Order       int    `json:"order"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+	Target      string `json:"target,omitempty"`
+	Selector    string `json:"selector,omitempty"` // 实际使用的选择器
+	Success     bool   `json:"success"`
+}
+
+// LLMEvent LLM 调用事件的详情，提供商未返回用量时 token 数为 0
+type LLMEvent struct {
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+}
@@ -25,17 +25,32 @@ type LLMConfig struct {
 	Endpoint string      `json:"endpoint"`
 	APIKey   string      `json:"api_key,omitempty"`
 	Options  *LLMOptions `json:"options,omitempty"`
+	// OpenAICompat 对支持原生接口的提供商（Ollama）改用 OpenAI 兼容接口
+	OpenAICompat bool `json:"openai_compat,omitempty"`
+	// Headers 附加到请求的自定义头（如网关的组织 ID、路由键），不覆盖客户端设置的认证与内容类型头
+	Headers map[string]string `json:"headers,omitempty"`
+	// Fallbacks 按顺序尝试的备用模型，主模型重试耗尽后仍失败时切换
+	Fallbacks []*LLMConfig `json:"fallbacks,omitempty"`
+	// EmbeddingModel 向量化使用的模型，OpenAI 未设置时使用 text-embedding-3-small
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+}
+
+// Name 返回 provider/model 形式的模型标识
+func (c *LLMConfig) Name() string {
+	return string(c.Provider) + "/" + c.Model
 }
 
 // LLMOptions LLM 高级选项
 type LLMOptions struct {
-	Temperature      float64 `json:"temperature"`
-	MaxTokens        int     `json:"max_tokens"`
-	TopP             float64 `json:"top_p"`
-	FrequencyPenalty float64 `json:"frequency_penalty"`
-	PresencePenalty  float64 `json:"presence_penalty"`
-	Timeout          int     `json:"timeout"`
-	RetryCount       int     `json:"retry_count"`
+	// Temperature、TopP 与 RetryCount 为 nil 表示未设置，0 是有效值（确定性输出、不重试）
+	Temperature      *float64 `json:"temperature,omitempty"`
+	MaxTokens        int      `json:"max_tokens"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	FrequencyPenalty float64  `json:"frequency_penalty"`
+	PresencePenalty  float64  `json:"presence_penalty"`
+	Timeout          *int     `json:"timeout,omitempty"` // 单次请求超时（秒），nil 或非正数使用默认值
+	RetryCount       *int     `json:"retry_count,omitempty"`
+	KeepAlive        string   `json:"keep_alive,omitempty"` // Ollama 模型保活时长，如 "10m"
 }
 
 // LLMPreset LLM 预设配置
@@ -121,10 +136,52 @@ func GetLLMPresets() []LLMPreset {
 // DefaultLLMOptions 默认 LLM 选项
 func DefaultLLMOptions() *LLMOptions {
 	return &LLMOptions{
-		Temperature: 0.7,
+		Temperature: Float64(0.7),
 		MaxTokens:   4096,
-		TopP:        1.0,
-		Timeout:     60,
-		RetryCount:  3,
+		TopP:        Float64(1.0),
+		Timeout:     Int(60),
+		RetryCount:  Int(3),
+	}
+}
+
+// Float64 返回 v 的指针，用于设置可选的数值选项
+func Float64(v float64) *float64 {
+	return &v
+}
+
+// Int 返回 v 的指针，用于设置可选的整数选项
+func Int(v int) *int {
+	return &v
+}
+
+// MergeLLMOptions 将用户选项合并到默认选项之上，零值字段使用默认值；
+// Temperature、TopP、Timeout 与 RetryCount 以 nil 表示未设置
+func MergeLLMOptions(opts *LLMOptions) *LLMOptions {
+	merged := DefaultLLMOptions()
+	if opts == nil {
+		return merged
+	}
+	if opts.Temperature != nil {
+		merged.Temperature = Float64(*opts.Temperature)
+	}
+	if opts.MaxTokens > 0 {
+		merged.MaxTokens = opts.MaxTokens
+	}
+	if opts.TopP != nil {
+		merged.TopP = Float64(*opts.TopP)
+	}
+	if opts.FrequencyPenalty != 0 {
+		merged.FrequencyPenalty = opts.FrequencyPenalty
+	}
+	if opts.PresencePenalty != 0 {
+		merged.PresencePenalty = opts.PresencePenalty
+	}
+	if opts.Timeout != nil && *opts.Timeout > 0 {
+		merged.Timeout = Int(*opts.Timeout)
+	}
+	if opts.RetryCount != nil && *opts.RetryCount >= 0 {
+		merged.RetryCount = Int(*opts.RetryCount)
 	}
+	merged.KeepAlive = opts.KeepAlive
+	return merged
 }
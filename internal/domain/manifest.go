@@ -0,0 +1,117 @@
+// Package domain 定义核心业务模型
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// ManifestFileName 任务清单文件名，位于任务输出目录下
+const ManifestFileName = "manifest.json"
+
+// Manifest 任务产物清单，路径均相对于任务输出目录，便于整体打包导出
+type Manifest struct {
+	TaskID      string               `json:"task_id"`
+	Description string               `json:"description"`
+	TargetURL   string               `json:"target_url"`
+	Status      TaskStatus           `json:"status"`
+	Tags        []string             `json:"tags,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	Documents   []ManifestDocument   `json:"documents"`
+	Screenshots []ManifestScreenshot `json:"screenshots"`
+	Downloads   []ManifestDownload   `json:"downloads,omitempty"`
+	GeneratedAt time.Time            `json:"generated_at"`
+}
+
+// ManifestDocument 清单中的文档
+type ManifestDocument struct {
+	ID     string    `json:"id"`
+	Format DocFormat `json:"format"`
+	Path   string    `json:"path"`
+	Size   int64     `json:"size"`
+}
+
+// ManifestScreenshot 清单中的截图
+type ManifestScreenshot struct {
+	ID          string `json:"id"`
+	StepOrder   int    `json:"step_order"`
+	Path        string `json:"path"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+// ManifestDownload 清单中的下载文件
+type ManifestDownload struct {
+	ID        string `json:"id"`
+	StepOrder int    `json:"step_order"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+}
+
+// NewManifest 根据任务结果生成清单
+func NewManifest(task *Task) *Manifest {
+	m := &Manifest{
+		TaskID:      task.ID,
+		Description: task.Description,
+		TargetURL:   task.TargetURL,
+		Status:      task.Status,
+		Tags:        task.Tags,
+		CreatedAt:   task.CreatedAt,
+		CompletedAt: task.CompletedAt,
+		Documents:   []ManifestDocument{},
+		Screenshots: []ManifestScreenshot{},
+		GeneratedAt: time.Now(),
+	}
+	if task.Result == nil {
+		return m
+	}
+	for _, doc := range task.Result.Documents {
+		m.Documents = append(m.Documents, ManifestDocument{
+			ID:     doc.ID,
+			Format: doc.Format,
+			Path:   doc.FileName(),
+			Size:   doc.Size,
+		})
+	}
+	for _, shot := range task.Result.Screenshots {
+		m.Screenshots = append(m.Screenshots, ManifestScreenshot{
+			ID:          shot.ID,
+			StepOrder:   shot.StepOrder,
+			Path:        shot.URL,
+			Width:       shot.Width,
+			Height:      shot.Height,
+			DuplicateOf: shot.DuplicateOf,
+		})
+	}
+	for _, d := range task.Result.Downloads {
+		if d.URL == "" {
+			continue // 未保存的下载
+		}
+		m.Downloads = append(m.Downloads, ManifestDownload{
+			ID:        d.ID,
+			StepOrder: d.StepOrder,
+			Name:      d.Name,
+			Path:      d.FileName(),
+			Size:      d.Size,
+		})
+	}
+	return m
+}
+
+// FileName 文档在任务输出目录下的文件名
+func (d *DocumentInfo) FileName() string {
+	return d.ID + d.Format.Extension()
+}
+
+// FileName 下载文件在任务输出目录下的相对路径，Name 需已去除路径部分
+func (d *Download) FileName() string {
+	return fmt.Sprintf("downloads/step_%d_%s", d.StepOrder, d.Name)
+}
+
+// FileName 截图在任务输出目录下的相对路径，与 Markdown 文档中的图片引用一致
+func (s *Screenshot) FileName() string {
+	return fmt.Sprintf("screenshots/step_%d.%s", s.StepOrder, s.Format.Extension())
+}
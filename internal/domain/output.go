@@ -1,6 +1,13 @@
 // Package domain 定义核心业务模型
 package domain
 
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
 // DocFormat 文档格式
 type DocFormat string
 
@@ -9,8 +16,45 @@ const (
 	DocFormatHTML     DocFormat = "html"
 	DocFormatPDF      DocFormat = "pdf"
 	DocFormatDOCX     DocFormat = "docx"
+	// DocFormatConfluence Confluence 存储格式（XHTML），可通过 Confluence REST API 或编辑器的源码模式导入
+	DocFormatConfluence DocFormat = "confluence"
+	// DocFormatDebug 调试产物（快照与原始计划 JSON），仅在输出开启 Debug 时生成，不可作为输出格式请求
+	DocFormatDebug DocFormat = "debug"
 )
 
+// Extension 文件扩展名（含点）
+func (f DocFormat) Extension() string {
+	if f == DocFormatDebug {
+		return ".debug.json"
+	}
+	for _, info := range GetSupportedFormats() {
+		if info.Format == f {
+			return info.Extension
+		}
+	}
+	return ".txt"
+}
+
+// ContentType 下载时使用的 MIME 类型
+func (f DocFormat) ContentType() string {
+	switch f {
+	case DocFormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case DocFormatHTML:
+		return "text/html; charset=utf-8"
+	case DocFormatPDF:
+		return "application/pdf"
+	case DocFormatDOCX:
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	case DocFormatConfluence:
+		return "application/xml; charset=utf-8"
+	case DocFormatDebug:
+		return "application/json"
+	default:
+		return "application/octet-stream"
+	}
+}
+
 // OutputConfig 文档输出配置
 type OutputConfig struct {
 	Formats          []DocFormat     `json:"formats"`           // 输出格式（支持多选）
@@ -19,14 +63,54 @@ type OutputConfig struct {
 	ScreenshotConfig *ScreenshotConf `json:"screenshot_config"` // 截图配置
 	StyleConfig      *StyleConfig    `json:"style_config"`      // 样式配置
 	ContentConfig    *ContentConfig  `json:"content_config"`    // 内容配置
+	Debug            bool            `json:"debug"`             // 额外生成调试产物（每步快照与原始计划），默认关闭
+}
+
+// ScreenshotFormat 截图格式
+type ScreenshotFormat string
+
+const (
+	ScreenshotFormatPNG  ScreenshotFormat = "png"
+	ScreenshotFormatJPEG ScreenshotFormat = "jpeg"
+	ScreenshotFormatWebP ScreenshotFormat = "webp"
+)
+
+// Valid 是否为支持的截图格式
+func (f ScreenshotFormat) Valid() bool {
+	switch f {
+	case ScreenshotFormatPNG, ScreenshotFormatJPEG, ScreenshotFormatWebP:
+		return true
+	}
+	return false
+}
+
+// IsLossy 是否为有损格式（仅有损格式支持质量参数）
+func (f ScreenshotFormat) IsLossy() bool {
+	return f == ScreenshotFormatJPEG || f == ScreenshotFormatWebP
+}
+
+// Extension 文件扩展名（不含点）
+func (f ScreenshotFormat) Extension() string {
+	switch f {
+	case ScreenshotFormatJPEG:
+		return "jpg"
+	case ScreenshotFormatWebP:
+		return "webp"
+	default:
+		return "png"
+	}
 }
 
 // ScreenshotConf 截图配置
 type ScreenshotConf struct {
-	Quality    int  `json:"quality"`     // 截图质量 1-100
-	Annotate   bool `json:"annotate"`    // 是否标注操作位置
-	FullPage   bool `json:"full_page"`   // 是否全页截图
-	HighlightColor string `json:"highlight_color"` // 标注颜色
+	Format         ScreenshotFormat `json:"format"`          // 截图格式: png, jpeg, webp
+	Quality        int              `json:"quality"`         // 截图质量 1-100
+	Annotate       bool             `json:"annotate"`        // 是否标注操作位置
+	FullPage       bool             `json:"full_page"`       // 是否全页截图
+	HighlightColor string           `json:"highlight_color"` // 标注颜色
+	Dedup          bool             `json:"dedup"`           // 是否去除相邻的近似截图
+	DedupThreshold float64          `json:"dedup_threshold"` // 去重相似度阈值 0-1，默认 0.95
+	Delay          int              `json:"delay"`           // 截图前等待的毫秒数，0 使用默认值
 }
 
 // StyleConfig 样式配置
@@ -36,12 +120,68 @@ type StyleConfig struct {
 	ThemeColor string `json:"theme_color"` // 主题色
 }
 
+// themeColorPattern 允许的主题色写法：#rgb/#rgba/#rrggbb/#rrggbbaa、rgb()/rgba()、hsl()/hsla()
+var themeColorPattern = regexp.MustCompile(`^(#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})` +
+	`|rgba?\(\s*\d{1,3}%?\s*(,\s*\d{1,3}%?\s*){2}(,\s*(0|1|0?\.\d+|\d{1,3}%)\s*)?\)` +
+	`|hsla?\(\s*\d{1,3}(deg)?\s*,\s*\d{1,3}%\s*,\s*\d{1,3}%\s*(,\s*(0|1|0?\.\d+|\d{1,3}%)\s*)?\))$`)
+
+// ValidThemeColor 判断主题色是否为安全的颜色值，文档模板会将其原样写入 CSS
+func ValidThemeColor(s string) bool {
+	return themeColorPattern.MatchString(s)
+}
+
+// ValidLogoURL 判断 Logo 地址是否为 http(s) URL
+func ValidLogoURL(s string) bool {
+	if strings.ContainsAny(s, " \t\r\n\"'<>") {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Validate 检查主题色与 Logo 地址，空值表示使用默认值
+func (s *StyleConfig) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.ThemeColor != "" && !ValidThemeColor(s.ThemeColor) {
+		return fmt.Errorf("invalid theme_color %q: must be a hex, rgb() or hsl() color", s.ThemeColor)
+	}
+	if s.LogoURL != "" && !ValidLogoURL(s.LogoURL) {
+		return fmt.Errorf("invalid logo_url %q: must be an http(s) URL", s.LogoURL)
+	}
+	return nil
+}
+
 // ContentConfig 内容配置
 type ContentConfig struct {
-	IncludeTOC     bool   `json:"include_toc"`     // 是否包含目录
-	IncludeCover   bool   `json:"include_cover"`   // 是否包含封面
-	StepNumbering  string `json:"step_numbering"`  // 步骤编号: number, letter, none
-	IncludeTips    bool   `json:"include_tips"`    // 是否包含提示信息
+	IncludeTOC    bool      `json:"include_toc"`    // 是否包含目录
+	IncludeCover  bool      `json:"include_cover"`  // 是否包含封面
+	StepNumbering string    `json:"step_numbering"` // 步骤编号: number, letter, none
+	IncludeTips   bool      `json:"include_tips"`   // 是否包含提示信息
+	FrontMatter   bool      `json:"front_matter"`   // Markdown 开头输出 YAML front matter，供 Hugo/Jekyll 等静态站点使用
+	Verbosity     Verbosity `json:"verbosity"`      // 说明文字详略: minimal, standard, detailed
+}
+
+// Verbosity 文档说明文字的详略程度
+type Verbosity string
+
+const (
+	VerbosityMinimal  Verbosity = "minimal"  // 只保留编号步骤与截图，适合速查清单
+	VerbosityStandard Verbosity = "standard" // 默认：概述、步骤说明、提示与总结
+	VerbosityDetailed Verbosity = "detailed" // 在默认基础上列出每步的操作目标、输入与执行结果
+)
+
+// VerbosityLevel 返回说明文字详略程度，未配置时为 standard
+func (c *ContentConfig) VerbosityLevel() Verbosity {
+	if c == nil {
+		return VerbosityStandard
+	}
+	switch c.Verbosity {
+	case VerbosityMinimal, VerbosityDetailed:
+		return c.Verbosity
+	}
+	return VerbosityStandard
 }
 
 // DefaultOutputConfig 默认输出配置
@@ -51,6 +191,7 @@ func DefaultOutputConfig() *OutputConfig {
 		Language: "zh",
 		Title:    "",
 		ScreenshotConfig: &ScreenshotConf{
+			Format:         ScreenshotFormatPNG,
 			Quality:        90,
 			Annotate:       true,
 			FullPage:       false,
@@ -100,6 +241,13 @@ func GetSupportedFormats() []FormatInfo {
 			Extension:   ".docx",
 			Icon:        "file-word",
 		},
+		{
+			Format:      DocFormatConfluence,
+			Name:        "Confluence",
+			Description: "Confluence 存储格式，可直接发布到 Confluence 知识库",
+			Extension:   ".confluence.xml",
+			Icon:        "book-open",
+		},
 	}
 }
 
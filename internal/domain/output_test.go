@@ -0,0 +1,77 @@
+package domain
+
+import "testing"
+
+func TestScreenshotFormat(t *testing.T) {
+	tests := []struct {
+		format ScreenshotFormat
+		valid  bool
+		lossy  bool
+		ext    string
+	}{
+		{ScreenshotFormatPNG, true, false, "png"},
+		{ScreenshotFormatJPEG, true, true, "jpg"},
+		{ScreenshotFormatWebP, true, true, "webp"},
+		{"gif", false, false, "png"},
+		{"", false, false, "png"},
+	}
+	for _, tt := range tests {
+		if got := tt.format.Valid(); got != tt.valid {
+			t.Errorf("%q.Valid() = %v, want %v", tt.format, got, tt.valid)
+		}
+		if got := tt.format.IsLossy(); got != tt.lossy {
+			t.Errorf("%q.IsLossy() = %v, want %v", tt.format, got, tt.lossy)
+		}
+		if got := tt.format.Extension(); got != tt.ext {
+			t.Errorf("%q.Extension() = %q, want %q", tt.format, got, tt.ext)
+		}
+	}
+}
+
+func TestStyleConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		style *StyleConfig
+		valid bool
+	}{
+		{"nil", nil, true},
+		{"defaults", &StyleConfig{}, true},
+		{"short hex", &StyleConfig{ThemeColor: "#3bf"}, true},
+		{"hex with alpha", &StyleConfig{ThemeColor: "#3B82F6CC"}, true},
+		{"rgb", &StyleConfig{ThemeColor: "rgb(59, 130, 246)"}, true},
+		{"rgba", &StyleConfig{ThemeColor: "rgba(59,130,246,0.5)"}, true},
+		{"hsl", &StyleConfig{ThemeColor: "hsl(217deg, 91%, 60%)"}, true},
+		{"https logo", &StyleConfig{LogoURL: "https://cdn.example.com/logo.png"}, true},
+		{"named color", &StyleConfig{ThemeColor: "red"}, false},
+		{"css injection", &StyleConfig{ThemeColor: "#fff; } body { display: none"}, false},
+		{"style close", &StyleConfig{ThemeColor: "</style><script>alert(1)</script>"}, false},
+		{"bad hex length", &StyleConfig{ThemeColor: "#12345"}, false},
+		{"javascript logo", &StyleConfig{LogoURL: "javascript:alert(1)"}, false},
+		{"data logo", &StyleConfig{LogoURL: "data:image/png;base64,AAAA"}, false},
+		{"quoted logo", &StyleConfig{LogoURL: `https://x.test/a" onerror="alert(1)`}, false},
+		{"relative logo", &StyleConfig{LogoURL: "/logo.png"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.style.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestVerbosityLevel(t *testing.T) {
+	tests := []struct {
+		config *ContentConfig
+		want   Verbosity
+	}{
+		{nil, VerbosityStandard},
+		{&ContentConfig{}, VerbosityStandard},
+		{&ContentConfig{Verbosity: "verbose"}, VerbosityStandard},
+		{&ContentConfig{Verbosity: VerbosityMinimal}, VerbosityMinimal},
+		{&ContentConfig{Verbosity: VerbosityDetailed}, VerbosityDetailed},
+	}
+	for _, tt := range tests {
+		if got := tt.config.VerbosityLevel(); got != tt.want {
+			t.Errorf("VerbosityLevel(%+v) = %q, want %q", tt.config, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package domain 定义核心业务模型
+package domain
+
+import "unicode/utf8"
+
+// DefaultMaxExampleTokens 规划示例默认的 token 预算
+const DefaultMaxExampleTokens = 4000
+
+// DefaultMaxIterations 迭代规划默认的最大轮数
+const DefaultMaxIterations = 10
+
+// PlanningMode 规划方式
+type PlanningMode string
+
+const (
+	// PlanningOneShot 根据初始页面一次生成完整计划（默认）
+	PlanningOneShot PlanningMode = "one_shot"
+	// PlanningIterative 每轮只规划当前页面上的步骤，执行后重新采集快照再规划下一轮，
+	// 直到模型判断任务完成或达到轮数上限，适合多页表单向导
+	PlanningIterative PlanningMode = "iterative"
+)
+
+// PlanningConfig 规划配置
+type PlanningConfig struct {
+	// Examples few-shot 示例，按顺序插入在系统提示与本次任务之间
+	Examples []PlanExample `json:"examples,omitempty"`
+	// MaxExampleTokens 示例的估算 token 上限，超出时丢弃最早的示例，0 使用默认值
+	MaxExampleTokens int `json:"max_example_tokens,omitempty"`
+	// StrictJSON 要求响应只包含计划 JSON，带说明文字或代码块时视为解析失败而不尝试提取
+	StrictJSON bool `json:"strict_json,omitempty"`
+	// Mode 规划方式，为空时同 one_shot
+	Mode PlanningMode `json:"mode,omitempty"`
+	// MaxIterations iterative 模式的最大轮数，0 使用 DefaultMaxIterations
+	MaxIterations int `json:"max_iterations,omitempty"`
+}
+
+// Iterative 是否使用迭代规划，nil 时为 false
+func (c *PlanningConfig) Iterative() bool {
+	return c != nil && c.Mode == PlanningIterative
+}
+
+// IterationLimit 返回迭代规划的最大轮数
+func (c *PlanningConfig) IterationLimit() int {
+	if c == nil || c.MaxIterations <= 0 {
+		return DefaultMaxIterations
+	}
+	return c.MaxIterations
+}
+
+// RequireStrictJSON 是否要求响应只包含 JSON，nil 时为 false
+func (c *PlanningConfig) RequireStrictJSON() bool {
+	return c != nil && c.StrictJSON
+}
+
+// PlanExample 一组示例对话：用户任务与期望的计划 JSON
+type PlanExample struct {
+	User      string `json:"user"`
+	Assistant string `json:"assistant"`
+}
+
+// EstimateTokens 粗略估算文本 token 数（约 3 个字符 1 个 token，中文偏保守）
+func EstimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 2) / 3
+}
+
+// BudgetedExamples 返回 token 预算内的示例：从最新的示例开始保留，超出预算时丢弃更早的示例
+func (c *PlanningConfig) BudgetedExamples() []PlanExample {
+	if c == nil || len(c.Examples) == 0 {
+		return nil
+	}
+	budget := c.MaxExampleTokens
+	if budget <= 0 {
+		budget = DefaultMaxExampleTokens
+	}
+
+	start := len(c.Examples)
+	used := 0
+	for i := len(c.Examples) - 1; i >= 0; i-- {
+		cost := EstimateTokens(c.Examples[i].User) + EstimateTokens(c.Examples[i].Assistant)
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		start = i
+	}
+	return c.Examples[start:]
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBudgetedExamplesDropsOldest(t *testing.T) {
+	examples := []PlanExample{
+		{User: strings.Repeat("a", 30), Assistant: strings.Repeat("b", 30)}, // 约 20 token
+		{User: strings.Repeat("c", 30), Assistant: strings.Repeat("d", 30)},
+		{User: strings.Repeat("e", 30), Assistant: strings.Repeat("f", 30)},
+	}
+	tests := []struct {
+		budget int
+		want   int
+	}{
+		{0, 3}, // 默认预算
+		{45, 2},
+		{20, 1},
+		{10, 0},
+	}
+	for _, tt := range tests {
+		got := (&PlanningConfig{Examples: examples, MaxExampleTokens: tt.budget}).BudgetedExamples()
+		if len(got) != tt.want || (tt.want > 0 && got[len(got)-1] != examples[2]) {
+			t.Errorf("budget %d: kept %d examples, want the newest %d", tt.budget, len(got), tt.want)
+		}
+	}
+}
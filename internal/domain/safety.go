@@ -0,0 +1,72 @@
+// Package domain 定义核心业务模型
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultDestructiveKeywords 默认的破坏性操作关键词，匹配时不区分大小写
+var DefaultDestructiveKeywords = []string{
+	"delete", "remove", "pay", "purchase", "checkout", "submit order", "place order", "unsubscribe",
+	"删除", "移除", "支付", "付款", "购买", "下单", "提交订单", "注销",
+}
+
+// SafetyConfig 破坏性操作确认配置
+type SafetyConfig struct {
+	ConfirmDestructive bool     `json:"confirm_destructive"` // 匹配关键词的点击步骤执行前暂停，等待人工批准
+	Keywords           []string `json:"keywords,omitempty"`  // 为空时使用 DefaultDestructiveKeywords
+}
+
+// ApprovalRequest 等待人工批准的步骤
+type ApprovalRequest struct {
+	StepOrder   int       `json:"step_order"`
+	Description string    `json:"description"`
+	Keyword     string    `json:"keyword"` // 命中的关键词
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// MatchDestructive 在步骤文本中查找破坏性关键词，未启用确认时始终返回 false。
+// 英文关键词按整词匹配，避免 "pay" 命中 "payload"
+func (c *SafetyConfig) MatchDestructive(texts ...string) (string, bool) {
+	if c == nil || !c.ConfirmDestructive {
+		return "", false
+	}
+	keywords := c.Keywords
+	if len(keywords) == 0 {
+		keywords = DefaultDestructiveKeywords
+	}
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		for _, kw := range keywords {
+			if kw != "" && containsKeyword(text, strings.ToLower(kw)) {
+				return kw, true
+			}
+		}
+	}
+	return "", false
+}
+
+// containsKeyword 判断 text 是否包含 kw，kw 两端为 ASCII 字母时要求词边界
+func containsKeyword(text, kw string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], kw)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(kw)
+		if !(isASCIILetter(kw[0]) && start > 0 && isWordByte(text[start-1])) &&
+			!(isASCIILetter(kw[len(kw)-1]) && end < len(text) && isWordByte(text[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func isWordByte(b byte) bool {
+	return isASCIILetter(b) || (b >= '0' && b <= '9') || b == '_'
+}
@@ -0,0 +1,40 @@
+// Package domain 定义核心业务模型
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// SuccessCriteria 任务的成功条件，计划执行完成后在最终页面上检查，设置的条件全部满足时任务才算完成
+type SuccessCriteria struct {
+	URLPattern string `json:"url_pattern,omitempty"` // 最终页面 URL 需匹配的正则表达式
+	Text       string `json:"text,omitempty"`        // 最终页面需出现的文本，如"提交成功"
+}
+
+// Validate 检查至少设置了一项条件且 URL 正则表达式有效
+func (c *SuccessCriteria) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.URLPattern == "" && c.Text == "" {
+		return errors.New("success_criteria requires url_pattern or text")
+	}
+	if _, err := regexp.Compile(c.URLPattern); err != nil {
+		return fmt.Errorf("invalid success_criteria url_pattern: %w", err)
+	}
+	return nil
+}
+
+// MatchURL 判断最终页面 URL 是否满足 URLPattern，未设置时视为满足
+func (c *SuccessCriteria) MatchURL(pageURL string) (bool, error) {
+	if c == nil || c.URLPattern == "" {
+		return true, nil
+	}
+	re, err := regexp.Compile(c.URLPattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(pageURL), nil
+}
@@ -7,64 +7,286 @@ import "time"
 type TaskStatus string
 
 const (
-	TaskStatusPending    TaskStatus = "pending"
-	TaskStatusRunning    TaskStatus = "running"
-	TaskStatusCompleted  TaskStatus = "completed"
-	TaskStatusFailed     TaskStatus = "failed"
-	TaskStatusCancelled  TaskStatus = "cancelled"
+	TaskStatusPending   TaskStatus = "pending"
+	TaskStatusRunning   TaskStatus = "running"
+	TaskStatusCompleted TaskStatus = "completed"
+	TaskStatusFailed    TaskStatus = "failed"
+	TaskStatusCancelled TaskStatus = "cancelled"
+	// TaskStatusWaitingForHuman 破坏性步骤执行前暂停，等待人工批准
+	TaskStatusWaitingForHuman TaskStatus = "waiting_for_human"
+)
+
+// Terminal 是否为终态（completed、failed、cancelled），终态任务不会再被执行
+func (s TaskStatus) Terminal() bool {
+	return s == TaskStatusCompleted || s == TaskStatusFailed || s == TaskStatusCancelled
+}
+
+// Pacing 操作节奏
+type Pacing string
+
+const (
+	PacingOff    Pacing = "off"    // 默认：步骤间仅等待固定的页面响应时间
+	PacingNormal Pacing = "normal" // 步骤间增加短暂的随机停顿
+	PacingHuman  Pacing = "human"  // 模拟真人：较长随机停顿，输入时逐字键入
+)
+
+// StepFailurePolicy 步骤最终失败（重新规划后仍失败）时的处理方式
+type StepFailurePolicy string
+
+const (
+	StepFailureContinue StepFailurePolicy = "continue" // 默认：记录失败并继续执行后续步骤
+	StepFailureStop     StepFailurePolicy = "stop"     // 任务立即失败，剩余步骤标记为跳过
+)
+
+// ErrorCode 任务失败原因代码
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidConfig ErrorCode = "invalid_config" // 配置无效（如 LLM 客户端创建失败）
+	ErrorCodeBrowser       ErrorCode = "browser"        // 浏览器连接或页面操作失败
+	ErrorCodeNavigation    ErrorCode = "navigation"     // 导航失败
+	ErrorCodeAuth          ErrorCode = "auth"           // 认证失败
+	ErrorCodePlanning      ErrorCode = "planning"       // LLM 规划失败
+	ErrorCodeStepExecution ErrorCode = "step_execution" // 步骤执行失败
+	ErrorCodeDocument      ErrorCode = "document"       // 文档生成或保存失败
+	ErrorCodeTimeout       ErrorCode = "timeout"        // 超过任务总时长上限
+	ErrorCodeVerification  ErrorCode = "verification"   // 计划执行完成但未满足任务的成功条件
+	ErrorCodeInternal      ErrorCode = "internal"       // 其他内部错误
 )
 
 // Task 任务实体
 type Task struct {
-	ID           string        `json:"id"`
-	Description  string        `json:"description"`   // 自然语言任务描述
-	TargetURL    string        `json:"target_url"`    // 目标网站 URL
-	Status       TaskStatus    `json:"status"`
-	Auth         *AuthConfig   `json:"auth,omitempty"`
-	LLM          *LLMConfig    `json:"llm"`
-	Output       *OutputConfig `json:"output"`
-	Result       *TaskResult   `json:"result,omitempty"`
-	ErrorMessage string        `json:"error_message,omitempty"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
-	CompletedAt  *time.Time    `json:"completed_at,omitempty"`
+	ID                string            `json:"id"`
+	Description       string            `json:"description"` // 自然语言任务描述
+	TargetURL         string            `json:"target_url"`  // 目标网站 URL
+	Status            TaskStatus        `json:"status"`
+	Progress          int               `json:"progress"` // 执行进度 0-100
+	Priority          int               `json:"priority"` // 排队优先级 0-10，数值越大越先执行
+	Tags              []string          `json:"tags,omitempty"`
+	Auth              *AuthConfig       `json:"auth,omitempty"`
+	LLM               *LLMConfig        `json:"llm"`
+	Planning          *PlanningConfig   `json:"planning,omitempty"` // few-shot 示例等规划配置
+	Output            *OutputConfig     `json:"output"`
+	Plan              *TaskPlan         `json:"plan,omitempty"` // AI 生成的执行计划
+	Result            *TaskResult       `json:"result,omitempty"`
+	ErrorMessage      string            `json:"error_message,omitempty"`
+	ErrorCode         ErrorCode         `json:"error_code,omitempty"`         // 机器可读的失败原因
+	ErrorStack        string            `json:"error_stack,omitempty"`        // 执行 panic 时的堆栈，便于排查
+	PlanOutput        string            `json:"plan_output,omitempty"`        // 规划失败时模型的原始输出（脱敏并截断）
+	NoCache           bool              `json:"no_cache,omitempty"`           // 跳过执行计划缓存
+	NavigationRetries int               `json:"navigation_retries,omitempty"` // 初始导航失败重试次数，0 使用默认值
+	KeepAlive         int               `json:"keep_alive,omitempty"`         // 完成后保留浏览器会话的空闲秒数，0 表示立即关闭
+	Locale            string            `json:"locale,omitempty"`             // 浏览器语言区域，为空时取输出语言
+	TimezoneID        string            `json:"timezone_id,omitempty"`        // 浏览器时区，为空时使用主机时区
+	SnapshotScope     string            `json:"snapshot_scope,omitempty"`     // 页面快照范围的 CSS 选择器，为空时采集整个页面
+	Pacing            Pacing            `json:"pacing,omitempty"`             // 操作节奏，为空时同 off
+	OnStepFailure     StepFailurePolicy `json:"on_step_failure,omitempty"`    // 步骤最终失败时的处理方式，为空时同 continue
+	MaxTaskRetries    int               `json:"max_task_retries,omitempty"`   // 任务整体失败后的重试次数，0 表示不重试
+	Deadline          int               `json:"deadline,omitempty"`           // 任务总时长上限（秒，含重试与手动登录），0 使用默认值
+	DismissOverlays   bool              `json:"dismiss_overlays,omitempty"`   // 规划和每步执行前关闭 Cookie 同意横幅
+	SnapshotEvery     int               `json:"snapshot_every,omitempty"`     // 每隔几步重新采集页面快照，0 或 1 表示每步
+	MaxLLMSnapshots   int               `json:"max_llm_snapshots,omitempty"`  // 发送给 LLM 的快照上限，用尽后失败步骤不再重新规划，0 表示不限制
+	Safety            *SafetyConfig     `json:"safety,omitempty"`             // 破坏性操作确认配置
+	PendingApproval   *ApprovalRequest  `json:"pending_approval,omitempty"`   // 等待人工批准的步骤
+	SuccessCriteria   *SuccessCriteria  `json:"success_criteria,omitempty"`   // 计划执行完成后检查的成功条件
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	CompletedAt       *time.Time        `json:"completed_at,omitempty"`
+}
+
+// TaskPlan 任务执行计划
+type TaskPlan struct {
+	Description string     `json:"description"`
+	Steps       []PlanStep `json:"steps"`
+}
+
+// PlanStep 计划中的操作步骤
+type PlanStep struct {
+	Order         int    `json:"order"`
+	Action        string `json:"action"`
+	Target        string `json:"target"`
+	Value         string `json:"value,omitempty"`
+	WaitFor       string `json:"wait_for,omitempty"`
+	WaitForJS     string `json:"wait_for_js,omitempty"`
+	Screenshot    bool   `json:"screenshot"`
+	Description   string `json:"description"`
+	NavigatesAway bool   `json:"navigates_away,omitempty"`
+	WaitEnabled   bool   `json:"wait_enabled,omitempty"` // 点击前等待目标可点击
+	FullPage      *bool  `json:"full_page,omitempty"`    // 覆盖全页截图默认设置
+	Clip          *Rect  `json:"clip,omitempty"`         // 只截取的页面区域
+}
+
+// Rect 页面上的矩形区域（CSS 像素）
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
 }
 
 // TaskResult 任务执行结果
 type TaskResult struct {
-	Steps       []StepResult   `json:"steps"`
-	Screenshots []Screenshot   `json:"screenshots"`
-	Documents   []DocumentInfo `json:"documents"`
-	Duration    time.Duration  `json:"duration"`
+	Steps       []StepResult      `json:"steps"`
+	Screenshots []Screenshot      `json:"screenshots"`
+	Documents   []DocumentInfo    `json:"documents"`
+	Downloads   []Download        `json:"downloads,omitempty"` // download 步骤保存的文件
+	Data        map[string]string `json:"data,omitempty"`      // extract 步骤提取的数据
+	Duration    time.Duration     `json:"duration"`
+	Attempts    []TaskAttempt     `json:"attempts,omitempty"` // 发生整体重试时记录每次尝试
+	Model       string            `json:"model,omitempty"`    // 实际生成计划的模型（provider/model），复用缓存计划时为空
+	// SnapshotsSent 发送给 LLM 的页面快照次数（初始规划与失败步骤重新规划）
+	SnapshotsSent int `json:"snapshots_sent"`
+}
+
+// TaskAttempt 一次任务执行尝试
+type TaskAttempt struct {
+	Attempt   int           `json:"attempt"` // 从 1 开始
+	Error     string        `json:"error,omitempty"`
+	ErrorCode ErrorCode     `json:"error_code,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
 }
 
 // StepResult 步骤执行结果
 type StepResult struct {
-	Order        int        `json:"order"`
-	Action       string     `json:"action"`
-	Description  string     `json:"description"`
-	Success      bool       `json:"success"`
-	Error        string     `json:"error,omitempty"`
-	Screenshot   *Screenshot `json:"screenshot,omitempty"`
-	ExecutedAt   time.Time  `json:"executed_at"`
+	Order       int         `json:"order"`
+	Action      string      `json:"action"`
+	Description string      `json:"description"`
+	Success     bool        `json:"success"`
+	Error       string      `json:"error,omitempty"`
+	Skipped     bool        `json:"skipped,omitempty"`  // stop 策略下前序步骤失败，未执行
+	Selector    string      `json:"selector,omitempty"` // 实际使用的选择器，重新规划或回退匹配后与计划中的目标不同
+	Screenshot  *Screenshot `json:"screenshot,omitempty"`
+	ExecutedAt  time.Time   `json:"executed_at"`
 }
 
 // Screenshot 截图信息
 type Screenshot struct {
+	ID          string           `json:"id"`
+	URL         string           `json:"url"`    // 存储地址
+	Format      ScreenshotFormat `json:"format"` // 图片格式
+	StepOrder   int              `json:"step_order"`
+	Width       int              `json:"width"`
+	Height      int              `json:"height"`
+	Hash        string           `json:"hash,omitempty"`         // 用于相邻截图去重
+	DuplicateOf string           `json:"duplicate_of,omitempty"` // 与该截图近似，复用其引用
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// Download download 步骤触发并保存的文件
+type Download struct {
 	ID        string    `json:"id"`
-	URL       string    `json:"url"`       // 存储地址
 	StepOrder int       `json:"step_order"`
-	Width     int       `json:"width"`
-	Height    int       `json:"height"`
+	Name      string    `json:"name"`          // 浏览器建议的文件名
+	URL       string    `json:"url,omitempty"` // 下载地址，未使用文件存储时为空
+	Size      int64     `json:"size"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 // DocumentInfo 生成的文档信息
 type DocumentInfo struct {
-	ID        string     `json:"id"`
-	Format    DocFormat  `json:"format"`
-	URL       string     `json:"url,omitempty"`
-	Content   string     `json:"content,omitempty"`
-	Size      int64      `json:"size"`
-	CreatedAt time.Time  `json:"created_at"`
+	ID        string    `json:"id"`
+	Format    DocFormat `json:"format"`
+	URL       string    `json:"url,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Size      int64     `json:"size"`
+	Truncated bool      `json:"truncated,omitempty"` // 超出大小上限，省略了部分步骤或提取的数据
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HasTag 判断任务是否包含标签
+func (t *Task) HasTag(tag string) bool {
+	for _, tg := range t.Tags {
+		if tg == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTaskDeadline 未配置 Deadline 时的任务总时长上限
+const DefaultTaskDeadline = 10 * time.Minute
+
+// DeadlineDuration 返回任务总时长上限
+func (t *Task) DeadlineDuration() time.Duration {
+	if t.Deadline <= 0 {
+		return DefaultTaskDeadline
+	}
+	return time.Duration(t.Deadline) * time.Second
+}
+
+// KeepAliveDuration 返回完成后保留浏览器会话的空闲时长
+func (t *Task) KeepAliveDuration() time.Duration {
+	return time.Duration(t.KeepAlive) * time.Second
+}
+
+// languageLocales 输出语言到浏览器语言区域的默认映射
+var languageLocales = map[string]string{
+	"zh": "zh-CN",
+	"en": "en-US",
+	"ja": "ja-JP",
+	"ko": "ko-KR",
+}
+
+// OutputLanguage 返回文档输出语言，未配置时为空
+func (t *Task) OutputLanguage() string {
+	if t.Output == nil {
+		return ""
+	}
+	return t.Output.Language
+}
+
+// BrowserLocale 返回浏览器上下文使用的语言区域：优先显式配置，否则由输出语言推导
+func (t *Task) BrowserLocale() string {
+	if t.Locale != "" {
+		return t.Locale
+	}
+	if t.Output == nil || t.Output.Language == "" {
+		return ""
+	}
+	if locale, ok := languageLocales[t.Output.Language]; ok {
+		return locale
+	}
+	return t.Output.Language
+}
+
+// ScreenshotFormat 返回任务配置的截图格式，未配置时为 PNG
+func (t *Task) ScreenshotFormat() ScreenshotFormat {
+	if t.Output != nil && t.Output.ScreenshotConfig != nil && t.Output.ScreenshotConfig.Format != "" {
+		return t.Output.ScreenshotConfig.Format
+	}
+	return ScreenshotFormatPNG
+}
+
+// ScreenshotFullPage 返回步骤截图是否截取整页，步骤未指定时使用输出配置
+func (t *Task) ScreenshotFullPage(step *bool) bool {
+	if step != nil {
+		return *step
+	}
+	return t.Output != nil && t.Output.ScreenshotConfig != nil && t.Output.ScreenshotConfig.FullPage
+}
+
+// DefaultScreenshotDelay 截图前的默认等待时间，用于让淡入淡出等过渡动画结束
+const DefaultScreenshotDelay = 300 * time.Millisecond
+
+// ScreenshotDelay 返回步骤截图前的等待时间，未配置时为 DefaultScreenshotDelay
+func (t *Task) ScreenshotDelay() time.Duration {
+	if t.Output != nil && t.Output.ScreenshotConfig != nil && t.Output.ScreenshotConfig.Delay > 0 {
+		return time.Duration(t.Output.ScreenshotConfig.Delay) * time.Millisecond
+	}
+	return DefaultScreenshotDelay
+}
+
+// DefaultScreenshotDedupThreshold 截图去重默认相似度阈值
+const DefaultScreenshotDedupThreshold = 0.95
+
+// ScreenshotDedupThreshold 返回截图去重的相似度阈值，未启用去重时 ok 为 false
+func (t *Task) ScreenshotDedupThreshold() (threshold float64, ok bool) {
+	if t.Output == nil || t.Output.ScreenshotConfig == nil || !t.Output.ScreenshotConfig.Dedup {
+		return 0, false
+	}
+	if th := t.Output.ScreenshotConfig.DedupThreshold; th > 0 && th <= 1 {
+		return th, true
+	}
+	return DefaultScreenshotDedupThreshold, true
 }
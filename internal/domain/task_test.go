@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBrowserLocale(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   string
+		language string
+		want     string
+	}{
+		{"explicit locale wins", "de-DE", "zh", "de-DE"},
+		{"derived from output language", "", "zh", "zh-CN"},
+		{"unmapped language used as is", "", "fr-FR", "fr-FR"},
+		{"host locale", "", "", ""},
+	}
+	for _, tt := range tests {
+		task := &Task{Locale: tt.locale, Output: &OutputConfig{Language: tt.language}}
+		if got := task.BrowserLocale(); got != tt.want {
+			t.Errorf("%s: BrowserLocale() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := (&Task{}).BrowserLocale(); got != "" {
+		t.Errorf("task without output: BrowserLocale() = %q", got)
+	}
+}
+
+func TestDeadlineDuration(t *testing.T) {
+	for deadline, want := range map[int]time.Duration{0: DefaultTaskDeadline, -5: DefaultTaskDeadline, 90: 90 * time.Second} {
+		if got := (&Task{Deadline: deadline}).DeadlineDuration(); got != want {
+			t.Errorf("Deadline %d: DeadlineDuration() = %s, want %s", deadline, got, want)
+		}
+	}
+}
+
+func TestScreenshotDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		output *OutputConfig
+		want   time.Duration
+	}{
+		{"no output", nil, DefaultScreenshotDelay},
+		{"no screenshot config", &OutputConfig{}, DefaultScreenshotDelay},
+		{"zero", &OutputConfig{ScreenshotConfig: &ScreenshotConf{}}, DefaultScreenshotDelay},
+		{"configured", &OutputConfig{ScreenshotConfig: &ScreenshotConf{Delay: 1200}}, 1200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := (&Task{Output: tt.output}).ScreenshotDelay(); got != tt.want {
+			t.Errorf("%s: ScreenshotDelay() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTaskStatusTerminal(t *testing.T) {
+	for status, want := range map[TaskStatus]bool{
+		TaskStatusPending:         false,
+		TaskStatusRunning:         false,
+		TaskStatusWaitingForHuman: false,
+		TaskStatusCompleted:       true,
+		TaskStatusFailed:          true,
+		TaskStatusCancelled:       true,
+	} {
+		if got := status.Terminal(); got != want {
+			t.Errorf("%s.Terminal() = %v, want %v", status, got, want)
+		}
+	}
+}
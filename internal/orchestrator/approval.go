@@ -0,0 +1,100 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// approvalTimeout 等待人工批准的最长时间，超时后任务失败。任务时限更早到达时以时限为准
+const approvalTimeout = 30 * time.Minute
+
+// ErrApprovalTimeout 破坏性步骤在超时前未获批准
+var ErrApprovalTimeout = errors.New("approval timed out")
+
+// confirmDestructive 破坏性点击步骤执行前暂停任务，直到调用 Approve 或超时
+func (o *Orchestrator) confirmDestructive(ctx context.Context, task *domain.Task, step planner.ActionStep) error {
+	if step.Action != browser.ActionClick {
+		return nil
+	}
+	keyword, ok := task.Safety.MatchDestructive(step.Description, step.Target, step.Value)
+	if !ok {
+		return nil
+	}
+
+	approved := make(chan struct{})
+	o.runMu.Lock()
+	o.approvals[task.ID] = approved
+	o.runMu.Unlock()
+	defer func() {
+		o.runMu.Lock()
+		delete(o.approvals, task.ID)
+		o.runMu.Unlock()
+	}()
+
+	log.Printf("[Task %s] Step %d matches destructive keyword %q, waiting for approval", task.ID, step.Order, keyword)
+	task.Status = domain.TaskStatusWaitingForHuman
+	task.PendingApproval = &domain.ApprovalRequest{
+		StepOrder:   step.Order,
+		Description: step.Description,
+		Keyword:     keyword,
+		RequestedAt: time.Now(),
+	}
+	task.UpdatedAt = time.Now()
+	if err := o.taskStore.Update(ctx, task); err != nil {
+		return fmt.Errorf("update task status: %w", err)
+	}
+	o.recordStatus(ctx, task)
+
+	wait := approvalTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		wait = time.Until(deadline)
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	timeout := newTaskError(domain.ErrorCodeStepExecution, fmt.Sprintf("step %d %s", step.Order, step.Action), ErrApprovalTimeout)
+	select {
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return timeout
+		}
+		return ctx.Err()
+	case <-timer.C:
+		return timeout
+	case <-approved:
+	}
+
+	log.Printf("[Task %s] Step %d approved", task.ID, step.Order)
+	task.Status = domain.TaskStatusRunning
+	task.PendingApproval = nil
+	task.UpdatedAt = time.Now()
+	if err := o.taskStore.Update(ctx, task); err != nil {
+		return fmt.Errorf("update task status: %w", err)
+	}
+	o.recordStatus(ctx, task)
+	return nil
+}
+
+// Approve 批准等待中的破坏性步骤，任务未处于等待状态时返回 false
+func (o *Orchestrator) Approve(ctx context.Context, taskID string) (bool, error) {
+	if _, err := o.taskStore.Get(ctx, taskID); err != nil {
+		return false, err
+	}
+
+	o.runMu.Lock()
+	defer o.runMu.Unlock()
+	approved, ok := o.approvals[taskID]
+	if !ok {
+		return false, nil
+	}
+	close(approved)
+	delete(o.approvals, taskID)
+	return true, nil
+}
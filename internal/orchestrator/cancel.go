@@ -0,0 +1,91 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/storage"
+)
+
+// trackRunning 记录执行中任务的取消函数
+func (o *Orchestrator) trackRunning(taskID string, cancel context.CancelFunc) {
+	o.runMu.Lock()
+	defer o.runMu.Unlock()
+	o.running[taskID] = cancel
+}
+
+// untrackRunning 任务结束后移除取消函数
+func (o *Orchestrator) untrackRunning(taskID string) {
+	o.runMu.Lock()
+	defer o.runMu.Unlock()
+	delete(o.running, taskID)
+}
+
+// Cancel 取消排队中、执行中或等待批准的任务：标记为已取消，执行中的任务同时中断其上下文。
+// 任务已结束时返回 false
+func (o *Orchestrator) Cancel(ctx context.Context, taskID string) (bool, error) {
+	task, err := o.taskStore.Get(ctx, taskID)
+	if err != nil {
+		return false, err
+	}
+	switch task.Status {
+	case domain.TaskStatusPending, domain.TaskStatusRunning, domain.TaskStatusWaitingForHuman:
+	default:
+		return false, nil
+	}
+	if err := o.taskStore.UpdateStatus(ctx, taskID, domain.TaskStatusCancelled); err != nil {
+		return false, err
+	}
+
+	o.runMu.Lock()
+	cancel, ok := o.running[taskID]
+	o.runMu.Unlock()
+	if ok {
+		log.Printf("[Task %s] Cancelling running task", taskID)
+		cancel()
+	}
+	return true, nil
+}
+
+// CancelAll 取消全部排队中和执行中的任务，返回已取消和跳过（已结束）的任务数
+func (o *Orchestrator) CancelAll(ctx context.Context) (cancelled, skipped int, err error) {
+	tasks, err := o.taskStore.List(ctx, storage.TaskFilter{}, math.MaxInt32, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, task := range tasks {
+		ok, err := o.Cancel(ctx, task.ID)
+		if err != nil {
+			log.Printf("[Task %s] Cancel failed: %v", task.ID, err)
+		}
+		if ok {
+			cancelled++
+		} else {
+			skipped++
+		}
+	}
+	return cancelled, skipped, nil
+}
+
+// ErrTaskNotRunning 任务未在执行中
+var ErrTaskNotRunning = errors.New("task is not running")
+
+// LiveScreenshot 截取执行中（含等待批准）任务当前的浏览器画面，返回 PNG
+func (o *Orchestrator) LiveScreenshot(ctx context.Context, taskID string) ([]byte, error) {
+	if _, err := o.taskStore.Get(ctx, taskID); err != nil {
+		return nil, err
+	}
+	o.runMu.Lock()
+	_, ok := o.running[taskID]
+	o.runMu.Unlock()
+	if !ok {
+		return nil, ErrTaskNotRunning
+	}
+	// 控制器为共享实例，执行中的任务即为当前持有浏览器的任务
+	return o.browserCtrl.TakeScreenshot(ctx, browser.ScreenshotOptions{Type: string(domain.ScreenshotFormatPNG)})
+}
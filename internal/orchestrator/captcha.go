@@ -0,0 +1,60 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"log"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// CaptchaSolver 外部验证码求解服务，按 reCAPTCHA 站点密钥和页面地址返回 g-recaptcha-response 令牌
+type CaptchaSolver interface {
+	Solve(ctx context.Context, siteKey, pageURL string) (token string, err error)
+}
+
+// NopCaptchaSolver 默认实现，不求解验证码，页面保持原样
+type NopCaptchaSolver struct{}
+
+// Solve 返回空令牌
+func (NopCaptchaSolver) Solve(ctx context.Context, siteKey, pageURL string) (string, error) {
+	return "", nil
+}
+
+// SetCaptchaSolver 设置验证码求解服务，为 nil 时不处理验证码
+func (o *Orchestrator) SetCaptchaSolver(solver CaptchaSolver) {
+	if solver == nil {
+		solver = NopCaptchaSolver{}
+	}
+	o.captcha = solver
+}
+
+// solveCaptcha 配置了求解服务且页面上有待完成的 reCAPTCHA 时写入令牌，失败只记录日志，由后续步骤自行处理
+func (o *Orchestrator) solveCaptcha(ctx context.Context, task *domain.Task) {
+	if _, nop := o.captcha.(NopCaptchaSolver); nop {
+		return
+	}
+	siteKey, err := o.browserCtrl.FindCaptchaSiteKey(ctx)
+	if err != nil {
+		log.Printf("[Task %s] Detect captcha failed: %v", task.ID, err)
+		return
+	}
+	if siteKey == "" {
+		return
+	}
+	pageURL, _ := o.browserCtrl.GetCurrentURL(ctx)
+	log.Printf("[Task %s] reCAPTCHA detected on %s, requesting token", task.ID, pageURL)
+	token, err := o.captcha.Solve(ctx, siteKey, pageURL)
+	if err != nil {
+		log.Printf("[Task %s] Solve captcha failed: %v", task.ID, err)
+		return
+	}
+	if token == "" {
+		return
+	}
+	if err := o.browserCtrl.InjectCaptchaToken(ctx, token); err != nil {
+		log.Printf("[Task %s] Inject captcha token failed: %v", task.ID, err)
+		return
+	}
+	log.Printf("[Task %s] Captcha token injected", task.ID)
+}
@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// recordingSolver 记录求解请求，返回固定令牌或错误
+type recordingSolver struct {
+	token string
+	err   error
+
+	mu    sync.Mutex
+	calls []string // siteKey|pageURL
+}
+
+func (s *recordingSolver) Solve(ctx context.Context, siteKey, pageURL string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, siteKey+"|"+pageURL)
+	return s.token, s.err
+}
+
+func TestCaptchaSolver(t *testing.T) {
+	click := planner.ActionStep{Action: browser.ActionClick, Target: "#signup", Description: "Sign up"}
+	tests := []struct {
+		name    string
+		siteKey string
+		solver  *recordingSolver // nil 时使用默认的 NopCaptchaSolver
+		calls   string
+		token   string
+	}{
+		{"solved", "site-key-1", &recordingSolver{token: "token-abc"},
+			"site-key-1|https://app.example.com/form", "token-abc"},
+		{"no captcha on page", "", &recordingSolver{token: "token-abc"}, "", ""},
+		// 未求解时验证码仍待完成，页面加载后与执行步骤前各尝试一次
+		{"solver error", "site-key-1", &recordingSolver{err: errors.New("balance exhausted")},
+			"site-key-1|https://app.example.com/form,site-key-1|https://app.example.com/form", ""},
+		{"empty token", "site-key-1", &recordingSolver{},
+			"site-key-1|https://app.example.com/form,site-key-1|https://app.example.com/form", ""},
+		{"no solver", "site-key-1", nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, planReply(click))
+			env.ctrl.CaptchaSiteKey = tt.siteKey
+			if tt.solver != nil {
+				env.orch.SetCaptchaSolver(tt.solver)
+			}
+			task := env.newTask(t, nil)
+
+			// 求解失败不影响任务，由后续步骤自行处理
+			if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+				t.Fatalf("ExecuteTask: %v", err)
+			}
+			if got := env.stored(t, task.ID); got.Status != domain.TaskStatusCompleted {
+				t.Errorf("status = %s, want completed", got.Status)
+			}
+
+			// 写入令牌后页面不再有待完成的验证码，之后的步骤不重复求解
+			if tt.solver != nil && strings.Join(tt.solver.calls, ",") != tt.calls {
+				t.Errorf("solver calls = %q, want %q", tt.solver.calls, tt.calls)
+			}
+			var injected []string
+			for _, a := range env.methods("InjectCaptchaToken") {
+				injected = append(injected, a.Value)
+			}
+			if got := strings.Join(injected, ","); got != tt.token {
+				t.Errorf("injected tokens = %q, want %q", got, tt.token)
+			}
+			if tt.solver == nil && len(env.methods("FindCaptchaSiteKey")) != 0 {
+				t.Error("page checked for captcha without a solver")
+			}
+			if tt.token != "" {
+				var order []string
+				for _, a := range env.ctrl.Actions() {
+					if a.Method == "InjectCaptchaToken" || a.Method == "Click" {
+						order = append(order, a.Method)
+					}
+				}
+				if strings.Join(order, ",") != "InjectCaptchaToken,Click" {
+					t.Errorf("actions = %v, want the token injected before the first step", order)
+				}
+			}
+		})
+	}
+}
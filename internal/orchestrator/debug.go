@@ -0,0 +1,107 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// debugNotice 调试产物标识，提醒该文件仅用于排查问题
+const debugNotice = "DEBUG ARTIFACT: page snapshots and raw plan for troubleshooting, not part of the guide"
+
+// debugReport 调试产物内容
+type debugReport struct {
+	Notice string            `json:"notice"`
+	TaskID string            `json:"task_id"`
+	Plan   *planner.TaskPlan `json:"plan"`
+	Steps  []debugStepRecord `json:"steps"`
+}
+
+// debugStepRecord 单个步骤执行前后的页面快照
+type debugStepRecord struct {
+	Order       int            `json:"order"`
+	Action      string         `json:"action"`
+	Target      string         `json:"target"`
+	Selector    string         `json:"selector,omitempty"` // 实际使用的选择器，重新规划或回退匹配后与 target 不同
+	Description string         `json:"description"`
+	Before      *debugSnapshot `json:"before,omitempty"`
+	After       *debugSnapshot `json:"after,omitempty"`
+}
+
+// debugSnapshot 快照摘要
+type debugSnapshot struct {
+	URL       string   `json:"url"`
+	Title     string   `json:"title"`
+	DOMSize   int      `json:"dom_size"`
+	Truncated bool     `json:"truncated"`
+	Elements  []string `json:"elements"`
+}
+
+// debugRecorder 记录调试信息；为 nil 时所有方法均为空操作
+type debugRecorder struct {
+	report debugReport
+}
+
+// newDebugRecorder 输出配置开启 Debug 时创建记录器，否则返回 nil
+func newDebugRecorder(task *domain.Task) *debugRecorder {
+	if task.Output == nil || !task.Output.Debug {
+		return nil
+	}
+	return &debugRecorder{report: debugReport{Notice: debugNotice, TaskID: task.ID}}
+}
+
+func (r *debugRecorder) setPlan(plan *planner.TaskPlan) {
+	if r == nil {
+		return
+	}
+	r.report.Plan = plan
+}
+
+func (r *debugRecorder) recordStep(step planner.ActionStep, result *planner.StepResult, before, after *browser.PageSnapshot) {
+	if r == nil {
+		return
+	}
+	selector := ""
+	if result != nil {
+		selector = result.Selector
+	}
+	r.report.Steps = append(r.report.Steps, debugStepRecord{
+		Order:       step.Order,
+		Action:      string(step.Action),
+		Target:      step.Target,
+		Selector:    selector,
+		Description: step.Description,
+		Before:      summarizeSnapshot(before),
+		After:       summarizeSnapshot(after),
+	})
+}
+
+// artifact 生成调试 JSON
+func (r *debugRecorder) artifact() ([]byte, error) {
+	data, err := json.MarshalIndent(r.report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal debug report: %w", err)
+	}
+	return data, nil
+}
+
+func summarizeSnapshot(snapshot *browser.PageSnapshot) *debugSnapshot {
+	if snapshot == nil {
+		return nil
+	}
+	elements := make([]string, 0, len(snapshot.Elements))
+	for _, el := range snapshot.Elements {
+		elements = append(elements, fmt.Sprintf("<%s> %s %q", el.TagName, el.Selector, el.Text))
+	}
+	return &debugSnapshot{
+		URL:       snapshot.URL,
+		Title:     snapshot.Title,
+		DOMSize:   snapshot.DOMSize,
+		Truncated: snapshot.Truncated,
+		Elements:  elements,
+	}
+}
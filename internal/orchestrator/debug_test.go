@@ -0,0 +1,56 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+func TestDebugArtifactOnlyWhenEnabled(t *testing.T) {
+	snapshot := &browser.PageSnapshot{
+		URL:      "https://app.example.com/form",
+		Title:    "Form",
+		Elements: []browser.Element{{TagName: "button", Selector: "#start", Text: "Start"}},
+	}
+	for _, enabled := range []bool{false, true} {
+		env := newTestEnv(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#start", Description: "Start"}), snapshot)
+		task := env.newTask(t, func(task *domain.Task) { task.Output.Debug = enabled })
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+
+		var debug *domain.DocumentInfo
+		for _, doc := range env.stored(t, task.ID).Result.Documents {
+			if doc.Format == domain.DocFormatDebug {
+				debug = &doc
+			} else if strings.Contains(doc.Content, debugNotice) {
+				t.Errorf("debug notice leaked into the %s document", doc.Format)
+			}
+		}
+		if !enabled {
+			if debug != nil {
+				t.Error("debug artifact generated although debug is off")
+			}
+			continue
+		}
+		if debug == nil {
+			t.Fatal("debug artifact missing")
+		}
+		var report debugReport
+		if err := json.Unmarshal([]byte(debug.Content), &report); err != nil {
+			t.Fatalf("decode debug artifact: %v", err)
+		}
+		if report.Notice != debugNotice || report.TaskID != task.ID || report.Plan == nil || len(report.Plan.Steps) != 1 {
+			t.Errorf("report = %+v", report)
+		}
+		if len(report.Steps) != 1 || report.Steps[0].Before == nil || report.Steps[0].Before.URL != snapshot.URL ||
+			len(report.Steps[0].Before.Elements) != 1 {
+			t.Errorf("step records = %+v", report.Steps)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"errors"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// TaskError 带错误码的任务执行错误
+type TaskError struct {
+	Code domain.ErrorCode
+	Op   string // 失败的操作
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	if e.Err == nil {
+		return string(e.Code)
+	}
+	if e.Op == "" {
+		return e.Err.Error()
+	}
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Is 与同错误码的哨兵错误匹配，如 errors.Is(err, ErrNavigation)
+func (e *TaskError) Is(target error) bool {
+	t, ok := target.(*TaskError)
+	return ok && t.Err == nil && t.Code == e.Code
+}
+
+// 各类失败的哨兵错误，用于 errors.Is 判断
+var (
+	ErrInvalidConfig  = &TaskError{Code: domain.ErrorCodeInvalidConfig}
+	ErrBrowser        = &TaskError{Code: domain.ErrorCodeBrowser}
+	ErrNavigation     = &TaskError{Code: domain.ErrorCodeNavigation}
+	ErrAuthFailed     = &TaskError{Code: domain.ErrorCodeAuth}
+	ErrPlanning       = &TaskError{Code: domain.ErrorCodePlanning}
+	ErrStepExecution  = &TaskError{Code: domain.ErrorCodeStepExecution}
+	ErrDocumentOutput = &TaskError{Code: domain.ErrorCodeDocument}
+	ErrVerification   = &TaskError{Code: domain.ErrorCodeVerification}
+)
+
+// newTaskError 包装错误并附加错误码
+func newTaskError(code domain.ErrorCode, op string, err error) error {
+	return &TaskError{Code: code, Op: op, Err: err}
+}
+
+// errorCode 提取错误码，非 TaskError 时返回 internal
+func errorCode(err error) domain.ErrorCode {
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) {
+		return taskErr.Code
+	}
+	return domain.ErrorCodeInternal
+}
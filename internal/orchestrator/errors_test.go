@@ -0,0 +1,61 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+func TestErrorCodePerFailurePath(t *testing.T) {
+	click := planner.ActionStep{Action: browser.ActionClick, Target: "#submit", Description: "Submit"}
+	respond := planReply(click)
+
+	tests := []struct {
+		name     string
+		respond  func(string) string
+		setup    func(env *testEnv, task *domain.Task)
+		want     domain.ErrorCode
+		sentinel error
+	}{
+		{"browser connect", respond, func(env *testEnv, _ *domain.Task) {
+			env.ctrl.Errors["Connect"] = errors.New("browser unavailable")
+		}, domain.ErrorCodeBrowser, ErrBrowser},
+		{"navigation", respond, func(env *testEnv, _ *domain.Task) {
+			env.ctrl.Errors["Navigate"] = errors.New("net::ERR_NAME_NOT_RESOLVED")
+		}, domain.ErrorCodeNavigation, ErrNavigation},
+		{"form login", respond, func(env *testEnv, task *domain.Task) {
+			env.ctrl.Errors["Fill"] = errors.New("element not found")
+			task.Auth = &domain.AuthConfig{
+				Type:        domain.AuthTypeForm,
+				Credentials: &domain.Credentials{Username: "alice", Password: "secret"},
+			}
+		}, domain.ErrorCodeAuth, ErrAuthFailed},
+		{"planning", func(string) string { return "no plan" }, nil, domain.ErrorCodePlanning, ErrPlanning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.respond)
+			task := env.newTask(t, func(task *domain.Task) {
+				if tt.setup != nil {
+					tt.setup(env, task)
+				}
+			})
+
+			err := env.orch.ExecuteTask(context.Background(), task)
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("err = %v, want errors.Is %s", err, tt.want)
+			}
+			got := env.stored(t, task.ID)
+			if got.Status != domain.TaskStatusFailed || got.ErrorCode != tt.want {
+				t.Errorf("status = %s/%s, want failed/%s", got.Status, got.ErrorCode, tt.want)
+			}
+			if got.ErrorMessage == "" {
+				t.Error("error message not stored")
+			}
+		})
+	}
+}
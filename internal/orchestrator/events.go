@@ -0,0 +1,102 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+	"github.com/browser-automation/internal/storage"
+)
+
+// recordEvent 文档存储支持事件日志时追加任务事件，失败只记录日志
+func (o *Orchestrator) recordEvent(ctx context.Context, task *domain.Task, event domain.TaskEvent) {
+	store, ok := o.docStore.(storage.EventStore)
+	if !ok {
+		return
+	}
+	event.Time = time.Now()
+	// 任务取消或超时后的终态事件仍需写入
+	if err := store.AppendEvent(context.WithoutCancel(ctx), task.ID, &event); err != nil {
+		log.Printf("[Task %s] Append event failed: %v", task.ID, err)
+	}
+}
+
+// recordStatus 记录任务的当前状态，失败时附带错误信息
+func (o *Orchestrator) recordStatus(ctx context.Context, task *domain.Task) {
+	o.recordEvent(ctx, task, domain.TaskEvent{Type: domain.TaskEventStatus, Status: task.Status, Error: task.ErrorMessage})
+}
+
+// recordPhase 记录进入执行阶段
+func (o *Orchestrator) recordPhase(ctx context.Context, task *domain.Task, phase string) {
+	o.recordEvent(ctx, task, domain.TaskEvent{Type: domain.TaskEventPhase, Phase: phase})
+}
+
+// recordStepEvent 记录步骤的执行结果与耗时
+func (o *Orchestrator) recordStepEvent(ctx context.Context, task *domain.Task, step planner.ActionStep, result *planner.StepResult, duration time.Duration) {
+	event := domain.TaskEvent{
+		Type:       domain.TaskEventStep,
+		DurationMS: duration.Milliseconds(),
+		Step: &domain.StepEvent{
+			Order:       step.Order,
+			Action:      string(step.Action),
+			Description: step.Description,
+			Target:      step.Target,
+		},
+	}
+	if result != nil {
+		event.Error = result.Error
+		event.Step.Selector = result.Selector
+		event.Step.Success = result.Success
+	}
+	o.recordEvent(ctx, task, event)
+}
+
+// eventLLMClient 记录每次 LLM 调用的模型、token 用量与耗时
+type eventLLMClient struct {
+	planner.LLMClient
+	model  string
+	record func(ctx context.Context, event domain.TaskEvent)
+}
+
+// withEventLog 文档存储支持事件日志时包装 LLM 客户端，否则原样返回
+func (o *Orchestrator) withEventLog(task *domain.Task, client planner.LLMClient) planner.LLMClient {
+	if _, ok := o.docStore.(storage.EventStore); !ok {
+		return client
+	}
+	return &eventLLMClient{
+		LLMClient: client,
+		model:     task.LLM.Name(),
+		record: func(ctx context.Context, event domain.TaskEvent) {
+			o.recordEvent(ctx, task, event)
+		},
+	}
+}
+
+// Chat 调用 LLM 并记录事件
+func (c *eventLLMClient) Chat(ctx context.Context, messages []planner.Message) (*planner.Response, error) {
+	start := time.Now()
+	resp, err := c.LLMClient.Chat(ctx, messages)
+	event := domain.TaskEvent{
+		Type:       domain.TaskEventLLM,
+		DurationMS: time.Since(start).Milliseconds(),
+		LLM:        &domain.LLMEvent{Model: c.model},
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if resp != nil {
+		if resp.Model != "" {
+			event.LLM.Model = resp.Model
+		}
+		if u := resp.Usage; u != nil {
+			event.LLM.PromptTokens = u.PromptTokens
+			event.LLM.CompletionTokens = u.CompletionTokens
+			event.LLM.TotalTokens = u.TotalTokens
+		}
+	}
+	c.record(ctx, event)
+	return resp, err
+}
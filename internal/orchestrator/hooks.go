@@ -0,0 +1,66 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// ErrStepAborted 步骤被钩子中止，不会再尝试优化重试
+var ErrStepAborted = errors.New("step aborted by hook")
+
+// StepHook 步骤钩子，在每个步骤执行前后调用。
+// 多个钩子时 BeforeStep 按注册顺序调用，AfterStep 按注册的逆序调用；
+// BeforeStep 返回错误时中止该步骤（后续钩子与步骤本身不再执行），
+// AfterStep 返回错误时该步骤记为失败。被钩子中止的步骤不会触发优化重试
+type StepHook interface {
+	BeforeStep(ctx context.Context, step planner.ActionStep) error
+	AfterStep(ctx context.Context, step planner.ActionStep, result *planner.StepResult) error
+}
+
+// NopStepHook 空实现，可嵌入以只实现需要的方法
+type NopStepHook struct{}
+
+// BeforeStep 不做任何处理
+func (NopStepHook) BeforeStep(ctx context.Context, step planner.ActionStep) error { return nil }
+
+// AfterStep 不做任何处理
+func (NopStepHook) AfterStep(ctx context.Context, step planner.ActionStep, result *planner.StepResult) error {
+	return nil
+}
+
+// AddStepHook 注册步骤钩子，需在任务开始执行前调用
+func (o *Orchestrator) AddStepHook(hook StepHook) {
+	o.hooks = append(o.hooks, hook)
+}
+
+// runStep 在钩子包围下执行单个步骤
+func (o *Orchestrator) runStep(ctx context.Context, task *domain.Task, step planner.ActionStep) (*planner.StepResult, *domain.Screenshot, error) {
+	for _, hook := range o.hooks {
+		if err := hook.BeforeStep(ctx, step); err != nil {
+			err = newTaskError(domain.ErrorCodeStepExecution, fmt.Sprintf("before step %d", step.Order), fmt.Errorf("%w: %v", ErrStepAborted, err))
+			result := &planner.StepResult{Success: false, Error: err.Error()}
+			o.recordStepEvent(ctx, task, step, result, 0)
+			return result, nil, err
+		}
+	}
+
+	start := time.Now()
+	result, screenshot, err := o.executeStep(ctx, task, step)
+	duration := time.Since(start)
+
+	for i := len(o.hooks) - 1; i >= 0; i-- {
+		if hookErr := o.hooks[i].AfterStep(ctx, step, result); hookErr != nil && err == nil {
+			err = newTaskError(domain.ErrorCodeStepExecution, fmt.Sprintf("after step %d", step.Order), fmt.Errorf("%w: %v", ErrStepAborted, hookErr))
+			result.Success = false
+			result.Error = err.Error()
+		}
+	}
+	o.recordStepEvent(ctx, task, step, result, duration)
+	return result, screenshot, err
+}
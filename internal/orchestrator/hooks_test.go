@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/planner"
+)
+
+// recordingHook 记录调用顺序，BeforeStep 遇到 abort 选择器时返回错误
+type recordingHook struct {
+	name  string
+	abort string
+
+	mu  *sync.Mutex
+	log *[]string
+}
+
+func (h *recordingHook) BeforeStep(ctx context.Context, step planner.ActionStep) error {
+	h.record("before", step, nil)
+	if h.abort != "" && step.Target == h.abort {
+		return errors.New("blocked by policy")
+	}
+	return nil
+}
+
+func (h *recordingHook) AfterStep(ctx context.Context, step planner.ActionStep, result *planner.StepResult) error {
+	h.record("after", step, result)
+	return nil
+}
+
+func (h *recordingHook) record(phase string, step planner.ActionStep, result *planner.StepResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry := fmt.Sprintf("%s %s %s", h.name, phase, step.Target)
+	if result != nil {
+		entry += fmt.Sprintf(" %v", result.Success)
+	}
+	*h.log = append(*h.log, entry)
+}
+
+func TestStepHooksOrder(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "#a", Description: "A"},
+		planner.ActionStep{Action: browser.ActionClick, Target: "#b", Description: "B"},
+	))
+	var mu sync.Mutex
+	var log []string
+	env.orch.AddStepHook(&recordingHook{name: "first", mu: &mu, log: &log})
+	env.orch.AddStepHook(&recordingHook{name: "second", mu: &mu, log: &log})
+	env.orch.AddStepHook(NopStepHook{})
+
+	task := env.newTask(t, nil)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	want := []string{
+		"first before #a", "second before #a", "second after #a true", "first after #a true",
+		"first before #b", "second before #b", "second after #b true", "first after #b true",
+	}
+	if strings.Join(log, "\n") != strings.Join(want, "\n") {
+		t.Errorf("hook calls:\n%s\nwant:\n%s", strings.Join(log, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestStepHookAbortsStep(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "#delete", Description: "Delete"},
+		planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"},
+	))
+	var mu sync.Mutex
+	var log []string
+	env.orch.AddStepHook(&recordingHook{name: "guard", abort: "#delete", mu: &mu, log: &log})
+	env.orch.AddStepHook(&recordingHook{name: "inner", mu: &mu, log: &log})
+
+	task := env.newTask(t, nil)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	for _, click := range env.methods("Click") {
+		if click.Selector == "#delete" {
+			t.Error("aborted step was executed")
+		}
+	}
+	for _, entry := range log {
+		if strings.HasPrefix(entry, "inner") && strings.HasSuffix(entry, "#delete") {
+			t.Errorf("hook after the aborting one was called: %q", entry)
+		}
+	}
+	steps := env.stored(t, task.ID).Result.Steps
+	if len(steps) != 2 || steps[0].Success || !strings.Contains(steps[0].Error, ErrStepAborted.Error()) || !steps[1].Success {
+		t.Errorf("steps = %+v, want aborted first step and successful second", steps)
+	}
+	if n := env.llm.calls("优化后的步骤"); n != 0 {
+		t.Errorf("aborted step refined %d times", n)
+	}
+}
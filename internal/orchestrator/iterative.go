@@ -0,0 +1,89 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// iterativeRun 迭代规划的执行结果：累积的计划与各步骤结果，done 表示模型判断任务已完成
+type iterativeRun struct {
+	plan        *planner.TaskPlan
+	results     []planner.StepResult
+	screenshots []domain.Screenshot
+	done        bool
+}
+
+// runIterative 迭代规划：每轮根据当前页面规划下一批步骤并执行，执行后重新采集快照再规划，
+// 直到模型判断任务完成或达到轮数上限。出错时返回已执行的部分
+func (o *Orchestrator) runIterative(ctx context.Context, task *domain.Task, aiPlanner *planner.AIPlanner, snapshot *browser.PageSnapshot, rec *debugRecorder, budget *snapshotBudget) (*iterativeRun, error) {
+	run := &iterativeRun{plan: &planner.TaskPlan{Description: task.Description}}
+	rec.setPlan(run.plan)
+	snaps := newPageSnapshots(o.browserCtrl, snapshot)
+	limit := task.Planning.IterationLimit()
+
+	for round := 1; round <= limit && !run.done; round++ {
+		if round > 1 {
+			// 上一轮通常以进入新页面结束，规划前关闭遮挡并采集新页面
+			o.recordPhase(ctx, task, domain.PhasePlanning)
+			o.dismissOverlays(ctx, task)
+			snaps.refresh(ctx)
+		}
+		log.Printf("[Task %s] Planning round %d/%d on %s", task.ID, round, limit, snaps.url())
+		budget.record()
+		next, err := aiPlanner.PlanNextSteps(ctx, &planner.IterationRequest{
+			UserInput:     task.Description,
+			TargetURL:     task.TargetURL,
+			PageSnapshot:  snaps.current,
+			History:       run.results,
+			Iteration:     round,
+			MaxIterations: limit,
+			Language:      task.OutputLanguage(),
+		})
+		if err != nil {
+			log.Printf("[Task %s] Planning round %d failed: %v", task.ID, round, err)
+			setPlanOutput(task, err)
+			return run, newTaskError(domain.ErrorCodePlanning, fmt.Sprintf("plan round %d", round), err)
+		}
+		task.PlanOutput = ""
+		if run.plan.Model == "" {
+			run.plan.Model = next.Model
+		}
+		run.done = next.Done
+		if len(next.Steps) == 0 {
+			log.Printf("[Task %s] Round %d: task complete: %s", task.ID, round, next.Reason)
+			break
+		}
+		log.Printf("[Task %s] Round %d: %d step(s): %s", task.ID, round, len(next.Steps), next.Reason)
+
+		// 本轮步骤接在已有步骤之后编号
+		offset := len(run.plan.Steps)
+		for i := range next.Steps {
+			next.Steps[i].Order = offset + i + 1
+		}
+		run.plan.Steps = append(run.plan.Steps, next.Steps...)
+		task.Plan = convertPlan(run.plan)
+
+		o.recordPhase(ctx, task, domain.PhaseExecuting)
+		batch := &planner.TaskPlan{Description: run.plan.Description, Steps: next.Steps}
+		results, screenshots, err := o.runSteps(ctx, task, aiPlanner, batch, snaps.current, run.results, run.screenshots, rec, budget)
+		run.results = append(run.results, results...)
+		run.screenshots = append(run.screenshots, screenshots...)
+		if err != nil {
+			return run, err
+		}
+		// 设置了成功条件时以其为准，满足即结束，不再询问模型
+		if !run.done && task.SuccessCriteria != nil && o.checkSuccess(ctx, task) == nil {
+			run.done = true
+		}
+	}
+	if !run.done {
+		log.Printf("[Task %s] Task not complete after %d planning round(s)", task.ID, limit)
+	}
+	return run, nil
+}
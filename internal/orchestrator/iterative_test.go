@@ -0,0 +1,163 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// wizardPage 返回向导某一页的快照：一个输入框和一个进入下一页的按钮
+func wizardPage(path, input, button string) *browser.PageSnapshot {
+	return &browser.PageSnapshot{
+		URL:   "https://app.example.com" + path,
+		Title: "Wizard " + path,
+		Elements: []browser.Element{
+			{TagName: "input", Selector: input},
+			{TagName: "button", Selector: button, Text: "Next"},
+		},
+	}
+}
+
+// iterationReply 编码一轮迭代规划的响应
+func iterationReply(done bool, steps ...planner.ActionStep) string {
+	data, _ := json.Marshal(planner.IterationPlan{Done: done, Reason: "test round", Steps: steps})
+	return string(data)
+}
+
+// iterativeTask 创建迭代规划任务，maxRounds 为 0 时使用默认轮数
+func iterativeTask(t *testing.T, env *testEnv, maxRounds int) *domain.Task {
+	t.Helper()
+	return env.newTask(t, func(task *domain.Task) {
+		task.TargetURL = "https://app.example.com/step1"
+		task.Planning = &domain.PlanningConfig{Mode: domain.PlanningIterative, MaxIterations: maxRounds}
+	})
+}
+
+// checkStepOrders 检查步骤结果数量及编号从 1 开始连续
+func checkStepOrders(t *testing.T, steps []domain.StepResult, want int) {
+	t.Helper()
+	if len(steps) != want {
+		t.Fatalf("executed %d steps, want %d: %+v", len(steps), want, steps)
+	}
+	for i, s := range steps {
+		if s.Order != i+1 || !s.Success {
+			t.Errorf("step %d = order %d success %v, want continuous orders across rounds", i, s.Order, s.Success)
+		}
+	}
+}
+
+func TestIterativeWizardStopsWhenDone(t *testing.T) {
+	page1 := wizardPage("/step1", "#name", "#next")
+	page2 := wizardPage("/step2", "#email", "#submit")
+	var rounds []string
+	env := newTestEnv(t, func(prompt string) string {
+		switch {
+		case strings.Contains(prompt, `{"tips"`):
+			return `{"tips": []}`
+		case strings.Contains(prompt, page2.URL):
+			rounds = append(rounds, "step2")
+			return iterationReply(true,
+				planner.ActionStep{Action: browser.ActionFill, Target: "#email", Value: "a@example.com", Description: "Enter email"},
+				planner.ActionStep{Action: browser.ActionClick, Target: "#submit", Description: "Submit"})
+		default:
+			rounds = append(rounds, "step1")
+			return iterationReply(false,
+				planner.ActionStep{Action: browser.ActionFill, Target: "#name", Value: "Alice", Description: "Enter name"},
+				planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next page"})
+		}
+	}, page1, page2)
+	task := iterativeTask(t, env, 0)
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	// 第一轮看到第一页，点击下一步后重新采集快照，第二轮看到第二页并判断完成
+	if got := strings.Join(rounds, ","); got != "step1,step2" {
+		t.Errorf("planning rounds = %s, want one round per page", got)
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusCompleted {
+		t.Fatalf("status = %s (%s), want completed", got.Status, got.ErrorMessage)
+	}
+	checkStepOrders(t, got.Result.Steps, 4)
+	if len(got.Plan.Steps) != 4 || got.Plan.Steps[3].Target != "#submit" || got.Plan.Steps[3].Order != 4 {
+		t.Errorf("plan = %+v, want both rounds merged in order", got.Plan.Steps)
+	}
+	var clicks []string
+	for _, a := range env.methods("Click") {
+		clicks = append(clicks, a.Selector)
+	}
+	if strings.Join(clicks, ",") != "#next,#submit" {
+		t.Errorf("clicks = %v", clicks)
+	}
+}
+
+func TestIterativeDoneWithoutSteps(t *testing.T) {
+	round := 0
+	env := newTestEnv(t, func(prompt string) string {
+		if strings.Contains(prompt, `{"tips"`) {
+			return `{"tips": []}`
+		}
+		round++
+		if round == 2 {
+			return iterationReply(true)
+		}
+		return iterationReply(false, planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next page"})
+	}, wizardPage("/step1", "#name", "#next"), wizardPage("/step2", "#email", "#submit"))
+	task := iterativeTask(t, env, 0)
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if round != 2 {
+		t.Errorf("planning rounds = %d, want to stop once the model reports done", round)
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusCompleted {
+		t.Fatalf("status = %s (%s), want completed", got.Status, got.ErrorMessage)
+	}
+	checkStepOrders(t, got.Result.Steps, 1)
+}
+
+func TestIterativeStopsAtRoundLimit(t *testing.T) {
+	round := 0
+	env := newTestEnv(t, func(prompt string) string {
+		if strings.Contains(prompt, `{"tips"`) {
+			return `{"tips": []}`
+		}
+		round++
+		return iterationReply(false, planner.ActionStep{
+			Action: browser.ActionClick, Target: fmt.Sprintf("#next-%d", round), Description: fmt.Sprintf("Next %d", round),
+		})
+	}, wizardPage("/step1", "#name", "#next"), wizardPage("/step2", "#email", "#submit"))
+	task := iterativeTask(t, env, 3)
+
+	err := env.orch.ExecuteTask(context.Background(), task)
+	if !errors.Is(err, ErrPlanning) {
+		t.Fatalf("err = %v, want ErrPlanning after the round limit", err)
+	}
+	if round != 3 {
+		t.Errorf("planning rounds = %d, want 3", round)
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodePlanning {
+		t.Errorf("status = %s/%s, want failed/planning", got.Status, got.ErrorCode)
+	}
+	// 未完成也保留已执行的步骤
+	checkStepOrders(t, got.Result.Steps, 3)
+	var clicks []string
+	for _, a := range env.methods("Click") {
+		clicks = append(clicks, a.Selector)
+	}
+	if strings.Join(clicks, ",") != "#next-1,#next-2,#next-3" {
+		t.Errorf("clicks = %v, want one per round", clicks)
+	}
+}
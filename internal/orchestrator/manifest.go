@@ -0,0 +1,94 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+	"github.com/browser-automation/internal/storage"
+	"github.com/google/uuid"
+)
+
+// saveScreenshot 记录截图尺寸；文件存储时同时保存截图文件，并将 URL 设为任务目录下的相对路径
+func (o *Orchestrator) saveScreenshot(ctx context.Context, task *domain.Task, shot *domain.Screenshot, data []byte) {
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		shot.Width, shot.Height = cfg.Width, cfg.Height
+	}
+	store, ok := o.docStore.(storage.ArtifactStore)
+	if !ok {
+		return
+	}
+	if err := store.SaveScreenshot(ctx, task.ID, shot, data); err != nil {
+		log.Printf("[Task %s] Save screenshot failed: %v", task.ID, err)
+		return
+	}
+	shot.URL = shot.FileName()
+}
+
+// downloadTimeout 点击后等待下载完成的时间
+const downloadTimeout = 60 * time.Second
+
+// saveDownload 等待步骤触发的下载并保存到文件存储，临时文件随后删除。
+// 未使用文件存储时只记录文件名和大小，不提供下载地址
+func (o *Orchestrator) saveDownload(ctx context.Context, task *domain.Task, step planner.ActionStep) (*domain.Download, error) {
+	path, err := o.browserCtrl.WaitForDownload(ctx, downloadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(filepath.Dir(path))
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open download: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("open download: %w", err)
+	}
+
+	d := &domain.Download{
+		ID:        uuid.New().String(),
+		StepOrder: step.Order,
+		Name:      browser.DownloadFileName(filepath.Base(path)),
+		Size:      info.Size(),
+		CreatedAt: time.Now(),
+	}
+	store, ok := o.docStore.(storage.ArtifactStore)
+	if !ok {
+		log.Printf("[Task %s] Downloaded %s (%d bytes), not persisted without file storage", task.ID, d.Name, d.Size)
+		return d, nil
+	}
+	if d.Size, err = store.SaveDownload(ctx, task.ID, d, f); err != nil {
+		return nil, err
+	}
+	d.URL = fmt.Sprintf("/api/v1/tasks/%s/downloads/%s", task.ID, d.ID)
+	log.Printf("[Task %s] Saved download %s (%d bytes)", task.ID, d.Name, d.Size)
+	return d, nil
+}
+
+// writeManifest 文件存储时写入任务清单，失败只记录日志
+func (o *Orchestrator) writeManifest(ctx context.Context, task *domain.Task) {
+	store, ok := o.docStore.(storage.ArtifactStore)
+	if !ok {
+		return
+	}
+	data, err := json.MarshalIndent(domain.NewManifest(task), "", "  ")
+	if err != nil {
+		log.Printf("[Task %s] Encode manifest failed: %v", task.ID, err)
+		return
+	}
+	if err := store.SaveManifest(ctx, task.ID, data); err != nil {
+		log.Printf("[Task %s] Save manifest failed: %v", task.ID, err)
+	}
+}
@@ -0,0 +1,110 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+	"github.com/browser-automation/internal/storage"
+)
+
+func TestManifestReferencesAllArtifacts(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "#new", Description: "New", Screenshot: true},
+		planner.ActionStep{Action: browser.ActionScreenshot, Description: "Result"},
+	))
+	dir := t.TempDir()
+	env.orch.SetDocumentStore(storage.NewFileDocumentStore(dir))
+	task := env.newTask(t, func(task *domain.Task) {
+		task.Tags = []string{"billing"}
+		task.Output.Formats = []domain.DocFormat{domain.DocFormatMarkdown, domain.DocFormatHTML}
+	})
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	result := env.stored(t, task.ID).Result
+
+	taskDir := filepath.Join(dir, task.ID)
+	data, err := os.ReadFile(filepath.Join(taskDir, domain.ManifestFileName))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	var m domain.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if m.TaskID != task.ID || m.Status != domain.TaskStatusCompleted || len(m.Tags) != 1 || m.CompletedAt == nil {
+		t.Errorf("manifest metadata = %+v", m)
+	}
+
+	if len(m.Documents) != len(result.Documents) || len(m.Documents) != 2 {
+		t.Fatalf("manifest lists %d documents, result has %d", len(m.Documents), len(result.Documents))
+	}
+	for i, doc := range m.Documents {
+		info, err := os.Stat(filepath.Join(taskDir, filepath.FromSlash(doc.Path)))
+		if err != nil || doc.ID != result.Documents[i].ID || info.Size() != doc.Size {
+			t.Errorf("document %+v: %v", doc, err)
+		}
+	}
+
+	if len(m.Screenshots) != len(result.Screenshots) || len(m.Screenshots) != 2 {
+		t.Fatalf("manifest lists %d screenshots, result has %d", len(m.Screenshots), len(result.Screenshots))
+	}
+	for i, shot := range m.Screenshots {
+		if _, err := os.Stat(filepath.Join(taskDir, filepath.FromSlash(shot.Path))); err != nil {
+			t.Errorf("screenshot %+v: %v", shot, err)
+		}
+		// FakeController 返回 1x1 的 PNG
+		if shot.StepOrder != i+1 || shot.Width != 1 || shot.Height != 1 {
+			t.Errorf("screenshot %d = %+v", i, shot)
+		}
+	}
+}
+
+func TestDownloadStepSavesFile(t *testing.T) {
+	for _, fileStore := range []bool{false, true} {
+		env := newTestEnv(t, planReply(
+			planner.ActionStep{Action: browser.ActionDownload, Target: "#export", Description: "Export the report"},
+		))
+		env.ctrl.Downloads = []browser.FakeDownload{{Name: "../report.csv", Data: []byte("id,total\n1,99\n")}}
+		dir := t.TempDir()
+		if fileStore {
+			env.orch.SetDocumentStore(storage.NewFileDocumentStore(dir))
+		}
+		task := env.newTask(t, nil)
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+
+		if clicks := env.methods("Click"); len(clicks) != 1 || clicks[0].Selector != "#export" {
+			t.Errorf("clicks = %+v", clicks)
+		}
+		result := env.stored(t, task.ID).Result
+		if len(result.Downloads) != 1 {
+			t.Fatalf("file store %v: downloads = %+v", fileStore, result.Downloads)
+		}
+		d := result.Downloads[0]
+		if d.Name != "report.csv" || d.Size != 14 || d.StepOrder != 1 || !result.Steps[0].Success {
+			t.Errorf("download = %+v", d)
+		}
+		if !fileStore {
+			// 未使用文件存储时不提供下载地址
+			if d.URL != "" {
+				t.Errorf("download url = %q without file storage", d.URL)
+			}
+			continue
+		}
+		if d.URL != "/api/v1/tasks/"+task.ID+"/downloads/"+d.ID {
+			t.Errorf("download url = %q", d.URL)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, task.ID, filepath.FromSlash(d.FileName())))
+		if err != nil || string(data) != "id,total\n1,99\n" {
+			t.Errorf("saved download = %q (%v)", data, err)
+		}
+	}
+}
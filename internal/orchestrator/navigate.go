@@ -0,0 +1,53 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// resolveNavigateTarget 将 navigate 步骤的目标解析为绝对 URL：相对路径以当前页面为基准，
+// 当前页面不是 http(s) 页面（如 about:blank）时以任务目标网站为基准。结果必须是 http(s) URL
+func resolveNavigateTarget(target, currentURL, taskURL string) (string, error) {
+	ref, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("invalid navigate target %q: %w", target, err)
+	}
+	if !ref.IsAbs() {
+		base, err := navigateBase(currentURL, taskURL)
+		if err != nil {
+			return "", fmt.Errorf("resolve navigate target %q: %w", target, err)
+		}
+		ref = base.ResolveReference(ref)
+	}
+	if ref.Scheme != "http" && ref.Scheme != "https" {
+		return "", fmt.Errorf("navigate target %q: unsupported scheme %q", target, ref.Scheme)
+	}
+	if ref.Host == "" {
+		return "", fmt.Errorf("navigate target %q: missing host", target)
+	}
+	return ref.String(), nil
+}
+
+// sameURL 判断两个地址是否指向同一页面，忽略片段与路径末尾的斜杠
+func sameURL(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil || ua.Host == "" {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host) &&
+		strings.TrimSuffix(ua.Path, "/") == strings.TrimSuffix(ub.Path, "/") && ua.RawQuery == ub.RawQuery
+}
+
+// navigateBase 返回解析相对路径的基准 URL
+func navigateBase(currentURL, taskURL string) (*url.URL, error) {
+	for _, candidate := range []string{currentURL, taskURL} {
+		u, err := url.Parse(candidate)
+		if err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("no http(s) page to resolve against")
+}
@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/planner"
+)
+
+func TestResolveNavigateTarget(t *testing.T) {
+	const taskURL = "https://app.example.com/form"
+	tests := []struct {
+		name, target, current string
+		want                  string
+		wantErr               string
+	}{
+		{"absolute target", "https://other.example.com/a", "https://app.example.com/x", "https://other.example.com/a", ""},
+		{"root-relative path", "/settings", "https://app.example.com/users/42?tab=1", "https://app.example.com/settings", ""},
+		{"document-relative path", "edit", "https://app.example.com/users/42", "https://app.example.com/users/edit", ""},
+		{"query only", "?page=2", "https://app.example.com/list", "https://app.example.com/list?page=2", ""},
+		{"blank page uses task URL", "/settings", "about:blank", "https://app.example.com/settings", ""},
+		{"unsupported scheme", "javascript:alert(1)", "https://app.example.com/", "", "unsupported scheme"},
+		{"file scheme", "file:///etc/passwd", "https://app.example.com/", "", "unsupported scheme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveNavigateTarget(tt.target, tt.current, taskURL)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveNavigateTarget(%q, %q) = %q, %v, want %q", tt.target, tt.current, got, err, tt.want)
+			}
+		})
+	}
+	if _, err := resolveNavigateTarget("/settings", "about:blank", ""); err == nil {
+		t.Error("relative target without an http(s) base resolved")
+	}
+}
+
+func TestRelativeNavigateStep(t *testing.T) {
+	env := newTestEnv(t, planReply(planner.ActionStep{Action: browser.ActionNavigate, Target: "/settings", Description: "Open settings"}))
+	task := env.newTask(t, nil)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	navigations := env.methods("Navigate")
+	if last := navigations[len(navigations)-1]; last.Selector != "https://app.example.com/settings" {
+		t.Errorf("navigated to %q, want the target resolved against the current page", last.Selector)
+	}
+	if steps := env.stored(t, task.ID).Result.Steps; len(steps) != 1 || !steps[0].Success {
+		t.Errorf("steps = %+v", steps)
+	}
+}
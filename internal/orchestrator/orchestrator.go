@@ -3,8 +3,11 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/browser-automation/internal/auth"
@@ -16,6 +19,23 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrSessionNotAlive 任务的浏览器会话已关闭或未保留
+var ErrSessionNotAlive = errors.New("browser session is not alive")
+
+// ErrSessionBusy 保留的浏览器会话正在执行其他追加指令
+var ErrSessionBusy = errors.New("browser session is busy")
+
+// MaxKeepAlive 任务完成后保留浏览器会话的最长空闲时间
+const MaxKeepAlive = 30 * time.Minute
+
+// 页面加载、动作完成与导航重试的固定等待时间，测试中缩短为 0
+var (
+	pageLoadWait = 2 * time.Second        // 导航到目标页面后、采集首个快照前
+	actionSettle = 500 * time.Millisecond // 每个动作完成后、截图前
+	bareWaitStep = 2 * time.Second        // 未指定等待条件的 wait 步骤
+	navRetryWait = time.Second            // 导航失败后首次重试前，之后逐次翻倍
+)
+
 // Orchestrator 任务编排器
 type Orchestrator struct {
 	browserCtrl browser.Controller
@@ -24,6 +44,36 @@ type Orchestrator struct {
 	docGen      docgen.Generator
 	taskStore   storage.TaskStore
 	llmFactory  *planner.LLMClientFactory
+
+	planCache   planner.PlanCache
+	docStore    storage.DocumentStore
+	credentials auth.CredentialProvider
+	queue       *taskQueue
+	hooks       []StepHook
+	captcha     CaptchaSolver
+	proxies     *browser.ProxyPool
+	maxDocSize  int // 生成文档的大小上限（字节），0 使用 docgen.DefaultMaxSize
+	tipCache    *tipCache
+
+	mu   sync.Mutex
+	live *liveSession
+
+	runMu     sync.Mutex
+	running   map[string]context.CancelFunc // 执行中任务的取消函数
+	approvals map[string]chan struct{}      // 等待人工批准的任务，关闭即批准
+}
+
+// liveSession 任务完成后保留的浏览器会话，用于追加指令
+type liveSession struct {
+	taskID      string
+	planner     *planner.AIPlanner
+	plan        *planner.TaskPlan
+	results     []planner.StepResult
+	screenshots []domain.Screenshot
+	snapshots   *snapshotBudget
+	idle        time.Duration
+	timer       *time.Timer
+	busy        chan struct{} // 执行追加指令期间非 nil，结束时关闭
 }
 
 // NewOrchestrator 创建任务编排器
@@ -37,260 +87,1297 @@ func NewOrchestrator(
 		authService: auth.NewService(browserCtrl),
 		taskStore:   taskStore,
 		llmFactory:  llmFactory,
+		queue:       newTaskQueue(),
+		tipCache:    newTipCache(),
+		captcha:     NopCaptchaSolver{},
+		running:     make(map[string]context.CancelFunc),
+		approvals:   make(map[string]chan struct{}),
 	}
 }
 
-// ExecuteTask 执行任务
-func (o *Orchestrator) ExecuteTask(ctx context.Context, task *domain.Task) error {
+// SetPlanCache 设置执行计划缓存，为 nil 时不使用缓存
+func (o *Orchestrator) SetPlanCache(cache planner.PlanCache) {
+	o.planCache = cache
+}
+
+// SetMaxDocumentSize 设置生成文档的大小上限（字节），超出时省略其后的步骤并附加截断说明，0 使用默认值
+func (o *Orchestrator) SetMaxDocumentSize(n int) {
+	o.maxDocSize = n
+}
+
+// SetDocumentStore 设置文档存储，为 nil 时文档内容内联保存在任务中
+func (o *Orchestrator) SetDocumentStore(store storage.DocumentStore) {
+	o.docStore = store
+}
+
+// SetProxyPool 设置代理池，每次连接浏览器（含整体重试）时按轮换方式取出一个代理，为 nil 时直连
+func (o *Orchestrator) SetProxyPool(pool *browser.ProxyPool) {
+	o.proxies = pool
+}
+
+// SetCredentialProvider 设置服务端凭据来源，请求未提供凭据时按目标域名查找，为 nil 时不使用
+func (o *Orchestrator) SetCredentialProvider(provider auth.CredentialProvider) {
+	o.credentials = provider
+}
+
+// HasCredentials 服务端是否保存了目标 URL 的凭据
+func (o *Orchestrator) HasCredentials(targetURL string) bool {
+	if o.credentials == nil {
+		return false
+	}
+	_, ok := o.credentials.Lookup(targetURL)
+	return ok
+}
+
+// ExecuteTask 执行任务，失败时按 MaxTaskRetries 使用新的浏览器会话整体重试。
+// 全部尝试共用 DeadlineDuration 的时长上限，超时后任务以 timeout 失败
+func (o *Orchestrator) ExecuteTask(ctx context.Context, task *domain.Task) (err error) {
+	defer o.finalizeTask(ctx, task, &err)
+	defer o.recoverTask(ctx, task, &err)
 	log.Printf("[Task %s] Starting execution", task.ID)
 
-	// 更新任务状态为运行中
+	deadline := task.DeadlineDuration()
+	ctx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+
+	// 更新任务状态为运行中，开始前已被取消的任务保持存储中的终态
 	task.Status = domain.TaskStatusRunning
 	task.UpdatedAt = time.Now()
 	if err := o.taskStore.Update(ctx, task); err != nil {
+		if errors.Is(err, storage.ErrTaskFinished) {
+			o.syncFinishedStatus(ctx, task)
+			log.Printf("[Task %s] Already %s, skipping execution", task.ID, task.Status)
+			return nil
+		}
 		return fmt.Errorf("update task status: %w", err)
 	}
+	o.recordStatus(ctx, task)
 
+	var attempts []domain.TaskAttempt
+	for n := 1; ; n++ {
+		startedAt := time.Now()
+		err = o.executeAttempt(ctx, task)
+		attempt := domain.TaskAttempt{
+			Attempt:   n,
+			StartedAt: startedAt,
+			Duration:  time.Since(startedAt),
+		}
+		if err == nil {
+			if len(attempts) == 0 {
+				return nil
+			}
+			// 经过重试才成功时补充记录各次尝试。结果可能已被存储或其他读取方引用，复制后再修改
+			result := *task.Result
+			result.Attempts = append(attempts, attempt)
+			task.Result = &result
+			if err := o.taskStore.Update(ctx, task); err != nil {
+				return fmt.Errorf("update task result: %w", err)
+			}
+			return nil
+		}
+
+		attempt.Error = err.Error()
+		attempt.ErrorCode = errorCode(err)
+		attempts = append(attempts, attempt)
+		if n > task.MaxTaskRetries || !retryableTaskError(ctx, err) {
+			break
+		}
+		log.Printf("[Task %s] Attempt %d failed, retrying (%d/%d): %v", task.ID, n, n, task.MaxTaskRetries, err)
+		o.recordEvent(ctx, task, domain.TaskEvent{Type: domain.TaskEventPhase, Phase: domain.PhaseRetrying, Error: err.Error()})
+	}
+
+	var result domain.TaskResult
+	if task.Result != nil {
+		result = *task.Result
+	}
+	result.Attempts = attempts
+	task.Result = &result
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("[Task %s] Deadline %s exceeded", task.ID, deadline)
+		// 等待批准时到达时限按批准超时报告，其余情况按任务超时
+		if !errors.Is(err, ErrApprovalTimeout) {
+			err = newTaskError(domain.ErrorCodeTimeout, fmt.Sprintf("task deadline %s exceeded", deadline), err)
+		}
+		// ctx 已过期，写入失败状态不受其影响
+		ctx = context.WithoutCancel(ctx)
+	}
+	return o.failTask(ctx, task, err)
+}
+
+// retryableTaskError 判断任务失败后是否值得整体重试，配置错误、凭据错误、LLM 认证或请求无效、未获批准和已取消的任务不重试
+func retryableTaskError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, auth.ErrAuthFailed) || errors.Is(err, ErrApprovalTimeout) ||
+		!planner.RetryableLLMError(err) {
+		return false
+	}
+	return errorCode(err) != domain.ErrorCodeInvalidConfig
+}
+
+// executeAttempt 执行一次完整的任务尝试，每次尝试都会重新连接浏览器
+func (o *Orchestrator) executeAttempt(ctx context.Context, task *domain.Task) error {
 	startTime := time.Now()
 
 	// 创建 LLM 客户端
 	log.Printf("[Task %s] Creating LLM client: provider=%s, model=%s", task.ID, task.LLM.Provider, task.LLM.Model)
 	llmClient, err := o.llmFactory.NewClient(task.LLM)
 	if err != nil {
-		return o.failTask(ctx, task, fmt.Errorf("create llm client: %w", err))
+		return newTaskError(domain.ErrorCodeInvalidConfig, "create llm client", err)
 	}
 
 	// 创建 AI 规划器
-	aiPlanner := planner.NewAIPlanner(llmClient)
+	aiPlanner := planner.NewAIPlanner(o.withEventLog(task, llmClient))
+	aiPlanner.SetPlanningConfig(task.Planning)
+
+	// 控制器为共享实例，新任务开始前释放上一个保留的会话
+	o.releaseLiveSession(ctx)
 
 	// 连接浏览器
-	log.Printf("[Task %s] Connecting browser", task.ID)
-	if err := o.browserCtrl.Connect(ctx); err != nil {
-		return o.failTask(ctx, task, fmt.Errorf("connect browser: %w", err))
+	o.enterPhase(ctx, task, domain.PhaseConnecting, progressConnecting)
+	var proxy *browser.Proxy
+	if o.proxies != nil {
+		proxy = o.proxies.Next()
+		log.Printf("[Task %s] Connecting browser via proxy %s", task.ID, proxy)
+	} else {
+		log.Printf("[Task %s] Connecting browser", task.ID)
+	}
+	if err := o.browserCtrl.Connect(ctx, browser.ContextOptions{
+		Locale:        task.BrowserLocale(),
+		TimezoneID:    task.TimezoneID,
+		SnapshotScope: task.SnapshotScope,
+		Proxy:         proxy,
+	}); err != nil {
+		return newTaskError(domain.ErrorCodeBrowser, "connect browser", err)
+	}
+	keepAlive := false
+	defer func() {
+		if !keepAlive {
+			o.browserCtrl.Close(ctx)
+		}
+	}()
+
+	// 处理认证并导航到目标页面
+	o.enterPhase(ctx, task, domain.PhaseAuthenticating, progressAuthenticating)
+	if err := o.authenticate(ctx, task); err != nil {
+		return err
+	}
+
+	// 等待页面加载
+	log.Printf("[Task %s] Waiting for page load (%s)", task.ID, pageLoadWait)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(pageLoadWait):
+	}
+
+	// 获取页面快照
+	o.dismissOverlays(ctx, task)
+	o.solveCaptcha(ctx, task)
+	log.Printf("[Task %s] Taking page snapshot", task.ID)
+	snapshot, err := o.browserCtrl.TakeSnapshot(ctx)
+	if err != nil {
+		return newTaskError(domain.ErrorCodeBrowser, "take snapshot", err)
 	}
-	defer o.browserCtrl.Close(ctx)
+	log.Printf("[Task %s] Snapshot: URL=%s, Title=%s, Elements=%d", task.ID, snapshot.URL, snapshot.Title, len(snapshot.Elements))
 
-	// 处理认证
+	o.enterPhase(ctx, task, domain.PhasePlanning, progressPlanning)
+	budget := newSnapshotBudget(task)
+	rec := newDebugRecorder(task)
+	var (
+		plan        *planner.TaskPlan
+		cached      bool
+		cacheKey    planner.PlanCacheKey
+		stepResults []planner.StepResult
+		screenshots []domain.Screenshot
+		goalErr     error // 迭代规划达到轮数上限仍未完成
+	)
+	if task.Planning.Iterative() {
+		// 迭代规划逐页生成并执行步骤，不使用计划缓存
+		run, err := o.runIterative(ctx, task, aiPlanner, snapshot, rec, budget)
+		if err != nil {
+			return err
+		}
+		plan, stepResults, screenshots = run.plan, run.results, run.screenshots
+		if !run.done {
+			goalErr = newTaskError(domain.ErrorCodePlanning, "iterative planning",
+				fmt.Errorf("task not complete after %d planning rounds", task.Planning.IterationLimit()))
+		}
+	} else {
+		// 优先使用缓存的计划，未命中时由 AI 解析任务生成计划
+		cacheKey = planner.NewPlanCacheKey(task.TargetURL, task.Description, task.LLM.Model, task.OutputLanguage())
+		plan, cached = o.lookupPlan(ctx, task, cacheKey)
+		if cached {
+			log.Printf("[Task %s] Plan cache hit, reusing %d steps", task.ID, len(plan.Steps))
+		} else {
+			log.Printf("[Task %s] Calling LLM to parse task...", task.ID)
+			budget.record()
+			plan, err = aiPlanner.ParseTask(ctx, &planner.PlanRequest{
+				UserInput:    task.Description,
+				TargetURL:    task.TargetURL,
+				PageSnapshot: snapshot,
+				Language:     task.OutputLanguage(),
+			})
+			if err != nil {
+				log.Printf("[Task %s] LLM parse failed: %v", task.ID, err)
+				setPlanOutput(task, err)
+				return newTaskError(domain.ErrorCodePlanning, "parse task", err)
+			}
+			log.Printf("[Task %s] LLM returned %d steps", task.ID, len(plan.Steps))
+			task.PlanOutput = ""
+		}
+
+		task.Plan = convertPlan(plan)
+
+		// 执行步骤
+		rec.setPlan(plan)
+		o.recordPhase(ctx, task, domain.PhaseExecuting)
+		stepResults, screenshots, err = o.runSteps(ctx, task, aiPlanner, plan, snapshot, nil, nil, rec, budget)
+		if err != nil {
+			return err
+		}
+	}
+	successErr := goalErr
+	if successErr == nil {
+		successErr = o.checkSuccess(ctx, task)
+	}
+
+	// 全部步骤成功且满足成功条件的计划才写入缓存
+	if !cached && !task.Planning.Iterative() && o.planCache != nil && allStepsSucceeded(stepResults) && successErr == nil {
+		o.planCache.Put(ctx, cacheKey, plan)
+	}
+
+	// 生成文档
+	o.recordPhase(ctx, task, domain.PhaseGenerating)
+	o.addTips(ctx, task, aiPlanner, plan)
+	docs, docErr := o.generateDocuments(ctx, task, plan, stepResults, rec)
+
+	task.Result = &domain.TaskResult{
+		Steps:         convertStepResults(stepResults, screenshots),
+		Screenshots:   screenshots,
+		Documents:     docs,
+		Downloads:     collectDownloads(stepResults),
+		Data:          collectData(stepResults),
+		Duration:      time.Since(startTime),
+		Model:         plan.Model,
+		SnapshotsSent: budget.sent,
+	}
+	if !cached && task.Result.Model == "" {
+		task.Result.Model = task.LLM.Name()
+	}
+	// 未完成、未满足成功条件或部分格式生成失败时保留已执行的步骤与已生成的文档，任务按失败结束
+	if successErr != nil {
+		return successErr
+	}
+	if docErr != nil {
+		return newTaskError(domain.ErrorCodeDocument, "generate docs", docErr)
+	}
+
+	// 更新任务结果
+	task.Status = domain.TaskStatusCompleted
+	task.Progress = 100
+	task.UpdatedAt = time.Now()
+	completedAt := time.Now()
+	task.CompletedAt = &completedAt
+	if err := o.taskStore.Update(ctx, task); err != nil {
+		return fmt.Errorf("update task result: %w", err)
+	}
+	o.recordStatus(ctx, task)
+	o.writeManifest(ctx, task)
+
+	// 保留浏览器会话以便追加指令
+	if task.KeepAlive > 0 {
+		keepAlive = true
+		o.holdLiveSession(&liveSession{
+			taskID:      task.ID,
+			planner:     aiPlanner,
+			plan:        plan,
+			results:     stepResults,
+			screenshots: screenshots,
+			snapshots:   budget,
+			idle:        task.KeepAliveDuration(),
+		})
+	}
+
+	return nil
+}
+
+// authenticate 按任务配置完成认证并导航到目标页面，浏览器重连后也用于恢复会话
+func (o *Orchestrator) authenticate(ctx context.Context, task *domain.Task) error {
 	if task.Auth != nil && task.Auth.Type != domain.AuthTypeNone {
 		log.Printf("[Task %s] Processing authentication: type=%s", task.ID, task.Auth.Type)
-		// 先导航到目标页面
-		if err := o.browserCtrl.Navigate(ctx, task.TargetURL); err != nil {
-			return o.failTask(ctx, task, fmt.Errorf("navigate for auth: %w", err))
+		// 在页面上登录的方式先导航到目标页面，Cookie/Token 注入等在首次导航前完成
+		if auth.NeedsTargetPage(task.Auth) {
+			if err := o.navigateWithRetry(ctx, task, task.TargetURL); err != nil {
+				return newTaskError(domain.ErrorCodeNavigation, "navigate for auth", err)
+			}
 		}
 
-		// 执行认证
-		session, err := o.authService.Authenticate(ctx, task.Auth)
-		if err != nil {
-			return o.failTask(ctx, task, fmt.Errorf("authenticate: %w", err))
+		// 默认只注入和保留目标站点域名下的 Cookie
+		authConfig := task.Auth
+		if !authConfig.AllCookies {
+			scoped := *authConfig
+			scoped.Cookies = domain.FilterCookiesForURL(authConfig.Cookies, task.TargetURL)
+			authConfig = &scoped
+		}
+		// 请求未提供凭据时使用服务端保存的凭据，只用于本次认证，不写回任务，避免出现在响应中
+		if authConfig.Credentials.IsEmpty() && o.credentials != nil {
+			if creds, ok := o.credentials.Lookup(task.TargetURL); ok {
+				log.Printf("[Task %s] Using server-side credentials for %s", task.ID, task.TargetURL)
+				scoped := *authConfig
+				scoped.Credentials = creds
+				authConfig = &scoped
+			}
 		}
 
-		// 注入会话
-		if len(session.Cookies) > 0 {
-			if err := o.browserCtrl.SetCookies(ctx, session.Cookies); err != nil {
-				return o.failTask(ctx, task, fmt.Errorf("set cookies: %w", err))
+		// 执行认证：Cookie 注入由认证服务写入浏览器，其他方式的会话 Cookie 由站点在登录时写入，无需再次注入
+		session, err := o.authService.Authenticate(ctx, authConfig)
+		if err != nil {
+			return newTaskError(domain.ErrorCodeAuth, "authenticate", err)
+		}
+		// 登录过程中写入的第三方 Cookie（统计、广告等）不保留在浏览器上下文中
+		if !authConfig.AllCookies {
+			if err := o.scopeSessionCookies(ctx, task, session); err != nil {
+				return newTaskError(domain.ErrorCodeAuth, "scope cookies", err)
+			}
+		}
+		// Token/Basic 认证的请求头只附加到目标站点同源的请求，不发给第三方资源
+		if len(session.Headers) > 0 {
+			if err := o.browserCtrl.SetOriginHeaders(ctx, task.TargetURL, session.Headers); err != nil {
+				return newTaskError(domain.ErrorCodeAuth, "set auth headers", err)
 			}
 		}
 
-		// 刷新页面应用认证
-		if err := o.browserCtrl.Navigate(ctx, task.TargetURL); err != nil {
-			return o.failTask(ctx, task, fmt.Errorf("navigate after auth: %w", err))
+		// 登录后已回到目标页面时直接复用，避免重复加载丢失页面状态
+		if currentURL, _ := o.browserCtrl.GetCurrentURL(ctx); sameURL(currentURL, task.TargetURL) {
+			log.Printf("[Task %s] Already on target page after auth, skipping navigation", task.ID)
+			return nil
+		}
+		if err := o.navigateWithRetry(ctx, task, task.TargetURL); err != nil {
+			return newTaskError(domain.ErrorCodeNavigation, "navigate after auth", err)
 		}
 	} else {
 		// 直接导航到目标页面
 		log.Printf("[Task %s] Navigating to: %s", task.ID, task.TargetURL)
-		if err := o.browserCtrl.Navigate(ctx, task.TargetURL); err != nil {
-			return o.failTask(ctx, task, fmt.Errorf("navigate: %w", err))
+		if err := o.navigateWithRetry(ctx, task, task.TargetURL); err != nil {
+			return newTaskError(domain.ErrorCodeNavigation, "navigate", err)
 		}
 	}
+	return nil
+}
 
-	// 等待页面加载
-	log.Printf("[Task %s] Waiting for page load (2s)", task.ID)
-	time.Sleep(2 * time.Second)
-
-	// 获取页面快照
-	log.Printf("[Task %s] Taking page snapshot", task.ID)
-	snapshot, err := o.browserCtrl.TakeSnapshot(ctx)
-	if err != nil {
-		return o.failTask(ctx, task, fmt.Errorf("take snapshot: %w", err))
+// scopeSessionCookies 将登录后捕获的 Cookie 过滤到目标站点域名，
+// 有其他站点的 Cookie 时清空浏览器上下文的 Cookie 并只写回过滤后的部分
+func (o *Orchestrator) scopeSessionCookies(ctx context.Context, task *domain.Task, session *domain.Session) error {
+	scoped := domain.FilterCookiesForURL(session.Cookies, task.TargetURL)
+	if len(scoped) == len(session.Cookies) {
+		return nil
 	}
-	log.Printf("[Task %s] Snapshot: URL=%s, Title=%s, Elements=%d", task.ID, snapshot.URL, snapshot.Title, len(snapshot.Elements))
+	log.Printf("[Task %s] Dropping %d cookies of other sites captured during login", task.ID, len(session.Cookies)-len(scoped))
+	session.Cookies = scoped
+	if err := o.browserCtrl.ClearCookies(ctx); err != nil {
+		return err
+	}
+	if len(scoped) == 0 {
+		return nil
+	}
+	return o.browserCtrl.SetCookies(ctx, scoped)
+}
 
-	// AI 解析任务生成计划
-	log.Printf("[Task %s] Calling LLM to parse task...", task.ID)
-	plan, err := aiPlanner.ParseTask(ctx, &planner.PlanRequest{
-		UserInput:    task.Description,
-		TargetURL:    task.TargetURL,
-		PageSnapshot: snapshot,
-	})
+// ensureBrowser 检查浏览器连接，断开重连后重新认证并回到原页面
+func (o *Orchestrator) ensureBrowser(ctx context.Context, task *domain.Task, resumeURL string) error {
+	reconnected, err := o.browserCtrl.EnsureConnected(ctx)
 	if err != nil {
-		log.Printf("[Task %s] LLM parse failed: %v", task.ID, err)
-		return o.failTask(ctx, task, fmt.Errorf("parse task: %w", err))
+		return newTaskError(domain.ErrorCodeBrowser, "reconnect browser", err)
+	}
+	if !reconnected {
+		return nil
+	}
+	log.Printf("[Task %s] Browser reconnected, restoring session", task.ID)
+	if err := o.authenticate(ctx, task); err != nil {
+		return err
+	}
+	if resumeURL != "" && resumeURL != task.TargetURL {
+		if err := o.navigateWithRetry(ctx, task, resumeURL); err != nil {
+			return newTaskError(domain.ErrorCodeNavigation, "navigate after reconnect", err)
+		}
 	}
-	log.Printf("[Task %s] LLM returned %d steps", task.ID, len(plan.Steps))
+	return nil
+}
 
-	// 执行步骤
+// runSteps 依次执行计划步骤，失败时尝试让 AI 优化选择器后重试。
+// prevResults/prevShots 为此前已完成的结果，仅用于写入中间进度；rec 为 nil 时不记录调试信息。
+// 浏览器断开且无法恢复时停止执行并返回错误
+func (o *Orchestrator) runSteps(ctx context.Context, task *domain.Task, aiPlanner *planner.AIPlanner, plan *planner.TaskPlan, snapshot *browser.PageSnapshot, prevResults []planner.StepResult, prevShots []domain.Screenshot, rec *debugRecorder, budget *snapshotBudget) ([]planner.StepResult, []domain.Screenshot, error) {
 	var stepResults []planner.StepResult
 	var screenshots []domain.Screenshot
+	saveProgress := func() {
+		advanceProgress(task, stepProgress(len(prevResults)+len(stepResults), len(prevResults)+len(plan.Steps)))
+		o.saveProgress(ctx, task,
+			append(append([]planner.StepResult(nil), prevResults...), stepResults...),
+			append(append([]domain.Screenshot(nil), prevShots...), screenshots...),
+			budget.sent)
+	}
+	// stopOnFailure 在 stop 策略下将剩余步骤记为跳过并返回任务错误，continue 策略下返回 nil
+	stopOnFailure := func(i int, reason string) error {
+		if task.OnStepFailure != domain.StepFailureStop {
+			return nil
+		}
+		failed := plan.Steps[i]
+		for _, rest := range plan.Steps[i+1:] {
+			log.Printf("[Task %s] Skipping step %d after step %d failed", task.ID, rest.Order, failed.Order)
+			stepResults = append(stepResults, stepResult(rest, planner.StepResult{
+				Success: false,
+				Skipped: true,
+				Error:   fmt.Sprintf("skipped: step %d failed", failed.Order),
+			}))
+		}
+		saveProgress()
+		return newTaskError(domain.ErrorCodeStepExecution, "stop on step failure", errors.New(reason))
+	}
+	snaps := newPageSnapshots(o.browserCtrl, snapshot)
+	deduper := newScreenshotDeduper(task)
+	var llmErr error // 重新规划遇到重试无效的 LLM 错误（如认证失败）后不再调用 LLM
 
 	for i, step := range plan.Steps {
 		log.Printf("[Task %s] Executing step %d/%d: %s", task.ID, i+1, len(plan.Steps), step.Description)
-		result, screenshot, err := o.executeStep(ctx, step)
+		if err := ctx.Err(); err != nil {
+			return stepResults, screenshots, err
+		}
+		if err := o.ensureBrowser(ctx, task, snaps.url()); err != nil {
+			return stepResults, screenshots, err
+		}
+		if err := o.confirmDestructive(ctx, task, step); err != nil {
+			return stepResults, screenshots, err
+		}
+		o.dismissOverlays(ctx, task)
+		o.solveCaptcha(ctx, task)
+		before := snaps.current
+		executed := step // 实际执行的步骤，重新规划后为优化后的步骤
+		result, screenshot, err := o.runStep(ctx, task, step)
+		if errors.Is(err, ErrStepAborted) {
+			log.Printf("[Task %s] Step %d aborted: %v", task.ID, i+1, err)
+			stepResults = append(stepResults, stepResult(step, *result))
+			saveProgress()
+			rec.recordStep(step, result, before, nil)
+			if stopErr := stopOnFailure(i, result.Error); stopErr != nil {
+				return stepResults, screenshots, stopErr
+			}
+			continue
+		}
+		if err != nil && (llmErr != nil || !budget.trySend()) {
+			if llmErr != nil {
+				log.Printf("[Task %s] Step %d failed: %v, skipping refine after LLM error: %v", task.ID, i+1, err, llmErr)
+			} else {
+				log.Printf("[Task %s] Step %d failed: %v, snapshot budget exhausted, skipping refine", task.ID, i+1, err)
+			}
+			stepResults = append(stepResults, stepResult(step, planner.StepResult{
+				Success: false,
+				Error:   err.Error(),
+			}))
+			saveProgress()
+			rec.recordStep(step, nil, before, nil)
+			if stopErr := stopOnFailure(i, err.Error()); stopErr != nil {
+				return stepResults, screenshots, stopErr
+			}
+			continue
+		}
 		if err != nil {
 			log.Printf("[Task %s] Step %d failed: %v, attempting refine...", task.ID, i+1, err)
+			// 失败的步骤可能已改变页面，交给 LLM 前确保快照反映当前页面
+			snaps.refresh(ctx)
 			// 尝试重新规划
-			refined, refineErr := aiPlanner.RefineStep(ctx, &step, snapshot)
+			refined, refineErr := aiPlanner.RefineStep(ctx, &step, snaps.current, task.OutputLanguage())
 			if refineErr != nil {
 				log.Printf("[Task %s] Refine failed: %v", task.ID, refineErr)
-				stepResults = append(stepResults, planner.StepResult{
+				if !planner.RetryableLLMError(refineErr) {
+					llmErr = refineErr
+				}
+				stepResults = append(stepResults, stepResult(step, planner.StepResult{
 					Success: false,
 					Error:   err.Error(),
-				})
+				}))
+				saveProgress()
+				rec.recordStep(step, nil, before, nil)
+				if stopErr := stopOnFailure(i, err.Error()); stopErr != nil {
+					return stepResults, screenshots, stopErr
+				}
 				continue
 			}
 			log.Printf("[Task %s] Refined step: %s -> %s", task.ID, step.Target, refined.Target)
 			// 重新执行
-			result, screenshot, _ = o.executeStep(ctx, *refined)
+			executed = refinedStep(step, refined)
+			result, screenshot, _ = o.runStep(ctx, task, executed)
 		}
 
-		stepResults = append(stepResults, *result)
+		stepResults = append(stepResults, stepResult(executed, *result))
 		if screenshot != nil {
+			deduper.apply(screenshot)
 			screenshots = append(screenshots, *screenshot)
 		}
+		saveProgress()
+		if !result.Success {
+			if stopErr := stopOnFailure(i, result.Error); stopErr != nil {
+				rec.recordStep(step, result, before, nil)
+				return stepResults, screenshots, stopErr
+			}
+		}
 
-		// 更新快照
-		snapshot, _ = o.browserCtrl.TakeSnapshot(ctx)
+		// 更新快照，按 snapshot_every 节流，页面未变化时沿用上次的快照
+		if !budget.resnapshot(i) {
+			rec.recordStep(step, result, before, nil)
+			continue
+		}
+		snaps.refresh(ctx)
+		rec.recordStep(step, result, before, snaps.current)
 	}
 
-	// 生成文档
-	docs, err := o.generateDocuments(ctx, task, plan, stepResults)
+	// 最后一步失败后仍可能已取消或到达任务时限，此时不应按完成处理
+	return stepResults, screenshots, ctx.Err()
+}
+
+// saveProgress 每步完成后写入部分结果，任务中途崩溃或被终止时保留已完成的进度
+func (o *Orchestrator) saveProgress(ctx context.Context, task *domain.Task, stepResults []planner.StepResult, screenshots []domain.Screenshot, snapshotsSent int) {
+	if ctx.Err() != nil {
+		return // 已取消，不覆盖 cancelled 状态
+	}
+	task.Status = domain.TaskStatusRunning
+	task.UpdatedAt = time.Now()
+	task.Result = &domain.TaskResult{
+		Steps:         convertStepResults(stepResults, screenshots),
+		Screenshots:   screenshots,
+		Downloads:     collectDownloads(stepResults),
+		Data:          collectData(stepResults),
+		SnapshotsSent: snapshotsSent,
+	}
+	if err := o.taskStore.Update(ctx, task); err != nil {
+		log.Printf("[Task %s] Save progress failed: %v", task.ID, err)
+	}
+}
+
+// setPlanOutput 计划解析或校验失败时在任务上保存模型的原始输出
+func setPlanOutput(task *domain.Task, err error) {
+	var planErr *planner.PlanError
+	if errors.As(err, &planErr) {
+		task.PlanOutput = planErr.Raw
+	}
+}
+
+// lookupPlan 查询计划缓存，任务要求跳过缓存时直接返回未命中
+func (o *Orchestrator) lookupPlan(ctx context.Context, task *domain.Task, key planner.PlanCacheKey) (*planner.TaskPlan, bool) {
+	if o.planCache == nil || task.NoCache {
+		return nil, false
+	}
+	return o.planCache.Get(ctx, key)
+}
+
+func allStepsSucceeded(results []planner.StepResult) bool {
+	for _, r := range results {
+		if !r.Success {
+			return false
+		}
+	}
+	return len(results) > 0
+}
+
+// HasLiveSession 判断任务是否仍保留浏览器会话
+func (o *Orchestrator) HasLiveSession(taskID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.live != nil && o.live.taskID == taskID
+}
+
+// ContinueTask 在保留的浏览器会话上规划并执行追加指令，步骤追加到原结果中
+// 执行期间可通过 Cancel 取消
+func (o *Orchestrator) ContinueTask(ctx context.Context, task *domain.Task, instruction string) (err error) {
+	defer o.recoverTask(ctx, task, &err)
+	live, err := o.claimLiveSession(task.ID)
 	if err != nil {
-		return o.failTask(ctx, task, fmt.Errorf("generate docs: %w", err))
+		return err
 	}
+	defer o.returnLiveSession(live)
 
-	// 更新任务结果
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	o.trackRunning(task.ID, cancel)
+	defer o.untrackRunning(task.ID)
+
+	log.Printf("[Task %s] Continuing with instruction: %s", task.ID, instruction)
+
+	// 追加指令为新的一轮执行，进度重新计算；已完成的任务允许重新进入运行状态
+	task.Status = domain.TaskStatusRunning
+	task.Progress = progressPlanning
+	task.UpdatedAt = time.Now()
+	if err := o.taskStore.ForceUpdate(ctx, task); err != nil {
+		return fmt.Errorf("update task status: %w", err)
+	}
+	o.recordStatus(ctx, task)
+	o.recordPhase(ctx, task, domain.PhasePlanning)
+
+	startTime := time.Now()
+
+	snapshot, err := o.browserCtrl.TakeSnapshot(ctx)
+	if err != nil {
+		return o.failTask(ctx, task, newTaskError(domain.ErrorCodeBrowser, "take snapshot", err))
+	}
+	currentURL, _ := o.browserCtrl.GetCurrentURL(ctx)
+
+	plan, err := live.planner.ParseTask(ctx, &planner.PlanRequest{
+		UserInput:    instruction,
+		TargetURL:    currentURL,
+		PageSnapshot: snapshot,
+		Language:     task.OutputLanguage(),
+	})
+	if err != nil {
+		setPlanOutput(task, err)
+		return o.failTask(ctx, task, newTaskError(domain.ErrorCodePlanning, "parse task", err))
+	}
+	log.Printf("[Task %s] LLM returned %d follow-up steps", task.ID, len(plan.Steps))
+	task.PlanOutput = ""
+
+	// 追加步骤并顺延序号
+	offset := len(live.plan.Steps)
+	for i := range plan.Steps {
+		plan.Steps[i].Order = offset + i + 1
+	}
+	rec := newDebugRecorder(task)
+	rec.setPlan(plan)
+	live.snapshots.record()
+	o.recordPhase(ctx, task, domain.PhaseExecuting)
+	results, screenshots, err := o.runSteps(ctx, task, live.planner, plan, snapshot, live.results, live.screenshots, rec, live.snapshots)
+	if err != nil {
+		return o.failTask(ctx, task, err)
+	}
+	live.plan.Steps = append(live.plan.Steps, plan.Steps...)
+	task.Plan = convertPlan(live.plan)
+	live.results = append(live.results, results...)
+	live.screenshots = append(live.screenshots, screenshots...)
+
+	o.recordPhase(ctx, task, domain.PhaseGenerating)
+	o.addTips(ctx, task, live.planner, live.plan)
+	docs, err := o.generateDocuments(ctx, task, live.plan, live.results, rec)
+	if err != nil {
+		return o.failTask(ctx, task, newTaskError(domain.ErrorCodeDocument, "generate docs", err))
+	}
+
+	var duration time.Duration
+	if task.Result != nil {
+		duration = task.Result.Duration
+	}
 	task.Status = domain.TaskStatusCompleted
+	task.Progress = 100
 	task.UpdatedAt = time.Now()
 	completedAt := time.Now()
 	task.CompletedAt = &completedAt
 	task.Result = &domain.TaskResult{
-		Steps:       convertStepResults(stepResults),
-		Screenshots: screenshots,
-		Documents:   docs,
-		Duration:    time.Since(startTime),
+		Steps:         convertStepResults(live.results, live.screenshots),
+		Screenshots:   live.screenshots,
+		Documents:     docs,
+		Downloads:     collectDownloads(live.results),
+		Data:          collectData(live.results),
+		Duration:      duration + time.Since(startTime),
+		SnapshotsSent: live.snapshots.sent,
 	}
 
 	if err := o.taskStore.Update(ctx, task); err != nil {
 		return fmt.Errorf("update task result: %w", err)
 	}
-
+	o.recordStatus(ctx, task)
+	o.writeManifest(ctx, task)
 	return nil
 }
 
-func (o *Orchestrator) executeStep(ctx context.Context, step planner.ActionStep) (*planner.StepResult, *domain.Screenshot, error) {
+// holdLiveSession 保留浏览器会话，空闲超时后自动关闭
+func (o *Orchestrator) holdLiveSession(live *liveSession) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if live.idle > MaxKeepAlive {
+		live.idle = MaxKeepAlive
+	}
+	log.Printf("[Task %s] Keeping browser session alive for %s", live.taskID, live.idle)
+	live.timer = time.AfterFunc(live.idle, func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.live != live || live.busy != nil {
+			return
+		}
+		log.Printf("[Task %s] Browser session idle timeout, closing", live.taskID)
+		o.live = nil
+		o.browserCtrl.Close(context.Background())
+	})
+	o.live = live
+}
+
+// claimLiveSession 占用任务保留的会话并暂停空闲计时，只在占用期间持有锁
+func (o *Orchestrator) claimLiveSession(taskID string) (*liveSession, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	live := o.live
+	if live == nil || live.taskID != taskID {
+		return nil, ErrSessionNotAlive
+	}
+	if live.busy != nil {
+		return nil, ErrSessionBusy
+	}
+	live.busy = make(chan struct{})
+	live.timer.Stop()
+	return live, nil
+}
+
+// returnLiveSession 结束占用，会话仍保留时重新开始空闲计时
+func (o *Orchestrator) returnLiveSession(live *liveSession) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	close(live.busy)
+	live.busy = nil
+	if o.live == live {
+		live.timer.Reset(live.idle)
+	}
+}
+
+// releaseLiveSession 立即关闭保留的浏览器会话，会话正在执行追加指令时等待其结束
+func (o *Orchestrator) releaseLiveSession(ctx context.Context) {
+	o.mu.Lock()
+	live := o.live
+	if live == nil {
+		o.mu.Unlock()
+		return
+	}
+	o.live = nil
+	live.timer.Stop()
+	busy := live.busy
+	o.mu.Unlock()
+
+	if busy != nil {
+		select {
+		case <-busy:
+		case <-ctx.Done():
+		}
+	}
+	log.Printf("[Task %s] Releasing kept-alive browser session", live.taskID)
+	o.browserCtrl.Close(ctx)
+}
+
+func (o *Orchestrator) executeStep(ctx context.Context, task *domain.Task, step planner.ActionStep) (*planner.StepResult, *domain.Screenshot, error) {
 	var err error
+	var data map[string]string
+	var download *domain.Download
+	var selector string
 
 	log.Printf("[Step] Executing action=%s, target=%s, value=%s", step.Action, step.Target, step.Value)
 
+	if pause := stepPause(task.Pacing); pause > 0 {
+		select {
+		case <-ctx.Done():
+			return &planner.StepResult{Success: false, Error: ctx.Err().Error()}, nil, ctx.Err()
+		case <-time.After(pause):
+		}
+	}
+
 	switch step.Action {
 	case browser.ActionNavigate:
-		log.Printf("[Step] Navigate to: %s", step.Target)
-		err = o.browserCtrl.Navigate(ctx, step.Target)
+		currentURL, _ := o.browserCtrl.GetCurrentURL(ctx)
+		var target string
+		if target, err = resolveNavigateTarget(step.Target, currentURL, task.TargetURL); err == nil {
+			log.Printf("[Step] Navigate to: %s", target)
+			err = o.browserCtrl.Navigate(ctx, target)
+		}
 	case browser.ActionClick:
 		log.Printf("[Step] Click on: %s", step.Target)
-		err = o.browserCtrl.Click(ctx, step.Target)
+		beforeURL, _ := o.browserCtrl.GetCurrentURL(ctx)
+		if opts, ok := browser.ParseClickOptions(step.Value); ok {
+			err = o.browserCtrl.ClickByText(ctx, step.Target, opts)
+		} else if err = o.waitEnabled(ctx, step); err == nil {
+			err = o.browserCtrl.Click(ctx, step.Target)
+		}
+		if err == nil && step.NavigatesAway {
+			o.waitForNavigationAfter(ctx, beforeURL)
+		}
 	case browser.ActionFill:
 		log.Printf("[Step] Fill %s with: %s", step.Target, step.Value)
-		err = o.browserCtrl.Fill(ctx, step.Target, step.Value)
+		if delay, typed := typingDelay(task.Pacing); typed {
+			err = o.browserCtrl.TypeText(ctx, step.Target, step.Value, delay)
+		} else {
+			err = o.browserCtrl.Fill(ctx, step.Target, step.Value)
+		}
 	case browser.ActionHover:
 		log.Printf("[Step] Hover on: %s", step.Target)
 		err = o.browserCtrl.Hover(ctx, step.Target)
 	case browser.ActionSelect:
 		log.Printf("[Step] Select %s in: %s", step.Value, step.Target)
 		err = o.browserCtrl.Select(ctx, step.Target, step.Value)
+	case browser.ActionExtract:
+		log.Printf("[Step] Extract %s from: %s", step.Value, step.Target)
+		if step.Value == "" {
+			err = fmt.Errorf("extract step requires a data key in value")
+			break
+		}
+		var text string
+		if text, err = o.browserCtrl.ExtractText(ctx, step.Target); err == nil {
+			data = map[string]string{step.Value: text}
+		}
+	case browser.ActionDownload:
+		log.Printf("[Step] Download via: %s", step.Target)
+		if opts, ok := browser.ParseClickOptions(step.Value); ok {
+			err = o.browserCtrl.ClickByText(ctx, step.Target, opts)
+		} else if err = o.waitEnabled(ctx, step); err == nil {
+			err = o.browserCtrl.Click(ctx, step.Target)
+		}
+		if err == nil {
+			download, err = o.saveDownload(ctx, task, step)
+		}
+	case browser.ActionScreenshot:
+		// 截图在动作完成后统一进行，screenshot 步骤总是截图
+		log.Printf("[Step] Screenshot: %s", step.Description)
+	case browser.ActionSwitchFrame:
+		log.Printf("[Step] Switch to frame: %s", step.Target)
+		err = o.browserCtrl.SwitchToFrame(ctx, step.Target)
+	case browser.ActionSwitchMainFrame:
+		log.Printf("[Step] Switch to main frame")
+		err = o.browserCtrl.SwitchToMainFrame(ctx)
 	case browser.ActionWait:
-		if step.WaitFor != "" {
+		if step.WaitForJS != "" {
+			log.Printf("[Step] Wait for condition: %s", step.WaitForJS)
+			err = o.browserCtrl.WaitForCondition(ctx, step.WaitForJS, 10*time.Second)
+		} else if alternatives := browser.SplitAlternatives(step.WaitFor); len(alternatives) > 1 {
+			// 多个候选时记录实际出现的那个，结果与文档据此体现走到了哪个分支
+			log.Printf("[Step] Wait for any of: %q", alternatives)
+			selector, err = o.browserCtrl.WaitForAny(ctx, alternatives, 10*time.Second)
+		} else if step.WaitFor != "" && step.WaitEnabled {
+			log.Printf("[Step] Wait for enabled: %s", step.WaitFor)
+			err = o.browserCtrl.WaitForEnabled(ctx, step.WaitFor, enabledTimeout)
+		} else if step.WaitFor != "" {
 			err = o.browserCtrl.WaitForSelector(ctx, step.WaitFor, 10*time.Second)
 		} else {
-			time.Sleep(2 * time.Second)
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-time.After(bareWaitStep):
+			}
 		}
 	}
 
 	if err != nil {
+		err = newTaskError(domain.ErrorCodeStepExecution, fmt.Sprintf("step %d %s", step.Order, step.Action), err)
 		return &planner.StepResult{Success: false, Error: err.Error()}, nil, err
 	}
+	// 记录控制器回退匹配后实际使用的选择器，便于手动复现
+	if step.Target != "" && elementActions[step.Action] {
+		selector = o.browserCtrl.ResolvedSelector(ctx)
+	}
 
-	// 等待动作完成
-	time.Sleep(500 * time.Millisecond)
+	// 等待动作完成（导航类点击已显式等待）
+	if !(step.Action == browser.ActionClick && step.NavigatesAway) {
+		select {
+		case <-ctx.Done():
+			return &planner.StepResult{Success: false, Error: ctx.Err().Error()}, nil, ctx.Err()
+		case <-time.After(actionSettle):
+		}
+	}
 
 	// 截图
 	var screenshot *domain.Screenshot
-	if step.Screenshot {
-		imgData, err := o.browserCtrl.TakeScreenshot(ctx, browser.ScreenshotOptions{
-			Quality: 90,
-			Type:    "png",
-		})
-		if err == nil {
-			screenshot = &domain.Screenshot{
-				ID:        uuid.New().String(),
-				StepOrder: step.Order,
-				CreatedAt: time.Now(),
+	if step.Screenshot || step.Action == browser.ActionScreenshot {
+		screenshot, err = o.captureScreenshot(ctx, task, step)
+		if err != nil && step.Action == browser.ActionScreenshot {
+			// 截图本身就是该步骤的目的，失败即步骤失败
+			err = newTaskError(domain.ErrorCodeStepExecution, fmt.Sprintf("step %d %s", step.Order, step.Action), err)
+			return &planner.StepResult{Success: false, Error: err.Error()}, nil, err
+		}
+		if err != nil {
+			log.Printf("[Step] Screenshot failed: %v", err)
+		}
+	}
+
+	return &planner.StepResult{Success: true, Selector: selector, Data: data, Download: download}, screenshot, nil
+}
+
+// elementActions 按选择器操作页面元素的步骤类型
+var elementActions = map[browser.ActionType]bool{
+	browser.ActionClick:    true,
+	browser.ActionFill:     true,
+	browser.ActionHover:    true,
+	browser.ActionSelect:   true,
+	browser.ActionExtract:  true,
+	browser.ActionDownload: true,
+}
+
+// captureScreenshot 按任务截图设置与步骤的 full_page/clip 截图并保存，设置了 clip 时只截取该区域
+func (o *Orchestrator) captureScreenshot(ctx context.Context, task *domain.Task, step planner.ActionStep) (*domain.Screenshot, error) {
+	format := task.ScreenshotFormat()
+	quality := 90
+	if task.Output != nil && task.Output.ScreenshotConfig != nil && task.Output.ScreenshotConfig.Quality > 0 {
+		quality = task.Output.ScreenshotConfig.Quality
+	}
+	if err := o.settleBeforeScreenshot(ctx, task); err != nil {
+		return nil, err
+	}
+	opts := browser.ScreenshotOptions{
+		FullPage: task.ScreenshotFullPage(step.FullPage),
+		Quality:  quality,
+		Type:     string(format),
+	}
+	if step.Clip != nil {
+		// Playwright 不允许同时设置 clip 与 full_page
+		opts.FullPage, opts.Clip = false, step.Clip
+	}
+	imgData, err := o.browserCtrl.TakeScreenshot(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	screenshot := &domain.Screenshot{
+		ID:        uuid.New().String(),
+		Format:    format,
+		StepOrder: step.Order,
+		Hash:      screenshotHash(imgData),
+		CreatedAt: time.Now(),
+	}
+	o.saveScreenshot(ctx, task, screenshot, imgData)
+	return screenshot, nil
+}
+
+// animationsSettledJS 页面上没有运行中的有限动画/过渡（加载图标等无限循环的动画不计）
+const animationsSettledJS = `() => document.getAnimations().every(a =>
+	a.playState !== 'running' || !a.effect || a.effect.getComputedTiming().iterations === Infinity)`
+
+// animationSettleTimeout 等待动画结束的上限，超时后照常截图
+const animationSettleTimeout = 2 * time.Second
+
+// settleBeforeScreenshot 截图前等待过渡动画结束并按配置延迟，避免截到淡入中的弹窗等中间状态
+func (o *Orchestrator) settleBeforeScreenshot(ctx context.Context, task *domain.Task) error {
+	if err := o.browserCtrl.WaitForCondition(ctx, animationsSettledJS, animationSettleTimeout); err != nil {
+		log.Printf("[Task %s] Animations still running before screenshot: %v", task.ID, err)
+	}
+	select {
+	case <-time.After(task.ScreenshotDelay()):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// defaultNavigationRetries 初始导航默认重试次数
+const defaultNavigationRetries = 2
+
+// navigateWithRetry 导航到 URL，失败时按指数退避重试；多次失败后重建页面再尝试一次
+func (o *Orchestrator) navigateWithRetry(ctx context.Context, task *domain.Task, url string) error {
+	retries := task.NavigationRetries
+	if retries <= 0 {
+		retries = defaultNavigationRetries
+	}
+
+	var err error
+	backoff := navRetryWait
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("[Task %s] Navigation failed (%v), retry %d/%d in %s", task.ID, err, attempt, retries, backoff)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
 			}
-			// TODO: 保存截图到存储
-			_ = imgData
+			backoff *= 2
+		}
+		if err = o.browserCtrl.Navigate(ctx, url); err == nil {
+			return nil
 		}
 	}
 
-	return &planner.StepResult{Success: true}, screenshot, nil
+	log.Printf("[Task %s] Navigation failed after %d retries, recreating page", task.ID, retries)
+	if pageErr := o.browserCtrl.NewPage(ctx); pageErr != nil {
+		return fmt.Errorf("%w (recreate page: %v)", err, pageErr)
+	}
+	return o.browserCtrl.Navigate(ctx, url)
+}
+
+// enabledTimeout 点击前等待目标可点击的时间上限
+const enabledTimeout = 10 * time.Second
+
+// waitEnabled 步骤要求时等待点击目标可点击，超时视为步骤失败
+func (o *Orchestrator) waitEnabled(ctx context.Context, step planner.ActionStep) error {
+	if !step.WaitEnabled {
+		return nil
+	}
+	log.Printf("[Step] Wait for enabled: %s", step.Target)
+	if err := o.browserCtrl.WaitForEnabled(ctx, step.Target, enabledTimeout); err != nil {
+		return fmt.Errorf("wait for enabled: %w", err)
+	}
+	return nil
 }
 
-func (o *Orchestrator) generateDocuments(ctx context.Context, task *domain.Task, plan *planner.TaskPlan, results []planner.StepResult) ([]domain.DocumentInfo, error) {
+// 点击后等待导航的时间上限
+const (
+	navigationStartTimeout = 5 * time.Second
+	navigationLoadTimeout  = 15 * time.Second
+)
+
+// waitForNavigationAfter 等待 URL 离开 beforeURL 并加载完成，超时只记录日志不视为失败
+func (o *Orchestrator) waitForNavigationAfter(ctx context.Context, beforeURL string) {
+	deadline := time.Now().Add(navigationStartTimeout)
+	for time.Now().Before(deadline) {
+		if current, _ := o.browserCtrl.GetCurrentURL(ctx); current != beforeURL {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	if err := o.browserCtrl.WaitForNavigation(ctx, navigationLoadTimeout); err != nil {
+		log.Printf("[Step] Wait for navigation failed: %v", err)
+	}
+}
+
+// generateDocuments 按输出格式生成并保存文档。单个格式失败不影响其他格式，
+// 返回已保存的文档及合并后的错误
+func (o *Orchestrator) generateDocuments(ctx context.Context, task *domain.Task, plan *planner.TaskPlan, results []planner.StepResult, rec *debugRecorder) ([]domain.DocumentInfo, error) {
 	var docs []domain.DocumentInfo
+	var errs []error
 
 	for _, format := range task.Output.Formats {
 		var gen docgen.Generator
 		switch format {
 		case domain.DocFormatMarkdown:
-			gen = docgen.NewMarkdownGenerator()
+			md := docgen.NewMarkdownGenerator()
+			md.SetMaxSize(o.maxDocSize)
+			gen = md
 		case domain.DocFormatHTML:
-			gen = docgen.NewHTMLGenerator()
+			html := docgen.NewHTMLGenerator()
+			html.SetMaxSize(o.maxDocSize)
+			gen = html
+		case domain.DocFormatConfluence:
+			confluence := docgen.NewConfluenceGenerator()
+			confluence.SetMaxSize(o.maxDocSize)
+			gen = confluence
 		default:
 			continue // 暂不支持的格式
 		}
 
 		doc, err := gen.Generate(ctx, task, plan, results)
 		if err != nil {
+			log.Printf("[Task %s] Generate %s document failed: %v", task.ID, format, err)
+			errs = append(errs, fmt.Errorf("generate %s document: %w", format, err))
 			continue
 		}
 
-		// 保存文档内容
-		docs = append(docs, domain.DocumentInfo{
-			ID:        uuid.New().String(),
-			Format:    format,
-			Content:   doc.Content,
-			Size:      int64(len(doc.Content)),
-			CreatedAt: time.Now(),
-		})
+		if doc.Truncated {
+			log.Printf("[Task %s] %s document exceeds the size limit, truncated", task.ID, format)
+		}
+		info, err := o.saveDocument(ctx, task, format, []byte(doc.Content))
+		if err != nil {
+			log.Printf("[Task %s] %v", task.ID, err)
+			errs = append(errs, err)
+			continue
+		}
+		info.Truncated = doc.Truncated
+		docs = append(docs, *info)
 	}
 
-	return docs, nil
+	// 调试产物单独保存，不混入指南正文
+	if rec != nil {
+		data, err := rec.artifact()
+		if err == nil {
+			var info *domain.DocumentInfo
+			if info, err = o.saveDocument(ctx, task, domain.DocFormatDebug, data); err == nil {
+				docs = append(docs, *info)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return docs, errors.Join(errs...)
+}
+
+// saveDocument 保存文档内容：配置了文档存储时写入存储并返回下载地址，否则内联在任务中
+func (o *Orchestrator) saveDocument(ctx context.Context, task *domain.Task, format domain.DocFormat, content []byte) (*domain.DocumentInfo, error) {
+	info := &domain.DocumentInfo{
+		ID:        uuid.New().String(),
+		Format:    format,
+		Size:      int64(len(content)),
+		CreatedAt: time.Now(),
+	}
+	if o.docStore != nil {
+		if err := o.docStore.Save(ctx, task.ID, info, content); err != nil {
+			return nil, fmt.Errorf("save %s document: %w", format, err)
+		}
+		info.URL = fmt.Sprintf("/api/v1/tasks/%s/documents/%s", task.ID, info.ID)
+	} else {
+		info.Content = string(content)
+	}
+	return info, nil
+}
+
+// recoverTask 捕获任务执行中的 panic：关闭浏览器，记录堆栈并将任务标记为失败
+func (o *Orchestrator) recoverTask(ctx context.Context, task *domain.Task, errp *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := string(debug.Stack())
+	log.Printf("[Task %s] Panic during execution: %v\n%s", task.ID, r, stack)
+
+	o.releaseLiveSession(ctx)
+	o.browserCtrl.Close(ctx)
+
+	task.ErrorStack = stack
+	*errp = o.failTask(ctx, task, newTaskError(domain.ErrorCodeInternal, "panic", fmt.Errorf("%v", r)))
 }
 
 func (o *Orchestrator) failTask(ctx context.Context, task *domain.Task, err error) error {
+	// 被取消的任务保持 cancelled 状态
+	if errors.Is(ctx.Err(), context.Canceled) {
+		task.Status = domain.TaskStatusCancelled
+		task.ErrorMessage = "task cancelled"
+		task.UpdatedAt = time.Now()
+		o.taskStore.Update(context.Background(), task)
+		o.recordStatus(ctx, task)
+		return err
+	}
 	task.Status = domain.TaskStatusFailed
 	task.ErrorMessage = err.Error()
+	task.ErrorCode = errorCode(err)
 	task.UpdatedAt = time.Now()
-	o.taskStore.Update(ctx, task)
+	// ctx 可能已超时，失败状态仍需写入；任务已被取消等终态时不覆盖
+	if updateErr := o.taskStore.Update(context.WithoutCancel(ctx), task); errors.Is(updateErr, storage.ErrTaskFinished) {
+		o.syncFinishedStatus(ctx, task)
+	}
+	o.recordStatus(ctx, task)
 	return err
 }
 
-func convertStepResults(results []planner.StepResult) []domain.StepResult {
+// syncFinishedStatus 存储拒绝更新已结束的任务时，以存储中的终态为准，避免之后的写入继续覆盖
+func (o *Orchestrator) syncFinishedStatus(ctx context.Context, task *domain.Task) {
+	if current, err := o.taskStore.Get(context.WithoutCancel(ctx), task.ID); err == nil {
+		task.Status = current.Status
+		task.ErrorMessage = current.ErrorMessage
+		task.ErrorCode = current.ErrorCode
+	}
+}
+
+// finalizeTask 保证 ExecuteTask 返回时任务处于终态：提前返回（如存储写入失败）未设置终态时按失败结束，
+// 已完成但结果写入失败时再写入一次
+func (o *Orchestrator) finalizeTask(ctx context.Context, task *domain.Task, errp *error) {
+	if !task.Status.Terminal() {
+		err := *errp
+		if err == nil {
+			err = newTaskError(domain.ErrorCodeInternal, "finalize", errors.New("task ended without a terminal status"))
+		}
+		log.Printf("[Task %s] Ended in %s status, marking failed: %v", task.ID, task.Status, err)
+		*errp = o.failTask(ctx, task, err)
+		return
+	}
+	if *errp != nil && task.Status == domain.TaskStatusCompleted {
+		if err := o.taskStore.Update(context.WithoutCancel(ctx), task); err != nil {
+			log.Printf("[Task %s] Persist final result failed: %v", task.ID, err)
+		}
+	}
+}
+
+func convertPlan(plan *planner.TaskPlan) *domain.TaskPlan {
+	steps := make([]domain.PlanStep, len(plan.Steps))
+	for i, step := range plan.Steps {
+		steps[i] = domain.PlanStep{
+			Order:         step.Order,
+			Action:        string(step.Action),
+			Target:        step.Target,
+			Value:         step.Value,
+			WaitFor:       step.WaitFor,
+			WaitForJS:     step.WaitForJS,
+			Screenshot:    step.Screenshot,
+			Description:   step.Description,
+			NavigatesAway: step.NavigatesAway,
+			WaitEnabled:   step.WaitEnabled,
+			FullPage:      step.FullPage,
+			Clip:          convertClip(step.Clip),
+		}
+	}
+	return &domain.TaskPlan{
+		Description: plan.Description,
+		Steps:       steps,
+	}
+}
+
+func convertClip(r *browser.Rect) *domain.Rect {
+	if r == nil {
+		return nil
+	}
+	return &domain.Rect{X: r.X, Y: r.Y, Width: r.Width, Height: r.Height}
+}
+
+// collectData 汇总各步骤提取的数据，同名键以后执行的步骤为准
+func collectData(results []planner.StepResult) map[string]string {
+	var data map[string]string
+	for _, r := range results {
+		for k, v := range r.Data {
+			if data == nil {
+				data = make(map[string]string)
+			}
+			data[k] = v
+		}
+	}
+	return data
+}
+
+// collectDownloads 汇总 download 步骤保存的文件
+func collectDownloads(results []planner.StepResult) []domain.Download {
+	var downloads []domain.Download
+	for _, r := range results {
+		if r.Download != nil {
+			downloads = append(downloads, *r.Download)
+		}
+	}
+	return downloads
+}
+
+// stepResult 为结果标注计划中的步骤序号、操作与说明
+func stepResult(step planner.ActionStep, r planner.StepResult) planner.StepResult {
+	r.Order = step.Order
+	r.Action = string(step.Action)
+	r.Description = step.Description
+	return r
+}
+
+// refinedStep 返回重新规划后实际执行的步骤，序号保持计划中的原值，优化结果缺少说明时沿用原说明
+func refinedStep(step planner.ActionStep, refined *planner.ActionStep) planner.ActionStep {
+	executed := *refined
+	executed.Order = step.Order
+	if executed.Description == "" {
+		executed.Description = step.Description
+	}
+	return executed
+}
+
+// convertStepResults 转换步骤结果，并附上按步骤序号对应的截图
+func convertStepResults(results []planner.StepResult, screenshots []domain.Screenshot) []domain.StepResult {
+	byOrder := make(map[int]*domain.Screenshot, len(screenshots))
+	for i := range screenshots {
+		byOrder[screenshots[i].StepOrder] = &screenshots[i]
+	}
 	var domainResults []domain.StepResult
 	for i, r := range results {
+		order := r.Order
+		if order == 0 {
+			order = i + 1
+		}
+		var shot *domain.Screenshot
+		if s, ok := byOrder[order]; ok {
+			c := *s
+			shot = &c
+		}
 		domainResults = append(domainResults, domain.StepResult{
-			Order:      i + 1,
-			Success:    r.Success,
-			Error:      r.Error,
-			ExecutedAt: time.Now(),
+			Order:       order,
+			Action:      r.Action,
+			Description: r.Description,
+			Success:     r.Success,
+			Error:       r.Error,
+			Skipped:     r.Skipped,
+			Selector:    r.Selector,
+			Screenshot:  shot,
+			ExecutedAt:  time.Now(),
 		})
 	}
 	return domainResults
@@ -0,0 +1,1846 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/browser-automation/internal/auth"
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+	"github.com/browser-automation/internal/storage"
+	"github.com/google/uuid"
+)
+
+func init() {
+	pageLoadWait, actionSettle, bareWaitStep, navRetryWait = 0, 0, 0, 0
+}
+
+// testLLM 模拟 OpenAI 兼容接口，按最后一条用户消息决定回复内容
+type testLLM struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	prompts []string
+	respond func(prompt string) string
+}
+
+func newTestLLM(t *testing.T, respond func(prompt string) string) *testLLM {
+	t.Helper()
+	l := &testLLM{respond: respond}
+	l.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []planner.Message `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt := req.Messages[len(req.Messages)-1].Content
+
+		l.mu.Lock()
+		l.prompts = append(l.prompts, prompt)
+		l.mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message":       map[string]string{"role": "assistant", "content": l.respond(prompt)},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	t.Cleanup(l.Close)
+	return l
+}
+
+// calls 返回收到的请求中包含 substr 的数量
+func (l *testLLM) calls(substr string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, p := range l.prompts {
+		if strings.Contains(p, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+// config 返回指向该服务的 LLM 配置，不重试
+func (l *testLLM) config() *domain.LLMConfig {
+	return &domain.LLMConfig{
+		Provider: domain.LLMProviderOpenAI,
+		Model:    "gpt-test",
+		Endpoint: l.URL,
+		APIKey:   "sk-test",
+		Options:  domain.MergeLLMOptions(&domain.LLMOptions{RetryCount: domain.Int(0)}),
+	}
+}
+
+// planReply 规划请求返回由 steps 组成的计划，提示请求返回空提示
+func planReply(steps ...planner.ActionStep) func(string) string {
+	plan, _ := json.Marshal(planner.TaskPlan{Description: "test plan", Steps: steps})
+	return func(prompt string) string {
+		if strings.Contains(prompt, `{"tips"`) {
+			return `{"tips": []}`
+		}
+		return string(plan)
+	}
+}
+
+// testEnv 由 FakeController、内存任务存储与模拟 LLM 组成的编排器
+type testEnv struct {
+	orch  *Orchestrator
+	ctrl  *browser.FakeController
+	store *storage.MemoryTaskStore
+	llm   *testLLM
+}
+
+func newTestEnv(t *testing.T, respond func(string) string, snapshots ...*browser.PageSnapshot) *testEnv {
+	t.Helper()
+	env := &testEnv{
+		ctrl:  browser.NewFakeController(snapshots...),
+		store: storage.NewMemoryTaskStore(),
+		llm:   newTestLLM(t, respond),
+	}
+	env.orch = NewOrchestrator(env.ctrl, env.store, planner.NewLLMClientFactory())
+	return env
+}
+
+// newTask 创建并保存待执行的任务，modify 可调整默认配置
+func (env *testEnv) newTask(t *testing.T, modify func(*domain.Task)) *domain.Task {
+	t.Helper()
+	output := domain.DefaultOutputConfig()
+	output.ContentConfig.IncludeTips = false
+	task := &domain.Task{
+		ID:          uuid.New().String(),
+		Description: "fill in the form",
+		TargetURL:   "https://app.example.com/form",
+		Status:      domain.TaskStatusPending,
+		LLM:         env.llm.config(),
+		Output:      output,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if modify != nil {
+		modify(task)
+	}
+	if err := env.store.Create(context.Background(), task); err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+// stored 返回存储中的任务
+func (env *testEnv) stored(t *testing.T, id string) *domain.Task {
+	t.Helper()
+	task, err := env.store.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+// methods 返回 FakeController 记录的调用中方法名为 method 的记录
+func (env *testEnv) methods(method string) []browser.FakeAction {
+	var actions []browser.FakeAction
+	for _, a := range env.ctrl.Actions() {
+		if a.Method == method {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
+
+func TestExecuteTaskWithFakeController(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "#start", Description: "Open the form"},
+		planner.ActionStep{Action: browser.ActionFill, Target: "#name", Value: "Alice", Description: "Enter the name"},
+		planner.ActionStep{Action: browser.ActionScreenshot, Description: "Capture the result"},
+	))
+	task := env.newTask(t, nil)
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusCompleted || got.Progress != 100 {
+		t.Fatalf("status = %s %d%%, want completed 100%%", got.Status, got.Progress)
+	}
+	if n := len(got.Result.Steps); n != 3 {
+		t.Fatalf("steps = %d, want 3", n)
+	}
+	for _, s := range got.Result.Steps {
+		if !s.Success {
+			t.Errorf("step %d failed: %s", s.Order, s.Error)
+		}
+	}
+	if len(got.Result.Screenshots) != 1 {
+		t.Errorf("screenshots = %d, want 1", len(got.Result.Screenshots))
+	}
+	if len(got.Result.Documents) != 1 || !strings.Contains(got.Result.Documents[0].Content, "Enter the name") {
+		t.Errorf("documents = %+v, want a markdown guide with the steps", got.Result.Documents)
+	}
+
+	if nav := env.methods("Navigate"); len(nav) == 0 || nav[0].Selector != task.TargetURL {
+		t.Errorf("navigations = %+v, want %s first", nav, task.TargetURL)
+	}
+	if fill := env.methods("Fill"); len(fill) != 1 || fill[0].Selector != "#name" || fill[0].Value != "Alice" {
+		t.Errorf("fills = %+v", fill)
+	}
+	if len(env.methods("Close")) != 1 {
+		t.Error("browser not closed after the task")
+	}
+}
+
+func TestExecuteTaskPlanningFailure(t *testing.T) {
+	env := newTestEnv(t, func(string) string { return "I cannot help with that." })
+	task := env.newTask(t, nil)
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err == nil {
+		t.Fatal("ExecuteTask succeeded, want a planning error")
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodePlanning {
+		t.Errorf("status = %s/%s, want failed/planning", got.Status, got.ErrorCode)
+	}
+	if got.PlanOutput == "" {
+		t.Error("raw model output not kept for a planning failure")
+	}
+}
+
+// loginSite 提交登录表单时写入会话 Cookie 和第三方 Cookie 的页面，
+// 登录后的点击记录当时浏览器中的 Cookie
+type loginSite struct {
+	*browser.FakeController
+	cookies []domain.Cookie
+	seen    []domain.Cookie
+}
+
+func (s *loginSite) Click(ctx context.Context, selector string) error {
+	if err := s.FakeController.Click(ctx, selector); err != nil {
+		return err
+	}
+	if selector == "button[type='submit']" {
+		return s.SetCookies(ctx, s.cookies)
+	}
+	s.seen, _ = s.GetCookies(ctx)
+	return nil
+}
+
+func TestFormLoginKeepsOnlyTargetCookies(t *testing.T) {
+	tests := []struct {
+		name       string
+		allCookies bool
+		want       []string
+	}{
+		{"scoped to target", false, []string{"session", "sso"}},
+		{"all cookies", true, []string{"session", "sso", "_ga"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &loginSite{
+				FakeController: browser.NewFakeController(),
+				cookies: []domain.Cookie{
+					{Name: "session", Value: "s", Domain: "app.example.com", Path: "/"},
+					{Name: "sso", Value: "t", Domain: ".example.com", Path: "/"},
+					{Name: "_ga", Value: "x", Domain: ".tracker.net", Path: "/"},
+				},
+			}
+			llm := newTestLLM(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"}))
+			store := storage.NewMemoryTaskStore()
+			orch := NewOrchestrator(site, store, planner.NewLLMClientFactory())
+			env := &testEnv{orch: orch, ctrl: site.FakeController, store: store, llm: llm}
+			task := env.newTask(t, func(task *domain.Task) {
+				task.Auth = &domain.AuthConfig{
+					Type:        domain.AuthTypeForm,
+					Credentials: &domain.Credentials{Username: "alice", Password: "secret"},
+					AllCookies:  tt.allCookies,
+				}
+			})
+
+			if err := orch.ExecuteTask(context.Background(), task); err != nil {
+				t.Fatalf("ExecuteTask: %v", err)
+			}
+			var got []string
+			for _, c := range site.seen {
+				got = append(got, c.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("cookies after login = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCookieAuthInjectsOnlyTargetCookies(t *testing.T) {
+	site := &loginSite{FakeController: browser.NewFakeController()}
+	store := storage.NewMemoryTaskStore()
+	env := &testEnv{
+		orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+		ctrl:  site.FakeController,
+		store: store,
+		llm:   newTestLLM(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"})),
+	}
+	task := env.newTask(t, func(task *domain.Task) {
+		task.Auth = &domain.AuthConfig{
+			Type: domain.AuthTypeCookie,
+			Cookies: []domain.Cookie{
+				{Name: "session", Value: "s", Domain: "app.example.com", Path: "/"},
+				{Name: "sso", Value: "t", Domain: ".example.com", Path: "/"},
+				{Name: "admin", Value: "a", Domain: "admin.app.example.com", Path: "/"},
+				{Name: "_ga", Value: "x", Domain: ".tracker.net", Path: "/"},
+			},
+		}
+	})
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	var got []string
+	for _, c := range site.seen {
+		got = append(got, c.Name)
+	}
+	if strings.Join(got, ",") != "session,sso" {
+		t.Errorf("injected cookies = %v, want [session sso]", got)
+	}
+}
+
+func TestScreenshotStepHonorsClipAndFullPage(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionScreenshot, Description: "Whole page", FullPage: boolPtr(true)},
+		planner.ActionStep{Action: browser.ActionScreenshot, Description: "Header only", FullPage: boolPtr(true),
+			Clip: &browser.Rect{X: 0, Y: 0, Width: 800, Height: 120}},
+	))
+	task := env.newTask(t, nil)
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	got := env.stored(t, task.ID)
+	if len(got.Result.Screenshots) != 2 {
+		t.Fatalf("screenshots = %d, want one per screenshot step", len(got.Result.Screenshots))
+	}
+	for _, s := range got.Result.Steps {
+		if s.Screenshot == nil {
+			t.Errorf("step %d has no screenshot", s.Order)
+		}
+	}
+
+	shots := env.ctrl.ScreenshotRequests()
+	if len(shots) != 2 {
+		t.Fatalf("screenshot calls = %d, want 2", len(shots))
+	}
+	if !shots[0].FullPage || shots[0].Clip != nil {
+		t.Errorf("first screenshot = %+v, want full page", shots[0])
+	}
+	if shots[1].FullPage || shots[1].Clip == nil || shots[1].Clip.Width != 800 || shots[1].Clip.Height != 120 {
+		t.Errorf("second screenshot = %+v, want only the 800x120 clip", shots[1])
+	}
+	if clip := got.Plan.Steps[1].Clip; clip == nil || clip.Width != 800 {
+		t.Errorf("plan step clip = %+v, want it kept in the task plan", clip)
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestExecuteTaskRetriesWholeTask(t *testing.T) {
+	plan := planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#go", Description: "Go"})
+	var parses int
+	var mu sync.Mutex
+	env := newTestEnv(t, func(prompt string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		if parses++; parses == 1 {
+			return "the model is overloaded, no plan"
+		}
+		return plan(prompt)
+	})
+	task := env.newTask(t, func(task *domain.Task) { task.MaxTaskRetries = 2 })
+
+	// 执行期间并发读取并编码任务，配合 -race 检查结果没有被原地修改
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if got, err := env.store.Get(context.Background(), task.ID); err == nil {
+					json.Marshal(got)
+				}
+			}
+		}
+	}()
+	err := env.orch.ExecuteTask(context.Background(), task)
+	close(done)
+	if err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusCompleted {
+		t.Fatalf("status = %s, want completed after the second attempt", got.Status)
+	}
+	attempts := got.Result.Attempts
+	if len(attempts) != 2 || attempts[0].Attempt != 1 || attempts[0].ErrorCode != domain.ErrorCodePlanning ||
+		attempts[1].Attempt != 2 || attempts[1].Error != "" {
+		t.Errorf("attempts = %+v, want a failed planning attempt then a successful one", attempts)
+	}
+	if n := len(env.methods("Connect")); n != 2 {
+		t.Errorf("browser connected %d times, want a fresh browser per attempt", n)
+	}
+}
+
+func TestExecuteTaskFailsAfterRetriesExhausted(t *testing.T) {
+	env := newTestEnv(t, func(string) string { return "no plan" })
+	task := env.newTask(t, func(task *domain.Task) { task.MaxTaskRetries = 1 })
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err == nil {
+		t.Fatal("ExecuteTask succeeded, want failure")
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusFailed || got.Result == nil || len(got.Result.Attempts) != 2 {
+		t.Errorf("task = %s with result %+v, want failed with 2 attempts", got.Status, got.Result)
+	}
+}
+
+func TestRetriesRotateProxies(t *testing.T) {
+	env := newTestEnv(t, func(string) string { return "no plan" })
+	pool, err := browser.ParseProxyPool("http://user:pw@a:3128,http://b:3128", browser.ProxyRoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.orch.SetProxyPool(pool)
+	task := env.newTask(t, func(task *domain.Task) { task.MaxTaskRetries = 2 })
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err == nil {
+		t.Fatal("ExecuteTask succeeded, want failure")
+	}
+	// 每次尝试都重新连接并换用下一个代理，记录中的地址不含账号密码
+	var proxies []string
+	for _, a := range env.methods("Connect") {
+		proxies = append(proxies, a.Selector)
+	}
+	if got := strings.Join(proxies, ","); got != "http://a:3128,http://b:3128,http://a:3128" {
+		t.Errorf("proxies per attempt = %s, want round robin across attempts", got)
+	}
+}
+
+func TestContinueTaskCanBeCancelled(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	initial := planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#start", Description: "Open the form"})
+	env := newTestEnv(t, func(prompt string) string {
+		if strings.Contains(prompt, "export the report") {
+			entered <- struct{}{}
+			<-release
+		}
+		return initial(prompt)
+	})
+	task := env.newTask(t, func(task *domain.Task) { task.KeepAlive = 60 })
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- env.orch.ContinueTask(context.Background(), task, "export the report") }()
+	<-entered
+
+	// 追加指令执行期间不持有会话锁，其他请求不被阻塞
+	checked := make(chan bool, 1)
+	go func() { checked <- env.orch.HasLiveSession(task.ID) }()
+	select {
+	case alive := <-checked:
+		if !alive {
+			t.Error("live session lost during continuation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("HasLiveSession blocked by the running continuation")
+	}
+	if err := env.orch.ContinueTask(context.Background(), env.stored(t, task.ID), "again"); !errors.Is(err, ErrSessionBusy) {
+		t.Errorf("concurrent ContinueTask err = %v, want ErrSessionBusy", err)
+	}
+
+	if ok, err := env.orch.Cancel(context.Background(), task.ID); !ok || err != nil {
+		t.Fatalf("Cancel = %v, %v", ok, err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("cancelled continuation returned nil")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("continuation not stopped by Cancel")
+	}
+	if got := env.stored(t, task.ID); got.Status != domain.TaskStatusCancelled {
+		t.Errorf("status = %s, want cancelled", got.Status)
+	}
+}
+
+func TestApprovalWaitEndsAtTaskDeadline(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "#delete", Description: "Delete the account"},
+	))
+	task := env.newTask(t, func(task *domain.Task) {
+		task.Deadline = 1
+		task.Safety = &domain.SafetyConfig{ConfirmDestructive: true}
+	})
+
+	start := time.Now()
+	err := env.orch.ExecuteTask(context.Background(), task)
+	if !errors.Is(err, ErrApprovalTimeout) {
+		t.Fatalf("err = %v, want ErrApprovalTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("approval wait took %s, not bounded by the 1s deadline", elapsed)
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodeStepExecution {
+		t.Errorf("task = %s %s, want failed with step_execution", got.Status, got.ErrorCode)
+	}
+	if len(env.methods("Click")) != 0 {
+		t.Error("destructive step ran without approval")
+	}
+}
+
+func TestDestructiveStepPausesUntilApproved(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionFill, Target: "#reason", Value: "cleanup", Description: "Enter the reason"},
+		planner.ActionStep{Action: browser.ActionClick, Target: "#delete", Description: "Delete the account"},
+	))
+	task := env.newTask(t, func(task *domain.Task) {
+		task.Safety = &domain.SafetyConfig{ConfirmDestructive: true}
+	})
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() { done <- env.orch.ExecuteTask(ctx, task) }()
+
+	// 等待任务进入等待批准状态
+	var waiting *domain.Task
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if got := env.stored(t, task.ID); got.Status == domain.TaskStatusWaitingForHuman {
+			waiting = got
+			break
+		}
+	}
+	if waiting == nil {
+		t.Fatal("task never paused for approval")
+	}
+	if p := waiting.PendingApproval; p == nil || p.StepOrder != 2 || p.Keyword != "delete" {
+		t.Errorf("pending approval = %+v, want step 2 matching delete", p)
+	}
+	if len(env.methods("Click")) != 0 {
+		t.Fatal("destructive step ran before approval")
+	}
+
+	if ok, err := env.orch.Approve(ctx, task.ID); !ok || err != nil {
+		t.Fatalf("Approve = %v, %v", ok, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusCompleted || got.PendingApproval != nil {
+		t.Errorf("task = %s, pending %+v, want completed", got.Status, got.PendingApproval)
+	}
+	if click := env.methods("Click"); len(click) != 1 || click[0].Selector != "#delete" {
+		t.Errorf("clicks = %+v, want the approved delete", click)
+	}
+	if ok, _ := env.orch.Approve(ctx, task.ID); ok {
+		t.Error("Approve succeeded for a task that is not waiting")
+	}
+}
+
+func TestContinueTaskThenIdleClose(t *testing.T) {
+	env := newTestEnv(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Go to the next page"}))
+	task := env.newTask(t, func(task *domain.Task) { task.KeepAlive = 1 })
+	ctx := context.Background()
+	if err := env.orch.ExecuteTask(ctx, task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if !env.orch.HasLiveSession(task.ID) || len(env.methods("Close")) != 0 {
+		t.Fatal("browser session not kept alive")
+	}
+
+	if err := env.orch.ContinueTask(ctx, task, "open the next page again"); err != nil {
+		t.Fatalf("ContinueTask: %v", err)
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusCompleted || len(got.Result.Steps) != 2 || got.Result.Steps[1].Order != 2 {
+		t.Fatalf("task = %s with %d steps, want completed with the follow-up step appended", got.Status, len(got.Result.Steps))
+	}
+	if len(got.Plan.Steps) != 2 {
+		t.Errorf("plan steps = %d, want 2", len(got.Plan.Steps))
+	}
+
+	// 空闲计时在追加指令结束后重新开始，超时后关闭浏览器
+	for start := time.Now(); env.orch.HasLiveSession(task.ID); time.Sleep(20 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("session not closed after the idle timeout")
+		}
+	}
+	if len(env.methods("Close")) != 1 {
+		t.Error("browser not closed on idle timeout")
+	}
+	if err := env.orch.ContinueTask(ctx, task, "too late"); !errors.Is(err, ErrSessionNotAlive) {
+		t.Errorf("ContinueTask after idle close err = %v, want ErrSessionNotAlive", err)
+	}
+}
+
+func TestScreenshotFormats(t *testing.T) {
+	for _, format := range []domain.ScreenshotFormat{domain.ScreenshotFormatPNG, domain.ScreenshotFormatJPEG, domain.ScreenshotFormatWebP} {
+		t.Run(string(format), func(t *testing.T) {
+			env := newTestEnv(t, planReply(planner.ActionStep{Action: browser.ActionScreenshot, Screenshot: true, Description: "Capture the page"}))
+			task := env.newTask(t, func(task *domain.Task) {
+				task.Output.ScreenshotConfig.Format = format
+				task.Output.ScreenshotConfig.Quality = 70
+			})
+			if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+				t.Fatalf("ExecuteTask: %v", err)
+			}
+
+			shots := env.ctrl.ScreenshotRequests()
+			if len(shots) != 1 || shots[0].Type != string(format) || shots[0].Quality != 70 {
+				t.Fatalf("screenshot requests = %+v, want one %s at quality 70", shots, format)
+			}
+			got := env.stored(t, task.ID)
+			if len(got.Result.Screenshots) != 1 || got.Result.Screenshots[0].Format != format {
+				t.Errorf("stored screenshots = %+v, want format %s", got.Result.Screenshots, format)
+			}
+			ref := "screenshots/step_1." + format.Extension()
+			if len(got.Result.Documents) != 1 || !strings.Contains(got.Result.Documents[0].Content, ref) {
+				t.Errorf("document does not reference %s", ref)
+			}
+		})
+	}
+}
+
+// linkSite 点击链接后跳转到对应页面的站点
+type linkSite struct {
+	*browser.FakeController
+	links map[string]string
+}
+
+func (s *linkSite) Click(ctx context.Context, selector string) error {
+	if err := s.FakeController.Click(ctx, selector); err != nil {
+		return err
+	}
+	if url, ok := s.links[selector]; ok {
+		return s.Navigate(ctx, url)
+	}
+	return nil
+}
+
+func TestNavigatingClickWaitsForNavigation(t *testing.T) {
+	site := &linkSite{
+		FakeController: browser.NewFakeController(),
+		links:          map[string]string{"a#orders": "https://app.example.com/orders"},
+	}
+	llm := newTestLLM(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "a#orders", Description: "Open the orders page"},
+		planner.ActionStep{Action: browser.ActionClick, Target: "#refresh", Description: "Refresh the list"},
+	))
+	store := storage.NewMemoryTaskStore()
+	env := &testEnv{orch: NewOrchestrator(site, store, planner.NewLLMClientFactory()), ctrl: site.FakeController, store: store, llm: llm}
+	task := env.newTask(t, nil)
+
+	start := time.Now()
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > navigationStartTimeout {
+		t.Errorf("task took %s, navigation wait did not end when the URL changed", elapsed)
+	}
+
+	// 只有链接点击（规划器推断为 navigates_away）之后等待导航
+	var sequence []string
+	for _, a := range env.ctrl.Actions() {
+		switch a.Method {
+		case "Click", "WaitForNavigation":
+			sequence = append(sequence, a.Method+" "+a.Selector)
+		}
+	}
+	want := []string{"Click a#orders", "WaitForNavigation ", "Click #refresh"}
+	if strings.Join(sequence, ", ") != strings.Join(want, ", ") {
+		t.Errorf("sequence = %q, want %q", sequence, want)
+	}
+	if got := env.stored(t, task.ID); !got.Plan.Steps[0].NavigatesAway || got.Plan.Steps[1].NavigatesAway {
+		t.Errorf("navigates_away = %v, %v, want true, false", got.Plan.Steps[0].NavigatesAway, got.Plan.Steps[1].NavigatesAway)
+	}
+}
+
+func TestDocumentsWrittenToDocumentStore(t *testing.T) {
+	env := newTestEnv(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#start", Description: "Open the form"}))
+	docs := storage.NewFileDocumentStore(t.TempDir())
+	env.orch.SetDocumentStore(docs)
+	task := env.newTask(t, nil)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	got := env.stored(t, task.ID)
+	if len(got.Result.Documents) != 1 {
+		t.Fatalf("documents = %d, want 1", len(got.Result.Documents))
+	}
+	doc := got.Result.Documents[0]
+	if doc.Content != "" || doc.URL != "/api/v1/tasks/"+task.ID+"/documents/"+doc.ID {
+		t.Errorf("document = content %d bytes, url %q, want content only in the store", len(doc.Content), doc.URL)
+	}
+	f, err := docs.Open(context.Background(), task.ID, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	if int64(len(data)) != doc.Size || !strings.Contains(string(data), "Open the form") {
+		t.Errorf("stored document = %d bytes, size %d", len(data), doc.Size)
+	}
+}
+
+// flakySite 前 failures 次导航失败的站点
+type flakySite struct {
+	*browser.FakeController
+	mu       sync.Mutex
+	failures int
+}
+
+func (s *flakySite) Navigate(ctx context.Context, url string) error {
+	s.mu.Lock()
+	fail := s.failures > 0
+	s.failures--
+	s.mu.Unlock()
+	if fail {
+		s.FakeController.Navigate(ctx, url)
+		return errors.New("net::ERR_NAME_NOT_RESOLVED")
+	}
+	return s.FakeController.Navigate(ctx, url)
+}
+
+func TestInitialNavigationRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		retries     int
+		wantErr     bool
+		wantNavs    int
+		wantNewPage int
+	}{
+		{"fails twice then succeeds", 2, 0, false, 3, 0},
+		{"recreates the page after retries", 3, 0, false, 4, 1},
+		{"gives up after the page recreation", 10, 1, true, 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &flakySite{FakeController: browser.NewFakeController(), failures: tt.failures}
+			llm := newTestLLM(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#start", Description: "Start"}))
+			store := storage.NewMemoryTaskStore()
+			env := &testEnv{orch: NewOrchestrator(site, store, planner.NewLLMClientFactory()), ctrl: site.FakeController, store: store, llm: llm}
+			task := env.newTask(t, func(task *domain.Task) { task.NavigationRetries = tt.retries })
+
+			err := env.orch.ExecuteTask(context.Background(), task)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteTask err = %v, want error %v", err, tt.wantErr)
+			}
+			if n := len(env.methods("Navigate")); n != tt.wantNavs {
+				t.Errorf("navigations = %d, want %d", n, tt.wantNavs)
+			}
+			if n := len(env.methods("NewPage")); n != tt.wantNewPage {
+				t.Errorf("page recreations = %d, want %d", n, tt.wantNewPage)
+			}
+			got := env.stored(t, task.ID)
+			if tt.wantErr && got.ErrorCode != domain.ErrorCodeNavigation {
+				t.Errorf("error code = %s, want navigation", got.ErrorCode)
+			}
+			if !tt.wantErr && got.Status != domain.TaskStatusCompleted {
+				t.Errorf("status = %s, want completed", got.Status)
+			}
+		})
+	}
+}
+
+func TestWaitStepPollsCondition(t *testing.T) {
+	const expr = `document.querySelectorAll('.cart-item').length === 3`
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "#add", Description: "Add the item"},
+		planner.ActionStep{Action: browser.ActionWait, WaitForJS: expr, Description: "Wait for three items in the cart"},
+		planner.ActionStep{Action: browser.ActionClick, Target: "#checkout", Description: "Check out"},
+	))
+	task := env.newTask(t, nil)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	var polled bool
+	for _, a := range env.methods("WaitForCondition") {
+		polled = polled || a.Selector == expr
+	}
+	if !polled {
+		t.Errorf("WaitForCondition not called with %q", expr)
+	}
+	if got := env.stored(t, task.ID); got.Plan.Steps[1].WaitForJS != expr {
+		t.Errorf("stored plan wait_for_js = %q", got.Plan.Steps[1].WaitForJS)
+	}
+
+	// 条件超时时该步骤失败
+	wait := planner.ActionStep{Action: browser.ActionWait, WaitForJS: expr, Description: "Wait"}
+	plan := planReply(wait, planner.ActionStep{Action: browser.ActionClick, Target: "#checkout", Description: "Check out"})
+	env = newTestEnv(t, func(prompt string) string {
+		// 优化失败步骤时模型返回原步骤
+		if strings.Contains(prompt, "优化后的步骤") {
+			step, _ := json.Marshal(wait)
+			return string(step)
+		}
+		return plan(prompt)
+	})
+	env.ctrl.Errors["WaitForCondition"] = errors.New("timeout 10000ms exceeded")
+	task = env.newTask(t, nil)
+	env.orch.ExecuteTask(context.Background(), task)
+	if got := env.stored(t, task.ID); got.Result == nil || len(got.Result.Steps) == 0 || got.Result.Steps[0].Success {
+		t.Error("wait step succeeded although the condition timed out")
+	}
+}
+
+// panicSite 点击指定选择器时 panic 的页面
+type panicSite struct {
+	*browser.FakeController
+	target string
+}
+
+func (s *panicSite) Click(ctx context.Context, selector string) error {
+	if selector == s.target {
+		panic("nil snapshot")
+	}
+	return s.FakeController.Click(ctx, selector)
+}
+
+func TestPanicFailsTaskAndWorkerKeepsRunning(t *testing.T) {
+	site := &panicSite{FakeController: browser.NewFakeController(), target: "#boom"}
+	store := storage.NewMemoryTaskStore()
+	env := &testEnv{
+		orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+		ctrl:  site.FakeController,
+		store: store,
+		llm: newTestLLM(t, func(prompt string) string {
+			target := "#ok"
+			if strings.Contains(prompt, "explode") {
+				target = "#boom"
+			}
+			return planReply(planner.ActionStep{Action: browser.ActionClick, Target: target, Description: "Click"})(prompt)
+		}),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	env.orch.Start(ctx, 1)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer waitCancel()
+	bad := env.newTask(t, func(task *domain.Task) { task.Description = "explode" })
+	env.orch.Submit(bad)
+	if err := env.orch.Wait(waitCtx, bad.ID); err != nil {
+		t.Fatal(err)
+	}
+	got := env.stored(t, bad.ID)
+	if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodeInternal {
+		t.Errorf("status = %s/%s, want failed/internal", got.Status, got.ErrorCode)
+	}
+	if !strings.Contains(got.ErrorMessage, "nil snapshot") || got.ErrorStack == "" {
+		t.Errorf("error = %q, stack stored %v", got.ErrorMessage, got.ErrorStack != "")
+	}
+	if len(env.methods("Close")) == 0 {
+		t.Error("browser not closed after the panic")
+	}
+
+	// 同一 worker 继续执行后续任务
+	good := env.newTask(t, nil)
+	env.orch.Submit(good)
+	if err := env.orch.Wait(waitCtx, good.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.stored(t, good.ID); got.Status != domain.TaskStatusCompleted {
+		t.Errorf("next task status = %s, want completed", got.Status)
+	}
+}
+
+func TestConnectUsesTaskLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*domain.Task)
+		want   string
+	}{
+		{"output language", func(task *domain.Task) { task.Output.Language = "zh" }, "zh-CN"},
+		{"explicit locale", func(task *domain.Task) {
+			task.Output.Language = "zh"
+			task.Locale = "ja-JP"
+		}, "ja-JP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"}))
+			task := env.newTask(t, tt.modify)
+			if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+				t.Fatalf("ExecuteTask: %v", err)
+			}
+			if connects := env.methods("Connect"); len(connects) == 0 || connects[0].Value != tt.want {
+				t.Errorf("Connect calls = %+v, want locale %q", connects, tt.want)
+			}
+		})
+	}
+}
+
+// pausingSite 点击 target 时暂停，直到测试放行
+type pausingSite struct {
+	*browser.FakeController
+	target  string
+	reached chan struct{}
+	release chan struct{}
+}
+
+func (s *pausingSite) Click(ctx context.Context, selector string) error {
+	if selector == s.target {
+		close(s.reached)
+		<-s.release
+	}
+	return s.FakeController.Click(ctx, selector)
+}
+
+func TestStepResultsPersistedDuringExecution(t *testing.T) {
+	site := &pausingSite{
+		FakeController: browser.NewFakeController(),
+		target:         "#step3",
+		reached:        make(chan struct{}),
+		release:        make(chan struct{}),
+	}
+	store := storage.NewMemoryTaskStore()
+	env := &testEnv{
+		orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+		ctrl:  site.FakeController,
+		store: store,
+		llm: newTestLLM(t, planReply(
+			planner.ActionStep{Action: browser.ActionClick, Target: "#step1", Description: "Step 1"},
+			planner.ActionStep{Action: browser.ActionClick, Target: "#step2", Description: "Step 2"},
+			planner.ActionStep{Action: browser.ActionClick, Target: "#step3", Description: "Step 3"},
+		)),
+	}
+	task := env.newTask(t, nil)
+	done := make(chan error, 1)
+	go func() { done <- env.orch.ExecuteTask(context.Background(), task) }()
+
+	select {
+	case <-site.reached:
+	case <-time.After(5 * time.Second):
+		close(site.release)
+		t.Fatal("step 3 not reached")
+	}
+	mid := env.stored(t, task.ID)
+	close(site.release)
+	if err := <-done; err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	if mid.Status != domain.TaskStatusRunning {
+		t.Errorf("status mid-run = %s, want running", mid.Status)
+	}
+	if mid.Result == nil || len(mid.Result.Steps) != 2 || !mid.Result.Steps[0].Success || !mid.Result.Steps[1].Success {
+		t.Fatalf("result mid-run = %+v, want two finished steps", mid.Result)
+	}
+	if mid.Progress <= 0 || mid.Progress >= 100 {
+		t.Errorf("progress mid-run = %d", mid.Progress)
+	}
+	if got := env.stored(t, task.ID); len(got.Result.Steps) != 3 {
+		t.Errorf("final result has %d steps, want 3", len(got.Result.Steps))
+	}
+}
+
+func TestClickStepValueOptions(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "text=Submit", Value: "exact,nth=2", Description: "Submit"},
+		planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"},
+	))
+	task := env.newTask(t, nil)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	byText := env.methods("ClickByText")
+	if len(byText) != 1 || byText[0].Selector != "text=Submit" || byText[0].Value != "exact=true,nth=2" {
+		t.Errorf("ClickByText calls = %+v", byText)
+	}
+	if clicks := env.methods("Click"); len(clicks) != 1 || clicks[0].Selector != "#next" {
+		t.Errorf("Click calls = %+v", clicks)
+	}
+}
+
+func TestExtractedDataInResultAndDocs(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionExtract, Target: "#price", Value: "price", Description: "Read the price"},
+		planner.ActionStep{Action: browser.ActionExtract, Target: "#stock", Value: "stock", Description: "Read the stock"},
+	))
+	env.ctrl.Texts["#price"] = "¥99.00"
+	env.ctrl.Texts["#stock"] = "12 left"
+	task := env.newTask(t, nil)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	got := env.stored(t, task.ID)
+	if got.Result.Data["price"] != "¥99.00" || got.Result.Data["stock"] != "12 left" {
+		t.Errorf("result data = %v", got.Result.Data)
+	}
+	var markdown string
+	for _, doc := range got.Result.Documents {
+		if doc.Format == domain.DocFormatMarkdown {
+			markdown = doc.Content
+		}
+	}
+	for _, want := range []string{"| price | ¥99.00 |", "| stock | 12 left |"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+}
+
+// droppingSite 第 drop 次检查连接时模拟浏览器断开：recover 为 true 时重连成功（页面丢失），否则重连失败
+type droppingSite struct {
+	*linkSite
+	drop    int
+	recover bool
+	checks  int
+}
+
+func (s *droppingSite) EnsureConnected(ctx context.Context) (bool, error) {
+	s.checks++
+	if s.checks != s.drop {
+		return s.FakeController.EnsureConnected(ctx)
+	}
+	if !s.recover {
+		return false, errors.New("browser disconnected: reconnect failed after 3 attempts")
+	}
+	return true, s.FakeController.Navigate(ctx, "about:blank")
+}
+
+func TestBrowserDisconnectRecovery(t *testing.T) {
+	const page2 = "https://app.example.com/form/page2"
+	for _, recover := range []bool{true, false} {
+		site := &droppingSite{
+			linkSite: &linkSite{FakeController: browser.NewFakeController(), links: map[string]string{"#next": page2}},
+			drop:     2, // 第二个步骤开始前
+			recover:  recover,
+		}
+		store := storage.NewMemoryTaskStore()
+		env := &testEnv{
+			orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+			ctrl:  site.FakeController,
+			store: store,
+			llm: newTestLLM(t, planReply(
+				planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next page"},
+				planner.ActionStep{Action: browser.ActionClick, Target: "#save", Description: "Save"},
+			)),
+		}
+		task := env.newTask(t, nil)
+		err := env.orch.ExecuteTask(context.Background(), task)
+		got := env.stored(t, task.ID)
+
+		var saved bool
+		for _, click := range env.methods("Click") {
+			saved = saved || click.Selector == "#save"
+		}
+		if !recover {
+			if err == nil || got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodeBrowser {
+				t.Errorf("failed reconnect: err %v, status %s/%s; want failed/browser", err, got.Status, got.ErrorCode)
+			}
+			if saved {
+				t.Error("step executed after the browser could not be recovered")
+			}
+			continue
+		}
+
+		if err != nil || got.Status != domain.TaskStatusCompleted || !saved {
+			t.Fatalf("recovered: err %v, status %s, saved %v", err, got.Status, saved)
+		}
+		// 重连后先回到目标页面，再恢复到断开前的页面
+		var navigated []string
+		for _, nav := range env.methods("Navigate") {
+			navigated = append(navigated, nav.Selector)
+		}
+		want := []string{task.TargetURL, page2, "about:blank", task.TargetURL, page2}
+		if strings.Join(navigated, " ") != strings.Join(want, " ") {
+			t.Errorf("navigations = %v, want %v", navigated, want)
+		}
+	}
+}
+
+func TestStepFullPageOverridesConfig(t *testing.T) {
+	for _, fullPage := range []bool{false, true} {
+		env := newTestEnv(t, planReply(
+			planner.ActionStep{Action: browser.ActionScreenshot, Description: "Overview", FullPage: boolPtr(true)},
+			planner.ActionStep{Action: browser.ActionScreenshot, Description: "Detail", FullPage: boolPtr(false)},
+			planner.ActionStep{Action: browser.ActionScreenshot, Description: "Default"},
+		))
+		task := env.newTask(t, func(task *domain.Task) { task.Output.ScreenshotConfig.FullPage = fullPage })
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+
+		var got []bool
+		for _, opts := range env.ctrl.ScreenshotRequests() {
+			got = append(got, opts.FullPage)
+		}
+		if want := []bool{true, false, fullPage}; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("config full_page %v: screenshots full_page = %v, want %v", fullPage, got, want)
+		}
+	}
+}
+
+func TestDismissOverlaysBeforeSnapshotAndSteps(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		env := newTestEnv(t, planReply(
+			planner.ActionStep{Action: browser.ActionClick, Target: "#a", Description: "A"},
+			planner.ActionStep{Action: browser.ActionClick, Target: "#b", Description: "B"},
+		))
+		task := env.newTask(t, func(task *domain.Task) { task.DismissOverlays = enabled })
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+
+		var calls []string
+		for _, a := range env.ctrl.Actions() {
+			switch a.Method {
+			case "DismissOverlays", "TakeSnapshot", "Click":
+				calls = append(calls, a.Method)
+			}
+		}
+		want := "TakeSnapshot Click TakeSnapshot Click TakeSnapshot"
+		if enabled {
+			want = "DismissOverlays TakeSnapshot DismissOverlays Click TakeSnapshot DismissOverlays Click TakeSnapshot"
+		}
+		if got := strings.Join(calls, " "); got != want {
+			t.Errorf("dismiss_overlays %v: calls = %s, want %s", enabled, got, want)
+		}
+	}
+}
+
+func TestServerCredentialsPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.yaml")
+	os.WriteFile(path, []byte("example.com:\n  username: docs-bot\n  password: from-file\n"), 0o600)
+	provider, err := auth.LoadCredentialFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		provider     auth.CredentialProvider
+		credentials  *domain.Credentials
+		wantPassword string
+		wantErr      bool
+	}{
+		{"resolved from the secrets file", provider, nil, "from-file", false},
+		{"empty request credentials", provider, &domain.Credentials{}, "from-file", false},
+		{"request overrides the file", provider, &domain.Credentials{Username: "alice", Password: "from-request"}, "from-request", false},
+		{"no provider configured", nil, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &loginSite{
+				FakeController: browser.NewFakeController(),
+				cookies:        []domain.Cookie{{Name: "session", Value: "s", Domain: "app.example.com", Path: "/"}},
+			}
+			store := storage.NewMemoryTaskStore()
+			env := &testEnv{
+				orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+				ctrl:  site.FakeController,
+				store: store,
+				llm:   newTestLLM(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"})),
+			}
+			if tt.provider != nil {
+				env.orch.SetCredentialProvider(tt.provider)
+			}
+			task := env.newTask(t, func(task *domain.Task) {
+				task.Auth = &domain.AuthConfig{Type: domain.AuthTypeForm, Credentials: tt.credentials}
+			})
+			env.orch.ExecuteTask(context.Background(), task)
+
+			got := env.stored(t, task.ID)
+			if tt.wantErr {
+				if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodeAuth {
+					t.Errorf("status %s code %s, want failed auth", got.Status, got.ErrorCode)
+				}
+				return
+			}
+			if got.Status != domain.TaskStatusCompleted {
+				t.Fatalf("status = %s: %s", got.Status, got.ErrorMessage)
+			}
+			var password string
+			for _, fill := range env.methods("Fill") {
+				if fill.Selector == "input[type='password']" {
+					password = fill.Value
+				}
+			}
+			if password != tt.wantPassword {
+				t.Errorf("password filled = %q, want %q", password, tt.wantPassword)
+			}
+			// 服务端凭据不写回任务
+			if c := got.Auth.Credentials; c != nil && c.Password == "from-file" {
+				t.Error("server-side password stored on the task")
+			}
+		})
+	}
+}
+
+func TestSwitchFrameSteps(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionSwitchFrame, Target: "payment", Description: "Enter the payment form"},
+		planner.ActionStep{Action: browser.ActionFill, Target: "#card", Value: "4242", Description: "Card number"},
+		planner.ActionStep{Action: browser.ActionSwitchMainFrame, Description: "Back to the page"},
+		planner.ActionStep{Action: browser.ActionClick, Target: "#confirm", Description: "Confirm"},
+	))
+	task := env.newTask(t, nil)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	var calls []string
+	for _, a := range env.ctrl.Actions() {
+		switch a.Method {
+		case "SwitchToFrame", "SwitchToMainFrame", "Fill", "Click":
+			calls = append(calls, strings.TrimSpace(a.Method+" "+a.Selector))
+		}
+	}
+	want := "SwitchToFrame payment, Fill #card, SwitchToMainFrame, Click #confirm"
+	if got := strings.Join(calls, ", "); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+	if frames := env.ctrl.ActiveFrame(); len(frames) != 0 {
+		t.Errorf("active frame after switching back = %v", frames)
+	}
+	for _, step := range env.stored(t, task.ID).Result.Steps {
+		if !step.Success {
+			t.Errorf("step failed: %+v", step)
+		}
+	}
+}
+
+func TestPlanOutputStoredOnPlanningFailure(t *testing.T) {
+	env := newTestEnv(t, func(string) string { return "I cannot help with that." })
+	task := env.newTask(t, nil)
+	env.orch.ExecuteTask(context.Background(), task)
+
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodePlanning {
+		t.Fatalf("status %s code %s, want failed planning", got.Status, got.ErrorCode)
+	}
+	if got.PlanOutput != "I cannot help with that." || !strings.Contains(got.ErrorMessage, "I cannot help with that.") {
+		t.Errorf("plan_output %q, error_message %q", got.PlanOutput, got.ErrorMessage)
+	}
+}
+
+func TestPlanningPromptFollowsOutputLanguage(t *testing.T) {
+	for _, language := range []string{"en", "zh"} {
+		env := newTestEnv(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#start", Description: "Start"}))
+		task := env.newTask(t, func(task *domain.Task) { task.Output.Language = language })
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+		want := map[string]string{"en": "## User task", "zh": "## 用户任务"}[language]
+		if n := env.llm.calls(want); n != 1 {
+			t.Errorf("%s task: planning prompts containing %q = %d, want 1", language, want, n)
+		}
+	}
+}
+
+func TestStepFailurePolicy(t *testing.T) {
+	click := planner.ActionStep{Action: browser.ActionClick, Target: "#submit", Description: "Submit"}
+	fill := planner.ActionStep{Action: browser.ActionFill, Target: "#note", Value: "done", Description: "Add a note"}
+	shot := planner.ActionStep{Action: browser.ActionScreenshot, Description: "Capture the result"}
+	plan := planReply(click, fill, shot)
+	// 优化失败步骤时模型返回原步骤，使注入的错误保持失败
+	respond := func(prompt string) string {
+		if strings.Contains(prompt, "优化后的步骤") {
+			step, _ := json.Marshal(click)
+			return string(step)
+		}
+		return plan(prompt)
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		env := newTestEnv(t, respond)
+		env.ctrl.Errors["Click"] = errors.New("element not found")
+		task := env.newTask(t, func(task *domain.Task) { task.OnStepFailure = domain.StepFailureStop })
+
+		if err := env.orch.ExecuteTask(context.Background(), task); !errors.Is(err, ErrStepExecution) {
+			t.Fatalf("err = %v, want errors.Is ErrStepExecution", err)
+		}
+		got := env.stored(t, task.ID)
+		if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodeStepExecution {
+			t.Errorf("status = %s/%s, want failed/%s", got.Status, got.ErrorCode, domain.ErrorCodeStepExecution)
+		}
+		if len(env.methods("Fill")) != 0 || len(env.ctrl.ScreenshotRequests()) != 0 {
+			t.Error("steps after the failed step were executed")
+		}
+		steps := got.Result.Steps
+		if len(steps) != 3 || steps[0].Success || steps[0].Skipped || !steps[1].Skipped || !steps[2].Skipped {
+			t.Errorf("steps = %+v, want the failed step then two skipped steps", steps)
+		}
+	})
+
+	t.Run("continue", func(t *testing.T) {
+		env := newTestEnv(t, respond)
+		env.ctrl.Errors["Click"] = errors.New("element not found")
+		task := env.newTask(t, func(task *domain.Task) { task.OnStepFailure = domain.StepFailureContinue })
+
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+		got := env.stored(t, task.ID)
+		if got.Status != domain.TaskStatusCompleted {
+			t.Errorf("status = %s, want completed", got.Status)
+		}
+		if fills := env.methods("Fill"); len(fills) != 1 || fills[0].Selector != "#note" {
+			t.Errorf("fills = %+v, want the step after the failure executed", fills)
+		}
+		if len(env.ctrl.ScreenshotRequests()) != 1 {
+			t.Error("screenshot step after the failure not executed")
+		}
+		steps := got.Result.Steps
+		if len(steps) != 3 || steps[0].Success || !steps[1].Success || !steps[2].Success {
+			t.Fatalf("steps = %+v, want the first step failed and the rest succeeded", steps)
+		}
+		for _, s := range steps {
+			if s.Skipped {
+				t.Errorf("step %d skipped under the continue policy", s.Order)
+			}
+		}
+	})
+}
+
+func TestRefinedStepKeepsPlanOrder(t *testing.T) {
+	plan := planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "#menu-old", Description: "Open the menu"},
+		planner.ActionStep{Action: browser.ActionFill, Target: "#name", Value: "Alice", Description: "Enter the name"},
+	)
+	env := newTestEnv(t, func(prompt string) string {
+		// 优化结果带有错误的序号且缺少说明
+		if strings.Contains(prompt, "优化后的步骤") {
+			return `{"order": 7, "action": "hover", "target": "#menu"}`
+		}
+		return plan(prompt)
+	})
+	env.ctrl.Errors["Click"] = errors.New("element not found")
+	task := env.newTask(t, nil)
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	steps := env.stored(t, task.ID).Result.Steps
+	if len(steps) != 2 {
+		t.Fatalf("steps = %d, want 2", len(steps))
+	}
+	if s := steps[0]; s.Order != 1 || s.Action != string(browser.ActionHover) || s.Description != "Open the menu" || !s.Success {
+		t.Errorf("refined step = %+v, want order 1, the executed hover and the planned description", s)
+	}
+	if s := steps[1]; s.Order != 2 || s.Action != string(browser.ActionFill) || s.Description != "Enter the name" {
+		t.Errorf("second step = %+v, want order 2 fill", s)
+	}
+}
+
+func TestWaitForAnyRecordsBranch(t *testing.T) {
+	wait := planner.ActionStep{Action: browser.ActionWait, WaitFor: ".toast-success | .error-banner", Description: "Wait for the result"}
+	tests := []struct {
+		name    string
+		visible map[string]bool
+		want    string
+		success bool
+	}{
+		{"success toast", map[string]bool{".toast-success": true}, ".toast-success", true},
+		{"error banner", map[string]bool{".error-banner": true}, ".error-banner", true},
+		{"neither", map[string]bool{"#other": true}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planReply(wait)
+			env := newTestEnv(t, func(prompt string) string {
+				// 优化结果仍是同一个等待步骤
+				if strings.Contains(prompt, "优化后的步骤") {
+					step, _ := json.Marshal(wait)
+					return string(step)
+				}
+				return plan(prompt)
+			})
+			env.ctrl.Visible = tt.visible
+			task := env.newTask(t, nil)
+
+			env.orch.ExecuteTask(context.Background(), task)
+			calls := env.methods("WaitForAny")
+			if len(calls) == 0 {
+				t.Fatal("WaitForAny not called")
+			}
+			for _, call := range calls {
+				if call.Selector != ".toast-success | .error-banner" {
+					t.Errorf("WaitForAny(%s), want both candidates in one call", call.Selector)
+				}
+			}
+			if len(env.methods("WaitForSelector")) != 0 {
+				t.Error("alternatives passed to WaitForSelector")
+			}
+			s := env.stored(t, task.ID).Result.Steps[0]
+			if s.Success != tt.success || s.Selector != tt.want {
+				t.Errorf("step = %+v, want success %v with selector %q", s, tt.success, tt.want)
+			}
+		})
+	}
+}
+
+// cookieSpy 每次点击时记录当前上下文中的 Cookie 名称
+type cookieSpy struct {
+	*browser.FakeController
+	seen [][]string
+}
+
+func (s *cookieSpy) Click(ctx context.Context, selector string) error {
+	cookies, _ := s.GetCookies(ctx)
+	var names []string
+	for _, c := range cookies {
+		names = append(names, c.Name)
+	}
+	s.seen = append(s.seen, names)
+	return s.FakeController.Click(ctx, selector)
+}
+
+func TestEachTaskGetsFreshBrowserContext(t *testing.T) {
+	site := &cookieSpy{FakeController: browser.NewFakeController()}
+	store := storage.NewMemoryTaskStore()
+	env := &testEnv{
+		orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+		ctrl:  site.FakeController,
+		store: store,
+		llm:   newTestLLM(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#go", Description: "Go"})),
+	}
+	withCookie := env.newTask(t, func(task *domain.Task) {
+		task.Auth = &domain.AuthConfig{Type: domain.AuthTypeCookie, Cookies: []domain.Cookie{
+			{Name: "session", Value: "alice", Domain: "app.example.com", Path: "/"},
+		}}
+	})
+	anonymous := env.newTask(t, nil)
+
+	for _, task := range []*domain.Task{withCookie, anonymous} {
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+	}
+	if len(site.seen) != 2 || strings.Join(site.seen[0], ",") != "session" || len(site.seen[1]) != 0 {
+		t.Errorf("cookies seen per task = %q, want the session cookie only in the first task", site.seen)
+	}
+
+	// 每个任务各自连接（新建上下文）并在结束时关闭，上下文不跨任务存活
+	var lifecycle []string
+	for _, a := range env.ctrl.Actions() {
+		if a.Method == "Connect" || a.Method == "Close" {
+			lifecycle = append(lifecycle, a.Method)
+		}
+	}
+	if got := strings.Join(lifecycle, ","); got != "Connect,Close,Connect,Close" {
+		t.Errorf("context lifecycle = %s, want one connect and close per task", got)
+	}
+}
+
+// framedSite 目标按钮只在 #checkout iframe 内，不带 frame: 前缀的点击找不到元素
+type framedSite struct {
+	*browser.FakeController
+}
+
+func (s *framedSite) Click(ctx context.Context, selector string) error {
+	if !strings.HasPrefix(selector, browser.FrameSelectorPrefix) {
+		return errors.New("element not found: " + selector)
+	}
+	return s.FakeController.Click(ctx, selector)
+}
+
+func TestRefineIntoFrameRecordsSelector(t *testing.T) {
+	const framed = "frame:#checkout >> #pay"
+	plan := planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#pay", Description: "Pay now"})
+	site := &framedSite{FakeController: browser.NewFakeController()}
+	store := storage.NewMemoryTaskStore()
+	env := &testEnv{
+		orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+		ctrl:  site.FakeController,
+		store: store,
+		llm: newTestLLM(t, func(prompt string) string {
+			if strings.Contains(prompt, "优化后的步骤") {
+				return `{"order": 1, "action": "click", "target": "` + framed + `", "description": "Pay now"}`
+			}
+			return plan(prompt)
+		}),
+	}
+	task := env.newTask(t, func(task *domain.Task) {
+		task.Output.ContentConfig.Verbosity = domain.VerbosityDetailed
+	})
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	got := env.stored(t, task.ID)
+	if s := got.Result.Steps[0]; !s.Success || s.Selector != framed {
+		t.Errorf("step = %+v, want the frame-scoped selector recorded", s)
+	}
+	if clicks := env.methods("Click"); len(clicks) != 1 || clicks[0].Selector != framed {
+		t.Errorf("clicks = %+v, want one click inside the frame", clicks)
+	}
+	if doc := got.Result.Documents[0].Content; !strings.Contains(doc, "`"+framed+"`") {
+		t.Errorf("document does not show the selector used:\n%s", doc)
+	}
+}
+
+// hangingSite 点击一直阻塞到 ctx 结束
+type hangingSite struct {
+	*browser.FakeController
+}
+
+func (s *hangingSite) Click(ctx context.Context, selector string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTaskDeadlineFailsWithTimeout(t *testing.T) {
+	site := &hangingSite{FakeController: browser.NewFakeController()}
+	store := storage.NewMemoryTaskStore()
+	env := &testEnv{
+		orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+		ctrl:  site.FakeController,
+		store: store,
+		llm:   newTestLLM(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#slow", Description: "Wait forever"})),
+	}
+	task := env.newTask(t, func(task *domain.Task) {
+		task.Deadline = 1
+		task.MaxTaskRetries = 3
+	})
+
+	start := time.Now()
+	if err := env.orch.ExecuteTask(context.Background(), task); err == nil {
+		t.Fatal("ExecuteTask succeeded, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("task ran for %s, not bounded by the 1s deadline", elapsed)
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodeTimeout {
+		t.Errorf("task = %s %s, want failed with timeout", got.Status, got.ErrorCode)
+	}
+	if !strings.Contains(got.ErrorMessage, "deadline") {
+		t.Errorf("error message %q does not mention the deadline", got.ErrorMessage)
+	}
+}
+
+func TestTaskDeadlineInterruptsWaits(t *testing.T) {
+	click := planner.ActionStep{Action: browser.ActionClick, Target: "#go", Description: "Go"}
+	// 首尾的无条件 wait 步骤会在规范化时被去掉，放在两次点击之间
+	bare := []planner.ActionStep{click, {Action: browser.ActionWait, Description: "Wait a moment"}, click}
+	tests := []struct {
+		name  string
+		wait  *time.Duration
+		steps []planner.ActionStep
+	}{
+		{"page load", &pageLoadWait, []planner.ActionStep{click}},
+		{"bare wait step", &bareWaitStep, bare},
+		{"action settle", &actionSettle, []planner.ActionStep{click}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 等待远长于 1 秒的时限，到期后应立即结束而不是等满
+			saved := *tt.wait
+			*tt.wait = time.Minute
+			t.Cleanup(func() { *tt.wait = saved })
+
+			env := newTestEnv(t, planReply(tt.steps...))
+			task := env.newTask(t, func(task *domain.Task) { task.Deadline = 1 })
+
+			start := time.Now()
+			if err := env.orch.ExecuteTask(context.Background(), task); err == nil {
+				t.Fatal("ExecuteTask succeeded, want a timeout")
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("task ran for %s, wait not bounded by the 1s deadline", elapsed)
+			}
+			got := env.stored(t, task.ID)
+			if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodeTimeout {
+				t.Errorf("task = %s %s, want failed with timeout", got.Status, got.ErrorCode)
+			}
+		})
+	}
+}
+
+// redirectingLoginSite 提交登录表单时写入会话 Cookie，redirect 不为空时随后跳转到该地址
+type redirectingLoginSite struct {
+	*browser.FakeController
+	redirect string
+}
+
+func (s *redirectingLoginSite) Click(ctx context.Context, selector string) error {
+	if err := s.FakeController.Click(ctx, selector); err != nil {
+		return err
+	}
+	if selector != "button[type='submit']" {
+		return nil
+	}
+	if err := s.SetCookies(ctx, []domain.Cookie{{Name: "session", Value: "s", Domain: "app.example.com", Path: "/"}}); err != nil {
+		return err
+	}
+	if s.redirect == "" {
+		return nil
+	}
+	return s.FakeController.Navigate(ctx, s.redirect)
+}
+
+func TestNavigationsPerAuthType(t *testing.T) {
+	creds := &domain.Credentials{Username: "alice", Password: "secret"}
+	tests := []struct {
+		name     string
+		auth     *domain.AuthConfig
+		redirect string
+		want     int
+	}{
+		{"no auth config", nil, "", 1},
+		{"none", &domain.AuthConfig{Type: domain.AuthTypeNone}, "", 1},
+		{"cookie", &domain.AuthConfig{Type: domain.AuthTypeCookie, Cookies: []domain.Cookie{
+			{Name: "session", Value: "s", Domain: "app.example.com", Path: "/"},
+		}}, "", 1},
+		{"token", &domain.AuthConfig{Type: domain.AuthTypeToken, Credentials: &domain.Credentials{Token: "t0ken"}}, "", 1},
+		{"basic", &domain.AuthConfig{Type: domain.AuthTypeBasic, Credentials: creds}, "", 1},
+		{"form staying on target", &domain.AuthConfig{Type: domain.AuthTypeForm, Credentials: creds}, "", 1},
+		// 站点登录后跳到首页，再导航回目标页面；中间一次为站点自身的跳转
+		{"form redirecting away", &domain.AuthConfig{Type: domain.AuthTypeForm, Credentials: creds}, "https://app.example.com/home", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := browser.NewFakeController()
+			site := &redirectingLoginSite{FakeController: fake, redirect: tt.redirect}
+			store := storage.NewMemoryTaskStore()
+			env := &testEnv{
+				orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+				ctrl:  fake,
+				store: store,
+				llm:   newTestLLM(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"})),
+			}
+			task := env.newTask(t, func(task *domain.Task) { task.Auth = tt.auth })
+
+			if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+				t.Fatalf("ExecuteTask: %v", err)
+			}
+			nav := env.methods("Navigate")
+			if len(nav) != tt.want {
+				t.Fatalf("navigations = %+v, want %d", nav, tt.want)
+			}
+			if last := nav[len(nav)-1].Selector; last != task.TargetURL {
+				t.Errorf("last navigation = %s, want %s", last, task.TargetURL)
+			}
+		})
+	}
+}
+
+func TestWaitForEnabledBeforeClick(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "#open", Description: "Open the form"},
+		planner.ActionStep{Action: browser.ActionClick, Target: "#submit", Description: "Submit", WaitEnabled: true},
+		planner.ActionStep{Action: browser.ActionWait, WaitFor: "#next", WaitEnabled: true, Description: "Wait for Next"},
+		planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"},
+	))
+	task := env.newTask(t, nil)
+
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	var calls []string
+	for _, a := range env.ctrl.Actions() {
+		switch a.Method {
+		case "Click", "WaitForEnabled", "WaitForSelector":
+			calls = append(calls, a.Method+" "+a.Selector)
+		}
+	}
+	want := "Click #open,WaitForEnabled #submit,Click #submit,WaitForEnabled #next,Click #next"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+}
+
+func TestWaitForEnabledTimeoutFailsStep(t *testing.T) {
+	submit := planner.ActionStep{Action: browser.ActionClick, Target: "#submit", Description: "Submit", WaitEnabled: true}
+	plan := planReply(submit)
+	// 优化失败步骤时模型返回原步骤，按钮仍不可点击
+	env := newTestEnv(t, func(prompt string) string {
+		if strings.Contains(prompt, "优化后的步骤") {
+			step, _ := json.Marshal(submit)
+			return string(step)
+		}
+		return plan(prompt)
+	})
+	env.ctrl.Errors["WaitForEnabled"] = errors.New("timeout 10000ms exceeded")
+	task := env.newTask(t, nil)
+	env.orch.ExecuteTask(context.Background(), task)
+
+	if n := len(env.methods("Click")); n != 0 {
+		t.Errorf("clicks = %d, want none while the target stays disabled", n)
+	}
+	steps := env.stored(t, task.ID).Result.Steps
+	if len(steps) != 1 || steps[0].Success || !strings.Contains(steps[0].Error, "wait for enabled") {
+		t.Errorf("steps = %+v, want a failed step reporting the enabled wait", steps)
+	}
+}
+
+// headerSite 点击时记录目标站点与第三方请求会附加的请求头
+type headerSite struct {
+	*browser.FakeController
+	target, thirdParty map[string]string
+}
+
+func (s *headerSite) Click(ctx context.Context, selector string) error {
+	s.target = s.HeadersFor("https://app.example.com/api/items")
+	s.thirdParty = s.HeadersFor("https://cdn.thirdparty.net/lib.js")
+	return s.FakeController.Click(ctx, selector)
+}
+
+func TestAuthHeadersScopedToTargetOrigin(t *testing.T) {
+	for _, cfg := range []*domain.AuthConfig{
+		{Type: domain.AuthTypeToken, Credentials: &domain.Credentials{Token: "t0ken"}},
+		{Type: domain.AuthTypeBasic, Credentials: &domain.Credentials{Username: "alice", Password: "secret"}},
+	} {
+		site := &headerSite{FakeController: browser.NewFakeController()}
+		store := storage.NewMemoryTaskStore()
+		env := &testEnv{
+			orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+			ctrl:  site.FakeController,
+			store: store,
+			llm:   newTestLLM(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"})),
+		}
+		task := env.newTask(t, func(task *domain.Task) { task.Auth = cfg })
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("%s: ExecuteTask: %v", cfg.Type, err)
+		}
+		if site.target["authorization"] == "" {
+			t.Errorf("%s: target origin headers = %v, want an authorization header", cfg.Type, site.target)
+		}
+		if site.thirdParty != nil {
+			t.Errorf("%s: third-party headers = %v, want none", cfg.Type, site.thirdParty)
+		}
+	}
+}
+
+func TestScreenshotWaitsForAnimationsAndDelay(t *testing.T) {
+	env := newTestEnv(t, planReply(
+		planner.ActionStep{Action: browser.ActionClick, Target: "#open", Description: "Open the dialog"},
+		planner.ActionStep{Action: browser.ActionScreenshot, Description: "Capture the dialog"},
+	))
+	// 动画等待超时不影响截图
+	env.ctrl.Errors["WaitForCondition"] = errors.New("timeout 2000ms exceeded")
+	task := env.newTask(t, func(task *domain.Task) { task.Output.ScreenshotConfig.Delay = 400 })
+
+	start := time.Now()
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("task took %s, want at least the 400ms screenshot delay", elapsed)
+	}
+
+	var calls []string
+	for _, a := range env.ctrl.Actions() {
+		switch a.Method {
+		case "WaitForCondition":
+			if a.Selector == animationsSettledJS {
+				calls = append(calls, "animations")
+			}
+		case "TakeScreenshot":
+			calls = append(calls, "screenshot")
+		}
+	}
+	if got := strings.Join(calls, ","); got != "animations,screenshot" {
+		t.Errorf("calls = %s, want the animation wait right before the screenshot", got)
+	}
+	if got := env.stored(t, task.ID); len(got.Result.Screenshots) != 1 || !got.Result.Steps[1].Success {
+		t.Errorf("result = %+v, want the screenshot step to succeed", got.Result)
+	}
+}
+
+// failingDocStore 保存指定格式的文档时失败
+type failingDocStore struct {
+	storage.DocumentStore
+	format domain.DocFormat
+}
+
+func (s *failingDocStore) Save(ctx context.Context, taskID string, doc *domain.DocumentInfo, content []byte) error {
+	if doc.Format == s.format {
+		return errors.New("disk full")
+	}
+	return s.DocumentStore.Save(ctx, taskID, doc, content)
+}
+
+func TestDocumentFailureKeepsPartialResult(t *testing.T) {
+	env := newTestEnv(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#start", Description: "Open the form"}))
+	env.orch.SetDocumentStore(&failingDocStore{DocumentStore: storage.NewFileDocumentStore(t.TempDir()), format: domain.DocFormatHTML})
+	task := env.newTask(t, func(task *domain.Task) {
+		task.Output.Formats = []domain.DocFormat{domain.DocFormatHTML, domain.DocFormatMarkdown}
+	})
+
+	if err := env.orch.ExecuteTask(context.Background(), task); !errors.Is(err, ErrDocumentOutput) {
+		t.Fatalf("err = %v, want ErrDocumentOutput", err)
+	}
+	got := env.stored(t, task.ID)
+	if got.Status != domain.TaskStatusFailed || got.ErrorCode != domain.ErrorCodeDocument {
+		t.Errorf("status = %s/%s, want failed/document", got.Status, got.ErrorCode)
+	}
+	if got.Result == nil || len(got.Result.Steps) != 1 || !got.Result.Steps[0].Success {
+		t.Fatalf("result = %+v, want the executed step kept", got.Result)
+	}
+	if docs := got.Result.Documents; len(docs) != 1 || docs[0].Format != domain.DocFormatMarkdown {
+		t.Errorf("documents = %+v, want the markdown document that was saved", docs)
+	}
+}
+
+// failingTaskStore 前 failures 次 Update 失败
+type failingTaskStore struct {
+	*storage.MemoryTaskStore
+	failures int
+}
+
+func (s *failingTaskStore) Update(ctx context.Context, task *domain.Task) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("database unavailable")
+	}
+	return s.MemoryTaskStore.Update(ctx, task)
+}
+
+func TestExecuteTaskAlwaysEndsTerminal(t *testing.T) {
+	store := &failingTaskStore{MemoryTaskStore: storage.NewMemoryTaskStore(), failures: 1}
+	ctrl := browser.NewFakeController()
+	env := &testEnv{
+		orch:  NewOrchestrator(ctrl, store, planner.NewLLMClientFactory()),
+		ctrl:  ctrl,
+		store: store.MemoryTaskStore,
+		llm:   newTestLLM(t, planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#start", Description: "Start"})),
+	}
+	task := env.newTask(t, nil)
+
+	// 写入 running 状态失败后提前返回，任务仍以失败结束而不是停在 pending
+	if err := env.orch.ExecuteTask(context.Background(), task); err == nil {
+		t.Fatal("ExecuteTask succeeded, want the store error")
+	}
+	got := env.stored(t, task.ID)
+	if !got.Status.Terminal() || got.Status != domain.TaskStatusFailed || got.ErrorMessage == "" {
+		t.Errorf("task = %s %q, want failed with the store error", got.Status, got.ErrorMessage)
+	}
+	if len(env.methods("Connect")) != 0 {
+		t.Error("task executed although its status could not be saved")
+	}
+}
@@ -0,0 +1,24 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"log"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// dismissOverlays 任务启用时关闭 Cookie 同意横幅等遮挡层，失败只记录日志
+func (o *Orchestrator) dismissOverlays(ctx context.Context, task *domain.Task) {
+	if !task.DismissOverlays {
+		return
+	}
+	n, err := o.browserCtrl.DismissOverlays(ctx)
+	if err != nil {
+		log.Printf("[Task %s] Dismiss overlays failed: %v", task.ID, err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[Task %s] Dismissed %d overlay(s)", task.ID, n)
+	}
+}
@@ -0,0 +1,33 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// stepPause 返回步骤开始前的随机停顿，off 模式不停顿
+func stepPause(pacing domain.Pacing) time.Duration {
+	switch pacing {
+	case domain.PacingNormal:
+		return randomDuration(300*time.Millisecond, time.Second)
+	case domain.PacingHuman:
+		return randomDuration(time.Second, 3*time.Second)
+	default:
+		return 0
+	}
+}
+
+// typingDelay 返回逐字键入的字符间隔，仅 human 模式逐字键入
+func typingDelay(pacing domain.Pacing) (time.Duration, bool) {
+	if pacing != domain.PacingHuman {
+		return 0, false
+	}
+	return randomDuration(50*time.Millisecond, 150*time.Millisecond), true
+}
+
+func randomDuration(min, max time.Duration) time.Duration {
+	return min + time.Duration(rand.Int63n(int64(max-min)))
+}
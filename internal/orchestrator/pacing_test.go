@@ -0,0 +1,54 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+func TestPacingDelays(t *testing.T) {
+	tests := []struct {
+		pacing   domain.Pacing
+		min, max time.Duration
+		typed    bool
+	}{
+		{"", 0, 0, false},
+		{domain.PacingOff, 0, 0, false},
+		{domain.PacingNormal, 300 * time.Millisecond, time.Second, false},
+		{domain.PacingHuman, time.Second, 3 * time.Second, true},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if pause := stepPause(tt.pacing); pause < tt.min || pause > tt.max {
+				t.Fatalf("%q: stepPause = %s, want within [%s, %s]", tt.pacing, pause, tt.min, tt.max)
+			}
+			delay, typed := typingDelay(tt.pacing)
+			if typed != tt.typed || (typed && (delay < 50*time.Millisecond || delay > 150*time.Millisecond)) {
+				t.Fatalf("%q: typingDelay = %s, %v", tt.pacing, delay, typed)
+			}
+		}
+	}
+}
+
+func TestHumanPacingTypesText(t *testing.T) {
+	for _, pacing := range []domain.Pacing{domain.PacingOff, domain.PacingHuman} {
+		env := newTestEnv(t, planReply(planner.ActionStep{Action: browser.ActionFill, Target: "#name", Value: "Alice", Description: "Enter the name"}))
+		task := env.newTask(t, func(task *domain.Task) { task.Pacing = pacing })
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+
+		typed, filled := env.methods("TypeText"), env.methods("Fill")
+		if pacing == domain.PacingHuman {
+			if len(typed) != 1 || typed[0].Value != "Alice" || len(filled) != 0 {
+				t.Errorf("human: TypeText %+v, Fill %+v; want typed input", typed, filled)
+			}
+		} else if len(typed) != 0 || len(filled) != 1 {
+			t.Errorf("%s: TypeText %+v, Fill %+v; want a plain fill", pacing, typed, filled)
+		}
+	}
+}
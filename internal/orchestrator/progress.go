@@ -0,0 +1,50 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// 计划确定前各阶段的进度，步骤执行占 progressStepsStart~progressStepsEnd，文档生成完成后为 100
+const (
+	progressConnecting     = 5
+	progressAuthenticating = 10
+	progressPlanning       = 15
+	progressStepsStart     = 20
+	progressStepsEnd       = 95
+)
+
+// stepProgress 按已完成步骤数换算进度
+func stepProgress(done, total int) int {
+	if total <= 0 {
+		return progressStepsEnd
+	}
+	return progressStepsStart + (progressStepsEnd-progressStepsStart)*done/total
+}
+
+// advanceProgress 更新任务进度，只增不减，整体重试时不回退
+func advanceProgress(task *domain.Task, progress int) {
+	if progress > 100 {
+		progress = 100
+	}
+	if progress > task.Progress {
+		task.Progress = progress
+	}
+}
+
+// enterPhase 记录进入计划确定前的阶段，更新阶段进度并写入存储，失败只记录日志
+func (o *Orchestrator) enterPhase(ctx context.Context, task *domain.Task, phase string, progress int) {
+	o.recordPhase(ctx, task, phase)
+	if progress <= task.Progress {
+		return
+	}
+	advanceProgress(task, progress)
+	task.UpdatedAt = time.Now()
+	if err := o.taskStore.Update(ctx, task); err != nil {
+		log.Printf("[Task %s] Save progress failed: %v", task.ID, err)
+	}
+}
@@ -0,0 +1,86 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+	"github.com/browser-automation/internal/storage"
+)
+
+// progressStore 记录每次写入存储时的任务进度
+type progressStore struct {
+	*storage.MemoryTaskStore
+
+	mu       sync.Mutex
+	progress []int
+}
+
+func (s *progressStore) Update(ctx context.Context, task *domain.Task) error {
+	s.mu.Lock()
+	s.progress = append(s.progress, task.Progress)
+	s.mu.Unlock()
+	return s.MemoryTaskStore.Update(ctx, task)
+}
+
+func TestProgressIncreasesMonotonically(t *testing.T) {
+	click := planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"}
+	env := newTestEnv(t, planReply(click, click, click, click))
+	store := &progressStore{MemoryTaskStore: env.store}
+	env.orch = NewOrchestrator(env.ctrl, store, planner.NewLLMClientFactory())
+
+	task := env.newTask(t, func(task *domain.Task) {
+		task.Auth = &domain.AuthConfig{Type: domain.AuthTypeCookie, Cookies: []domain.Cookie{{Name: "sid", Value: "1", Domain: "app.example.com", Path: "/"}}}
+	})
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+
+	got := store.progress
+	for i := 1; i < len(got); i++ {
+		if got[i] < got[i-1] {
+			t.Fatalf("progress went backwards: %v", got)
+		}
+	}
+	// 连接、认证、规划三个阶段，四个步骤各一次，完成时为 100
+	seen := make(map[int]bool)
+	for _, p := range got {
+		seen[p] = true
+	}
+	for _, want := range []int{progressConnecting, progressAuthenticating, progressPlanning,
+		stepProgress(1, 4), stepProgress(2, 4), stepProgress(3, 4), stepProgress(4, 4), 100} {
+		if !seen[want] {
+			t.Errorf("progress %d never persisted: %v", want, got)
+		}
+	}
+	if final := env.stored(t, task.ID).Progress; final != 100 {
+		t.Errorf("final progress = %d, want 100", final)
+	}
+}
+
+func TestStepProgress(t *testing.T) {
+	tests := []struct {
+		done, total, want int
+	}{
+		{0, 4, progressStepsStart},
+		{2, 4, (progressStepsStart + progressStepsEnd) / 2},
+		{4, 4, progressStepsEnd},
+		{0, 0, progressStepsEnd},
+	}
+	for _, tt := range tests {
+		if got := stepProgress(tt.done, tt.total); got != tt.want {
+			t.Errorf("stepProgress(%d, %d) = %d, want %d", tt.done, tt.total, got, tt.want)
+		}
+	}
+
+	// 整体重试时不回退
+	task := &domain.Task{Progress: 60}
+	advanceProgress(task, progressPlanning)
+	advanceProgress(task, 120)
+	if task.Progress != 100 {
+		t.Errorf("progress = %d, want 100", task.Progress)
+	}
+}
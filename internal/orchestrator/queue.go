@@ -0,0 +1,165 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"sync"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// taskQueue 待执行任务的优先队列：优先级高者先出，同优先级按创建时间先后
+type taskQueue struct {
+	mu     sync.Mutex
+	items  taskHeap
+	notify chan struct{}
+	done   map[string]chan struct{} // 任务结束信号，执行完成或跳过后关闭
+}
+
+func newTaskQueue() *taskQueue {
+	return &taskQueue{
+		notify: make(chan struct{}, 1),
+		done:   make(map[string]chan struct{}),
+	}
+}
+
+// push 入队并唤醒一个等待中的 worker
+func (q *taskQueue) push(task *domain.Task) {
+	q.mu.Lock()
+	heap.Push(&q.items, task)
+	q.done[task.ID] = make(chan struct{})
+	q.mu.Unlock()
+	q.signal()
+}
+
+// finish 通知任务已结束
+func (q *taskQueue) finish(taskID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if ch, ok := q.done[taskID]; ok {
+		close(ch)
+		delete(q.done, taskID)
+	}
+}
+
+// doneChan 返回任务结束信号，任务不在队列中（已结束）时返回 nil
+func (q *taskQueue) doneChan(taskID string) <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.done[taskID]
+}
+
+// pop 取出优先级最高的任务，队列为空时返回 nil
+func (q *taskQueue) pop() *domain.Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.items.Len() == 0 {
+		return nil
+	}
+	task := heap.Pop(&q.items).(*domain.Task)
+	if q.items.Len() > 0 {
+		// 仍有任务时继续唤醒其他 worker
+		q.signal()
+	}
+	return task
+}
+
+// len 当前排队任务数
+func (q *taskQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.Len()
+}
+
+func (q *taskQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// taskHeap 实现 heap.Interface
+type taskHeap []*domain.Task
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+	return h[i].CreatedAt.Before(h[j].CreatedAt)
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x interface{}) { *h = append(*h, x.(*domain.Task)) }
+
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return task
+}
+
+// Start 启动 workers 个任务执行协程，ctx 结束后停止取新任务
+func (o *Orchestrator) Start(ctx context.Context, workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go o.worker(ctx)
+	}
+}
+
+// Wait 阻塞直到任务执行结束（含排队期间被取消而跳过）或 ctx 结束
+func (o *Orchestrator) Wait(ctx context.Context, taskID string) error {
+	done := o.queue.doneChan(taskID)
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Submit 将任务加入执行队列，之后任务由执行协程修改，调用方不应再读写
+func (o *Orchestrator) Submit(task *domain.Task) {
+	o.queue.push(task)
+	log.Printf("[Task %s] Queued with priority %d (%d waiting)", task.ID, task.Priority, o.queue.len())
+}
+
+func (o *Orchestrator) worker(ctx context.Context) {
+	for {
+		task := o.queue.pop()
+		if task == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-o.queue.notify:
+			}
+			continue
+		}
+
+		// 排队期间已被取消的任务直接跳过
+		if current, err := o.taskStore.Get(ctx, task.ID); err == nil && current.Status == domain.TaskStatusCancelled {
+			log.Printf("[Task %s] Skipping cancelled task", task.ID)
+			o.queue.finish(task.ID)
+			continue
+		}
+		taskCtx, cancel := context.WithCancel(context.Background())
+		o.trackRunning(task.ID, cancel)
+		if err := o.ExecuteTask(taskCtx, task); err != nil {
+			log.Printf("Task execution failed: %v", err)
+		}
+		o.untrackRunning(task.ID)
+		cancel()
+		o.queue.finish(task.ID)
+	}
+}
@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+func TestHighPriorityTaskRunsFirst(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	}()
+	reply := planReply(planner.ActionStep{Action: browser.ActionClick, Target: "#submit", Description: "Submit"})
+	env := newTestEnv(t, func(prompt string) string {
+		// 第一个任务占住唯一的 worker，直到另外两个任务都已排队
+		if strings.Contains(prompt, "blocker task") {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+		}
+		return reply(prompt)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	env.orch.Start(ctx, 1)
+
+	blocker := env.newTask(t, func(task *domain.Task) { task.Description = "blocker task" })
+	env.orch.Submit(blocker)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocker task did not start")
+	}
+
+	low := env.newTask(t, func(task *domain.Task) { task.Description = "low task" })
+	high := env.newTask(t, func(task *domain.Task) {
+		task.Description = "high task"
+		task.Priority = 10
+	})
+	env.orch.Submit(low)
+	env.orch.Submit(high)
+	close(release)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer waitCancel()
+	for _, task := range []*domain.Task{blocker, low, high} {
+		if err := env.orch.Wait(waitCtx, task.ID); err != nil {
+			t.Fatalf("wait %s: %v", task.Description, err)
+		}
+	}
+
+	env.llm.mu.Lock()
+	defer env.llm.mu.Unlock()
+	var order []string
+	for _, p := range env.llm.prompts {
+		for _, name := range []string{"low task", "high task"} {
+			if strings.Contains(p, name) && (len(order) == 0 || order[len(order)-1] != name) {
+				order = append(order, name)
+			}
+		}
+	}
+	if len(order) != 2 || order[0] != "high task" {
+		t.Errorf("execution order = %v, want high task first", order)
+	}
+}
+
+func TestTaskQueueOrder(t *testing.T) {
+	q := newTaskQueue()
+	now := time.Now()
+	q.push(&domain.Task{ID: "low-old", CreatedAt: now})
+	q.push(&domain.Task{ID: "high", Priority: 5, CreatedAt: now.Add(2 * time.Second)})
+	q.push(&domain.Task{ID: "low-new", CreatedAt: now.Add(time.Second)})
+
+	for _, want := range []string{"high", "low-old", "low-new"} {
+		if task := q.pop(); task == nil || task.ID != want {
+			t.Fatalf("pop = %v, want %s", task, want)
+		}
+	}
+	if task := q.pop(); task != nil {
+		t.Errorf("pop on empty queue = %s", task.ID)
+	}
+}
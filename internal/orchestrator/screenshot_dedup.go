@@ -0,0 +1,116 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/jpeg" // 注册 JPEG 解码
+	_ "image/png"  // 注册 PNG 解码
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// 截图哈希前缀：p 为感知哈希（可比较相似度），s 为内容摘要（仅判断完全相同）
+const (
+	perceptualHashPrefix = "p:"
+	digestHashPrefix     = "s:"
+)
+
+// screenshotHash 计算截图哈希。可解码的图片使用 8x8 平均哈希，
+// 其他格式（如 WebP）退化为 SHA-256 内容摘要
+func screenshotHash(data []byte) string {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		sum := sha256.Sum256(data)
+		return digestHashPrefix + hex.EncodeToString(sum[:])
+	}
+	return perceptualHashPrefix + fmt.Sprintf("%016x", averageHash(img))
+}
+
+// averageHash 将图片缩放为 8x8 灰度，按是否高于平均亮度生成 64 位哈希
+func averageHash(img image.Image) uint64 {
+	b := img.Bounds()
+	var gray [64]uint32
+	var total uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			// 对每个格子内的像素取平均
+			x0, x1 := b.Min.X+x*b.Dx()/8, b.Min.X+(x+1)*b.Dx()/8
+			y0, y1 := b.Min.Y+y*b.Dy()/8, b.Min.Y+(y+1)*b.Dy()/8
+			var sum, n uint64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, bl, _ := img.At(px, py).RGBA()
+					sum += uint64(299*r+587*g+114*bl) / 1000
+					n++
+				}
+			}
+			if n > 0 {
+				gray[y*8+x] = uint32(sum / n)
+			}
+			total += uint64(gray[y*8+x])
+		}
+	}
+
+	avg := uint32(total / 64)
+	var hash uint64
+	for i, v := range gray {
+		if v > avg {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// screenshotSimilarity 返回两个截图哈希的相似度（0-1）
+func screenshotSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	if !strings.HasPrefix(a, perceptualHashPrefix) || !strings.HasPrefix(b, perceptualHashPrefix) {
+		return 0
+	}
+	ha, errA := strconv.ParseUint(strings.TrimPrefix(a, perceptualHashPrefix), 16, 64)
+	hb, errB := strconv.ParseUint(strings.TrimPrefix(b, perceptualHashPrefix), 16, 64)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return 1 - float64(bits.OnesCount64(ha^hb))/64
+}
+
+// screenshotDeduper 比较相邻截图，与上一张保留的截图足够相似时标记为重复
+type screenshotDeduper struct {
+	threshold float64
+	last      *domain.Screenshot
+}
+
+// newScreenshotDeduper 根据任务配置创建去重器，未启用时返回 nil
+func newScreenshotDeduper(task *domain.Task) *screenshotDeduper {
+	threshold, ok := task.ScreenshotDedupThreshold()
+	if !ok {
+		return nil
+	}
+	return &screenshotDeduper{threshold: threshold}
+}
+
+// apply 判断截图是否与上一张重复；重复时复用上一张的引用
+func (d *screenshotDeduper) apply(shot *domain.Screenshot) {
+	if d == nil {
+		return
+	}
+	if d.last != nil && screenshotSimilarity(d.last.Hash, shot.Hash) >= d.threshold {
+		shot.DuplicateOf = d.last.ID
+		shot.URL = d.last.URL
+		return
+	}
+	d.last = shot
+}
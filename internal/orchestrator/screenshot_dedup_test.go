@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// testPNG 生成 64x64 的 PNG，dark 返回 true 的像素为黑色，其余为白色
+func testPNG(t *testing.T, dark func(x, y int) bool) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetGray(x, y, color.Gray{Y: 255})
+			if dark(x, y) {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestScreenshotDeduper(t *testing.T) {
+	leftHalf := testPNG(t, func(x, y int) bool { return x < 32 })
+	topHalf := testPNG(t, func(x, y int) bool { return y < 32 })
+	checker := testPNG(t, func(x, y int) bool { return (x/8+y/8)%2 == 0 })
+
+	d := &screenshotDeduper{threshold: domain.DefaultScreenshotDedupThreshold}
+	shots := []*domain.Screenshot{
+		{ID: "s1", URL: "screenshots/step_1.png", Hash: screenshotHash(leftHalf)},
+		{ID: "s2", URL: "screenshots/step_2.png", Hash: screenshotHash(leftHalf)},
+		{ID: "s3", URL: "screenshots/step_3.png", Hash: screenshotHash(topHalf)},
+		{ID: "s4", URL: "screenshots/step_4.png", Hash: screenshotHash(checker)},
+	}
+	for _, shot := range shots {
+		d.apply(shot)
+	}
+
+	if shots[1].DuplicateOf != "s1" || shots[1].URL != shots[0].URL {
+		t.Errorf("identical screenshot: duplicate_of %q url %q, want s1's reference", shots[1].DuplicateOf, shots[1].URL)
+	}
+	for _, shot := range shots[2:] {
+		if shot.DuplicateOf != "" {
+			t.Errorf("%s marked as duplicate of %s", shot.ID, shot.DuplicateOf)
+		}
+	}
+
+	// 无法解码的图片只比较内容摘要
+	webp := []byte("RIFF....WEBPVP8 ")
+	if got := screenshotSimilarity(screenshotHash(webp), screenshotHash(webp)); got != 1 {
+		t.Errorf("identical undecodable images similarity = %v, want 1", got)
+	}
+	if got := screenshotSimilarity(screenshotHash(webp), screenshotHash(append(webp, 0))); got != 0 {
+		t.Errorf("different undecodable images similarity = %v, want 0", got)
+	}
+}
+
+func TestScreenshotDedupOutputFlag(t *testing.T) {
+	for _, dedup := range []bool{false, true} {
+		env := newTestEnv(t, planReply(
+			planner.ActionStep{Action: browser.ActionScreenshot, Description: "Before"},
+			planner.ActionStep{Action: browser.ActionScreenshot, Description: "After"},
+		))
+		task := env.newTask(t, func(task *domain.Task) {
+			task.Output.ScreenshotConfig.Dedup = dedup
+		})
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+
+		// FakeController 每次返回相同的图片
+		shots := env.stored(t, task.ID).Result.Screenshots
+		if len(shots) != 2 {
+			t.Fatalf("dedup %v: %d screenshots, want 2", dedup, len(shots))
+		}
+		if want := map[bool]string{true: shots[0].ID}[dedup]; shots[1].DuplicateOf != want {
+			t.Errorf("dedup %v: duplicate_of = %q, want %q", dedup, shots[1].DuplicateOf, want)
+		}
+	}
+}
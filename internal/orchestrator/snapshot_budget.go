@@ -0,0 +1,34 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import "github.com/browser-automation/internal/domain"
+
+// snapshotBudget 控制页面快照的采集频率和发送给 LLM 的次数，零值为不限制
+type snapshotBudget struct {
+	every int // 每隔几步重新采集快照，<=1 表示每步
+	max   int // 发送给 LLM 的快照上限，0 表示不限制
+	sent  int
+}
+
+func newSnapshotBudget(task *domain.Task) *snapshotBudget {
+	return &snapshotBudget{every: task.SnapshotEvery, max: task.MaxLLMSnapshots}
+}
+
+// record 记录一次必须发送的快照（如初始规划），不受上限约束
+func (b *snapshotBudget) record() {
+	b.sent++
+}
+
+// trySend 上限未用尽时记录一次发送并返回 true
+func (b *snapshotBudget) trySend() bool {
+	if b.max > 0 && b.sent >= b.max {
+		return false
+	}
+	b.sent++
+	return true
+}
+
+// resnapshot 第 i 步（从 0 开始）完成后是否重新采集快照
+func (b *snapshotBudget) resnapshot(i int) bool {
+	return b.every <= 1 || (i+1)%b.every == 0
+}
@@ -0,0 +1,79 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+func TestMaxLLMSnapshotsCapsRefines(t *testing.T) {
+	click := planner.ActionStep{Action: browser.ActionClick, Target: "#missing", Description: "Click"}
+	plan := planReply(click, click, click, click)
+	// 优化失败步骤时模型返回原步骤，使每一步都失败
+	respond := func(prompt string) string {
+		if strings.Contains(prompt, "优化后的步骤") {
+			step, _ := json.Marshal(click)
+			return string(step)
+		}
+		return plan(prompt)
+	}
+
+	tests := []struct {
+		name        string
+		max         int
+		wantRefines int
+	}{
+		{"unlimited", 0, 4},
+		{"cap leaves two refines", 3, 2},
+		{"cap used by the initial plan", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, respond)
+			env.ctrl.Errors["Click"] = errors.New("element not found")
+			task := env.newTask(t, func(task *domain.Task) { task.MaxLLMSnapshots = tt.max })
+			if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+				t.Fatalf("ExecuteTask: %v", err)
+			}
+
+			if n := env.llm.calls("优化后的步骤"); n != tt.wantRefines {
+				t.Errorf("refine requests = %d, want %d", n, tt.wantRefines)
+			}
+			got := env.stored(t, task.ID).Result
+			if got.SnapshotsSent != tt.wantRefines+1 {
+				t.Errorf("snapshots_sent = %d, want %d", got.SnapshotsSent, tt.wantRefines+1)
+			}
+			if len(got.Steps) != 4 {
+				t.Errorf("%d step results, want 4", len(got.Steps))
+			}
+		})
+	}
+}
+
+func TestSnapshotEveryThrottlesSnapshots(t *testing.T) {
+	click := planner.ActionStep{Action: browser.ActionClick, Target: "#next", Description: "Next"}
+	tests := []struct {
+		every         int
+		wantSnapshots int
+	}{
+		{0, 5}, // 初始快照 + 每步一次
+		{2, 3},
+		{4, 2},
+	}
+	for _, tt := range tests {
+		env := newTestEnv(t, planReply(click, click, click, click))
+		task := env.newTask(t, func(task *domain.Task) { task.SnapshotEvery = tt.every })
+		if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+			t.Fatalf("ExecuteTask: %v", err)
+		}
+		if n := len(env.methods("TakeSnapshot")); n != tt.wantSnapshots {
+			t.Errorf("snapshot_every %d: %d snapshots, want %d", tt.every, n, tt.wantSnapshots)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"log"
+
+	"github.com/browser-automation/internal/browser"
+)
+
+// pageSnapshots 步骤执行期间的页面快照：采集前先比较页面指纹，页面未变化时沿用上次的快照
+type pageSnapshots struct {
+	ctrl        browser.Controller
+	current     *browser.PageSnapshot
+	fingerprint string // current 采集时的页面指纹，为空表示未知，下次刷新总会重新采集
+}
+
+func newPageSnapshots(ctrl browser.Controller, initial *browser.PageSnapshot) *pageSnapshots {
+	return &pageSnapshots{ctrl: ctrl, current: initial}
+}
+
+// url 当前快照的页面地址，没有快照时为空
+func (p *pageSnapshots) url() string {
+	if p.current == nil {
+		return ""
+	}
+	return p.current.URL
+}
+
+// refresh 使 current 反映当前页面：指纹与上次采集时相同则直接沿用，否则重新采集。
+// 采集失败时保留旧快照并清空指纹
+func (p *pageSnapshots) refresh(ctx context.Context) {
+	fp, err := p.ctrl.PageFingerprint(ctx)
+	if err != nil {
+		fp = ""
+	}
+	if fp != "" && fp == p.fingerprint && p.current != nil {
+		log.Printf("[Snapshot] Page unchanged, reusing snapshot of %s", p.current.URL)
+		return
+	}
+	s, err := p.ctrl.TakeSnapshot(ctx)
+	if err != nil {
+		log.Printf("[Snapshot] Take snapshot failed: %v", err)
+		p.fingerprint = ""
+		return
+	}
+	p.current, p.fingerprint = s, fp
+}
@@ -0,0 +1,164 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/planner"
+	"github.com/browser-automation/internal/storage"
+)
+
+// page 返回只有一个按钮的快照
+func page(text string) *browser.PageSnapshot {
+	return &browser.PageSnapshot{Elements: []browser.Element{{TagName: "button", Selector: "button", Text: text}}}
+}
+
+func TestPageSnapshotsReuseUnchangedPage(t *testing.T) {
+	ctx := context.Background()
+	ctrl := browser.NewFakeController(page("first"), page("second"), page("third"), page("fourth"), page("fifth"))
+	if err := ctrl.Connect(ctx, browser.ContextOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	snaps := newPageSnapshots(ctrl, nil)
+	taken := func() int {
+		n := 0
+		for _, a := range ctrl.Actions() {
+			if a.Method == "TakeSnapshot" {
+				n++
+			}
+		}
+		return n
+	}
+	text := func() string { return snaps.current.Elements[0].Text }
+
+	ctrl.Fingerprint = "fp-1"
+	snaps.refresh(ctx)
+	if taken() != 1 || text() != "first" {
+		t.Fatalf("first refresh: %d snapshots, current %q", taken(), text())
+	}
+	// 指纹不变：沿用快照
+	snaps.refresh(ctx)
+	if taken() != 1 || text() != "first" {
+		t.Errorf("unchanged page: %d snapshots, current %q, want the snapshot reused", taken(), text())
+	}
+	// DOM 变化
+	ctrl.Fingerprint = "fp-2"
+	snaps.refresh(ctx)
+	if taken() != 2 || text() != "second" {
+		t.Errorf("DOM changed: %d snapshots, current %q", taken(), text())
+	}
+	// 跳转到其他页面
+	if err := ctrl.Navigate(ctx, "https://app.example.com/next"); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.Fingerprint = "fp-3"
+	snaps.refresh(ctx)
+	if taken() != 3 || text() != "third" || snaps.url() != "https://app.example.com/next" {
+		t.Errorf("navigated: %d snapshots, current %q on %s", taken(), text(), snaps.url())
+	}
+
+	// 采集失败时保留旧快照并清空指纹，之后即使指纹相同也重新采集
+	ctrl.Errors["TakeSnapshot"] = errors.New("page crashed")
+	ctrl.Fingerprint = "fp-4"
+	snaps.refresh(ctx)
+	if text() != "third" {
+		t.Errorf("failed snapshot replaced the current one with %q", text())
+	}
+	delete(ctrl.Errors, "TakeSnapshot")
+	ctrl.Fingerprint = "fp-3"
+	snaps.refresh(ctx)
+	if text() != "fourth" {
+		t.Errorf("after a failed snapshot: current %q, want a fresh snapshot", text())
+	}
+
+	// 无法计算指纹时每次都重新采集
+	ctrl.Fingerprint = ""
+	before := taken()
+	snaps.refresh(ctx)
+	snaps.refresh(ctx)
+	if taken() != before+2 {
+		t.Errorf("unknown fingerprint: %d new snapshots, want 2", taken()-before)
+	}
+}
+
+// changingSite 点击 #open 打开对话框；点击 #confirm 失败，failChanges 为 true 时失败的点击也改变了页面
+type changingSite struct {
+	*browser.FakeController
+	failChanges bool
+}
+
+func (s *changingSite) Click(ctx context.Context, selector string) error {
+	switch selector {
+	case "#open":
+		s.Fingerprint = "dialog"
+	case "#confirm":
+		if s.failChanges {
+			s.Fingerprint = "error"
+		}
+		return errors.New("element not found: " + selector)
+	}
+	return s.FakeController.Click(ctx, selector)
+}
+
+func TestRefineSeesCurrentPage(t *testing.T) {
+	open := planner.ActionStep{Action: browser.ActionClick, Target: "#open", Description: "Open the dialog"}
+	confirm := planner.ActionStep{Action: browser.ActionClick, Target: "#confirm", Description: "Confirm"}
+	plan := planReply(open, confirm)
+
+	tests := []struct {
+		name          string
+		failChanges   bool
+		refineSees    string
+		wantSnapshots int
+	}{
+		// 初始快照、第一步后的快照；失败的点击未改变页面，优化与第二步后都沿用快照
+		{"page unchanged", false, "Dialog button", 2},
+		// 失败的点击弹出错误提示，优化前重新采集
+		{"page changed by the failure", true, "Error toast", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &changingSite{
+				FakeController: browser.NewFakeController(page("Start button"), page("Dialog button"), page("Error toast")),
+				failChanges:    tt.failChanges,
+			}
+			site.Fingerprint = "start"
+			var refinePrompt string
+			store := storage.NewMemoryTaskStore()
+			env := &testEnv{
+				orch:  NewOrchestrator(site, store, planner.NewLLMClientFactory()),
+				ctrl:  site.FakeController,
+				store: store,
+				llm: newTestLLM(t, func(prompt string) string {
+					if strings.Contains(prompt, "优化后的步骤") {
+						refinePrompt = prompt
+						return `{"action": "click", "target": "#dialog-ok", "description": "Confirm"}`
+					}
+					return plan(prompt)
+				}),
+			}
+			task := env.newTask(t, nil)
+			if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+				t.Fatalf("ExecuteTask: %v", err)
+			}
+
+			if n := env.llm.calls("优化后的步骤"); n != 1 {
+				t.Fatalf("refine requests = %d, want 1", n)
+			}
+			for _, text := range []string{"Start button", "Dialog button", "Error toast"} {
+				if strings.Contains(refinePrompt, text) != (text == tt.refineSees) {
+					t.Errorf("refine prompt contains %q = %v, want only %q", text, text != tt.refineSees, tt.refineSees)
+				}
+			}
+			if n := len(env.methods("TakeSnapshot")); n != tt.wantSnapshots {
+				t.Errorf("%d snapshots, want %d", n, tt.wantSnapshots)
+			}
+			if steps := env.stored(t, task.ID).Result.Steps; len(steps) != 2 || !steps[1].Success {
+				t.Errorf("steps = %+v, want the refined confirm step to succeed", steps)
+			}
+		})
+	}
+}
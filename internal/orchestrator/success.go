@@ -0,0 +1,52 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// successTextTimeout 等待成功文本出现的时长，提交后的成功提示常异步显示
+const successTextTimeout = 5 * time.Second
+
+// checkSuccess 计划执行完成后在最终页面上检查任务的成功条件，未设置时直接通过。
+// 未满足时返回 verification 错误，说明哪些条件不满足
+func (o *Orchestrator) checkSuccess(ctx context.Context, task *domain.Task) error {
+	criteria := task.SuccessCriteria
+	if criteria == nil {
+		return nil
+	}
+	var unmet []string
+	if criteria.URLPattern != "" {
+		pageURL, err := o.browserCtrl.GetCurrentURL(ctx)
+		if err != nil {
+			return newTaskError(domain.ErrorCodeBrowser, "check success criteria", err)
+		}
+		matched, err := criteria.MatchURL(pageURL)
+		if err != nil {
+			return newTaskError(domain.ErrorCodeInvalidConfig, "check success criteria", err)
+		}
+		if !matched {
+			unmet = append(unmet, fmt.Sprintf("final URL %q does not match %q", pageURL, criteria.URLPattern))
+		}
+	}
+	if criteria.Text != "" {
+		if err := o.browserCtrl.WaitForText(ctx, criteria.Text, successTextTimeout); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			unmet = append(unmet, fmt.Sprintf("text %q not found on the final page", criteria.Text))
+		}
+	}
+	if len(unmet) > 0 {
+		return newTaskError(domain.ErrorCodeVerification, "success criteria not met", errors.New(strings.Join(unmet, "; ")))
+	}
+	log.Printf("[Task %s] Success criteria met", task.ID)
+	return nil
+}
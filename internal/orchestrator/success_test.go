@@ -0,0 +1,57 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+func TestSuccessCriteria(t *testing.T) {
+	click := planner.ActionStep{Action: browser.ActionClick, Target: "#submit", Description: "Submit"}
+
+	tests := []struct {
+		name     string
+		criteria *domain.SuccessCriteria
+		textErr  error
+		status   domain.TaskStatus
+		code     domain.ErrorCode
+		sentinel error
+	}{
+		{"url and text met", &domain.SuccessCriteria{URLPattern: `^https://app\.example\.com/form$`, Text: "Saved"},
+			nil, domain.TaskStatusCompleted, "", nil},
+		{"invalid pattern", &domain.SuccessCriteria{URLPattern: "(unclosed"},
+			nil, domain.TaskStatusFailed, domain.ErrorCodeInvalidConfig, ErrInvalidConfig},
+		{"url unmet", &domain.SuccessCriteria{URLPattern: `/done$`},
+			nil, domain.TaskStatusFailed, domain.ErrorCodeVerification, ErrVerification},
+		{"text unmet", &domain.SuccessCriteria{Text: "Saved"},
+			errors.New("timeout"), domain.TaskStatusFailed, domain.ErrorCodeVerification, ErrVerification},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, planReply(click))
+			if tt.textErr != nil {
+				env.ctrl.Errors["WaitForText"] = tt.textErr
+			}
+			task := env.newTask(t, func(task *domain.Task) { task.SuccessCriteria = tt.criteria })
+
+			err := env.orch.ExecuteTask(context.Background(), task)
+			if tt.sentinel == nil && err != nil {
+				t.Fatalf("ExecuteTask: %v", err)
+			}
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Errorf("err = %v, want errors.Is %s", err, tt.code)
+			}
+			got := env.stored(t, task.ID)
+			if got.Status != tt.status || got.ErrorCode != tt.code {
+				t.Errorf("status = %s/%s, want %s/%s", got.Status, got.ErrorCode, tt.status, tt.code)
+			}
+			if len(env.methods("Click")) != 1 {
+				t.Error("plan not executed before checking success criteria")
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package orchestrator 提供任务编排功能
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+// maxTipCacheEntries 提示缓存条目上限，超出后整体清空
+const maxTipCacheEntries = 1000
+
+// tipCacheKey 提示缓存键：相同语言下的同一操作复用提示
+type tipCacheKey struct {
+	language    string
+	action      string
+	target      string
+	value       string
+	description string
+}
+
+// tipCache 步骤提示缓存，避免相同步骤重复调用 LLM
+type tipCache struct {
+	mu      sync.Mutex
+	entries map[tipCacheKey][]string
+}
+
+func newTipCache() *tipCache {
+	return &tipCache{entries: make(map[tipCacheKey][]string)}
+}
+
+func (c *tipCache) get(key tipCacheKey) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tips, ok := c.entries[key]
+	return tips, ok
+}
+
+func (c *tipCache) put(key tipCacheKey, tips []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxTipCacheEntries {
+		c.entries = make(map[tipCacheKey][]string)
+	}
+	c.entries[key] = tips
+}
+
+// addTips 为计划步骤生成提示，仅在输出配置启用提示时调用 LLM；生成失败的步骤保持无提示，由文档生成器降级处理
+func (o *Orchestrator) addTips(ctx context.Context, task *domain.Task, p planner.Planner, plan *planner.TaskPlan) {
+	if task.Output == nil || task.Output.ContentConfig == nil || !task.Output.ContentConfig.IncludeTips {
+		return
+	}
+	language := task.Output.Language
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		if len(step.Tips) > 0 {
+			continue
+		}
+		key := tipCacheKey{
+			language:    language,
+			action:      string(step.Action),
+			target:      step.Target,
+			value:       step.Value,
+			description: step.Description,
+		}
+		if tips, ok := o.tipCache.get(key); ok {
+			step.Tips = tips
+			continue
+		}
+		tips, err := p.GenerateTips(ctx, step, language)
+		if err != nil {
+			log.Printf("[Task %s] Generate tips for step %d failed: %v", task.ID, step.Order, err)
+			continue
+		}
+		o.tipCache.put(key, tips)
+		step.Tips = tips
+	}
+}
@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+	"github.com/browser-automation/internal/planner"
+)
+
+func TestTipsGeneratedInOutputLanguage(t *testing.T) {
+	plan := planReply(planner.ActionStep{Action: browser.ActionFill, Target: "#email", Value: "alice@example.com", Description: "Enter your email"})
+	env := newTestEnv(t, func(prompt string) string {
+		if strings.Contains(prompt, `{"tips"`) {
+			return `{"tips": ["Use your work email address."]}`
+		}
+		return plan(prompt)
+	})
+	withTips := func(task *domain.Task) {
+		task.Output.Language = "en"
+		task.Output.ContentConfig.IncludeTips = true
+	}
+
+	task := env.newTask(t, withTips)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if n := env.llm.calls("提示使用 English 书写"); n != 1 {
+		t.Errorf("tip requests in English = %d, want 1", n)
+	}
+	got := env.stored(t, task.ID)
+	var markdown string
+	for _, doc := range got.Result.Documents {
+		if doc.Format == domain.DocFormatMarkdown {
+			markdown = doc.Content
+		}
+	}
+	if !strings.Contains(markdown, "Use your work email address.") {
+		t.Errorf("markdown does not contain the tip:\n%s", markdown)
+	}
+
+	// 相同步骤复用缓存的提示
+	task = env.newTask(t, withTips)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if n := env.llm.calls(`{"tips"`); n != 1 {
+		t.Errorf("tip requests after a cached run = %d, want 1", n)
+	}
+
+	// 未启用提示时不调用 LLM
+	env = newTestEnv(t, plan)
+	task = env.newTask(t, nil)
+	if err := env.orch.ExecuteTask(context.Background(), task); err != nil {
+		t.Fatalf("ExecuteTask: %v", err)
+	}
+	if n := env.llm.calls(`{"tips"`); n != 0 {
+		t.Errorf("tip requests with tips disabled = %d", n)
+	}
+}
@@ -0,0 +1,175 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrLLMCircuitOpen 同一接口连续失败后熔断，冷却期内的请求直接失败而不发送
+var ErrLLMCircuitOpen = errors.New("llm circuit open")
+
+// CircuitBreakerOptions LLM 接口熔断配置，按接口地址（由提供商与 endpoint 决定）分别计数
+type CircuitBreakerOptions struct {
+	// Threshold 连续失败多少次后熔断，0 使用默认值，负数表示不熔断
+	Threshold int
+	// Cooldown 熔断后拒绝请求的时长，之后放行一个探测请求，成功则恢复，失败则重新熔断；0 使用默认值
+	Cooldown time.Duration
+}
+
+// 熔断的默认配置
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// DefaultRetryJitter 默认的重试间隔抖动比例
+const DefaultRetryJitter = 0.2
+
+// SetCircuitBreaker 设置熔断配置，替换已有的熔断状态
+func (f *LLMClientFactory) SetCircuitBreaker(opts CircuitBreakerOptions) {
+	f.breakers = newBreakerSet(opts)
+}
+
+// SetRetryJitter 设置重试间隔的随机抖动比例（0-1），如 0.2 表示间隔在 ±20% 内随机，
+// 避免多个任务在服务恢复时同时重试；<0 时使用默认值
+func (f *LLMClientFactory) SetRetryJitter(fraction float64) {
+	if fraction < 0 {
+		fraction = DefaultRetryJitter
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	f.retryJitter = fraction
+}
+
+// jitter 按比例随机调整重试间隔
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + fraction*(2*rand.Float64()-1)))
+}
+
+// breakerOutcome 一次请求对熔断状态的影响
+type breakerOutcome int
+
+const (
+	outcomeSuccess breakerOutcome = iota // 服务有响应（含 4xx 等非故障错误）
+	outcomeFailure                       // 网络错误、超时或服务端错误
+	outcomeIgnore                        // 调用方取消等，不说明服务状态
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breakerSet 各接口的熔断器，为 nil 时不熔断
+type breakerSet struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func newBreakerSet(opts CircuitBreakerOptions) *breakerSet {
+	if opts.Threshold < 0 {
+		return nil
+	}
+	if opts.Threshold == 0 {
+		opts.Threshold = DefaultBreakerThreshold
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = DefaultBreakerCooldown
+	}
+	return &breakerSet{threshold: opts.Threshold, cooldown: opts.Cooldown, breakers: make(map[string]*breaker)}
+}
+
+// get 返回接口地址对应的熔断器，地址只取协议、主机与路径，查询参数中可能含密钥
+func (s *breakerSet) get(u *url.URL) *breaker {
+	if s == nil {
+		return nil
+	}
+	key := u.Scheme + "://" + u.Host + u.Path
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.breakers[key]
+	if !ok {
+		b = &breaker{key: key, threshold: s.threshold, cooldown: s.cooldown}
+		s.breakers[key] = b
+	}
+	return b
+}
+
+// breaker 单个接口的熔断器：closed 时计数连续失败，达到阈值后 open；
+// 冷却期过后 half-open 放行一个探测请求，其余请求仍直接失败
+type breaker struct {
+	key       string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow 判断是否放行请求，熔断中返回 ErrLLMCircuitOpen
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if wait := b.openedAt.Add(b.cooldown).Sub(time.Now()); wait > 0 {
+			return fmt.Errorf("%w: %s failed %d times in a row, retry in %s", ErrLLMCircuitOpen, b.key, b.failures, (wait + time.Second - 1).Truncate(time.Second))
+		}
+		log.Printf("[LLM] Circuit half-open for %s, probing", b.key)
+		b.state = breakerHalfOpen
+		b.probing = true
+	case breakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%w: %s is being probed after failures", ErrLLMCircuitOpen, b.key)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record 记录放行请求的结果
+func (b *breaker) record(outcome breakerOutcome) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.probing = false
+	}
+	switch outcome {
+	case outcomeSuccess:
+		if b.state != breakerClosed {
+			log.Printf("[LLM] Circuit closed for %s", b.key)
+		}
+		b.state, b.failures = breakerClosed, 0
+	case outcomeFailure:
+		b.failures++
+		if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= b.threshold) {
+			log.Printf("[LLM] Circuit open for %s after %d consecutive failures, cooling down %s", b.key, b.failures, b.cooldown)
+			b.state, b.openedAt = breakerOpen, time.Now()
+		}
+	}
+}
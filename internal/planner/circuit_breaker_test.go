@@ -0,0 +1,231 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// flakyServer 模拟 OpenAI 兼容接口，状态码可随时切换；hold 不为 nil 时请求阻塞到其关闭
+type flakyServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	status  int
+	calls   int
+	hold    chan struct{}
+	entered chan struct{}
+}
+
+func newFlakyServer(t *testing.T, status int) *flakyServer {
+	t.Helper()
+	s := &flakyServer{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *flakyServer) serve(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	s.mu.Lock()
+	s.calls++
+	hold, entered := s.hold, s.entered
+	s.mu.Unlock()
+	if hold != nil {
+		entered <- struct{}{}
+		<-hold
+	}
+
+	s.mu.Lock()
+	status := s.status
+	s.mu.Unlock()
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		io.WriteString(w, `{"error":{"message":"unavailable"}}`)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"choices": []map[string]interface{}{{
+			"message":       map[string]string{"role": "assistant", "content": "ok"},
+			"finish_reason": "stop",
+		}},
+	})
+}
+
+// set 切换后续响应的状态码
+func (s *flakyServer) set(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// holdRequests 之后的请求阻塞，直到调用返回的 release
+func (s *flakyServer) holdRequests() (entered <-chan struct{}, release func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hold, s.entered = make(chan struct{}), make(chan struct{}, 8)
+	hold := s.hold
+	return s.entered, func() {
+		s.mu.Lock()
+		s.hold = nil
+		s.mu.Unlock()
+		close(hold)
+	}
+}
+
+func (s *flakyServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// breakerClient 创建不重试、使用指定熔断配置的客户端
+func breakerClient(t *testing.T, factory *LLMClientFactory, endpoint string) LLMClient {
+	t.Helper()
+	client, err := factory.NewClient(&domain.LLMConfig{
+		Provider: domain.LLMProviderOpenAI,
+		Model:    "gpt-test",
+		Endpoint: endpoint,
+		APIKey:   "sk-test",
+		Options:  &domain.LLMOptions{RetryCount: domain.Int(0)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func chatOnce(client LLMClient) error {
+	_, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}})
+	return err
+}
+
+func TestCircuitBreakerOpensOnServerErrors(t *testing.T) {
+	const cooldown = 200 * time.Millisecond
+	srv := newFlakyServer(t, http.StatusServiceUnavailable)
+	other := newFlakyServer(t, http.StatusOK)
+	factory := NewLLMClientFactory()
+	factory.SetCircuitBreaker(CircuitBreakerOptions{Threshold: 2, Cooldown: cooldown})
+	client := breakerClient(t, factory, srv.URL)
+
+	for i := 0; i < 2; i++ {
+		if err := chatOnce(client); !errors.Is(err, ErrLLMServer) {
+			t.Fatalf("call %d: err = %v, want ErrLLMServer", i+1, err)
+		}
+	}
+
+	// 冷却期内直接失败，不发送请求；同一工厂的新客户端共享熔断状态，其他接口不受影响
+	start := time.Now()
+	for _, c := range []LLMClient{client, breakerClient(t, factory, srv.URL)} {
+		if err := chatOnce(c); !errors.Is(err, ErrLLMCircuitOpen) {
+			t.Errorf("err = %v, want ErrLLMCircuitOpen during cooldown", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > cooldown/2 {
+		t.Errorf("open circuit took %s to fail, want fail fast", elapsed)
+	}
+	if n := srv.count(); n != 2 {
+		t.Errorf("server received %d requests, want 2 before the circuit opened", n)
+	}
+	if err := chatOnce(breakerClient(t, factory, other.URL)); err != nil {
+		t.Errorf("other endpoint: %v, want unaffected by the open circuit", err)
+	}
+
+	// 冷却期过后只放行一个探测请求，探测进行中的其余请求仍直接失败
+	time.Sleep(cooldown)
+	entered, release := srv.holdRequests()
+	srv.set(http.StatusOK)
+	probe := make(chan error, 1)
+	go func() { probe <- chatOnce(client) }()
+	<-entered
+	for i := 0; i < 3; i++ {
+		if err := chatOnce(client); !errors.Is(err, ErrLLMCircuitOpen) {
+			t.Errorf("concurrent call %d during probe: err = %v, want ErrLLMCircuitOpen", i+1, err)
+		}
+	}
+	release()
+	if err := <-probe; err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if n := srv.count(); n != 3 {
+		t.Errorf("server received %d requests, want exactly one probe", n)
+	}
+
+	// 探测成功后恢复，失败计数清零
+	if err := chatOnce(client); err != nil {
+		t.Errorf("after successful probe: %v, want circuit closed", err)
+	}
+	srv.set(http.StatusBadGateway)
+	if err := chatOnce(client); !errors.Is(err, ErrLLMServer) {
+		t.Errorf("err = %v, want a single failure to reach the server after closing", err)
+	}
+	if n := srv.count(); n != 5 {
+		t.Errorf("server received %d requests, want 5", n)
+	}
+}
+
+func TestCircuitBreakerFailedProbeReopens(t *testing.T) {
+	const cooldown = 100 * time.Millisecond
+	srv := newFlakyServer(t, http.StatusInternalServerError)
+	factory := NewLLMClientFactory()
+	factory.SetCircuitBreaker(CircuitBreakerOptions{Threshold: 1, Cooldown: cooldown})
+	client := breakerClient(t, factory, srv.URL)
+
+	if err := chatOnce(client); !errors.Is(err, ErrLLMServer) {
+		t.Fatalf("err = %v, want ErrLLMServer", err)
+	}
+	time.Sleep(cooldown)
+	if err := chatOnce(client); !errors.Is(err, ErrLLMServer) {
+		t.Fatalf("probe: err = %v, want the probe to reach the server", err)
+	}
+	// 探测失败立即重新熔断并重新计算冷却期
+	if err := chatOnce(client); !errors.Is(err, ErrLLMCircuitOpen) {
+		t.Errorf("err = %v, want ErrLLMCircuitOpen after a failed probe", err)
+	}
+	if n := srv.count(); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			srv := newFlakyServer(t, status)
+			factory := NewLLMClientFactory()
+			factory.SetCircuitBreaker(CircuitBreakerOptions{Threshold: 2, Cooldown: time.Minute})
+			client := breakerClient(t, factory, srv.URL)
+
+			// 接口有响应，说明服务可用，连续失败也不熔断
+			for i := 0; i < 5; i++ {
+				err := chatOnce(client)
+				if err == nil || errors.Is(err, ErrLLMCircuitOpen) {
+					t.Fatalf("call %d: err = %v, want the %d error from the server", i+1, err, status)
+				}
+			}
+			if n := srv.count(); n != 5 {
+				t.Errorf("server received %d requests, want all 5", n)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	srv := newFlakyServer(t, http.StatusServiceUnavailable)
+	factory := NewLLMClientFactory()
+	factory.SetCircuitBreaker(CircuitBreakerOptions{Threshold: -1})
+	client := breakerClient(t, factory, srv.URL)
+
+	for i := 0; i < DefaultBreakerThreshold+2; i++ {
+		if err := chatOnce(client); !errors.Is(err, ErrLLMServer) {
+			t.Fatalf("call %d: err = %v, want ErrLLMServer with the breaker disabled", i+1, err)
+		}
+	}
+}
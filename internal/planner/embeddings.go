@@ -0,0 +1,112 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// defaultOpenAIEmbeddingModel OpenAI 未配置向量模型时使用的模型
+const defaultOpenAIEmbeddingModel = "text-embedding-3-small"
+
+// Embedder 文本向量化接口，供计划缓存的语义匹配等功能使用
+type Embedder interface {
+	// Embed 按输入顺序返回每段文本的向量
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// NewEmbedder 根据配置创建向量化客户端，使用 OpenAI 兼容的 /embeddings 接口。
+// Ollama 原生配置改用其 /v1 兼容端点，Anthropic 不提供向量接口
+func (f *LLMClientFactory) NewEmbedder(config *domain.LLMConfig) (Embedder, error) {
+	cfg := *config
+	cfg.Options = domain.MergeLLMOptions(config.Options)
+	switch cfg.Provider {
+	case domain.LLMProviderAnthropic:
+		return nil, fmt.Errorf("provider %s does not support embeddings", cfg.Provider)
+	case domain.LLMProviderOllama:
+		if !cfg.OpenAICompat && cfg.Endpoint != "" {
+			cfg.Endpoint = strings.TrimSuffix(strings.TrimSuffix(cfg.Endpoint, "/"), "/v1") + "/v1"
+		}
+	}
+	if cfg.EmbeddingModel == "" {
+		if cfg.Provider != domain.LLMProviderOpenAI {
+			return nil, fmt.Errorf("embedding model required for provider %s", cfg.Provider)
+		}
+		cfg.EmbeddingModel = defaultOpenAIEmbeddingModel
+	}
+	client := NewOpenAICompatibleClient(&cfg, f.httpClient)
+	client.sender = f.newSender()
+	return client, nil
+}
+
+// embeddingResponse OpenAI /embeddings 响应
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed 调用 /embeddings 接口，按响应中的 index 对齐输入顺序
+func (c *OpenAICompatibleClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	model := c.config.EmbeddingModel
+	if model == "" {
+		return nil, fmt.Errorf("embedding model not configured")
+	}
+	log.Printf("[LLM] Embeddings request: model=%s, endpoint=%s, inputs=%d", model, c.config.Endpoint, len(texts))
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model": model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	respBody, err := c.sendWithRetry(ctx, c.config.Options, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST",
+			c.config.Endpoint+"/embeddings", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.config.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		}
+		applyHeaders(req, c.config.Headers)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result embeddingResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings: got %d vectors for %d inputs", len(result.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) || vectors[d.Index] != nil {
+			return nil, fmt.Errorf("embeddings: invalid index %d", d.Index)
+		}
+		if len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("embeddings: empty vector at index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+var _ Embedder = (*OpenAICompatibleClient)(nil)
@@ -0,0 +1,101 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// embeddingServer 返回逆序 index 的向量，记录请求路径、鉴权头与请求体
+type embeddingServer struct {
+	*httptest.Server
+	path, auth string
+	body       struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}
+	drop int // 少返回的向量数
+}
+
+func newEmbeddingServer(t *testing.T) *embeddingServer {
+	t.Helper()
+	s := &embeddingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.path, s.auth = r.URL.Path, r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&s.body)
+		var data []map[string]interface{}
+		for i := len(s.body.Input) - 1; i >= s.drop; i-- {
+			data = append(data, map[string]interface{}{"index": i, "embedding": []float32{float32(i), 1}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestEmbedAlignsVectorsByIndex(t *testing.T) {
+	srv := newEmbeddingServer(t)
+	embedder, err := NewLLMClientFactory().NewEmbedder(&domain.LLMConfig{
+		Provider: domain.LLMProviderOpenAI,
+		Endpoint: srv.URL,
+		APIKey:   "sk-test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.path != "/embeddings" || srv.auth != "Bearer sk-test" {
+		t.Errorf("request = %s with %q, want /embeddings with the API key", srv.path, srv.auth)
+	}
+	if srv.body.Model != defaultOpenAIEmbeddingModel || strings.Join(srv.body.Input, ",") != "a,b,c" {
+		t.Errorf("body = %+v, want the default model and inputs in order", srv.body)
+	}
+	for i, v := range vectors {
+		if len(v) != 2 || v[0] != float32(i) {
+			t.Errorf("vector %d = %v, want the vector with index %d", i, v, i)
+		}
+	}
+
+	if vectors, err := embedder.Embed(context.Background(), nil); err != nil || vectors != nil {
+		t.Errorf("empty input = %v, %v, want no request", vectors, err)
+	}
+	srv.drop = 1
+	if _, err := embedder.Embed(context.Background(), []string{"a", "b"}); err == nil || !strings.Contains(err.Error(), "got 1 vectors for 2 inputs") {
+		t.Errorf("short response err = %v", err)
+	}
+}
+
+func TestNewEmbedderProviders(t *testing.T) {
+	srv := newEmbeddingServer(t)
+	factory := NewLLMClientFactory()
+
+	if _, err := factory.NewEmbedder(&domain.LLMConfig{Provider: domain.LLMProviderAnthropic, EmbeddingModel: "x"}); err == nil {
+		t.Error("anthropic embedder created")
+	}
+	if _, err := factory.NewEmbedder(&domain.LLMConfig{Provider: domain.LLMProviderOllama, Endpoint: srv.URL}); err == nil {
+		t.Error("ollama embedder created without an embedding model")
+	}
+
+	config := &domain.LLMConfig{Provider: domain.LLMProviderOllama, Endpoint: srv.URL + "/", EmbeddingModel: "nomic-embed-text"}
+	embedder, err := factory.NewEmbedder(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := embedder.Embed(context.Background(), []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if srv.path != "/v1/embeddings" || srv.body.Model != "nomic-embed-text" {
+		t.Errorf("ollama request = %s model %s, want the /v1 compatible endpoint", srv.path, srv.body.Model)
+	}
+	if config.Endpoint != srv.URL+"/" {
+		t.Errorf("config endpoint modified to %q", config.Endpoint)
+	}
+}
@@ -0,0 +1,53 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// fallbackClient 按顺序尝试主模型和备用模型，前一个在重试耗尽后仍失败时切换到下一个
+type fallbackClient struct {
+	configs []*domain.LLMConfig
+	clients []LLMClient
+}
+
+// Chat 发送对话请求，Response.Model 记录实际提供响应的模型
+func (c *fallbackClient) Chat(ctx context.Context, messages []Message) (*Response, error) {
+	var errs []error
+	for i, client := range c.clients {
+		resp, err := client.Chat(ctx, messages)
+		if err == nil {
+			resp.Model = c.configs[i].Name()
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", c.configs[i].Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+		if i+1 < len(c.clients) {
+			log.Printf("[LLM] %s failed, falling back to %s: %v", c.configs[i].Name(), c.configs[i+1].Name(), err)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Validate 任一模型可用即视为配置有效
+func (c *fallbackClient) Validate(ctx context.Context) error {
+	var errs []error
+	for i, client := range c.clients {
+		err := client.Validate(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", c.configs[i].Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
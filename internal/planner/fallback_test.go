@@ -0,0 +1,66 @@
+package planner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+func TestFallbackChain(t *testing.T) {
+	overloaded := chatReply{status: http.StatusTooManyRequests, body: `{"error":{"message":"overloaded"}}`}
+	tests := []struct {
+		name          string
+		fallback      chatReply
+		wantModel     string
+		wantErr       error
+		wantFallbacks int
+	}{
+		{"fallback serves the plan", chatReply{content: validPlan}, "openai/gpt-fallback", nil, 1},
+		{"every model fails", chatReply{status: http.StatusServiceUnavailable, body: `{"error":{"message":"down"}}`}, "", ErrLLMServer, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newChatServer(t, overloaded)
+			secondary := newChatServer(t, tt.fallback)
+			config := primary.config(&domain.LLMOptions{RetryCount: domain.Int(1)})
+			fallback := secondary.config(&domain.LLMOptions{RetryCount: domain.Int(1)})
+			fallback.Model = "gpt-fallback"
+			config.Fallbacks = []*domain.LLMConfig{fallback}
+
+			factory := NewLLMClientFactory()
+			factory.SetRetryJitter(0)
+			client, err := factory.NewClient(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan, err := NewAIPlanner(client).ParseTask(context.Background(), &PlanRequest{UserInput: "submit", TargetURL: "https://app.example.com"})
+
+			// 主模型重试耗尽后才切换
+			if primary.calls() != 2 {
+				t.Errorf("primary calls = %d, want 2", primary.calls())
+			}
+			if secondary.calls() != tt.wantFallbacks {
+				t.Errorf("fallback calls = %d, want %d", secondary.calls(), tt.wantFallbacks)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), "openai/gpt-test") || !strings.Contains(err.Error(), "openai/gpt-fallback") {
+					t.Errorf("err = %v, want %v naming both models", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.Model != tt.wantModel || len(plan.Steps) != 1 {
+				t.Errorf("plan model %q steps %d, want %q", plan.Model, len(plan.Steps), tt.wantModel)
+			}
+			if secondary.request(0)["model"] != "gpt-fallback" {
+				t.Errorf("fallback request model = %v", secondary.request(0)["model"])
+			}
+		})
+	}
+}
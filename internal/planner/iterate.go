@@ -0,0 +1,142 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/browser-automation/internal/browser"
+)
+
+// IterationRequest 迭代规划一轮的请求：任务目标、已执行的步骤与当前页面
+type IterationRequest struct {
+	UserInput     string
+	TargetURL     string
+	PageSnapshot  *browser.PageSnapshot
+	History       []StepResult // 之前各轮已执行步骤的结果，按执行顺序
+	Iteration     int          // 本轮序号，从 1 开始
+	MaxIterations int
+	Language      string
+}
+
+// IterationPlan 一轮规划的结果
+type IterationPlan struct {
+	// Done 模型判断任务已完成；同时给出步骤时执行完这些步骤后结束
+	Done   bool         `json:"done"`
+	Reason string       `json:"reason,omitempty"` // 本轮目的或判断完成的依据
+	Steps  []ActionStep `json:"steps"`
+	Model  string       `json:"-"`
+}
+
+// maxHistoryError 已执行步骤中错误信息的长度上限（字符）
+const maxHistoryError = 160
+
+// PlanNextSteps 迭代规划：根据当前页面与已执行的步骤规划下一批步骤，或判断任务已完成。
+// 未完成时步骤经过与 ParseTask 相同的修正与校验
+func (p *AIPlanner) PlanNextSteps(ctx context.Context, req *IterationRequest) (*IterationPlan, error) {
+	prompts := promptsFor(req.Language)
+	baseURL := req.TargetURL
+	if req.PageSnapshot != nil && req.PageSnapshot.URL != "" {
+		baseURL = req.PageSnapshot.URL
+	}
+
+	messages := []Message{{Role: "system", Content: prompts.system}}
+	for _, ex := range p.planning.BudgetedExamples() {
+		messages = append(messages,
+			Message{Role: "user", Content: ex.User},
+			Message{Role: "assistant", Content: ex.Assistant})
+	}
+	messages = append(messages, Message{Role: "user", Content: p.buildIterationPrompt(req)})
+
+	resp, err := p.chatUntilComplete(ctx, prompts, messages)
+	if err != nil {
+		return nil, fmt.Errorf("llm chat: %w", err)
+	}
+	next, errs, err := p.decodeIteration(resp, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		log.Printf("[Planner] Iteration %d plan has %d problem(s), requesting correction: %v", req.Iteration, len(errs), joinErrors(errs))
+		corrected := append(append([]Message(nil), messages...),
+			Message{Role: "assistant", Content: resp.Content},
+			Message{Role: "user", Content: correctionPrompt(prompts, errs)})
+		if resp, err = p.chatUntilComplete(ctx, prompts, corrected); err != nil {
+			return nil, fmt.Errorf("llm chat: correction: %w", err)
+		}
+		if next, errs, err = p.decodeIteration(resp, baseURL); err != nil {
+			return nil, err
+		}
+		if len(errs) > 0 {
+			return nil, newPlanError("invalid plan after correction", resp.Content, joinErrors(errs))
+		}
+	}
+	inferNavigation(next.Steps)
+	next.Model = resp.Model
+	return next, nil
+}
+
+// decodeIteration 解析一轮规划的响应并修正、校验步骤，返回的 errs 为需要模型修正的问题
+func (p *AIPlanner) decodeIteration(resp *Response, baseURL string) (*IterationPlan, []error, error) {
+	var next IterationPlan
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Content)), &next); err != nil {
+		if p.planning.RequireStrictJSON() {
+			return nil, nil, newPlanError("parse plan: response is not pure JSON", resp.Content, err)
+		}
+		if err := json.Unmarshal([]byte(extractJSON(resp.Content)), &next); err != nil {
+			return nil, nil, newPlanError("parse plan", resp.Content, err)
+		}
+	}
+	if next.Done && len(next.Steps) == 0 {
+		return &next, nil, nil
+	}
+	plan := &TaskPlan{Steps: next.Steps}
+	normalizePlan(plan, baseURL)
+	next.Steps = plan.Steps
+	return &next, validatePlan(plan), nil
+}
+
+func (p *AIPlanner) buildIterationPrompt(req *IterationRequest) string {
+	prompts := promptsFor(req.Language)
+	history := prompts.historyNone
+	if len(req.History) > 0 {
+		var b strings.Builder
+		for _, r := range req.History {
+			status := prompts.historyOK
+			switch {
+			case r.Skipped:
+				status = prompts.historySkipped
+			case !r.Success:
+				status = prompts.historyFailed + ": " + truncateRunes(r.Error, maxHistoryError)
+			}
+			op := r.Action
+			if r.Selector != "" {
+				op += " " + r.Selector
+			}
+			fmt.Fprintf(&b, "%d. [%s] %s (%s)\n", r.Order, status, r.Description, op)
+		}
+		history = b.String()
+	}
+
+	pageInfo := ""
+	if s := req.PageSnapshot; s != nil {
+		pageInfo = fmt.Sprintf(prompts.pageInfo, s.URL, s.Title, formatElements(s.Elements))
+		pageInfo += prompts.stats(s.Stats)
+		if s.Truncated {
+			pageInfo += fmt.Sprintf(prompts.truncated, s.DOMSize)
+		}
+	}
+	return fmt.Sprintf(prompts.iterate, req.UserInput, req.TargetURL, req.Iteration, req.MaxIterations, history, pageInfo)
+}
+
+// truncateRunes 按字符截断，超出时以省略号结尾
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
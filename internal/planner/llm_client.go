@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -31,6 +32,7 @@ type Response struct {
 	Content      string `json:"content"`
 	FinishReason string `json:"finish_reason"`
 	Usage        *Usage `json:"usage"`
+	Model        string `json:"model,omitempty"` // 配置了备用模型时，实际提供响应的模型
 }
 
 // Usage 使用量
@@ -42,33 +44,98 @@ type Usage struct {
 
 // LLMClientFactory LLM 客户端工厂
 type LLMClientFactory struct {
-	httpClient *http.Client
+	httpClient       *http.Client
+	logPolicy        LogPolicy
+	maxResponseBytes int64
+	breakers         *breakerSet
+	retryJitter      float64
 }
 
 // NewLLMClientFactory 创建 LLM 客户端工厂
 func NewLLMClientFactory() *LLMClientFactory {
 	return &LLMClientFactory{
 		httpClient: &http.Client{
-			Timeout: 120 * time.Second, // 增加超时时间
+			Timeout:   120 * time.Second, // 增加超时时间
+			Transport: newTransport(DefaultTransportOptions()),
 		},
+		logPolicy:        DefaultLogPolicy(),
+		maxResponseBytes: DefaultMaxResponseBytes,
+		breakers:         newBreakerSet(CircuitBreakerOptions{}),
+		retryJitter:      DefaultRetryJitter,
 	}
 }
 
-// NewClient 根据配置创建客户端
+// SetLogPolicy 设置 LLM 请求日志策略
+func (f *LLMClientFactory) SetLogPolicy(policy LogPolicy) {
+	f.logPolicy = policy
+}
+
+// SetMaxResponseBytes 设置 LLM 响应体大小上限，<=0 时使用默认值
+func (f *LLMClientFactory) SetMaxResponseBytes(n int64) {
+	if n <= 0 {
+		n = DefaultMaxResponseBytes
+	}
+	f.maxResponseBytes = n
+}
+
+func (f *LLMClientFactory) newSender() sender {
+	return sender{
+		httpClient:       f.httpClient,
+		logPolicy:        f.logPolicy,
+		maxResponseBytes: f.maxResponseBytes,
+		breakers:         f.breakers,
+		retryJitter:      f.retryJitter,
+	}
+}
+
+// NewClient 根据配置创建客户端，配置了备用模型时返回按顺序切换的客户端
 func (f *LLMClientFactory) NewClient(config *domain.LLMConfig) (LLMClient, error) {
+	if len(config.Fallbacks) == 0 {
+		return f.newClient(config)
+	}
+	chain := &fallbackClient{}
+	for _, cfg := range append([]*domain.LLMConfig{config}, config.Fallbacks...) {
+		client, err := f.newClient(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.Name(), err)
+		}
+		chain.configs = append(chain.configs, cfg)
+		chain.clients = append(chain.clients, client)
+	}
+	return chain, nil
+}
+
+// newClient 创建单个模型的客户端。客户端使用配置的副本，合并默认选项、补全默认地址不影响调用方（如存储中的任务）
+func (f *LLMClientFactory) newClient(base *domain.LLMConfig) (LLMClient, error) {
+	cfg := *base
+	cfg.Options = domain.MergeLLMOptions(base.Options)
+	config := &cfg
 	switch config.Provider {
 	case domain.LLMProviderAnthropic:
-		return NewAnthropicClient(config, f.httpClient), nil
+		client := NewAnthropicClient(config, f.httpClient)
+		client.sender = f.newSender()
+		return client, nil
+	case domain.LLMProviderOllama:
+		if config.OpenAICompat {
+			client := NewOpenAICompatibleClient(config, f.httpClient)
+			client.sender = f.newSender()
+			return client, nil
+		}
+		client := NewOllamaClient(config, f.httpClient)
+		client.sender = f.newSender()
+		return client, nil
 	default:
 		// OpenAI 兼容接口（包括 OpenAI、DeepSeek、Ollama、本地代理等）
-		return NewOpenAICompatibleClient(config, f.httpClient), nil
+		client := NewOpenAICompatibleClient(config, f.httpClient)
+		client.sender = f.newSender()
+		return client, nil
 	}
 }
 
 // OpenAICompatibleClient OpenAI 兼容客户端
 type OpenAICompatibleClient struct {
-	config     *domain.LLMConfig
-	httpClient *http.Client
+	config *domain.LLMConfig
+	sender
 }
 
 // NewOpenAICompatibleClient 创建 OpenAI 兼容客户端
@@ -88,24 +155,33 @@ func NewOpenAICompatibleClient(config *domain.LLMConfig, httpClient *http.Client
 			config.Endpoint = "https://dashscope.aliyuncs.com/compatible-mode/v1"
 		}
 	}
-	return &OpenAICompatibleClient{config: config, httpClient: httpClient}
+	return &OpenAICompatibleClient{config: config, sender: newSender(httpClient)}
 }
 
 // Chat 发送对话请求
 func (c *OpenAICompatibleClient) Chat(ctx context.Context, messages []Message) (*Response, error) {
 	log.Printf("[LLM] Chat request: model=%s, endpoint=%s", c.config.Model, c.config.Endpoint)
-	
+
 	reqBody := map[string]interface{}{
 		"model":    c.config.Model,
 		"messages": messages,
 	}
-	
-	if c.config.Options != nil {
-		if c.config.Options.Temperature > 0 {
-			reqBody["temperature"] = c.config.Options.Temperature
+
+	if opts := c.config.Options; opts != nil {
+		if opts.Temperature != nil {
+			reqBody["temperature"] = *opts.Temperature
+		}
+		if opts.MaxTokens > 0 {
+			reqBody["max_tokens"] = opts.MaxTokens
 		}
-		if c.config.Options.MaxTokens > 0 {
-			reqBody["max_tokens"] = c.config.Options.MaxTokens
+		if opts.TopP != nil {
+			reqBody["top_p"] = *opts.TopP
+		}
+		if opts.FrequencyPenalty != 0 {
+			reqBody["frequency_penalty"] = opts.FrequencyPenalty
+		}
+		if opts.PresencePenalty != 0 {
+			reqBody["presence_penalty"] = opts.PresencePenalty
 		}
 	}
 
@@ -113,38 +189,30 @@ func (c *OpenAICompatibleClient) Chat(ctx context.Context, messages []Message) (
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
-	
-	log.Printf("[LLM] Request body size: %d bytes", len(body))
-
-	req, err := http.NewRequestWithContext(ctx, "POST",
-		c.config.Endpoint+"/chat/completions", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
 
-	req.Header.Set("Content-Type", "application/json")
-	if c.config.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
-	}
+	c.logPolicy.Debugf("[LLM] Request body size: %d bytes", len(body))
 
-	log.Printf("[LLM] Sending request...")
-	resp, err := c.httpClient.Do(req)
+	c.logPolicy.Debugf("[LLM] Sending request...")
+	respBody, err := c.sendWithRetry(ctx, c.config.Options, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST",
+			c.config.Endpoint+"/chat/completions", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.config.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		}
+		applyHeaders(req, c.config.Headers)
+		return req, nil
+	})
 	if err != nil {
 		log.Printf("[LLM] Request failed: %v", err)
-		return nil, fmt.Errorf("send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	log.Printf("[LLM] Response status: %s", resp.Status)
-
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		log.Printf("[LLM] Error response: %s", string(respBody))
-		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(respBody))
+		return nil, err
 	}
 
 	var result OpenAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.Unmarshal(respBody, &result); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
@@ -152,7 +220,7 @@ func (c *OpenAICompatibleClient) Chat(ctx context.Context, messages []Message) (
 		return nil, fmt.Errorf("no choices in response")
 	}
 
-	log.Printf("[LLM] Response received, content length: %d", len(result.Choices[0].Message.Content))
+	c.logPolicy.Debugf("[LLM] Response received, content length: %d", len(result.Choices[0].Message.Content))
 
 	return &Response{
 		Content:      result.Choices[0].Message.Content,
@@ -173,6 +241,139 @@ func (c *OpenAICompatibleClient) Validate(ctx context.Context) error {
 	return err
 }
 
+// DefaultMaxResponseBytes 默认 LLM 响应体大小上限
+const DefaultMaxResponseBytes int64 = 8 << 20
+
+// ErrResponseTooLarge 响应体超过大小上限
+var ErrResponseTooLarge = errors.New("llm response too large")
+
+// sender 负责发送请求、重试、熔断、限制响应大小与日志策略，由各提供商客户端共享
+type sender struct {
+	httpClient       *http.Client
+	logPolicy        LogPolicy
+	maxResponseBytes int64
+	breakers         *breakerSet // 同一工厂创建的客户端共享，为 nil 时不熔断
+	retryJitter      float64
+}
+
+func newSender(httpClient *http.Client) sender {
+	return sender{
+		httpClient:       httpClient,
+		logPolicy:        DefaultLogPolicy(),
+		maxResponseBytes: DefaultMaxResponseBytes,
+		retryJitter:      DefaultRetryJitter,
+	}
+}
+
+// applyHeaders 合并配置的自定义请求头。已由客户端设置的头（Content-Type、认证等）不会被覆盖，
+// 需要自定义认证头时不填写 api_key 即可
+func applyHeaders(req *http.Request, headers map[string]string) {
+	for name, value := range headers {
+		if req.Header.Get(name) != "" {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+}
+
+// sendWithRetry 按 Timeout/RetryCount 发送请求，网络错误、限流与服务端错误会重试，
+// 重试间隔随次数线性增加并随机抖动
+func (s sender) sendWithRetry(ctx context.Context, opts *domain.LLMOptions, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, error) {
+	opts = domain.MergeLLMOptions(opts)
+	timeout := time.Duration(*opts.Timeout) * time.Second
+	retries := *opts.RetryCount
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("[LLM] Retrying (%d/%d) after error: %v", attempt, retries, lastErr)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(jitter(time.Duration(attempt)*time.Second, s.retryJitter)):
+			}
+		}
+
+		respBody, retryable, err := s.sendOnce(ctx, timeout, newRequest)
+		if err == nil {
+			return respBody, nil
+		}
+		lastErr = err
+		if !retryable || ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// sendOnce 发送单次请求，返回响应体以及错误是否可重试。接口处于熔断中时不发送，直接返回 ErrLLMCircuitOpen
+func (s sender) sendOnce(ctx context.Context, timeout time.Duration, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, bool, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := newRequest(reqCtx)
+	if err != nil {
+		return nil, false, fmt.Errorf("create request: %w", err)
+	}
+
+	b := s.breakers.get(req.URL)
+	if err := b.allow(); err != nil {
+		return nil, false, err
+	}
+	respBody, retryable, err := s.do(req)
+	b.record(classifyOutcome(ctx, err))
+	return respBody, retryable, err
+}
+
+// classifyOutcome 网络错误、超时与服务端错误计为接口故障；调用方取消不计入
+func classifyOutcome(ctx context.Context, err error) breakerOutcome {
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case ctx.Err() != nil:
+		return outcomeIgnore
+	case errors.As(err, &apiErr):
+		if errors.Is(err, ErrLLMServer) {
+			return outcomeFailure
+		}
+		return outcomeSuccess
+	case errors.Is(err, ErrResponseTooLarge):
+		return outcomeSuccess
+	}
+	return outcomeFailure
+}
+
+// do 发送请求并读取响应，返回响应体以及错误是否可重试
+func (s sender) do(req *http.Request) ([]byte, bool, error) {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	log.Printf("[LLM] Response status: %s", resp.Status)
+
+	if s.maxResponseBytes > 0 && resp.ContentLength > s.maxResponseBytes {
+		return nil, false, fmt.Errorf("%w: content-length %d exceeds %d bytes", ErrResponseTooLarge, resp.ContentLength, s.maxResponseBytes)
+	}
+	// 多读 1 字节用于判断是否超限
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, s.maxResponseBytes+1))
+	if err != nil {
+		return nil, true, fmt.Errorf("read response: %w", err)
+	}
+	if int64(len(respBody)) > s.maxResponseBytes {
+		return nil, false, fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, s.maxResponseBytes)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		s.logPolicy.Debugf("[LLM] Error response: %s", s.logPolicy.FormatBody(respBody))
+		apiErr := newAPIError(resp, respBody, s.logPolicy)
+		return nil, apiErr.Retryable(), apiErr
+	}
+	return respBody, false, nil
+}
+
 // OpenAIResponse OpenAI API 响应
 type OpenAIResponse struct {
 	Choices []struct {
@@ -190,8 +391,8 @@ type OpenAIResponse struct {
 
 // AnthropicClient Anthropic 客户端
 type AnthropicClient struct {
-	config     *domain.LLMConfig
-	httpClient *http.Client
+	config *domain.LLMConfig
+	sender
 }
 
 // NewAnthropicClient 创建 Anthropic 客户端
@@ -199,7 +400,7 @@ func NewAnthropicClient(config *domain.LLMConfig, httpClient *http.Client) *Anth
 	if config.Endpoint == "" {
 		config.Endpoint = "https://api.anthropic.com/v1"
 	}
-	return &AnthropicClient{config: config, httpClient: httpClient}
+	return &AnthropicClient{config: config, sender: newSender(httpClient)}
 }
 
 // Chat 发送对话请求
@@ -210,12 +411,16 @@ func (c *AnthropicClient) Chat(ctx context.Context, messages []Message) (*Respon
 		"max_tokens": 4096,
 	}
 
-	if c.config.Options != nil {
-		if c.config.Options.Temperature > 0 {
-			reqBody["temperature"] = c.config.Options.Temperature
+	if opts := c.config.Options; opts != nil {
+		if opts.Temperature != nil {
+			reqBody["temperature"] = *opts.Temperature
+		}
+		if opts.MaxTokens > 0 {
+			reqBody["max_tokens"] = opts.MaxTokens
 		}
-		if c.config.Options.MaxTokens > 0 {
-			reqBody["max_tokens"] = c.config.Options.MaxTokens
+		// Anthropic 不支持 frequency/presence penalty；top_p 为 1 时与 API 默认一致，无需发送
+		if opts.TopP != nil && *opts.TopP < 1 {
+			reqBody["top_p"] = *opts.TopP
 		}
 	}
 
@@ -224,29 +429,24 @@ func (c *AnthropicClient) Chat(ctx context.Context, messages []Message) (*Respon
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST",
-		c.config.Endpoint+"/messages", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", c.config.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	resp, err := c.httpClient.Do(req)
+	respBody, err := c.sendWithRetry(ctx, c.config.Options, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST",
+			c.config.Endpoint+"/messages", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", c.config.APIKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+		applyHeaders(req, c.config.Headers)
+		return req, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(respBody))
+		return nil, err
 	}
 
 	var result AnthropicResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.Unmarshal(respBody, &result); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
@@ -0,0 +1,345 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// chatServer 模拟 OpenAI 兼容接口，按顺序返回预设的响应，最后一个重复使用
+type chatServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	headers  []http.Header
+	replies  []chatReply
+}
+
+// chatReply 一次响应：status 为 0 时返回 200 与 content
+type chatReply struct {
+	status  int
+	body    string
+	content string
+	finish  string
+}
+
+func newChatServer(t *testing.T, replies ...chatReply) *chatServer {
+	t.Helper()
+	s := &chatServer{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *chatServer) serve(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+	var body map[string]interface{}
+	json.Unmarshal(data, &body)
+
+	s.mu.Lock()
+	n := len(s.requests)
+	s.requests = append(s.requests, body)
+	s.headers = append(s.headers, r.Header.Clone())
+	reply := chatReply{content: "ok"}
+	if len(s.replies) > 0 {
+		reply = s.replies[min(n, len(s.replies)-1)]
+	}
+	s.mu.Unlock()
+
+	if reply.status != 0 {
+		w.WriteHeader(reply.status)
+		io.WriteString(w, reply.body)
+		return
+	}
+	if reply.finish == "" {
+		reply.finish = "stop"
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"choices": []map[string]interface{}{{
+			"message":       map[string]string{"role": "assistant", "content": reply.content},
+			"finish_reason": reply.finish,
+		}},
+		"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+	})
+}
+
+// calls 返回收到的请求数
+func (s *chatServer) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// request 返回第 i 个请求体
+func (s *chatServer) request(i int) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[i]
+}
+
+// config 返回指向该服务的 OpenAI 配置
+func (s *chatServer) config(opts *domain.LLMOptions) *domain.LLMConfig {
+	return &domain.LLMConfig{
+		Provider: domain.LLMProviderOpenAI,
+		Model:    "gpt-test",
+		Endpoint: s.URL,
+		APIKey:   "sk-test",
+		Options:  opts,
+	}
+}
+
+func TestChatSendsMergedOptions(t *testing.T) {
+	srv := newChatServer(t)
+	client, err := NewLLMClientFactory().NewClient(srv.config(&domain.LLMOptions{
+		TopP:             domain.Float64(0.5),
+		FrequencyPenalty: 0.3,
+		PresencePenalty:  0.2,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := srv.request(0)
+	want := map[string]interface{}{
+		"model":             "gpt-test",
+		"temperature":       0.7,
+		"max_tokens":        4096.0,
+		"top_p":             0.5,
+		"frequency_penalty": 0.3,
+		"presence_penalty":  0.2,
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("%s = %v, want %v", key, body[key], value)
+		}
+	}
+}
+
+func TestChatTemperatureZeroIsSent(t *testing.T) {
+	tests := []struct {
+		provider domain.LLMProvider
+		options  string // 采样参数所在的字段，为空时位于请求体顶层
+		topP     bool   // 未设置时是否发送默认的 top_p 1，Anthropic 只发送小于 1 的值
+	}{
+		{domain.LLMProviderOpenAI, "", true},
+		{domain.LLMProviderAnthropic, "", false},
+		{domain.LLMProviderOllama, "options", true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			var mu sync.Mutex
+			var bodies []map[string]interface{}
+			// 同时包含三种接口的响应字段
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&body)
+				mu.Lock()
+				bodies = append(bodies, body)
+				mu.Unlock()
+				io.WriteString(w, `{"choices": [{"message": {"content": "ok"}, "finish_reason": "stop"}],
+"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn",
+"message": {"role": "assistant", "content": "ok"}, "done": true}`)
+			}))
+			defer srv.Close()
+
+			for _, opts := range []*domain.LLMOptions{
+				{Temperature: domain.Float64(0), TopP: domain.Float64(0)},
+				{},
+			} {
+				opts.RetryCount = domain.Int(0)
+				client, _ := NewLLMClientFactory().NewClient(&domain.LLMConfig{Provider: tt.provider, Model: "m", Endpoint: srv.URL, APIKey: "sk", Options: opts})
+				if _, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			params := func(body map[string]interface{}) map[string]interface{} {
+				if tt.options == "" {
+					return body
+				}
+				m, _ := body[tt.options].(map[string]interface{})
+				return m
+			}
+			explicit, unset := params(bodies[0]), params(bodies[1])
+			if v, ok := explicit["temperature"]; !ok || v != 0.0 {
+				t.Errorf("temperature = %v (present %v), want 0", v, ok)
+			}
+			if v, ok := explicit["top_p"]; !ok || v != 0.0 {
+				t.Errorf("top_p = %v (present %v), want 0", v, ok)
+			}
+			// 未设置时使用默认值
+			if v := unset["temperature"]; v != 0.7 {
+				t.Errorf("default temperature = %v, want 0.7", v)
+			}
+			if v, ok := unset["top_p"]; ok != tt.topP || (ok && v != 1.0) {
+				t.Errorf("default top_p = %v (present %v)", v, ok)
+			}
+		})
+	}
+}
+
+func TestChatRetryCount(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryCount *int
+		wantCalls  int
+	}{
+		{"zero disables retries", domain.Int(0), 1},
+		{"explicit count", domain.Int(1), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newChatServer(t, chatReply{status: http.StatusServiceUnavailable, body: `{"error":{"message":"busy"}}`})
+			factory := NewLLMClientFactory()
+			factory.SetRetryJitter(0)
+			client, _ := factory.NewClient(srv.config(&domain.LLMOptions{RetryCount: tt.retryCount}))
+
+			_, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}})
+			if !errors.Is(err, ErrLLMServer) {
+				t.Fatalf("err = %v, want ErrLLMServer", err)
+			}
+			if got := srv.calls(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMergeLLMOptionsDefaults(t *testing.T) {
+	merged := domain.MergeLLMOptions(&domain.LLMOptions{MaxTokens: 100})
+	if *merged.Timeout != 60 || *merged.RetryCount != 3 || *merged.Temperature != 0.7 || merged.MaxTokens != 100 {
+		t.Errorf("merged = timeout %d retry %d temperature %v max_tokens %d",
+			*merged.Timeout, *merged.RetryCount, *merged.Temperature, merged.MaxTokens)
+	}
+}
+
+func TestNewClientLeavesConfigUntouched(t *testing.T) {
+	config := &domain.LLMConfig{Provider: domain.LLMProviderDeepSeek, Model: "deepseek-chat", APIKey: "sk"}
+	if _, err := NewLLMClientFactory().NewClient(config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Options != nil || config.Endpoint != "" {
+		t.Errorf("config modified: options %+v, endpoint %q", config.Options, config.Endpoint)
+	}
+}
+
+func TestOversizedResponseIsBounded(t *testing.T) {
+	big := strings.Repeat("x", 64*1024)
+	tests := []struct {
+		name     string
+		provider domain.LLMProvider
+		chunked  bool // 不设置 Content-Length，只能在读取时截断
+	}{
+		{"openai with content-length", domain.LLMProviderOpenAI, false},
+		{"openai chunked", domain.LLMProviderOpenAI, true},
+		{"anthropic chunked", domain.LLMProviderAnthropic, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				if !tt.chunked {
+					w.Header().Set("Content-Length", strconv.Itoa(len(big)))
+				}
+				io.WriteString(w, big)
+				if tt.chunked {
+					w.(http.Flusher).Flush()
+				}
+			}))
+			defer srv.Close()
+
+			factory := NewLLMClientFactory()
+			factory.SetMaxResponseBytes(1024)
+			factory.SetRetryJitter(0)
+			client, _ := factory.NewClient(&domain.LLMConfig{
+				Provider: tt.provider, Model: "m", Endpoint: srv.URL, APIKey: "sk",
+				Options: &domain.LLMOptions{RetryCount: domain.Int(2)},
+			})
+			_, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}})
+			if !errors.Is(err, ErrResponseTooLarge) {
+				t.Fatalf("err = %v, want ErrResponseTooLarge", err)
+			}
+			if len(err.Error()) > 200 {
+				t.Errorf("error message is %d bytes, includes the body", len(err.Error()))
+			}
+			if calls.Load() != 1 {
+				t.Errorf("calls = %d, oversized response retried", calls.Load())
+			}
+		})
+	}
+}
+
+func TestCustomHeadersReachRequest(t *testing.T) {
+	tests := []struct {
+		provider domain.LLMProvider
+		authName string
+		auth     string
+	}{
+		{domain.LLMProviderOpenAI, "Authorization", "Bearer sk-test"},
+		{domain.LLMProviderAnthropic, "X-Api-Key", "sk-test"},
+		{domain.LLMProviderOllama, "Authorization", "Bearer sk-test"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			var mu sync.Mutex
+			var got http.Header
+			// 同时包含三种接口的响应字段
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				got = r.Header.Clone()
+				mu.Unlock()
+				io.WriteString(w, `{"choices": [{"message": {"content": "ok"}, "finish_reason": "stop"}],
+"content": [{"type": "text", "text": "ok"}], "stop_reason": "end_turn",
+"message": {"role": "assistant", "content": "ok"}, "done": true}`)
+			}))
+			defer srv.Close()
+
+			client, err := NewLLMClientFactory().NewClient(&domain.LLMConfig{
+				Provider: tt.provider,
+				Model:    "m",
+				Endpoint: srv.URL,
+				APIKey:   "sk-test",
+				Headers: map[string]string{
+					"OpenAI-Organization": "org-1",
+					"X-Route-Key":         "eu",
+					"Content-Type":        "text/plain",
+					tt.authName:           "spoofed",
+				},
+				Options: &domain.LLMOptions{RetryCount: domain.Int(0)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got.Get("OpenAI-Organization") != "org-1" || got.Get("X-Route-Key") != "eu" {
+				t.Errorf("custom headers missing: %v", got)
+			}
+			// 客户端管理的头不被覆盖
+			if got.Get("Content-Type") != "application/json" || got.Get(tt.authName) != tt.auth {
+				t.Errorf("managed headers overridden: Content-Type %q, %s %q", got.Get("Content-Type"), tt.authName, got.Get(tt.authName))
+			}
+		})
+	}
+}
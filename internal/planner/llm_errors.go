@@ -0,0 +1,136 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// LLM 接口错误的分类，用 errors.Is 判断，如 errors.Is(err, ErrLLMAuth)
+var (
+	ErrLLMAuth          = errors.New("llm authentication failed") // API Key 无效或无权限，需修改配置
+	ErrLLMRateLimit     = errors.New("llm rate limited")          // 限流，需稍后重试
+	ErrLLMQuota         = errors.New("llm quota exceeded")        // 账户额度或余额不足，需充值或更换 API Key
+	ErrLLMContentFilter = errors.New("llm content filtered")      // 请求或响应被内容审核拦截
+	ErrLLMBadRequest    = errors.New("llm rejected request")      // 请求无效，如模型不存在、上下文过长
+	ErrLLMServer        = errors.New("llm server error")          // 服务端错误或过载
+	errLLMUnknown       = errors.New("llm request failed")        // 其他状态码
+)
+
+// APIError LLM 接口返回的非 200 响应，保留提供商的原始错误信息
+type APIError struct {
+	StatusCode int
+	Status     string
+	Type       string // 提供商的错误类型，如 invalid_request_error
+	Code       string // 提供商的错误代码，如 invalid_api_key
+	Message    string // 提供商的错误说明，无法解析时为（已脱敏并截断的）响应体
+	kind       error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v (%s): %s", e.kind, e.Status, e.Message)
+}
+
+// Unwrap 返回错误分类
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// Retryable 限流与服务端错误可以重试，其他错误重试也不会成功
+func (e *APIError) Retryable() bool {
+	return e.kind == ErrLLMRateLimit || e.kind == ErrLLMServer
+}
+
+// providerError 各提供商错误响应的共同结构：
+// OpenAI 兼容 {"error":{"message","type","code"}}、Anthropic {"type":"error","error":{"type","message"}}、
+// Ollama {"error":"..."}
+type providerError struct {
+	Error json.RawMessage `json:"error"`
+}
+
+type providerErrorDetail struct {
+	Message    string          `json:"message"`
+	Type       string          `json:"type"`
+	Code       json.RawMessage `json:"code"`
+	InnerError struct {
+		Code string `json:"code"`
+	} `json:"innererror"` // Azure OpenAI 内容审核的详细原因
+}
+
+// newAPIError 解析错误响应体并分类，错误说明按日志策略脱敏并截断
+func newAPIError(resp *http.Response, body []byte, policy LogPolicy) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode, Status: resp.Status, Message: policy.FormatBody(body)}
+
+	var pe providerError
+	if json.Unmarshal(body, &pe) == nil && len(pe.Error) > 0 {
+		var detail providerErrorDetail
+		var msg string
+		switch {
+		case json.Unmarshal(pe.Error, &msg) == nil:
+			e.Message = policy.FormatBody([]byte(msg))
+		case json.Unmarshal(pe.Error, &detail) == nil:
+			if detail.Message != "" {
+				e.Message = policy.FormatBody([]byte(detail.Message))
+			}
+			e.Type = detail.Type
+			e.Code = rawCode(detail.Code)
+			if detail.InnerError.Code != "" {
+				e.Code = detail.InnerError.Code
+			}
+		}
+	}
+	e.kind = classifyAPIError(e)
+	return e
+}
+
+// rawCode 错误代码可能是字符串或数字
+func rawCode(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// classifyAPIError 优先按错误类型和代码分类（部分提供商用 400 返回内容审核、用 429 返回额度不足），再按状态码分类
+func classifyAPIError(e *APIError) error {
+	tag := strings.ToLower(e.Type + " " + e.Code)
+	switch {
+	case strings.Contains(tag, "content_filter") || strings.Contains(tag, "content_policy") ||
+		strings.Contains(tag, "responsibleaipolicyviolation") || strings.Contains(tag, "safety"):
+		return ErrLLMContentFilter
+	case strings.Contains(tag, "authentication") || strings.Contains(tag, "permission") ||
+		strings.Contains(tag, "invalid_api_key"):
+		return ErrLLMAuth
+	case strings.Contains(tag, "insufficient_quota") || strings.Contains(tag, "billing"):
+		return ErrLLMQuota
+	case strings.Contains(tag, "rate_limit"):
+		return ErrLLMRateLimit
+	case strings.Contains(tag, "overloaded"):
+		return ErrLLMServer
+	}
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrLLMAuth
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrLLMRateLimit
+	case e.StatusCode >= 500:
+		return ErrLLMServer
+	case e.StatusCode >= 400:
+		return ErrLLMBadRequest
+	}
+	return errLLMUnknown
+}
+
+// RetryableLLMError 判断 LLM 调用失败后整体重试是否可能成功，认证、额度不足、请求无效和内容审核错误不会因重试而改变，
+// 接口熔断期间立即重试也只会再次失败
+func RetryableLLMError(err error) bool {
+	return !errors.Is(err, ErrLLMAuth) && !errors.Is(err, ErrLLMQuota) && !errors.Is(err, ErrLLMBadRequest) &&
+		!errors.Is(err, ErrLLMContentFilter) && !errors.Is(err, ErrLLMCircuitOpen)
+}
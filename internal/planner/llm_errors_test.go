@@ -0,0 +1,50 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		want      error
+		retryable bool
+	}{
+		// OpenAI 兼容
+		{"openai invalid key", 401, `{"error": {"message": "Incorrect API key provided", "type": "invalid_request_error", "code": "invalid_api_key"}}`, ErrLLMAuth, false},
+		{"openai rate limit", 429, `{"error": {"message": "Rate limit reached for requests", "type": "requests", "code": "rate_limit_exceeded"}}`, ErrLLMRateLimit, true},
+		{"openai insufficient quota", 429, `{"error": {"message": "You exceeded your current quota", "type": "insufficient_quota", "code": "insufficient_quota"}}`, ErrLLMQuota, false},
+		{"openai billing", 403, `{"error": {"message": "Billing hard limit has been reached", "type": "billing_hard_limit_reached", "code": null}}`, ErrLLMQuota, false},
+		{"openai model not found", 404, `{"error": {"message": "The model gpt-9 does not exist", "type": "invalid_request_error", "code": "model_not_found"}}`, ErrLLMBadRequest, false},
+		{"openai content filter", 400, `{"error": {"message": "filtered", "type": null, "code": "content_filter"}}`, ErrLLMContentFilter, false},
+		{"azure content filter", 400, `{"error": {"message": "filtered", "code": "content_filter", "innererror": {"code": "ResponsibleAIPolicyViolation"}}}`, ErrLLMContentFilter, false},
+		{"openai server error", 500, `{"error": {"message": "The server had an error", "type": "server_error", "code": null}}`, ErrLLMServer, true},
+		// Anthropic
+		{"anthropic auth", 401, `{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`, ErrLLMAuth, false},
+		{"anthropic permission", 403, `{"type": "error", "error": {"type": "permission_error", "message": "not allowed"}}`, ErrLLMAuth, false},
+		{"anthropic rate limit", 429, `{"type": "error", "error": {"type": "rate_limit_error", "message": "Number of requests has exceeded your rate limit"}}`, ErrLLMRateLimit, true},
+		{"anthropic overloaded", 529, `{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`, ErrLLMServer, true},
+		{"anthropic invalid request", 400, `{"type": "error", "error": {"type": "invalid_request_error", "message": "max_tokens: field required"}}`, ErrLLMBadRequest, false},
+		// Ollama
+		{"ollama model missing", 404, `{"error": "model \"qwen9\" not found, try pulling it first"}`, ErrLLMBadRequest, false},
+		{"ollama server error", 500, `{"error": "llama runner process has terminated"}`, ErrLLMServer, true},
+		// 无法解析的响应体按状态码分类
+		{"plain text 429", 429, `Too Many Requests`, ErrLLMRateLimit, true},
+		{"plain text 502", 502, `<html>Bad Gateway</html>`, ErrLLMServer, true},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Status: fmt.Sprintf("%d %s", tt.status, http.StatusText(tt.status))}
+		err := newAPIError(resp, []byte(tt.body), DefaultLogPolicy())
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: classified as %v, want %v", tt.name, err.kind, tt.want)
+		}
+		if err.Retryable() != tt.retryable || RetryableLLMError(err) != tt.retryable {
+			t.Errorf("%s: retryable = %v/%v, want %v", tt.name, err.Retryable(), RetryableLLMError(err), tt.retryable)
+		}
+	}
+}
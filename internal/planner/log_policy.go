@@ -0,0 +1,54 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"unicode/utf8"
+)
+
+// LogPolicy LLM 请求日志策略
+type LogPolicy struct {
+	MaxBodyBytes int  // 日志及错误信息中请求/响应体的最大字节数，<=0 表示不截断
+	Debug        bool // 是否输出详细日志（请求体大小、错误响应体等）
+}
+
+// DefaultLogPolicy 默认日志策略
+func DefaultLogPolicy() LogPolicy {
+	return LogPolicy{MaxBodyBytes: 512}
+}
+
+// secretPatterns 需要脱敏的常见密钥格式
+var secretPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`), "${1}[REDACTED]"},
+	{regexp.MustCompile(`sk-[A-Za-z0-9_\-]{8,}`), "[REDACTED]"},
+	{regexp.MustCompile(`(?i)("?(?:api[_-]?key|x-api-key|access[_-]?token|token|secret|password)"?\s*[:=]\s*"?)[^"\s,}&]+`), "${1}[REDACTED]"},
+}
+
+// FormatBody 脱敏并截断请求/响应体，用于日志和错误信息
+func (p LogPolicy) FormatBody(body []byte) string {
+	s := string(body)
+	for _, pattern := range secretPatterns {
+		s = pattern.re.ReplaceAllString(s, pattern.repl)
+	}
+	if p.MaxBodyBytes > 0 && len(s) > p.MaxBodyBytes {
+		// 在字符边界截断，避免截断多字节字符
+		cut := p.MaxBodyBytes
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = fmt.Sprintf("%s...(truncated, %d bytes total)", s[:cut], len(s))
+	}
+	return s
+}
+
+// Debugf 仅在调试模式下输出日志
+func (p LogPolicy) Debugf(format string, args ...interface{}) {
+	if p.Debug {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,59 @@
+package planner
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogPolicyFormatBody(t *testing.T) {
+	policy := LogPolicy{MaxBodyBytes: 32}
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"short body unchanged", `{"ok":true}`, `{"ok":true}`},
+		{"long body truncated", strings.Repeat("a", 100), strings.Repeat("a", 32) + "...(truncated, 100 bytes total)"},
+		{"multibyte boundary", strings.Repeat("中", 20), strings.Repeat("中", 10) + "...(truncated, 60 bytes total)"},
+		{"bearer token", "Authorization: Bearer abc.def", "Authorization: Bearer [REDACTED]"},
+		{"api key", `{"api_key": "k-123"}`, `{"api_key": "[REDACTED]"}`},
+		{"openai key", "key sk-0123456789abcdef", "key [REDACTED]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.FormatBody([]byte(tt.body)); got != tt.want {
+				t.Errorf("FormatBody = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorBodyTruncatedInLogAndError(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	long := `{"error":{"message":"key sk-0123456789abcdef ` + strings.Repeat("x", 2000) + `"}}`
+	srv := newChatServer(t, chatReply{status: http.StatusBadRequest, body: long})
+	factory := NewLLMClientFactory()
+	factory.SetLogPolicy(LogPolicy{MaxBodyBytes: 64, Debug: true})
+	client, _ := factory.NewClient(srv.config(nil))
+
+	_, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}})
+	if err == nil {
+		t.Fatal("Chat succeeded on a 400 response")
+	}
+	for name, out := range map[string]string{"error": err.Error(), "log": logs.String()} {
+		if strings.Contains(out, strings.Repeat("x", 100)) || !strings.Contains(out, "truncated") {
+			t.Errorf("%s not truncated: %.200s", name, out)
+		}
+		if strings.Contains(out, "sk-0123456789abcdef") {
+			t.Errorf("%s contains the api key", name)
+		}
+	}
+}
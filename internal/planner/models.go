@@ -0,0 +1,117 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// ModelLister 查询提供商当前可用的模型
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// ListModels 查询提供商的模型列表接口：Ollama 原生配置使用 /api/tags，其余使用 OpenAI 兼容的 /models。
+// 返回按名称排序的模型，提供商不支持时返回错误
+func (f *LLMClientFactory) ListModels(ctx context.Context, config *domain.LLMConfig) ([]string, error) {
+	cfg := *config
+	cfg.Fallbacks = nil
+	client, err := f.newClient(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := client.(ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support model listing", cfg.Provider)
+	}
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// openAIModelsResponse OpenAI /models 响应
+type openAIModelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// ListModels 调用 /models 接口
+func (c *OpenAICompatibleClient) ListModels(ctx context.Context) ([]string, error) {
+	log.Printf("[LLM] List models request: endpoint=%s", c.config.Endpoint)
+	respBody, err := c.sendWithRetry(ctx, c.config.Options, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.config.Endpoint+"/models", nil)
+		if err != nil {
+			return nil, err
+		}
+		if c.config.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		}
+		applyHeaders(req, c.config.Headers)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result openAIModelsResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	models := make([]string, 0, len(result.Data))
+	for _, m := range result.Data {
+		if m.ID != "" {
+			models = append(models, m.ID)
+		}
+	}
+	return models, nil
+}
+
+// ollamaTagsResponse Ollama /api/tags 响应
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// ListModels 调用 /api/tags 接口，返回本地已拉取的模型
+func (c *OllamaClient) ListModels(ctx context.Context) ([]string, error) {
+	log.Printf("[LLM] Ollama list models request: endpoint=%s", c.config.Endpoint)
+	respBody, err := c.sendWithRetry(ctx, c.config.Options, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.config.Endpoint+"/api/tags", nil)
+		if err != nil {
+			return nil, err
+		}
+		applyHeaders(req, c.config.Headers)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result ollamaTagsResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	models := make([]string, 0, len(result.Models))
+	for _, m := range result.Models {
+		if m.Name != "" {
+			models = append(models, m.Name)
+		}
+	}
+	return models, nil
+}
+
+var (
+	_ ModelLister = (*OpenAICompatibleClient)(nil)
+	_ ModelLister = (*OllamaClient)(nil)
+)
@@ -0,0 +1,69 @@
+package planner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+func TestListModels(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/models":
+			if r.Header.Get("Authorization") != "Bearer sk-test" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"object": "list", "data": [{"id": "gpt-4o"}, {"id": ""}, {"id": "gpt-4o-mini"}, {"id": "dall-e-3"}]}`))
+		case "/api/tags":
+			w.Write([]byte(`{"models": [{"name": "qwen2.5:7b"}, {"name": "llama3.1:8b"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	noRetry := &domain.LLMOptions{RetryCount: domain.Int(0)}
+	tests := []struct {
+		name   string
+		config *domain.LLMConfig
+		want   string
+		path   string
+	}{
+		{"openai", &domain.LLMConfig{Provider: domain.LLMProviderOpenAI, Endpoint: srv.URL + "/v1", APIKey: "sk-test", Options: noRetry},
+			"dall-e-3,gpt-4o,gpt-4o-mini", "/v1/models"},
+		{"ollama native", &domain.LLMConfig{Provider: domain.LLMProviderOllama, Endpoint: srv.URL + "/v1", Options: noRetry},
+			"llama3.1:8b,qwen2.5:7b", "/api/tags"},
+	}
+	for _, tt := range tests {
+		paths = nil
+		models, err := NewLLMClientFactory().ListModels(context.Background(), tt.config)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := strings.Join(models, ","); got != tt.want {
+			t.Errorf("%s: models = %s, want %s", tt.name, got, tt.want)
+		}
+		if len(paths) != 1 || paths[0] != tt.path {
+			t.Errorf("%s: requested %v, want %s", tt.name, paths, tt.path)
+		}
+	}
+
+	// 密钥错误按认证错误返回，由调用方回退到预设列表
+	bad := &domain.LLMConfig{Provider: domain.LLMProviderOpenAI, Endpoint: srv.URL + "/v1", APIKey: "sk-wrong", Options: noRetry}
+	if _, err := NewLLMClientFactory().ListModels(context.Background(), bad); !errors.Is(err, ErrLLMAuth) {
+		t.Errorf("rejected key err = %v, want ErrLLMAuth", err)
+	}
+	// Anthropic 没有模型列表接口
+	anthropic := &domain.LLMConfig{Provider: domain.LLMProviderAnthropic, Endpoint: srv.URL, Options: noRetry}
+	if _, err := NewLLMClientFactory().ListModels(context.Background(), anthropic); err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Errorf("anthropic err = %v, want unsupported", err)
+	}
+}
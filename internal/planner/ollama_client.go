@@ -0,0 +1,132 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// defaultOllamaKeepAlive 默认模型保活时长，避免每次规划都重新加载模型
+const defaultOllamaKeepAlive = "10m"
+
+// OllamaClient Ollama 原生 /api/chat 客户端
+type OllamaClient struct {
+	config *domain.LLMConfig
+	sender
+}
+
+// NewOllamaClient 创建 Ollama 原生客户端
+func NewOllamaClient(config *domain.LLMConfig, httpClient *http.Client) *OllamaClient {
+	if config.Endpoint == "" {
+		config.Endpoint = "http://localhost:11434"
+	}
+	// 兼容预设中的 OpenAI 兼容端点
+	config.Endpoint = strings.TrimSuffix(strings.TrimSuffix(config.Endpoint, "/"), "/v1")
+	return &OllamaClient{config: config, sender: newSender(httpClient)}
+}
+
+// Chat 发送对话请求
+func (c *OllamaClient) Chat(ctx context.Context, messages []Message) (*Response, error) {
+	log.Printf("[LLM] Ollama chat request: model=%s, endpoint=%s", c.config.Model, c.config.Endpoint)
+
+	keepAlive := defaultOllamaKeepAlive
+	options := map[string]interface{}{}
+	if opts := c.config.Options; opts != nil {
+		if opts.KeepAlive != "" {
+			keepAlive = opts.KeepAlive
+		}
+		if opts.Temperature != nil {
+			options["temperature"] = *opts.Temperature
+		}
+		if opts.MaxTokens > 0 {
+			options["num_predict"] = opts.MaxTokens
+		}
+		if opts.TopP != nil {
+			options["top_p"] = *opts.TopP
+		}
+		if opts.FrequencyPenalty != 0 {
+			options["frequency_penalty"] = opts.FrequencyPenalty
+		}
+		if opts.PresencePenalty != 0 {
+			options["presence_penalty"] = opts.PresencePenalty
+		}
+	}
+
+	reqBody := map[string]interface{}{
+		"model":      c.config.Model,
+		"messages":   messages,
+		"stream":     false,
+		"format":     "json",
+		"keep_alive": keepAlive,
+		"options":    options,
+	}
+
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	c.logPolicy.Debugf("[LLM] Request body size: %d bytes", len(body))
+
+	respBody, err := c.sendWithRetry(ctx, c.config.Options, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST",
+			c.config.Endpoint+"/api/chat", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.config.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		}
+		applyHeaders(req, c.config.Headers)
+		return req, nil
+	})
+	if err != nil {
+		log.Printf("[LLM] Request failed: %v", err)
+		return nil, err
+	}
+
+	var result OllamaResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if result.Message.Content == "" {
+		return nil, fmt.Errorf("no content in response")
+	}
+
+	return &Response{
+		Content:      result.Message.Content,
+		FinishReason: result.DoneReason,
+		Usage: &Usage{
+			PromptTokens:     result.PromptEvalCount,
+			CompletionTokens: result.EvalCount,
+			TotalTokens:      result.PromptEvalCount + result.EvalCount,
+		},
+	}, nil
+}
+
+// Validate 验证配置
+func (c *OllamaClient) Validate(ctx context.Context) error {
+	_, err := c.Chat(ctx, []Message{
+		{Role: "user", Content: "hi"},
+	})
+	return err
+}
+
+// OllamaResponse Ollama /api/chat 响应
+type OllamaResponse struct {
+	Message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+}
@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// ollamaServer 模拟 Ollama 原生接口，记录请求路径与请求体
+func ollamaServer(t *testing.T) (*httptest.Server, func() (string, map[string]interface{})) {
+	t.Helper()
+	var mu sync.Mutex
+	var path string
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Unlock()
+		if r.URL.Path != "/api/chat" {
+			// OpenAI 兼容接口
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"choices": []map[string]interface{}{{"message": map[string]string{"content": `{"compat":true}`}, "finish_reason": "stop"}},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":             "qwen2.5",
+			"message":           map[string]string{"role": "assistant", "content": `{"steps":[]}`},
+			"done":              true,
+			"done_reason":       "stop",
+			"prompt_eval_count": 12,
+			"eval_count":        8,
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() (string, map[string]interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		return path, body
+	}
+}
+
+func TestOllamaNativeChat(t *testing.T) {
+	srv, last := ollamaServer(t)
+	client, err := NewLLMClientFactory().NewClient(&domain.LLMConfig{
+		Provider: domain.LLMProviderOllama,
+		Model:    "qwen2.5",
+		Endpoint: srv.URL + "/v1", // 预设中的兼容端点也指向原生接口
+		Options:  &domain.LLMOptions{Temperature: domain.Float64(0), MaxTokens: 256, KeepAlive: "30m"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "plan"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != `{"steps":[]}` || resp.FinishReason != "stop" || resp.Usage.TotalTokens != 20 {
+		t.Errorf("response = %+v, usage %+v", resp, resp.Usage)
+	}
+
+	path, body := last()
+	if path != "/api/chat" {
+		t.Fatalf("path = %s, want /api/chat", path)
+	}
+	if body["format"] != "json" || body["keep_alive"] != "30m" || body["stream"] != false {
+		t.Errorf("request = %v, want format json, keep_alive 30m, no streaming", body)
+	}
+	options, _ := body["options"].(map[string]interface{})
+	if options["temperature"] != 0.0 || options["num_predict"] != 256.0 {
+		t.Errorf("options = %v", options)
+	}
+}
+
+func TestOllamaOpenAICompat(t *testing.T) {
+	srv, last := ollamaServer(t)
+	client, _ := NewLLMClientFactory().NewClient(&domain.LLMConfig{
+		Provider:     domain.LLMProviderOllama,
+		Model:        "qwen2.5",
+		Endpoint:     srv.URL + "/v1",
+		OpenAICompat: true,
+	})
+	resp, err := client.Chat(context.Background(), []Message{{Role: "user", Content: "plan"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path, _ := last(); path != "/v1/chat/completions" || resp.Content != `{"compat":true}` {
+		t.Errorf("path = %s, content %q, want the OpenAI compatible endpoint", path, resp.Content)
+	}
+}
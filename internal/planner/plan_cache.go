@@ -0,0 +1,100 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PlanCache 执行计划缓存接口
+type PlanCache interface {
+	Get(ctx context.Context, key PlanCacheKey) (*TaskPlan, bool)
+	Put(ctx context.Context, key PlanCacheKey, plan *TaskPlan)
+}
+
+// PlanCacheKey 计划缓存键
+type PlanCacheKey struct {
+	URL         string
+	Description string
+	Model       string
+	Language    string // 提示词语言，不同语言生成的步骤说明不同
+}
+
+// NewPlanCacheKey 创建计划缓存键，URL 与描述会被规范化
+func NewPlanCacheKey(targetURL, description, model, language string) PlanCacheKey {
+	return PlanCacheKey{
+		URL:         normalizeURL(targetURL),
+		Description: strings.Join(strings.Fields(description), " "),
+		Model:       model,
+		Language:    promptLanguage(language),
+	}
+}
+
+// MemoryPlanCache 内存计划缓存
+type MemoryPlanCache struct {
+	ttl     time.Duration
+	entries map[PlanCacheKey]planCacheEntry
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+type planCacheEntry struct {
+	plan     *TaskPlan
+	storedAt time.Time
+}
+
+// NewMemoryPlanCache 创建内存计划缓存
+func NewMemoryPlanCache(ttl time.Duration) *MemoryPlanCache {
+	return &MemoryPlanCache{
+		ttl:     ttl,
+		entries: make(map[PlanCacheKey]planCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// Get 获取缓存的计划，过期条目会被移除
+func (c *MemoryPlanCache) Get(ctx context.Context, key PlanCacheKey) (*TaskPlan, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.now().Sub(entry.storedAt) > c.ttl {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return copyPlan(entry.plan), true
+}
+
+// Put 缓存计划
+func (c *MemoryPlanCache) Put(ctx context.Context, key PlanCacheKey, plan *TaskPlan) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = planCacheEntry{plan: copyPlan(plan), storedAt: c.now()}
+}
+
+// copyPlan 复制计划，避免调用方修改缓存内容
+func copyPlan(plan *TaskPlan) *TaskPlan {
+	cp := *plan
+	cp.Steps = append([]ActionStep(nil), plan.Steps...)
+	return &cp
+}
+
+// normalizeURL 规范化 URL：小写 scheme/host，去掉片段和末尾斜杠，查询参数排序
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
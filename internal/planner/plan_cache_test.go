@@ -0,0 +1,50 @@
+package planner
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryPlanCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cache := NewMemoryPlanCache(time.Hour)
+	cache.now = func() time.Time { return now }
+
+	key := NewPlanCacheKey("https://Example.com/app/?b=2&a=1#top", "导出  报表", "gpt-4o", "zh")
+	if _, ok := cache.Get(ctx, key); ok {
+		t.Fatal("hit on an empty cache")
+	}
+	cache.Put(ctx, key, &TaskPlan{Description: "plan", Steps: []ActionStep{{Order: 1, Target: "#a"}}})
+
+	// URL 与描述规范化后相同即命中
+	same := NewPlanCacheKey("https://example.com/app?a=1&b=2", "导出 报表", "gpt-4o", "zh")
+	plan, ok := cache.Get(ctx, same)
+	if !ok || len(plan.Steps) != 1 {
+		t.Fatalf("Get(normalized key) = %v, %v, want a hit", plan, ok)
+	}
+	// 返回副本，修改不影响缓存
+	plan.Steps[0].Target = "#changed"
+	if again, _ := cache.Get(ctx, key); again.Steps[0].Target != "#a" {
+		t.Error("cached plan modified through a returned copy")
+	}
+
+	for _, miss := range []PlanCacheKey{
+		NewPlanCacheKey("https://example.com/other", "导出 报表", "gpt-4o", "zh"),
+		NewPlanCacheKey("https://example.com/app", "导出 报表", "gpt-4o-mini", "zh"),
+		NewPlanCacheKey("https://example.com/app", "导出 报表", "gpt-4o", "en"),
+	} {
+		if _, ok := cache.Get(ctx, miss); ok {
+			t.Errorf("unexpected hit for %+v", miss)
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := cache.Get(ctx, key); ok {
+		t.Error("expired entry returned")
+	}
+	if len(cache.entries) != 0 {
+		t.Error("expired entry not removed")
+	}
+}
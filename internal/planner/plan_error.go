@@ -0,0 +1,31 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import "fmt"
+
+// maxRawOutput 错误中附带的模型原始输出上限（字节）
+const maxRawOutput = 1000
+
+// PlanError 计划解析或校验失败，附带模型原始输出（已脱敏并截断）便于排查
+type PlanError struct {
+	Reason string
+	Raw    string
+	Err    error
+}
+
+func newPlanError(reason, raw string, err error) *PlanError {
+	policy := LogPolicy{MaxBodyBytes: maxRawOutput}
+	return &PlanError{Reason: reason, Raw: policy.FormatBody([]byte(raw)), Err: err}
+}
+
+func (e *PlanError) Error() string {
+	msg := e.Reason
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return fmt.Sprintf("%s; model output: %q", msg, e.Raw)
+}
+
+func (e *PlanError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,185 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/browser-automation/internal/browser"
+)
+
+// validActions 计划中允许的操作类型
+var validActions = map[browser.ActionType]bool{
+	browser.ActionNavigate:        true,
+	browser.ActionClick:           true,
+	browser.ActionFill:            true,
+	browser.ActionHover:           true,
+	browser.ActionSelect:          true,
+	browser.ActionScreenshot:      true,
+	browser.ActionWait:            true,
+	browser.ActionScroll:          true,
+	browser.ActionExtract:         true,
+	browser.ActionSwitchFrame:     true,
+	browser.ActionSwitchMainFrame: true,
+	browser.ActionDownload:        true,
+}
+
+// targetActions 需要目标元素的操作类型
+var targetActions = map[browser.ActionType]bool{
+	browser.ActionClick:       true,
+	browser.ActionFill:        true,
+	browser.ActionHover:       true,
+	browser.ActionSelect:      true,
+	browser.ActionExtract:     true,
+	browser.ActionSwitchFrame: true,
+	browser.ActionDownload:    true,
+}
+
+// validatePlan 检查计划中明显错误的步骤：无步骤、未知操作、缺少目标、fill/select 缺少值、
+// extract 缺少数据名称、navigate 的目标既不是 http(s) URL 也不是相对路径、截图区域宽高不为正。返回全部问题，无问题时为空
+func validatePlan(plan *TaskPlan) []error {
+	if len(plan.Steps) == 0 {
+		return []error{errors.New("plan has no steps")}
+	}
+	var errs []error
+	for i, step := range plan.Steps {
+		n := i + 1
+		if !validActions[step.Action] {
+			errs = append(errs, fmt.Errorf("step %d: invalid action %q", n, step.Action))
+			continue
+		}
+		if targetActions[step.Action] && strings.TrimSpace(step.Target) == "" {
+			errs = append(errs, fmt.Errorf("step %d: %s requires a target", n, step.Action))
+		}
+		switch step.Action {
+		case browser.ActionFill, browser.ActionSelect:
+			if step.Value == "" {
+				errs = append(errs, fmt.Errorf("step %d: %s requires a value", n, step.Action))
+			}
+		case browser.ActionExtract:
+			if strings.TrimSpace(step.Value) == "" {
+				errs = append(errs, fmt.Errorf("step %d: extract requires a data key in value", n))
+			}
+		case browser.ActionNavigate:
+			if !isHTTPURL(step.Target) && !isRelativeURL(step.Target) {
+				errs = append(errs, fmt.Errorf("step %d: navigate target %q is not an http(s) URL", n, step.Target))
+			}
+		}
+		if c := step.Clip; c != nil && (c.Width <= 0 || c.Height <= 0) {
+			errs = append(errs, fmt.Errorf("step %d: clip width and height must be positive", n))
+		}
+	}
+	return errs
+}
+
+// normalizePlan 自动修正无需模型参与的小问题：去掉目标首尾空白、合并多余的 wait、按顺序重排序号、
+// 将 navigate 的相对路径按目标网站补全、为缺少协议的域名补 https://
+func normalizePlan(plan *TaskPlan, targetURL string) {
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		step.Target = strings.TrimSpace(step.Target)
+		step.WaitFor = strings.TrimSpace(step.WaitFor)
+		step.WaitForJS = strings.TrimSpace(step.WaitForJS)
+		step.Action = browser.ActionType(strings.ToLower(strings.TrimSpace(string(step.Action))))
+	}
+	plan.Steps = collapseWaits(plan.Steps)
+
+	base, _ := url.Parse(targetURL)
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		step.Order = i + 1
+		if step.Action != browser.ActionNavigate || step.Target == "" || isHTTPURL(step.Target) {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(step.Target, "/") && base != nil && base.Host != "":
+			if ref, err := url.Parse(step.Target); err == nil {
+				step.Target = base.ResolveReference(ref).String()
+			}
+		case !strings.Contains(step.Target, "://") && !strings.HasPrefix(step.Target, ".") &&
+			strings.Contains(step.Target, ".") && !strings.ContainsAny(step.Target, " \t"):
+			step.Target = "https://" + step.Target
+		}
+	}
+}
+
+// collapseWaits 去掉不影响执行的 wait，避免文档中出现多条"等待页面加载"：
+// 相邻的无条件 wait 合并为一条，条件完全相同的相邻 wait 只保留一条；
+// 紧跟 navigate、位于开头或末尾的无条件 wait 去掉（导航和快照前已等待页面加载）。
+// 等待元素或 JS 条件的 wait（含 | 分隔的多个候选）总是保留，如等待成功提示后再截图。
+// 需要截图的 wait 不会被单独去掉，合并时截图标记保留到留下的步骤上
+func collapseWaits(steps []ActionStep) []ActionStep {
+	out := make([]ActionStep, 0, len(steps))
+	for _, step := range steps {
+		if step.Action != browser.ActionWait {
+			out = append(out, step)
+			continue
+		}
+		if len(out) == 0 || out[len(out)-1].Action == browser.ActionNavigate {
+			if !hasWaitCondition(step) && !step.Screenshot {
+				continue
+			}
+		}
+		if len(out) > 0 && out[len(out)-1].Action == browser.ActionWait {
+			prev := &out[len(out)-1]
+			if (!hasWaitCondition(*prev) && !hasWaitCondition(step)) || (hasWaitCondition(step) && sameWaitCondition(*prev, step)) {
+				prev.Screenshot = prev.Screenshot || step.Screenshot
+				continue
+			}
+		}
+		out = append(out, step)
+	}
+	for len(out) > 0 {
+		last := out[len(out)-1]
+		if last.Action != browser.ActionWait || hasWaitCondition(last) || last.Screenshot {
+			break
+		}
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// hasWaitCondition wait 步骤是否等待具体条件（元素或 JS 表达式），否则为固定时长的等待
+func hasWaitCondition(step ActionStep) bool {
+	return step.WaitFor != "" || step.WaitForJS != ""
+}
+
+func sameWaitCondition(a, b ActionStep) bool {
+	return a.WaitFor == b.WaitFor && a.WaitForJS == b.WaitForJS && a.WaitEnabled == b.WaitEnabled
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isRelativeURL 判断是否为相对路径（如 "./settings"、"../list"），执行时以当前页面为基准解析
+func isRelativeURL(s string) bool {
+	if s == "" || strings.ContainsAny(s, " \t") {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme == "" && u.Host == "" && u.Path != ""
+}
+
+// correctionPrompt 校验失败时请模型修正计划的提示
+func correctionPrompt(prompts *promptSet, errs []error) string {
+	var b strings.Builder
+	b.WriteString(prompts.correctionHead)
+	for _, err := range errs {
+		fmt.Fprintf(&b, "- %s\n", err)
+	}
+	b.WriteString(prompts.correctionTail)
+	return b.String()
+}
+
+// joinErrors 将校验问题合并为一个错误
+func joinErrors(errs []error) error {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
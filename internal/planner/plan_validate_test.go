@@ -0,0 +1,155 @@
+package planner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+)
+
+func TestValidatePlan(t *testing.T) {
+	tests := []struct {
+		name string
+		step ActionStep
+		want string // 为空表示无问题
+	}{
+		{"valid click", ActionStep{Action: browser.ActionClick, Target: "#save"}, ""},
+		{"unknown action", ActionStep{Action: "teleport", Target: "#a"}, `invalid action "teleport"`},
+		{"click without target", ActionStep{Action: browser.ActionClick, Target: "  "}, "click requires a target"},
+		{"fill without value", ActionStep{Action: browser.ActionFill, Target: "#name"}, "fill requires a value"},
+		{"select without value", ActionStep{Action: browser.ActionSelect, Target: "#country"}, "select requires a value"},
+		{"extract without key", ActionStep{Action: browser.ActionExtract, Target: ".price"}, "extract requires a data key"},
+		{"navigate to a non-URL", ActionStep{Action: browser.ActionNavigate, Target: "the settings page"}, "is not an http(s) URL"},
+		{"navigate to a relative path", ActionStep{Action: browser.ActionNavigate, Target: "/settings"}, ""},
+		{"empty clip", ActionStep{Action: browser.ActionScreenshot, Clip: &browser.Rect{Width: 0, Height: 100}}, "clip width and height must be positive"},
+		{"wait needs no target", ActionStep{Action: browser.ActionWait}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validatePlan(&TaskPlan{Steps: []ActionStep{tt.step}})
+			if tt.want == "" {
+				if len(errs) != 0 {
+					t.Errorf("errors = %v, want none", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), "step 1: ") || !strings.Contains(errs[0].Error(), tt.want) {
+				t.Errorf("errors = %v, want %q", errs, tt.want)
+			}
+		})
+	}
+
+	if errs := validatePlan(&TaskPlan{}); len(errs) != 1 || errs[0].Error() != "plan has no steps" {
+		t.Errorf("empty plan errors = %v", errs)
+	}
+	// 返回全部问题
+	errs := validatePlan(&TaskPlan{Steps: []ActionStep{
+		{Action: browser.ActionClick},
+		{Action: browser.ActionFill, Target: "#a"},
+	}})
+	if len(errs) != 2 || !strings.HasPrefix(errs[1].Error(), "step 2:") {
+		t.Errorf("errors = %v, want one per step", errs)
+	}
+}
+
+func TestNormalizePlan(t *testing.T) {
+	plan := &TaskPlan{Steps: []ActionStep{
+		{Order: 7, Action: " Click ", Target: "  #save "},
+		{Order: 3, Action: browser.ActionNavigate, Target: "/settings?tab=1"},
+		{Order: 9, Action: browser.ActionNavigate, Target: "docs.example.com/start"},
+		{Order: 1, Action: browser.ActionNavigate, Target: "https://other.example.com"},
+	}}
+	normalizePlan(plan, "https://app.example.com/home")
+
+	want := []ActionStep{
+		{Order: 1, Action: browser.ActionClick, Target: "#save"},
+		{Order: 2, Action: browser.ActionNavigate, Target: "https://app.example.com/settings?tab=1"},
+		{Order: 3, Action: browser.ActionNavigate, Target: "https://docs.example.com/start"},
+		{Order: 4, Action: browser.ActionNavigate, Target: "https://other.example.com"},
+	}
+	for i, step := range plan.Steps {
+		if step.Order != want[i].Order || step.Action != want[i].Action || step.Target != want[i].Target {
+			t.Errorf("step %d = %d %s %q, want %d %s %q", i, step.Order, step.Action, step.Target, want[i].Order, want[i].Action, want[i].Target)
+		}
+	}
+}
+
+func TestCollapseWaits(t *testing.T) {
+	var (
+		nav     = ActionStep{Action: browser.ActionNavigate, Target: "https://app.example.com"}
+		click   = ActionStep{Action: browser.ActionClick, Target: "#save"}
+		wait    = ActionStep{Action: browser.ActionWait}
+		waitImg = ActionStep{Action: browser.ActionWait, Screenshot: true}
+		toast   = ActionStep{Action: browser.ActionWait, WaitFor: ".toast-success"}
+		either  = ActionStep{Action: browser.ActionWait, WaitFor: ".toast-success | .error-banner"}
+		loaded  = ActionStep{Action: browser.ActionWait, WaitForJS: "window.loaded"}
+	)
+	tests := []struct {
+		name  string
+		steps []ActionStep
+		want  string
+	}{
+		{"leading wait", []ActionStep{wait, click}, "click #save"},
+		{"wait after navigate", []ActionStep{nav, wait, click}, "navigate https://app.example.com,click #save"},
+		{"adjacent duration waits", []ActionStep{click, wait, wait, wait, click}, "click #save,wait ,click #save"},
+		{"screenshot kept when merged", []ActionStep{click, wait, waitImg, click}, "click #save,wait *,click #save"},
+		{"duplicate condition", []ActionStep{click, toast, toast, click}, "click #save,wait .toast-success,click #save"},
+		{"duration then condition kept", []ActionStep{click, wait, toast, click}, "click #save,wait ,wait .toast-success,click #save"},
+		{"condition then duration kept", []ActionStep{click, toast, wait, click}, "click #save,wait .toast-success,wait ,click #save"},
+		{"different conditions kept", []ActionStep{click, toast, loaded}, "click #save,wait .toast-success,wait window.loaded"},
+		{"trailing duration wait", []ActionStep{click, wait, wait}, "click #save"},
+		{"trailing conditional wait", []ActionStep{click, toast}, "click #save,wait .toast-success"},
+		{"trailing wait for any", []ActionStep{click, wait, either}, "click #save,wait ,wait .toast-success | .error-banner"},
+		{"trailing screenshot wait", []ActionStep{click, waitImg}, "click #save,wait *"},
+		{"conditional wait after navigate", []ActionStep{nav, loaded}, "navigate https://app.example.com,wait window.loaded"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, step := range collapseWaits(tt.steps) {
+			target := step.Target + step.WaitFor + step.WaitForJS
+			if step.Screenshot {
+				target += "*"
+			}
+			got = append(got, string(step.Action)+" "+target)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%s: steps = %s, want %s", tt.name, strings.Join(got, ","), tt.want)
+		}
+	}
+}
+
+func TestParseTaskRequestsOneCorrection(t *testing.T) {
+	const fillWithoutValue = `{"description": "x", "steps": [{"order": 1, "action": "fill", "target": "#name", "description": "Name"}]}`
+	tests := []struct {
+		name    string
+		replies []string
+		wantErr bool
+	}{
+		{"corrected plan accepted", []string{fillWithoutValue, validPlan}, false},
+		{"still invalid after correction", []string{fillWithoutValue, fillWithoutValue}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newScriptedClient(tt.replies...)
+			plan, err := NewAIPlanner(client).ParseTask(context.Background(), &PlanRequest{UserInput: "submit", TargetURL: "https://app.example.com"})
+			if len(client.calls) != 2 {
+				t.Fatalf("calls = %d, want the plan request and one correction", len(client.calls))
+			}
+			correction := client.calls[1]
+			last := correction[len(correction)-1]
+			if correction[len(correction)-2].Content != fillWithoutValue || !strings.Contains(last.Content, "step 1: fill requires a value") {
+				t.Errorf("correction request does not carry the plan and its problems: %q", last.Content)
+			}
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid plan after correction") {
+					t.Errorf("err = %v", err)
+				}
+				return
+			}
+			if err != nil || plan.Steps[0].Target != "#submit" {
+				t.Errorf("plan %+v, err %v", plan, err)
+			}
+		})
+	}
+}
@@ -5,22 +5,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
 )
 
 // Planner AI 规划器接口
 type Planner interface {
 	ParseTask(ctx context.Context, req *PlanRequest) (*TaskPlan, error)
-	RefineStep(ctx context.Context, step *ActionStep, snapshot *browser.PageSnapshot) (*ActionStep, error)
-	GenerateStepDescription(ctx context.Context, step *ActionStep, result *StepResult) (string, error)
+	PlanNextSteps(ctx context.Context, req *IterationRequest) (*IterationPlan, error)
+	RefineStep(ctx context.Context, step *ActionStep, snapshot *browser.PageSnapshot, language string) (*ActionStep, error)
+	GenerateStepDescription(ctx context.Context, step *ActionStep, result *StepResult, language string) (string, error)
+	GenerateTips(ctx context.Context, step *ActionStep, language string) ([]string, error)
 }
 
 // PlanRequest 规划请求
 type PlanRequest struct {
-	UserInput    string                 `json:"user_input"`
-	TargetURL    string                 `json:"target_url"`
-	PageSnapshot *browser.PageSnapshot  `json:"page_snapshot"`
+	UserInput    string                `json:"user_input"`
+	TargetURL    string                `json:"target_url"`
+	PageSnapshot *browser.PageSnapshot `json:"page_snapshot"`
+	// Language 文档输出语言，决定提示词及步骤说明的语言，为空时使用中文
+	Language string `json:"language,omitempty"`
 }
 
 // TaskPlan 任务计划
@@ -28,6 +35,7 @@ type TaskPlan struct {
 	TaskID      string       `json:"task_id"`
 	Description string       `json:"description"`
 	Steps       []ActionStep `json:"steps"`
+	Model       string       `json:"-"` // 配置了备用模型时，实际生成计划的模型
 }
 
 // ActionStep 操作步骤
@@ -37,20 +45,42 @@ type ActionStep struct {
 	Target      string             `json:"target"`
 	Value       string             `json:"value,omitempty"`
 	WaitFor     string             `json:"wait_for,omitempty"`
+	WaitForJS   string             `json:"wait_for_js,omitempty"` // 等待为真的 JS 表达式
 	Screenshot  bool               `json:"screenshot"`
 	Description string             `json:"description"`
+	// NavigatesAway 点击后会触发页面跳转，执行时等待导航完成
+	NavigatesAway bool `json:"navigates_away,omitempty"`
+	// WaitEnabled 点击前等待目标可点击（如表单填写完整后才启用的提交按钮）；wait 步骤等待 WaitFor 可点击
+	WaitEnabled bool `json:"wait_enabled,omitempty"`
+	// Tips 文档中展示的操作提示，由 GenerateTips 填充
+	Tips []string `json:"tips,omitempty"`
+	// FullPage 覆盖输出配置中的全页截图设置，为空时使用配置默认值
+	FullPage *bool `json:"full_page,omitempty"`
+	// Clip 只截取页面上的矩形区域（CSS 像素），设置后忽略 FullPage
+	Clip *browser.Rect `json:"clip,omitempty"`
 }
 
 // StepResult 步骤执行结果
 type StepResult struct {
-	Success    bool   `json:"success"`
-	Error      string `json:"error,omitempty"`
-	Screenshot []byte `json:"screenshot,omitempty"`
+	// Order、Action、Description 为实际执行的计划步骤，重新规划后为优化后的操作
+	Order       int    `json:"order"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+	Success     bool   `json:"success"`
+	Error       string `json:"error,omitempty"`
+	Skipped     bool   `json:"skipped,omitempty"`  // 前序步骤失败且策略为 stop，未执行
+	Selector    string `json:"selector,omitempty"` // 实际使用的选择器，重新规划或回退匹配后与计划中的目标不同
+	Screenshot  []byte `json:"screenshot,omitempty"`
+	// Data extract 步骤提取的数据，键为步骤 value
+	Data map[string]string `json:"data,omitempty"`
+	// Download download 步骤保存的文件
+	Download *domain.Download `json:"download,omitempty"`
 }
 
 // AIPlanner AI 规划器实现
 type AIPlanner struct {
 	llmClient LLMClient
+	planning  *domain.PlanningConfig
 }
 
 // NewAIPlanner 创建 AI 规划器
@@ -58,179 +88,250 @@ func NewAIPlanner(llmClient LLMClient) *AIPlanner {
 	return &AIPlanner{llmClient: llmClient}
 }
 
+// SetPlanningConfig 设置规划配置（few-shot 示例等），为 nil 时不使用示例
+func (p *AIPlanner) SetPlanningConfig(cfg *domain.PlanningConfig) {
+	p.planning = cfg
+}
+
 // ParseTask 解析任务生成执行计划
 func (p *AIPlanner) ParseTask(ctx context.Context, req *PlanRequest) (*TaskPlan, error) {
+	prompts := promptsFor(req.Language)
 	prompt := p.buildTaskParsePrompt(req)
-	
-	messages := []Message{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: prompt},
+
+	// few-shot 示例位于系统提示与本次任务之间，超出预算时丢弃最早的示例
+	messages := []Message{{Role: "system", Content: prompts.system}}
+	for _, ex := range p.planning.BudgetedExamples() {
+		messages = append(messages,
+			Message{Role: "user", Content: ex.User},
+			Message{Role: "assistant", Content: ex.Assistant})
 	}
-	
-	resp, err := p.llmClient.Chat(ctx, messages)
+	messages = append(messages, Message{Role: "user", Content: prompt})
+
+	resp, err := p.chatUntilComplete(ctx, prompts, messages)
 	if err != nil {
 		return nil, fmt.Errorf("llm chat: %w", err)
 	}
-	
-	// 解析 JSON 响应
+	plan, err := p.decodePlan(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// 自动修正小问题后校验，仍有问题时请模型修正一次
+	normalizePlan(plan, req.TargetURL)
+	if errs := validatePlan(plan); len(errs) > 0 {
+		log.Printf("[Planner] Plan has %d problem(s), requesting correction: %v", len(errs), joinErrors(errs))
+		corrected := append(append([]Message(nil), messages...),
+			Message{Role: "assistant", Content: resp.Content},
+			Message{Role: "user", Content: correctionPrompt(prompts, errs)})
+		if resp, err = p.chatUntilComplete(ctx, prompts, corrected); err != nil {
+			return nil, fmt.Errorf("llm chat: correction: %w", err)
+		}
+		if plan, err = p.decodePlan(resp); err != nil {
+			return nil, err
+		}
+		normalizePlan(plan, req.TargetURL)
+		if errs := validatePlan(plan); len(errs) > 0 {
+			return nil, newPlanError("invalid plan after correction", resp.Content, joinErrors(errs))
+		}
+	}
+	inferNavigation(plan.Steps)
+	plan.Model = resp.Model
+
+	return plan, nil
+}
+
+// decodePlan 解析响应中的计划 JSON，未要求严格 JSON 时可从说明文字中提取
+func (p *AIPlanner) decodePlan(resp *Response) (*TaskPlan, error) {
 	var plan TaskPlan
-	if err := json.Unmarshal([]byte(resp.Content), &plan); err != nil {
+	if err := json.Unmarshal([]byte(strings.TrimSpace(resp.Content)), &plan); err != nil {
+		if p.planning.RequireStrictJSON() {
+			return nil, newPlanError("parse plan: response is not pure JSON", resp.Content, err)
+		}
 		// 尝试提取 JSON
 		jsonStr := extractJSON(resp.Content)
 		if err := json.Unmarshal([]byte(jsonStr), &plan); err != nil {
-			return nil, fmt.Errorf("parse plan: %w", err)
+			if isTruncated(resp.FinishReason) {
+				return nil, newPlanError(fmt.Sprintf("parse plan: response still truncated after %d continuations", maxContinuations), resp.Content, err)
+			}
+			return nil, newPlanError("parse plan", resp.Content, err)
 		}
 	}
-	
 	return &plan, nil
 }
 
-// RefineStep 根据页面状态优化步骤
-func (p *AIPlanner) RefineStep(ctx context.Context, step *ActionStep, snapshot *browser.PageSnapshot) (*ActionStep, error) {
-	prompt := fmt.Sprintf(`当前步骤执行失败，请根据页面状态优化选择器。
+// maxContinuations 响应因长度截断时最多追加的续写请求次数
+const maxContinuations = 2
 
-原步骤:
-- 操作: %s
-- 目标: %s
-- 描述: %s
+// isTruncated 判断响应是否因 max_tokens 被截断（OpenAI/Ollama 为 length，Anthropic 为 max_tokens）
+func isTruncated(finishReason string) bool {
+	return finishReason == "length" || finishReason == "max_tokens"
+}
 
-当前页面 URL: %s
-页面标题: %s
+// chatUntilComplete 发送对话请求，响应被截断时使用 prompts 的语言请求模型续写并拼接内容
+func (p *AIPlanner) chatUntilComplete(ctx context.Context, prompts *promptSet, messages []Message) (*Response, error) {
+	resp, err := p.llmClient.Chat(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	content := resp.Content
+	for i := 1; i <= maxContinuations && isTruncated(resp.FinishReason); i++ {
+		log.Printf("[Planner] Response truncated (%s), requesting continuation %d/%d", resp.FinishReason, i, maxContinuations)
+		continued := append(append([]Message(nil), messages...),
+			Message{Role: "assistant", Content: content},
+			Message{Role: "user", Content: prompts.continuation})
+		if resp, err = p.llmClient.Chat(ctx, continued); err != nil {
+			return nil, fmt.Errorf("continuation: %w", err)
+		}
+		content += resp.Content
+	}
+	resp.Content = content
+	return resp, nil
+}
 
-可交互元素:
-%s
+// inferNavigation 为明显会跳转的点击（链接、提交按钮）补充 NavigatesAway 标记
+func inferNavigation(steps []ActionStep) {
+	for i := range steps {
+		if steps[i].Action != browser.ActionClick || steps[i].NavigatesAway {
+			continue
+		}
+		target := strings.ToLower(strings.TrimSpace(steps[i].Target))
+		if target == "a" || strings.HasPrefix(target, "a[") || strings.HasPrefix(target, "a.") ||
+			strings.HasPrefix(target, "a#") || strings.HasPrefix(target, "a:") ||
+			strings.Contains(target, "[type='submit']") || strings.Contains(target, `[type="submit"]`) ||
+			strings.Contains(target, "[type=submit]") || strings.Contains(target, "[href") {
+			steps[i].NavigatesAway = true
+		}
+	}
+}
 
-请输出优化后的步骤 JSON。`, 
+// RefineStep 根据页面状态优化步骤，提示词与优化后的步骤描述使用 language 指定的语言
+func (p *AIPlanner) RefineStep(ctx context.Context, step *ActionStep, snapshot *browser.PageSnapshot, language string) (*ActionStep, error) {
+	prompts := promptsFor(language)
+	prompt := fmt.Sprintf(prompts.refine,
 		step.Action, step.Target, step.Description,
 		snapshot.URL, snapshot.Title,
 		formatElements(snapshot.Elements))
-	
+
 	messages := []Message{
-		{Role: "system", Content: systemPrompt},
+		{Role: "system", Content: prompts.system},
 		{Role: "user", Content: prompt},
 	}
-	
+
 	resp, err := p.llmClient.Chat(ctx, messages)
 	if err != nil {
 		return nil, fmt.Errorf("llm chat: %w", err)
 	}
-	
+
 	var refined ActionStep
 	jsonStr := extractJSON(resp.Content)
 	if err := json.Unmarshal([]byte(jsonStr), &refined); err != nil {
 		return nil, fmt.Errorf("parse refined step: %w", err)
 	}
-	
+
 	return &refined, nil
 }
 
-// GenerateStepDescription 生成步骤描述
-func (p *AIPlanner) GenerateStepDescription(ctx context.Context, step *ActionStep, result *StepResult) (string, error) {
-	prompt := fmt.Sprintf(`请为以下操作步骤生成用户友好的描述（用于帮助文档）：
-
-操作: %s
-目标: %s
-值: %s
-执行结果: %v
-
-要求：
-1. 使用简洁明了的语言
-2. 面向普通用户，不要使用技术术语
-3. 描述应该是指导性的，告诉用户如何操作
-
-直接输出描述文本，不要包含其他内容。`,
+// GenerateStepDescription 使用 language 指定的语言生成步骤描述
+func (p *AIPlanner) GenerateStepDescription(ctx context.Context, step *ActionStep, result *StepResult, language string) (string, error) {
+	prompt := fmt.Sprintf(promptsFor(language).stepDescription,
 		step.Action, step.Target, step.Value, result.Success)
-	
+
 	messages := []Message{
 		{Role: "user", Content: prompt},
 	}
-	
+
 	resp, err := p.llmClient.Chat(ctx, messages)
 	if err != nil {
 		return step.Description, nil // 降级使用原描述
 	}
-	
+
 	return resp.Content, nil
 }
 
+// tipLanguageNames 提示语言代码到提示词中语言名称的映射
+var tipLanguageNames = map[string]string{
+	"zh": "简体中文",
+	"en": "English",
+	"ja": "日本語",
+	"ko": "한국어",
+}
+
+// GenerateTips 为步骤生成面向用户的操作提示，使用指定语言输出
+func (p *AIPlanner) GenerateTips(ctx context.Context, step *ActionStep, language string) ([]string, error) {
+	langName := tipLanguageNames[language]
+	if langName == "" {
+		langName = language
+	}
+	if langName == "" {
+		langName = tipLanguageNames["zh"]
+	}
+
+	prompt := fmt.Sprintf(`请为帮助文档中的以下操作步骤生成 0-2 条实用提示（如注意事项、常见错误、输入格式要求）：
+
+操作: %s
+目标: %s
+值: %s
+步骤描述: %s
+
+要求：
+1. 提示使用 %s 书写
+2. 每条提示一句话，面向普通用户，不要使用技术术语
+3. 没有有价值的提示时返回空数组
+
+输出 JSON：{"tips": ["提示1", "提示2"]}`,
+		step.Action, step.Target, step.Value, step.Description, langName)
+
+	resp, err := p.llmClient.Chat(ctx, []Message{{Role: "user", Content: prompt}})
+	if err != nil {
+		return nil, fmt.Errorf("llm chat: %w", err)
+	}
+
+	var out struct {
+		Tips []string `json:"tips"`
+	}
+	if err := json.Unmarshal([]byte(extractJSON(resp.Content)), &out); err != nil {
+		return nil, fmt.Errorf("parse tips: %w", err)
+	}
+	if len(out.Tips) > 2 {
+		out.Tips = out.Tips[:2]
+	}
+	return out.Tips, nil
+}
+
 func (p *AIPlanner) buildTaskParsePrompt(req *PlanRequest) string {
+	prompts := promptsFor(req.Language)
 	pageInfo := ""
 	if req.PageSnapshot != nil {
-		pageInfo = fmt.Sprintf(`
-当前页面 URL: %s
-页面标题: %s
-
-可交互元素:
-%s`,
+		pageInfo = fmt.Sprintf(prompts.pageInfo,
 			req.PageSnapshot.URL,
 			req.PageSnapshot.Title,
 			formatElements(req.PageSnapshot.Elements))
+		pageInfo += prompts.stats(req.PageSnapshot.Stats)
+		if req.PageSnapshot.Truncated {
+			pageInfo += fmt.Sprintf(prompts.truncated, req.PageSnapshot.DOMSize)
+		}
 	}
-	
-	return fmt.Sprintf(`## 用户任务
-%s
-
-## 目标网站
-%s
-%s
-
-## 输出要求
-生成 JSON 格式的操作步骤列表，格式如下：
-{
-  "task_id": "uuid",
-  "description": "任务总体描述",
-  "steps": [
-    {
-      "order": 1,
-      "action": "navigate|click|fill|hover|screenshot|wait",
-      "target": "CSS选择器或URL",
-      "value": "输入值（如适用）",
-      "wait_for": "等待条件（如适用）",
-      "screenshot": true,
-      "description": "用户友好的步骤说明"
-    }
-  ]
-}
 
-## 注意事项
-1. 优先使用稳定的选择器（id > name > class > xpath）
-2. 每个关键操作后添加截图（screenshot: true）
-3. 步骤说明要清晰易懂，面向普通用户
-4. 包含必要的等待步骤，确保页面加载完成
-
-请输出 JSON：`, req.UserInput, req.TargetURL, pageInfo)
+	return fmt.Sprintf(prompts.taskParse, req.UserInput, req.TargetURL, pageInfo)
 }
 
-const systemPrompt = `你是一个浏览器自动化专家，负责将用户的自然语言任务描述转换为可执行的浏览器操作步骤。
-
-你的输出必须是有效的 JSON 格式，包含以下字段：
-- task_id: 任务唯一标识
-- description: 任务描述
-- steps: 操作步骤数组
-
-每个步骤包含：
-- order: 步骤序号
-- action: 操作类型（navigate/click/fill/hover/screenshot/wait）
-- target: 目标（URL 或 CSS 选择器）
-- value: 输入值（可选）
-- wait_for: 等待条件（可选）
-- screenshot: 是否截图
-- description: 步骤描述（用户友好）
-
-确保生成的选择器是稳定可靠的，优先使用 id、name 属性。`
-
 func formatElements(elements []browser.Element) string {
 	if len(elements) == 0 {
 		return "（无可交互元素）"
 	}
-	
+
 	result := ""
 	for i, el := range elements {
 		if i >= 20 { // 限制数量
 			result += fmt.Sprintf("... 还有 %d 个元素\n", len(elements)-20)
 			break
 		}
-		result += fmt.Sprintf("- <%s> %s\n", el.TagName, el.Text)
+		text := browser.CleanElementText(el.Text, 0)
+		if el.Frame != "" {
+			result += fmt.Sprintf("- [%s] <%s> %s\n", el.Frame, el.TagName, text)
+			continue
+		}
+		result += fmt.Sprintf("- <%s> %s\n", el.TagName, text)
 	}
 	return result
 }
@@ -240,7 +341,7 @@ func extractJSON(content string) string {
 	start := -1
 	end := -1
 	depth := 0
-	
+
 	for i, c := range content {
 		if c == '{' {
 			if start == -1 {
@@ -255,7 +356,7 @@ func extractJSON(content string) string {
 			}
 		}
 	}
-	
+
 	if start != -1 && end != -1 {
 		return content[start:end]
 	}
@@ -0,0 +1,230 @@
+package planner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+	"github.com/browser-automation/internal/domain"
+)
+
+// scriptedClient 按顺序返回预设响应的 LLMClient，最后一个重复使用，并记录每次调用的消息
+type scriptedClient struct {
+	mu      sync.Mutex
+	replies []Response
+	calls   [][]Message
+}
+
+func newScriptedClient(contents ...string) *scriptedClient {
+	c := &scriptedClient{}
+	for _, content := range contents {
+		c.replies = append(c.replies, Response{Content: content, FinishReason: "stop"})
+	}
+	return c
+}
+
+func (c *scriptedClient) Chat(ctx context.Context, messages []Message) (*Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.calls)
+	c.calls = append(c.calls, append([]Message(nil), messages...))
+	reply := c.replies[min(n, len(c.replies)-1)]
+	return &reply, nil
+}
+
+func (c *scriptedClient) Validate(ctx context.Context) error { return nil }
+
+// validPlan 单步点击计划
+const validPlan = `{"description": "submit", "steps": [{"order": 1, "action": "click", "target": "#submit", "description": "Submit"}]}`
+
+func TestInferNavigation(t *testing.T) {
+	tests := []struct {
+		action browser.ActionType
+		target string
+		want   bool
+	}{
+		{browser.ActionClick, "a#next", true},
+		{browser.ActionClick, "a[href='/orders']", true},
+		{browser.ActionClick, "button[type='submit']", true},
+		{browser.ActionClick, "#save", false},
+		{browser.ActionClick, "article.card", false},
+		{browser.ActionFill, "a#next", false},
+	}
+	for _, tt := range tests {
+		steps := []ActionStep{{Action: tt.action, Target: tt.target}}
+		inferNavigation(steps)
+		if steps[0].NavigatesAway != tt.want {
+			t.Errorf("%s %q: NavigatesAway = %v, want %v", tt.action, tt.target, steps[0].NavigatesAway, tt.want)
+		}
+	}
+}
+
+func TestParseTaskSendsExamplesInOrder(t *testing.T) {
+	client := newScriptedClient(validPlan)
+	p := NewAIPlanner(client)
+	p.SetPlanningConfig(&domain.PlanningConfig{Examples: []domain.PlanExample{
+		{User: "example task 1", Assistant: `{"steps": [1]}`},
+		{User: "example task 2", Assistant: `{"steps": [2]}`},
+	}})
+	if _, err := p.ParseTask(context.Background(), &PlanRequest{UserInput: "submit the form", TargetURL: "https://app.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := client.calls[0]
+	var roles []string
+	for _, m := range messages {
+		roles = append(roles, m.Role)
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,user,assistant,user" {
+		t.Fatalf("roles = %v", roles)
+	}
+	if messages[1].Content != "example task 1" || messages[2].Content != `{"steps": [1]}` ||
+		messages[3].Content != "example task 2" || messages[4].Content != `{"steps": [2]}` {
+		t.Errorf("examples out of order: %+v", messages[1:5])
+	}
+	if !strings.Contains(messages[5].Content, "submit the form") {
+		t.Errorf("task prompt is not the last message: %q", messages[5].Content)
+	}
+}
+
+func TestParseTaskContinuesTruncatedResponse(t *testing.T) {
+	head, tail := validPlan[:40], validPlan[40:]
+	tests := []struct {
+		name      string
+		replies   []Response
+		wantCalls int
+		wantErr   string
+	}{
+		{"continuation completes the plan", []Response{
+			{Content: head, FinishReason: "length"},
+			{Content: tail, FinishReason: "stop"},
+		}, 2, ""},
+		{"anthropic max_tokens", []Response{
+			{Content: head, FinishReason: "max_tokens"},
+			{Content: tail, FinishReason: "end_turn"},
+		}, 2, ""},
+		{"still truncated", []Response{
+			{Content: head, FinishReason: "length"},
+		}, maxContinuations + 1, "still truncated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &scriptedClient{replies: tt.replies}
+			plan, err := NewAIPlanner(client).ParseTask(context.Background(), &PlanRequest{UserInput: "submit", TargetURL: "https://app.example.com"})
+			if len(client.calls) != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", len(client.calls), tt.wantCalls)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan.Steps) != 1 || plan.Steps[0].Target != "#submit" {
+				t.Errorf("steps = %+v", plan.Steps)
+			}
+
+			// 续写请求带上已输出的内容
+			continued := client.calls[1]
+			n := len(continued)
+			if n < 2 || continued[n-2].Role != "assistant" || continued[n-2].Content != head || continued[n-1].Content != zhPrompts.continuation {
+				t.Errorf("continuation messages = %+v", continued[max(0, n-2):])
+			}
+		})
+	}
+}
+
+func TestParseTaskSurfacesModelOutput(t *testing.T) {
+	const emptySteps = `{"description": "nothing", "steps": []}`
+	const badAction = `{"description": "x", "steps": [{"order": 1, "action": "teleport", "target": "#a", "description": "Go"}]}`
+	prose := "Sure! Here is the plan:\n" + validPlan
+	tests := []struct {
+		name    string
+		strict  bool
+		replies []string
+		wantErr string // 为空表示成功
+		wantRaw string
+	}{
+		{"prose around JSON is extracted", false, []string{prose}, "", ""},
+		{"strict JSON rejects prose", true, []string{prose}, "not pure JSON", prose},
+		{"garbage", false, []string{"I cannot help with that."}, "parse plan", "I cannot help with that."},
+		{"empty steps", false, []string{emptySteps}, "plan has no steps", emptySteps},
+		{"invalid action", false, []string{badAction}, `invalid action "teleport"`, badAction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newScriptedClient(tt.replies...)
+			p := NewAIPlanner(client)
+			p.SetPlanningConfig(&domain.PlanningConfig{StrictJSON: tt.strict})
+			plan, err := p.ParseTask(context.Background(), &PlanRequest{UserInput: "submit", TargetURL: "https://app.example.com"})
+			if tt.wantErr == "" {
+				if err != nil || len(plan.Steps) != 1 {
+					t.Fatalf("plan %+v, err %v", plan, err)
+				}
+				return
+			}
+
+			var planErr *PlanError
+			if !errors.As(err, &planErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want PlanError containing %q", err, tt.wantErr)
+			}
+			if planErr.Raw != tt.wantRaw {
+				t.Errorf("raw output = %q, want %q", planErr.Raw, tt.wantRaw)
+			}
+		})
+	}
+
+	// 原始输出截断后附加到错误中
+	client := newScriptedClient(strings.Repeat("x", 5000))
+	_, err := NewAIPlanner(client).ParseTask(context.Background(), &PlanRequest{UserInput: "submit", TargetURL: "https://app.example.com"})
+	var planErr *PlanError
+	if !errors.As(err, &planErr) || len(planErr.Raw) > maxRawOutput+100 || len(err.Error()) > maxRawOutput+300 {
+		t.Errorf("raw output not truncated: %d bytes", len(planErr.Raw))
+	}
+}
+
+func TestParseTaskPromptIncludesPageStats(t *testing.T) {
+	client := newScriptedClient(validPlan)
+	snapshot := &browser.PageSnapshot{
+		URL:   "https://app.example.com/login",
+		Title: "Sign in",
+		Stats: browser.PageStats{
+			TagCounts:    map[string]int{"form": 1, "a": 500, "iframe": 2},
+			Forms:        1,
+			Links:        500,
+			Buttons:      4,
+			Inputs:       2,
+			HasLoginForm: true,
+		},
+	}
+	if _, err := NewAIPlanner(client).ParseTask(context.Background(), &PlanRequest{
+		UserInput: "sign in", TargetURL: snapshot.URL, PageSnapshot: snapshot,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	prompt := client.calls[0][len(client.calls[0])-1].Content
+	if want := "页面结构: 表单 1 个，链接 500 个，按钮 4 个，输入框 2 个，iframe 2 个，登录表单: 是"; !strings.Contains(prompt, want) {
+		t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+	}
+
+	if got := formatStats(browser.PageStats{}); got != "" {
+		t.Errorf("empty stats formatted as %q", got)
+	}
+}
+
+func TestFormatElementsOneLinePerElement(t *testing.T) {
+	got := formatElements([]browser.Element{
+		{TagName: "button", Text: "  Save\n\n   changes "},
+		{TagName: "input", Text: "Card number", Frame: "frame:#pay >>"},
+	})
+	want := "- <button> Save changes\n- [frame:#pay >>] <input> Card number\n"
+	if got != want {
+		t.Errorf("formatElements = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,380 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/browser-automation/internal/browser"
+)
+
+// promptSet 一种语言的规划提示词，计划中的步骤说明随提示词语言输出
+type promptSet struct {
+	system    string
+	taskParse string // 参数依次为用户任务、目标网站、页面信息
+	pageInfo  string // 参数依次为页面 URL、标题、可交互元素
+	truncated string // 参数为页面节点总数
+	stats     func(browser.PageStats) string
+
+	correctionHead string
+	correctionTail string
+
+	// iterate 迭代规划一轮的提示，参数依次为用户任务、目标网站、本轮序号、最大轮数、已执行步骤、页面信息
+	iterate        string
+	historyNone    string
+	historyOK      string
+	historyFailed  string
+	historySkipped string
+
+	// refine 步骤失败后优化选择器的提示，参数依次为操作、目标、描述、页面 URL、标题、可交互元素
+	refine string
+	// stepDescription 生成步骤描述的提示，参数依次为操作、目标、值、执行结果
+	stepDescription string
+	// continuation 响应因长度截断时请求续写的提示
+	continuation string
+}
+
+// promptSets 已提供的提示词语言，未列出的语言使用中文
+var promptSets = map[string]*promptSet{
+	"zh": zhPrompts,
+	"en": enPrompts,
+}
+
+// promptLanguage 返回输出语言对应的提示词语言，支持 "en-US" 这类带地区的写法
+func promptLanguage(language string) string {
+	lang := strings.ToLower(language)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := promptSets[lang]; ok {
+		return lang
+	}
+	return "zh"
+}
+
+// promptsFor 按输出语言选择提示词
+func promptsFor(language string) *promptSet {
+	return promptSets[promptLanguage(language)]
+}
+
+var zhPrompts = &promptSet{
+	system: `你是一个浏览器自动化专家，负责将用户的自然语言任务描述转换为可执行的浏览器操作步骤。
+
+你的输出必须是有效的 JSON 格式，包含以下字段：
+- task_id: 任务唯一标识
+- description: 任务描述
+- steps: 操作步骤数组
+
+每个步骤包含：
+- order: 步骤序号
+- action: 操作类型（navigate/click/fill/hover/screenshot/wait/extract/download/switch_frame/switch_main_frame）；extract 读取 target 元素的文本，value 为数据名称（如 "order_id"），用于需要提取页面数据的任务；download 点击 target 触发文件下载（如导出报表）并保存文件，不会跳转页面；switch_frame 进入 target 指定的 iframe，switch_main_frame 回到主文档
+- target: 目标（URL 或 CSS 选择器）；iframe 内的元素在选择器前加元素列表中标注的前缀，如 "frame:#pay >> input[name='card']"
+- value: 输入值（可选）；click 步骤可用 "exact"、"nth=2" 或 "exact,nth=2" 指定按文本精确匹配及第几个匹配元素
+- wait_for: 等待条件（可选），等待结果有多种可能（如成功提示或错误横幅）时用 | 分隔多个选择器，任一出现即继续
+- wait_for_js: 等待为真的 JS 布尔表达式（可选）
+- screenshot: 是否截图
+- full_page: 截图是否截取整页（可选），概览类步骤设为 true，省略时使用默认设置
+- clip: 只截取的页面区域 {"x", "y", "width", "height"}（可选，CSS 像素），设置后忽略 full_page
+- navigates_away: 点击后是否会跳转页面（可选）
+- wait_enabled: 点击前是否等待目标可点击（可选），用于初始禁用的按钮；wait 步骤设置时等待 wait_for 元素可点击
+- description: 步骤描述（用户友好）
+
+确保生成的选择器是稳定可靠的，优先使用 id、name 属性。`,
+	taskParse: `## 用户任务
+%s
+
+## 目标网站
+%s
+%s
+
+## 输出要求
+生成 JSON 格式的操作步骤列表，格式如下：
+{
+  "task_id": "uuid",
+  "description": "任务总体描述",
+  "steps": [
+    {
+      "order": 1,
+      "action": "navigate|click|fill|hover|screenshot|wait|extract|download|switch_frame|switch_main_frame",
+      "target": "CSS选择器或URL",
+      "value": "输入值（如适用）",
+      "wait_for": "等待条件（如适用）",
+      "wait_for_js": "等待为真的 JS 表达式（如适用）",
+      "screenshot": true,
+      "full_page": false,
+      "navigates_away": false,
+      "wait_enabled": false,
+      "description": "用户友好的步骤说明"
+    }
+  ]
+}
+
+## 注意事项
+1. 优先使用稳定的选择器（id > name > class > xpath）
+2. 每个关键操作后添加截图（screenshot: true）
+3. 步骤说明要清晰易懂，面向普通用户
+4. 包含必要的等待步骤，确保页面加载完成
+5. 点击链接或提交表单等会导致页面跳转的操作，设置 navigates_away: true
+6. 需要等待动态变化（如数量变为某值、加载提示消失）时，使用 wait 操作并设置 wait_for_js，例如 "document.querySelectorAll('.cart-item').length === 3" 或 "!document.querySelector('.spinner')"
+7. 展示页面整体布局的概览步骤设置 full_page: true 截取整页，聚焦具体控件的步骤设置 full_page: false 只截取可视区域
+8. 连续多步操作同一个 iframe（如支付表单）时，先用 switch_frame 进入（target 为 iframe 的 name、id 或选择器），之后的选择器不再加 frame: 前缀，操作完成后用 switch_main_frame 回到主文档
+9. 表单填写完整后才启用的按钮（如提交、下一步），点击步骤设置 wait_enabled: true，先等待按钮可点击
+
+请输出 JSON：`,
+	pageInfo: `
+当前页面 URL: %s
+页面标题: %s
+
+可交互元素:
+%s`,
+	truncated:      "（页面较大，共 %d 个节点，仅列出部分元素）\n",
+	stats:          formatStats,
+	correctionHead: "你输出的计划存在以下问题：\n",
+	correctionTail: "请修正这些问题，重新输出完整的计划 JSON，不要添加任何说明。",
+	iterate: `## 用户任务
+%s
+
+## 目标网站
+%s
+
+## 规划方式
+任务按页面逐轮规划，这是第 %d 轮（最多 %d 轮）。你只能看到当前页面：只规划在当前页面上能完成的步骤，
+到点击"下一步""提交"等进入新页面或新表单步骤的操作为止（含该操作）；之后的页面会在执行后重新提供给你，不要猜测其中的元素。
+
+## 已执行的步骤
+%s%s
+
+## 输出要求
+任务已完成（当前页面表明目标已达成，如显示提交成功）时输出：
+{"done": true, "reason": "判断依据", "steps": []}
+
+否则输出本轮的步骤，步骤字段与注意事项同完整计划：
+{
+  "done": false,
+  "reason": "本轮要完成的内容",
+  "steps": [
+    {
+      "order": 1,
+      "action": "click",
+      "target": "CSS选择器",
+      "screenshot": true,
+      "navigates_away": true,
+      "description": "用户友好的步骤说明"
+    }
+  ]
+}
+
+不要重复已成功执行的步骤；失败的步骤可换用其他选择器重试。请输出 JSON：`,
+	historyNone:    "（尚未执行任何步骤）\n",
+	historyOK:      "成功",
+	historyFailed:  "失败",
+	historySkipped: "未执行",
+	refine: `当前步骤执行失败，请根据页面状态优化选择器。
+
+原步骤:
+- 操作: %s
+- 目标: %s
+- 描述: %s
+
+当前页面 URL: %s
+页面标题: %s
+
+可交互元素:
+%s
+
+请输出优化后的步骤 JSON。`,
+	stepDescription: `请为以下操作步骤生成用户友好的描述（用于帮助文档）：
+
+操作: %s
+目标: %s
+值: %s
+执行结果: %v
+
+要求：
+1. 使用简洁明了的语言
+2. 面向普通用户，不要使用技术术语
+3. 描述应该是指导性的，告诉用户如何操作
+
+直接输出描述文本，不要包含其他内容。`,
+	continuation: "你的上一条回复因长度限制被截断。请从截断处继续输出剩余的 JSON，不要重复已输出的内容，也不要添加任何说明。",
+}
+
+var enPrompts = &promptSet{
+	system: `You are a browser automation expert. Your job is to turn the user's natural-language task description into executable browser steps.
+
+Your output must be valid JSON with the following fields:
+- task_id: unique task identifier
+- description: task description
+- steps: array of steps
+
+Each step contains:
+- order: step number
+- action: action type (navigate/click/fill/hover/screenshot/wait/extract/download/switch_frame/switch_main_frame); extract reads the text of the target element, with value as the data key (e.g. "order_id"), for tasks that need to capture page data; download clicks target to trigger a file download (e.g. exporting a report) and saves the file without leaving the page; switch_frame enters the iframe given by target, switch_main_frame returns to the main document
+- target: target (URL or CSS selector); for elements inside an iframe, prefix the selector with the prefix shown in the element list, e.g. "frame:#pay >> input[name='card']"
+- value: input value (optional); click steps may use "exact", "nth=2" or "exact,nth=2" to match text exactly and choose which match to click
+- wait_for: wait condition (optional); when the outcome may vary (such as a success toast or an error banner), separate several selectors with | and the step continues as soon as any of them appears
+- wait_for_js: JS boolean expression to wait for (optional)
+- screenshot: whether to take a screenshot
+- full_page: whether the screenshot covers the full page (optional); set true for overview steps, omit to use the default
+- clip: capture only this page region {"x", "y", "width", "height"} (optional, CSS pixels); full_page is ignored when set
+- navigates_away: whether the click navigates to another page (optional)
+- wait_enabled: whether to wait until the target is clickable before clicking (optional), for buttons that start disabled; on a wait step it waits for the wait_for element to become clickable
+- description: step description (user friendly), written in English
+
+Make sure the selectors are stable and reliable; prefer id and name attributes.`,
+	taskParse: `## User task
+%s
+
+## Target website
+%s
+%s
+
+## Output requirements
+Produce the list of steps as JSON in the following format:
+{
+  "task_id": "uuid",
+  "description": "overall task description",
+  "steps": [
+    {
+      "order": 1,
+      "action": "navigate|click|fill|hover|screenshot|wait|extract|download|switch_frame|switch_main_frame",
+      "target": "CSS selector or URL",
+      "value": "input value (if applicable)",
+      "wait_for": "wait condition (if applicable)",
+      "wait_for_js": "JS expression to wait for (if applicable)",
+      "screenshot": true,
+      "full_page": false,
+      "navigates_away": false,
+      "wait_enabled": false,
+      "description": "user-friendly step description"
+    }
+  ]
+}
+
+## Notes
+1. Prefer stable selectors (id > name > class > xpath)
+2. Take a screenshot after every key action (screenshot: true)
+3. Step descriptions must be clear, aimed at ordinary users, and written in English
+4. Include the necessary wait steps to make sure pages have finished loading
+5. For actions that navigate away, such as clicking a link or submitting a form, set navigates_away: true
+6. To wait for dynamic changes (a count reaching a value, a loading indicator disappearing), use a wait action with wait_for_js, e.g. "document.querySelectorAll('.cart-item').length === 3" or "!document.querySelector('.spinner')"
+7. Overview steps that show the whole page layout set full_page: true; steps focused on a specific control set full_page: false to capture only the viewport
+8. When several consecutive steps work inside the same iframe (such as a payment form), enter it first with switch_frame (target is the iframe's name, id or selector), drop the frame: prefix from the following selectors, and return with switch_main_frame when done
+9. For buttons that stay disabled until the form is complete (such as submit or next), set wait_enabled: true on the click step so it waits until the button is clickable
+
+Output the JSON:`,
+	pageInfo: `
+Current page URL: %s
+Page title: %s
+
+Interactive elements:
+%s`,
+	truncated:      "(Large page with %d nodes; only some elements are listed)\n",
+	stats:          formatStatsEN,
+	correctionHead: "The plan you produced has the following problems:\n",
+	correctionTail: "Fix these problems and output the complete plan JSON again, without any explanation.",
+	iterate: `## User task
+%s
+
+## Target website
+%s
+
+## Planning mode
+The task is planned page by page; this is round %d (at most %d). You can only see the current page: plan only the steps that can be done on it,
+up to and including the action that moves to a new page or form step, such as clicking "Next" or "Submit". Later pages will be shown to you after these steps run; do not guess their elements.
+
+## Steps executed so far
+%s%s
+
+## Output requirements
+If the task is complete (the current page shows the goal was reached, e.g. a submission confirmation), output:
+{"done": true, "reason": "why the task is complete", "steps": []}
+
+Otherwise output this round's steps, with the same step fields and notes as a full plan:
+{
+  "done": false,
+  "reason": "what this round accomplishes",
+  "steps": [
+    {
+      "order": 1,
+      "action": "click",
+      "target": "CSS selector",
+      "screenshot": true,
+      "navigates_away": true,
+      "description": "user-friendly step description"
+    }
+  ]
+}
+
+Do not repeat steps that already succeeded; failed steps may be retried with a different selector. Output the JSON:`,
+	historyNone:    "(no steps executed yet)\n",
+	historyOK:      "ok",
+	historyFailed:  "failed",
+	historySkipped: "skipped",
+	refine: `The current step failed. Improve its selector based on the page state.
+
+Original step:
+- Action: %s
+- Target: %s
+- Description: %s
+
+Current page URL: %s
+Page title: %s
+
+Interactive elements:
+%s
+
+Output the refined step JSON.`,
+	stepDescription: `Write a user-friendly description of the following step for a help document:
+
+Action: %s
+Target: %s
+Value: %s
+Succeeded: %v
+
+Requirements:
+1. Use short, clear language
+2. Write for non-technical users and avoid technical terms
+3. Make it instructional: tell the user what to do
+
+Output only the description text, nothing else.`,
+	continuation: "Your previous reply was cut off by the length limit. Continue the remaining JSON exactly where it stopped, without repeating what was already output or adding any explanation.",
+}
+
+// formatStats 将页面结构统计格式化为一行摘要，无统计数据时返回空
+func formatStats(s browser.PageStats) string {
+	if len(s.TagCounts) == 0 && s.Links == 0 && s.Buttons == 0 {
+		return ""
+	}
+	login := "否"
+	if s.HasLoginForm {
+		login = "是"
+	}
+	line := fmt.Sprintf("页面结构: 表单 %d 个，链接 %d 个，按钮 %d 个，输入框 %d 个", s.Forms, s.Links, s.Buttons, s.Inputs)
+	if n := s.TagCounts["iframe"]; n > 0 {
+		line += fmt.Sprintf("，iframe %d 个", n)
+	}
+	if n := s.TagCounts["table"]; n > 0 {
+		line += fmt.Sprintf("，表格 %d 个", n)
+	}
+	return fmt.Sprintf("%s，登录表单: %s\n", line, login)
+}
+
+// formatStatsEN formatStats 的英文版本
+func formatStatsEN(s browser.PageStats) string {
+	if len(s.TagCounts) == 0 && s.Links == 0 && s.Buttons == 0 {
+		return ""
+	}
+	login := "no"
+	if s.HasLoginForm {
+		login = "yes"
+	}
+	line := fmt.Sprintf("Page structure: %d forms, %d links, %d buttons, %d inputs", s.Forms, s.Links, s.Buttons, s.Inputs)
+	if n := s.TagCounts["iframe"]; n > 0 {
+		line += fmt.Sprintf(", %d iframes", n)
+	}
+	if n := s.TagCounts["table"]; n > 0 {
+		line += fmt.Sprintf(", %d tables", n)
+	}
+	return fmt.Sprintf("%s, login form: %s\n", line, login)
+}
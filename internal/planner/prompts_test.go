@@ -0,0 +1,135 @@
+package planner
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/browser"
+)
+
+func TestPromptLanguage(t *testing.T) {
+	tests := []struct{ language, want string }{
+		{"en", "en"},
+		{"en-US", "en"},
+		{"EN_gb", "en"},
+		{"zh", "zh"},
+		{"zh-CN", "zh"},
+		{"", "zh"},
+		{"fr", "zh"},
+	}
+	for _, tt := range tests {
+		if got := promptLanguage(tt.language); got != tt.want {
+			t.Errorf("promptLanguage(%q) = %q, want %q", tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestParseTaskUsesOutputLanguagePrompt(t *testing.T) {
+	snapshot := &browser.PageSnapshot{
+		URL:      "https://app.example.com",
+		Title:    "Home",
+		Elements: []browser.Element{{TagName: "button", Selector: "#submit", Text: "Submit"}},
+		Stats:    browser.PageStats{Forms: 1, Buttons: 1},
+	}
+	tests := []struct {
+		language   string
+		wantSystem string
+		wantUser   []string
+		notInUser  string
+	}{
+		{"en-US", enPrompts.system, []string{"## User task", "Page structure: 1 forms"}, "用户任务"},
+		{"zh", zhPrompts.system, []string{"## 用户任务", "页面结构: 表单 1 个"}, "User task"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			client := newScriptedClient(validPlan)
+			_, err := NewAIPlanner(client).ParseTask(context.Background(), &PlanRequest{
+				UserInput: "submit the form", TargetURL: snapshot.URL, PageSnapshot: snapshot, Language: tt.language,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages := client.calls[0]
+			if messages[0].Content != tt.wantSystem {
+				t.Errorf("system prompt is not the %s variant", tt.language)
+			}
+			user := messages[len(messages)-1].Content
+			for _, want := range tt.wantUser {
+				if !strings.Contains(user, want) {
+					t.Errorf("user prompt does not contain %q:\n%s", want, user)
+				}
+			}
+			if strings.Contains(user, tt.notInUser) {
+				t.Errorf("user prompt mixes languages:\n%s", user)
+			}
+		})
+	}
+}
+
+func TestFollowUpPromptsUseOutputLanguage(t *testing.T) {
+	snapshot := &browser.PageSnapshot{
+		URL:      "https://app.example.com",
+		Title:    "Home",
+		Elements: []browser.Element{{TagName: "button", Selector: "#save", Text: "Save"}},
+	}
+	step := &ActionStep{Order: 1, Action: browser.ActionClick, Target: "#submit", Description: "Submit"}
+	tests := []struct {
+		language    string
+		prompts     *promptSet
+		refine      string
+		description string
+		other       string // 另一种语言特有的文字，不应出现
+	}{
+		{"en-US", enPrompts, "Original step", "help document", "步骤"},
+		{"zh", zhPrompts, "原步骤", "帮助文档", "step"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			// 步骤优化
+			client := newScriptedClient(`{"order": 1, "action": "click", "target": "#save", "description": "Save"}`)
+			if _, err := NewAIPlanner(client).RefineStep(context.Background(), step, snapshot, tt.language); err != nil {
+				t.Fatal(err)
+			}
+			messages := client.calls[0]
+			if messages[0].Content != tt.prompts.system {
+				t.Errorf("refine system prompt is not the %s variant", tt.language)
+			}
+			if user := messages[1].Content; !strings.Contains(user, tt.refine) || strings.Contains(user, tt.other) {
+				t.Errorf("refine prompt is not in %s:\n%s", tt.language, user)
+			}
+
+			// 步骤描述
+			client = newScriptedClient("Click Save.")
+			if _, err := NewAIPlanner(client).GenerateStepDescription(context.Background(), step, &StepResult{Success: true}, tt.language); err != nil {
+				t.Fatal(err)
+			}
+			if user := client.calls[0][0].Content; !strings.Contains(user, tt.description) || strings.Contains(user, tt.other) {
+				t.Errorf("description prompt is not in %s:\n%s", tt.language, user)
+			}
+
+			// 截断续写
+			head := validPlan[:20]
+			client = &scriptedClient{replies: []Response{
+				{Content: head, FinishReason: "length"},
+				{Content: validPlan[20:], FinishReason: "stop"},
+			}}
+			if _, err := NewAIPlanner(client).ParseTask(context.Background(), &PlanRequest{
+				UserInput: "submit", TargetURL: snapshot.URL, Language: tt.language,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			continued := client.calls[1]
+			if got := continued[len(continued)-1].Content; got != tt.prompts.continuation {
+				t.Errorf("continuation prompt = %q, want the %s variant", got, tt.language)
+			}
+		})
+	}
+
+	// 每种语言都提供了全部提示词
+	for lang, set := range promptSets {
+		if set.refine == "" || set.stepDescription == "" || set.continuation == "" {
+			t.Errorf("prompt set %q is missing follow-up prompts", lang)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package planner 提供 AI 规划功能
+package planner
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// TransportOptions LLM 请求的连接池设置，零值字段使用默认值
+type TransportOptions struct {
+	// MaxIdleConns 全部主机的空闲连接上限，默认 100
+	MaxIdleConns int
+	// MaxIdleConnsPerHost 每个主机保留的空闲连接数，默认 32。并发任务多时调大可减少重复建连
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost 每个主机的连接总数上限，默认 0 不限制
+	MaxConnsPerHost int
+	// IdleConnTimeout 空闲连接保留时长，默认 90 秒
+	IdleConnTimeout time.Duration
+	// DisableHTTP2 关闭 HTTP/2，默认对 HTTPS 端点尝试 HTTP/2 以多路复用同一连接
+	DisableHTTP2 bool
+}
+
+// 连接池默认值，面向多个任务并发调用同一提供商
+const (
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 32
+	defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// DefaultTransportOptions 返回默认连接池设置
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		MaxIdleConns:        defaultMaxIdleConns,
+		MaxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     defaultIdleConnTimeout,
+	}
+}
+
+// newTransport 基于 http.DefaultTransport 的拨号与 TLS 设置创建连接池
+func newTransport(opts TransportOptions) *http.Transport {
+	defaults := DefaultTransportOptions()
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = defaults.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = defaults.IdleConnTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.MaxIdleConns
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = opts.MaxConnsPerHost
+	transport.IdleConnTimeout = opts.IdleConnTimeout
+	transport.ForceAttemptHTTP2 = !opts.DisableHTTP2
+	if opts.DisableHTTP2 {
+		// 非 nil 的空映射禁止 TLS 协商升级到 HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return transport
+}
+
+// SetTransportOptions 设置 LLM 请求的连接池，应在创建客户端前调用，已创建的客户端沿用原连接池
+func (f *LLMClientFactory) SetTransportOptions(opts TransportOptions) {
+	f.httpClient = &http.Client{
+		Timeout:   f.httpClient.Timeout,
+		Transport: newTransport(opts),
+	}
+}
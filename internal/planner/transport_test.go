@@ -0,0 +1,84 @@
+package planner
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewTransportDefaults(t *testing.T) {
+	tr := newTransport(TransportOptions{})
+	if tr.MaxIdleConns != defaultMaxIdleConns || tr.MaxIdleConnsPerHost != defaultMaxIdleConnsPerHost ||
+		tr.IdleConnTimeout != defaultIdleConnTimeout || tr.MaxConnsPerHost != 0 || !tr.ForceAttemptHTTP2 {
+		t.Errorf("default transport = idle %d/%d, timeout %s, max %d, http2 %v",
+			tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout, tr.MaxConnsPerHost, tr.ForceAttemptHTTP2)
+	}
+	if tr.Proxy == nil || tr.TLSHandshakeTimeout == 0 {
+		t.Error("dial, proxy and TLS settings of http.DefaultTransport not kept")
+	}
+
+	tr = newTransport(TransportOptions{MaxIdleConns: 10, MaxIdleConnsPerHost: 4, MaxConnsPerHost: 8, IdleConnTimeout: time.Second, DisableHTTP2: true})
+	if tr.MaxIdleConns != 10 || tr.MaxIdleConnsPerHost != 4 || tr.MaxConnsPerHost != 8 || tr.IdleConnTimeout != time.Second {
+		t.Errorf("configured transport = idle %d/%d, max %d, timeout %s", tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.MaxConnsPerHost, tr.IdleConnTimeout)
+	}
+	if tr.ForceAttemptHTTP2 || tr.TLSNextProto == nil || len(tr.TLSNextProto) != 0 {
+		t.Error("HTTP/2 not disabled")
+	}
+}
+
+func TestTransportHTTP2AndReuse(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	srv.EnableHTTP2 = true
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	for _, tt := range []struct {
+		disable bool
+		want    string
+	}{{false, "HTTP/2.0"}, {true, "HTTP/1.1"}} {
+		conns.Store(0)
+		tr := newTransport(TransportOptions{DisableHTTP2: tt.disable})
+		tr.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+		client := &http.Client{Transport: tr}
+		for i := 0; i < 3; i++ {
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != tt.want {
+				t.Errorf("disable http2 %v: protocol = %s, want %s", tt.disable, body, tt.want)
+			}
+		}
+		if n := conns.Load(); n != 1 {
+			t.Errorf("disable http2 %v: %d connections for 3 sequential requests, want 1", tt.disable, n)
+		}
+		tr.CloseIdleConnections()
+	}
+}
+
+func TestSetTransportOptionsKeepsTimeout(t *testing.T) {
+	f := NewLLMClientFactory()
+	timeout := f.httpClient.Timeout
+	f.SetTransportOptions(TransportOptions{MaxIdleConnsPerHost: 64})
+	tr, ok := f.httpClient.Transport.(*http.Transport)
+	if !ok || tr.MaxIdleConnsPerHost != 64 {
+		t.Fatalf("transport = %T %+v, want the configured pool", f.httpClient.Transport, f.httpClient.Transport)
+	}
+	if f.httpClient.Timeout != timeout {
+		t.Errorf("timeout = %s, want %s", f.httpClient.Timeout, timeout)
+	}
+}
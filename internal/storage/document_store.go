@@ -0,0 +1,204 @@
+// Package storage 提供数据存储接口
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// DocumentStore 文档存储接口
+type DocumentStore interface {
+	Save(ctx context.Context, taskID string, doc *domain.DocumentInfo, content []byte) error
+	// Open 打开文档内容用于流式读取，调用方负责关闭
+	Open(ctx context.Context, taskID string, doc *domain.DocumentInfo) (io.ReadSeekCloser, error)
+}
+
+// ArtifactStore 除文档外还能保存截图与任务清单的存储，目前由 FileDocumentStore 实现
+type ArtifactStore interface {
+	DocumentStore
+	// SaveScreenshot 保存截图到任务输出目录下的 shot.FileName()
+	SaveScreenshot(ctx context.Context, taskID string, shot *domain.Screenshot, data []byte) error
+	OpenScreenshot(ctx context.Context, taskID string, shot *domain.Screenshot) (io.ReadSeekCloser, error)
+	SaveManifest(ctx context.Context, taskID string, manifest []byte) error
+	OpenManifest(ctx context.Context, taskID string) (io.ReadSeekCloser, error)
+	// SaveDownload 流式保存下载文件到任务输出目录下的 d.FileName()，返回写入的字节数
+	SaveDownload(ctx context.Context, taskID string, d *domain.Download, r io.Reader) (int64, error)
+	OpenDownload(ctx context.Context, taskID string, d *domain.Download) (io.ReadSeekCloser, error)
+}
+
+// FileDocumentStore 文件系统文档存储，每个任务一个目录：
+//
+//	baseDir/taskID/
+//	  manifest.json
+//	  events.jsonl
+//	  <docID>.md / .html / ...
+//	  screenshots/step_N.png
+//	  downloads/step_N_<name>
+type FileDocumentStore struct {
+	baseDir string
+	eventMu sync.Mutex // 串行追加事件日志，避免并发写入的行交错
+}
+
+// NewFileDocumentStore 创建文件系统文档存储
+func NewFileDocumentStore(baseDir string) *FileDocumentStore {
+	return &FileDocumentStore{baseDir: baseDir}
+}
+
+// Save 保存文档内容到 baseDir/taskID/docID.ext
+func (s *FileDocumentStore) Save(ctx context.Context, taskID string, doc *domain.DocumentInfo, content []byte) error {
+	path, err := s.path(taskID, doc)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, content); err != nil {
+		return fmt.Errorf("write document: %w", err)
+	}
+	return nil
+}
+
+// SaveScreenshot 保存截图到 baseDir/taskID/screenshots/step_N.ext
+func (s *FileDocumentStore) SaveScreenshot(ctx context.Context, taskID string, shot *domain.Screenshot, data []byte) error {
+	dir, err := s.taskDir(taskID)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, filepath.FromSlash(shot.FileName())), data); err != nil {
+		return fmt.Errorf("write screenshot: %w", err)
+	}
+	return nil
+}
+
+// OpenScreenshot 打开截图文件
+func (s *FileDocumentStore) OpenScreenshot(ctx context.Context, taskID string, shot *domain.Screenshot) (io.ReadSeekCloser, error) {
+	dir, err := s.taskDir(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return openFile(filepath.Join(dir, filepath.FromSlash(shot.FileName())))
+}
+
+// SaveManifest 保存任务清单到 baseDir/taskID/manifest.json
+func (s *FileDocumentStore) SaveManifest(ctx context.Context, taskID string, manifest []byte) error {
+	dir, err := s.taskDir(taskID)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, domain.ManifestFileName), manifest); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// OpenManifest 打开任务清单
+func (s *FileDocumentStore) OpenManifest(ctx context.Context, taskID string) (io.ReadSeekCloser, error) {
+	dir, err := s.taskDir(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return openFile(filepath.Join(dir, domain.ManifestFileName))
+}
+
+// SaveDownload 保存下载文件到 baseDir/taskID/downloads/step_N_<name>
+func (s *FileDocumentStore) SaveDownload(ctx context.Context, taskID string, d *domain.Download, r io.Reader) (int64, error) {
+	path, err := s.downloadPath(taskID, d)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("write download: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("write download: %w", err)
+	}
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, fmt.Errorf("write download: %w", err)
+	}
+	return n, nil
+}
+
+// OpenDownload 打开下载文件
+func (s *FileDocumentStore) OpenDownload(ctx context.Context, taskID string, d *domain.Download) (io.ReadSeekCloser, error) {
+	path, err := s.downloadPath(taskID, d)
+	if err != nil {
+		return nil, err
+	}
+	return openFile(path)
+}
+
+// downloadPath 文件名来自网页，拒绝路径分隔符防止越界写入
+func (s *FileDocumentStore) downloadPath(taskID string, d *domain.Download) (string, error) {
+	dir, err := s.taskDir(taskID)
+	if err != nil {
+		return "", err
+	}
+	if d.Name == "" || filepath.Base(d.Name) != d.Name || d.Name == ".." {
+		return "", ErrInvalidData
+	}
+	return filepath.Join(dir, filepath.FromSlash(d.FileName())), nil
+}
+
+// openFile 打开文件，不存在时返回 ErrNotFound
+func openFile(path string) (io.ReadSeekCloser, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", filepath.Base(path), err)
+	}
+	return f, nil
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+// Open 打开文档文件，支持按范围读取
+func (s *FileDocumentStore) Open(ctx context.Context, taskID string, doc *domain.DocumentInfo) (io.ReadSeekCloser, error) {
+	path, err := s.path(taskID, doc)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open document: %w", err)
+	}
+	return f, nil
+}
+
+func (s *FileDocumentStore) path(taskID string, doc *domain.DocumentInfo) (string, error) {
+	dir, err := s.taskDir(taskID)
+	if err != nil {
+		return "", err
+	}
+	// ID 由服务端生成，这里仍拒绝路径分隔符防止越界访问
+	if doc.ID == "" || filepath.Base(doc.ID) != doc.ID {
+		return "", ErrInvalidData
+	}
+	return filepath.Join(dir, doc.FileName()), nil
+}
+
+func (s *FileDocumentStore) taskDir(taskID string) (string, error) {
+	if taskID == "" || filepath.Base(taskID) != taskID {
+		return "", ErrInvalidData
+	}
+	return filepath.Join(s.baseDir, taskID), nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+func TestFileDocumentStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileDocumentStore(t.TempDir())
+	doc := &domain.DocumentInfo{ID: "d1", Format: domain.DocFormatMarkdown}
+
+	if _, err := store.Open(ctx, "t1", doc); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open before Save err = %v, want ErrNotFound", err)
+	}
+	if err := store.Save(ctx, "t1", doc, []byte("# Guide")); err != nil {
+		t.Fatal(err)
+	}
+	f, err := store.Open(ctx, "t1", doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if data, _ := io.ReadAll(f); string(data) != "# Guide" {
+		t.Errorf("content = %q", data)
+	}
+
+	// 任务 ID 与文档 ID 不能越出存储目录
+	for _, tt := range []struct {
+		taskID string
+		doc    *domain.DocumentInfo
+	}{
+		{"../t1", doc},
+		{"t1", &domain.DocumentInfo{ID: "../../etc/passwd", Format: domain.DocFormatMarkdown}},
+		{"", doc},
+	} {
+		if err := store.Save(ctx, tt.taskID, tt.doc, nil); !errors.Is(err, ErrInvalidData) {
+			t.Errorf("Save(%q, %q) err = %v, want ErrInvalidData", tt.taskID, tt.doc.ID, err)
+		}
+	}
+}
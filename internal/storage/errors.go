@@ -12,4 +12,7 @@ var (
 	
 	// ErrInvalidData 无效数据
 	ErrInvalidData = errors.New("invalid data")
+	
+	// ErrTaskFinished 任务已处于终态，拒绝将其改回其他状态
+	ErrTaskFinished = errors.New("task already finished")
 )
@@ -0,0 +1,57 @@
+// Package storage 提供数据存储接口
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+// EventStore 只追加的任务事件日志，目前由 FileDocumentStore 实现
+type EventStore interface {
+	AppendEvent(ctx context.Context, taskID string, event *domain.TaskEvent) error
+	// OpenEvents 打开 JSON Lines 格式的事件日志，尚无事件时返回 ErrNotFound
+	OpenEvents(ctx context.Context, taskID string) (io.ReadSeekCloser, error)
+}
+
+// AppendEvent 将事件作为一行 JSON 追加到 baseDir/taskID/events.jsonl
+func (s *FileDocumentStore) AppendEvent(ctx context.Context, taskID string, event *domain.TaskEvent) error {
+	dir, err := s.taskDir(taskID)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.eventMu.Lock()
+	defer s.eventMu.Unlock()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("write event: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, domain.EventLogFileName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("write event: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("write event: %w", err)
+	}
+	return f.Close()
+}
+
+// OpenEvents 打开任务事件日志
+func (s *FileDocumentStore) OpenEvents(ctx context.Context, taskID string) (io.ReadSeekCloser, error) {
+	dir, err := s.taskDir(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return openFile(filepath.Join(dir, domain.EventLogFileName))
+}
@@ -3,7 +3,10 @@ package storage
 
 import (
 	"context"
+	"maps"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/browser-automation/internal/domain"
 )
@@ -12,13 +15,41 @@ import (
 type TaskStore interface {
 	Create(ctx context.Context, task *domain.Task) error
 	Get(ctx context.Context, id string) (*domain.Task, error)
+	// Update 保存任务；任务在存储中已处于终态（completed/failed/cancelled）时，
+	// 改为其他状态的更新返回 ErrTaskFinished，避免延迟的写入覆盖已结束的任务
 	Update(ctx context.Context, task *domain.Task) error
+	// ForceUpdate 保存任务且不检查终态，用于明确允许的状态转换（如已完成任务继续执行追加指令）
+	ForceUpdate(ctx context.Context, task *domain.Task) error
 	UpdateStatus(ctx context.Context, id string, status domain.TaskStatus) error
 	Delete(ctx context.Context, id string) error
-	List(ctx context.Context, limit, offset int) ([]*domain.Task, error)
+	List(ctx context.Context, filter TaskFilter, limit, offset int) ([]*domain.Task, error)
 }
 
-// MemoryTaskStore 内存任务存储（开发用）
+// TaskFilter 任务列表过滤条件，零值表示不过滤
+type TaskFilter struct {
+	Tags          []string  // 需同时包含的全部标签
+	CreatedAfter  time.Time // 创建时间不早于该时刻
+	CreatedBefore time.Time // 创建时间早于该时刻
+}
+
+// Match 判断任务是否满足过滤条件
+func (f TaskFilter) Match(task *domain.Task) bool {
+	if !f.CreatedAfter.IsZero() && task.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !task.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !task.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// MemoryTaskStore 内存任务存储（开发用）。保存和返回的都是任务副本，
+// 调用方修改自己持有的任务不会绕过 Update 的终态检查
 type MemoryTaskStore struct {
 	tasks map[string]*domain.Task
 	mu    sync.RWMutex
@@ -35,7 +66,7 @@ func NewMemoryTaskStore() *MemoryTaskStore {
 func (s *MemoryTaskStore) Create(ctx context.Context, task *domain.Task) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.tasks[task.ID] = task
+	s.tasks[task.ID] = cloneTask(task)
 	return nil
 }
 
@@ -47,17 +78,30 @@ func (s *MemoryTaskStore) Get(ctx context.Context, id string) (*domain.Task, err
 	if !ok {
 		return nil, ErrNotFound
 	}
-	return task, nil
+	return cloneTask(task), nil
 }
 
-// Update 更新任务
+// Update 更新任务，拒绝将已结束的任务改为其他状态
 func (s *MemoryTaskStore) Update(ctx context.Context, task *domain.Task) error {
+	return s.update(task, false)
+}
+
+// ForceUpdate 更新任务，不检查终态
+func (s *MemoryTaskStore) ForceUpdate(ctx context.Context, task *domain.Task) error {
+	return s.update(task, true)
+}
+
+func (s *MemoryTaskStore) update(task *domain.Task, force bool) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.tasks[task.ID]; !ok {
+	current, ok := s.tasks[task.ID]
+	if !ok {
 		return ErrNotFound
 	}
-	s.tasks[task.ID] = task
+	if !force && current.Status.Terminal() && task.Status != current.Status {
+		return ErrTaskFinished
+	}
+	s.tasks[task.ID] = cloneTask(task)
 	return nil
 }
 
@@ -69,7 +113,12 @@ func (s *MemoryTaskStore) UpdateStatus(ctx context.Context, id string, status do
 	if !ok {
 		return ErrNotFound
 	}
-	task.Status = status
+	if task.Status.Terminal() && status != task.Status {
+		return ErrTaskFinished
+	}
+	updated := cloneTask(task)
+	updated.Status = status
+	s.tasks[id] = updated
 	return nil
 }
 
@@ -82,15 +131,17 @@ func (s *MemoryTaskStore) Delete(ctx context.Context, id string) error {
 }
 
 // List 列出任务
-func (s *MemoryTaskStore) List(ctx context.Context, limit, offset int) ([]*domain.Task, error) {
+func (s *MemoryTaskStore) List(ctx context.Context, filter TaskFilter, limit, offset int) ([]*domain.Task, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	
+
 	tasks := make([]*domain.Task, 0, len(s.tasks))
 	for _, task := range s.tasks {
-		tasks = append(tasks, task)
+		if filter.Match(task) {
+			tasks = append(tasks, cloneTask(task))
+		}
 	}
-	
+
 	// 简单分页
 	if offset >= len(tasks) {
 		return []*domain.Task{}, nil
@@ -101,3 +152,61 @@ func (s *MemoryTaskStore) List(ctx context.Context, limit, offset int) ([]*domai
 	}
 	return tasks[offset:end], nil
 }
+
+// cloneTask 复制任务，标签、计划、结果与待批准步骤连同其中的切片一并复制，
+// 存储中的任务与调用方持有的任务互不影响。认证、LLM 与输出配置创建后不再修改，仍然共享
+func cloneTask(task *domain.Task) *domain.Task {
+	c := *task
+	c.Tags = slices.Clone(task.Tags)
+	c.Plan = clonePlan(task.Plan)
+	c.Result = cloneResult(task.Result)
+	if task.PendingApproval != nil {
+		p := *task.PendingApproval
+		c.PendingApproval = &p
+	}
+	if task.CompletedAt != nil {
+		t := *task.CompletedAt
+		c.CompletedAt = &t
+	}
+	return &c
+}
+
+func clonePlan(plan *domain.TaskPlan) *domain.TaskPlan {
+	if plan == nil {
+		return nil
+	}
+	c := *plan
+	c.Steps = slices.Clone(plan.Steps)
+	for i := range c.Steps {
+		step := &c.Steps[i]
+		if step.FullPage != nil {
+			v := *step.FullPage
+			step.FullPage = &v
+		}
+		if step.Clip != nil {
+			r := *step.Clip
+			step.Clip = &r
+		}
+	}
+	return &c
+}
+
+func cloneResult(result *domain.TaskResult) *domain.TaskResult {
+	if result == nil {
+		return nil
+	}
+	c := *result
+	c.Steps = slices.Clone(result.Steps)
+	for i := range c.Steps {
+		if shot := c.Steps[i].Screenshot; shot != nil {
+			s := *shot
+			c.Steps[i].Screenshot = &s
+		}
+	}
+	c.Screenshots = slices.Clone(result.Screenshots)
+	c.Documents = slices.Clone(result.Documents)
+	c.Downloads = slices.Clone(result.Downloads)
+	c.Attempts = slices.Clone(result.Attempts)
+	c.Data = maps.Clone(result.Data)
+	return &c
+}
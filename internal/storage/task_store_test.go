@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/browser-automation/internal/domain"
+)
+
+func TestMemoryTaskStoreRejectsStaleUpdateAfterCancel(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTaskStore()
+	task := &domain.Task{ID: "t1", Status: domain.TaskStatusRunning}
+	if err := store.Create(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateStatus(ctx, "t1", domain.TaskStatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	// 执行协程仍持有 running 状态的任务，延迟写入不能覆盖取消
+	task.Progress = 80
+	if err := store.Update(ctx, task); !errors.Is(err, ErrTaskFinished) {
+		t.Fatalf("stale Update err = %v, want ErrTaskFinished", err)
+	}
+	if got, _ := store.Get(ctx, "t1"); got.Status != domain.TaskStatusCancelled || got.Progress != 0 {
+		t.Errorf("stored task = %s %d%%, want untouched cancelled task", got.Status, got.Progress)
+	}
+
+	// 保持终态的写入（如补充结果）与显式强制更新仍然允许
+	cancelled := &domain.Task{ID: "t1", Status: domain.TaskStatusCancelled, ErrorMessage: "cancelled by user"}
+	if err := store.Update(ctx, cancelled); err != nil {
+		t.Errorf("Update keeping the terminal status: %v", err)
+	}
+	task.Status = domain.TaskStatusRunning
+	if err := store.ForceUpdate(ctx, task); err != nil {
+		t.Errorf("ForceUpdate: %v", err)
+	}
+	if err := store.UpdateStatus(ctx, "missing", domain.TaskStatusFailed); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateStatus on missing task err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryTaskStoreReturnsIndependentCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTaskStore()
+	fullPage := true
+	task := &domain.Task{
+		ID:     "t1",
+		Status: domain.TaskStatusRunning,
+		Tags:   []string{"a"},
+		Plan:   &domain.TaskPlan{Steps: []domain.PlanStep{{Order: 1, FullPage: &fullPage}}},
+		Result: &domain.TaskResult{
+			Steps:    []domain.StepResult{{Order: 1, Success: true, Screenshot: &domain.Screenshot{ID: "s1"}}},
+			Attempts: []domain.TaskAttempt{{Attempt: 1}},
+			Data:     map[string]string{"k": "v"},
+		},
+	}
+	if err := store.Create(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	// 调用方在保存后继续修改自己的任务
+	task.Tags[0] = "changed"
+	task.Result.Steps[0].Success = false
+	task.Result.Attempts = append(task.Result.Attempts, domain.TaskAttempt{Attempt: 2})
+	task.Result.Data["k"] = "changed"
+	*task.Plan.Steps[0].FullPage = false
+
+	got, err := store.Get(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 读取方修改返回的任务
+	got.Result.Steps[0].Screenshot.ID = "changed"
+
+	again, _ := store.Get(ctx, "t1")
+	switch {
+	case again.Tags[0] != "a":
+		t.Errorf("tags = %v, shared with the caller", again.Tags)
+	case !again.Result.Steps[0].Success:
+		t.Error("step result shared with the caller")
+	case len(again.Result.Attempts) != 1:
+		t.Errorf("attempts = %d, shared with the caller", len(again.Result.Attempts))
+	case again.Result.Data["k"] != "v":
+		t.Error("result data shared with the caller")
+	case !*again.Plan.Steps[0].FullPage:
+		t.Error("plan step shared with the caller")
+	case again.Result.Steps[0].Screenshot.ID != "s1":
+		t.Error("step screenshot shared between readers")
+	}
+}
+
+func TestMemoryTaskStoreKeepsEmptySlices(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTaskStore()
+	store.Create(ctx, &domain.Task{ID: "t1", Result: &domain.TaskResult{Steps: []domain.StepResult{}}})
+	got, _ := store.Get(ctx, "t1")
+	if got.Result.Steps == nil {
+		t.Error("empty steps became nil and would encode as null")
+	}
+}
+
+func TestMemoryTaskStoreFiltersByTags(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTaskStore()
+	for id, tags := range map[string][]string{
+		"billing-guide": {"billing", "guide"},
+		"billing":       {"billing"},
+		"guide":         {"guide"},
+		"untagged":      nil,
+	} {
+		store.Create(ctx, &domain.Task{ID: id, Tags: tags})
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"no filter", nil, []string{"billing", "billing-guide", "guide", "untagged"}},
+		{"single tag", []string{"billing"}, []string{"billing", "billing-guide"}},
+		{"all tags required", []string{"billing", "guide"}, []string{"billing-guide"}},
+		{"unknown tag", []string{"missing"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := store.List(ctx, TaskFilter{Tags: tt.tags}, 100, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, task := range tasks {
+				got = append(got, task.ID)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("tasks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}